@@ -10,9 +10,14 @@ echo "开始编译 Linux x86_64 版本..."
 export GOOS=linux
 export GOARCH=amd64
 
+# 版本信息：git describe 和提交哈希（不在 git 仓库中时为 dev / unknown）
+VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+COMMIT=$(git rev-parse HEAD 2>/dev/null || echo unknown)
+
 # 编译
-go build -o cpumembusy .
+go build -ldflags "-X cpumembusy/pkg/busy.Version=${VERSION} -X cpumembusy/pkg/busy.Commit=${COMMIT}" -o cpumembusy .
 
 echo "编译完成！"
+echo "版本: ${VERSION} (${COMMIT})"
 echo "输出文件: ./cpumembusy"
 
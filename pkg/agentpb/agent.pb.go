@@ -0,0 +1,546 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: agent.proto
+
+// agent 的 gRPC 接口：controller 通过它向每台主机下发峰值，监控工具可以订阅实时采样
+
+package agentpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_agent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{0}
+}
+
+// Status agent 当前状态（与 HTTP 接口 GET /status 的主要字段相同）
+type Status struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PeakUsageOrigin   int32                  `protobuf:"varint,1,opt,name=peak_usage_origin,json=peakUsageOrigin,proto3" json:"peak_usage_origin,omitempty"`
+	PeakUsage         int32                  `protobuf:"varint,2,opt,name=peak_usage,json=peakUsage,proto3" json:"peak_usage,omitempty"`
+	ExpectedUsage     float64                `protobuf:"fixed64,3,opt,name=expected_usage,json=expectedUsage,proto3" json:"expected_usage,omitempty"`
+	IsNightTime       bool                   `protobuf:"varint,4,opt,name=is_night_time,json=isNightTime,proto3" json:"is_night_time,omitempty"`
+	CpuPercent        float64                `protobuf:"fixed64,5,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent     float64                `protobuf:"fixed64,6,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	TotalMemory       uint64                 `protobuf:"varint,7,opt,name=total_memory,json=totalMemory,proto3" json:"total_memory,omitempty"`
+	BufferMemoryBytes uint64                 `protobuf:"varint,8,opt,name=buffer_memory_bytes,json=bufferMemoryBytes,proto3" json:"buffer_memory_bytes,omitempty"`
+	CpuCount          uint64                 `protobuf:"varint,9,opt,name=cpu_count,json=cpuCount,proto3" json:"cpu_count,omitempty"`
+	SelfCpuPercent    float64                `protobuf:"fixed64,10,opt,name=self_cpu_percent,json=selfCpuPercent,proto3" json:"self_cpu_percent,omitempty"`
+	SelfMemoryPercent float64                `protobuf:"fixed64,11,opt,name=self_memory_percent,json=selfMemoryPercent,proto3" json:"self_memory_percent,omitempty"`
+	// peakUsage 当前是否由 controller 托管（托管期间不再本地随机更新）
+	Managed       bool                   `protobuf:"varint,12,opt,name=managed,proto3" json:"managed,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Status) Reset() {
+	*x = Status{}
+	mi := &file_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Status) GetPeakUsageOrigin() int32 {
+	if x != nil {
+		return x.PeakUsageOrigin
+	}
+	return 0
+}
+
+func (x *Status) GetPeakUsage() int32 {
+	if x != nil {
+		return x.PeakUsage
+	}
+	return 0
+}
+
+func (x *Status) GetExpectedUsage() float64 {
+	if x != nil {
+		return x.ExpectedUsage
+	}
+	return 0
+}
+
+func (x *Status) GetIsNightTime() bool {
+	if x != nil {
+		return x.IsNightTime
+	}
+	return false
+}
+
+func (x *Status) GetCpuPercent() float64 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *Status) GetMemoryPercent() float64 {
+	if x != nil {
+		return x.MemoryPercent
+	}
+	return 0
+}
+
+func (x *Status) GetTotalMemory() uint64 {
+	if x != nil {
+		return x.TotalMemory
+	}
+	return 0
+}
+
+func (x *Status) GetBufferMemoryBytes() uint64 {
+	if x != nil {
+		return x.BufferMemoryBytes
+	}
+	return 0
+}
+
+func (x *Status) GetCpuCount() uint64 {
+	if x != nil {
+		return x.CpuCount
+	}
+	return 0
+}
+
+func (x *Status) GetSelfCpuPercent() float64 {
+	if x != nil {
+		return x.SelfCpuPercent
+	}
+	return 0
+}
+
+func (x *Status) GetSelfMemoryPercent() float64 {
+	if x != nil {
+		return x.SelfMemoryPercent
+	}
+	return 0
+}
+
+func (x *Status) GetManaged() bool {
+	if x != nil {
+		return x.Managed
+	}
+	return false
+}
+
+func (x *Status) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// SetPeakRequest controller 下发的峰值设置（1-100）
+type SetPeakRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PeakUsageOrigin int32                  `protobuf:"varint,1,opt,name=peak_usage_origin,json=peakUsageOrigin,proto3" json:"peak_usage_origin,omitempty"`
+	PeakUsage       int32                  `protobuf:"varint,2,opt,name=peak_usage,json=peakUsage,proto3" json:"peak_usage,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SetPeakRequest) Reset() {
+	*x = SetPeakRequest{}
+	mi := &file_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPeakRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPeakRequest) ProtoMessage() {}
+
+func (x *SetPeakRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPeakRequest.ProtoReflect.Descriptor instead.
+func (*SetPeakRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SetPeakRequest) GetPeakUsageOrigin() int32 {
+	if x != nil {
+		return x.PeakUsageOrigin
+	}
+	return 0
+}
+
+func (x *SetPeakRequest) GetPeakUsage() int32 {
+	if x != nil {
+		return x.PeakUsage
+	}
+	return 0
+}
+
+type WatchSamplesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 不为空时先补发该时间之后的历史记录
+	Since         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchSamplesRequest) Reset() {
+	*x = WatchSamplesRequest{}
+	mi := &file_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchSamplesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchSamplesRequest) ProtoMessage() {}
+
+func (x *WatchSamplesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchSamplesRequest.ProtoReflect.Descriptor instead.
+func (*WatchSamplesRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WatchSamplesRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+// Sample 一个监控周期的采样值和决定（与 HTTP 接口 GET /history 的元素相同）
+type Sample struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Time              *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	PeakUsage         int32                  `protobuf:"varint,2,opt,name=peak_usage,json=peakUsage,proto3" json:"peak_usage,omitempty"`
+	ExpectedUsage     float64                `protobuf:"fixed64,3,opt,name=expected_usage,json=expectedUsage,proto3" json:"expected_usage,omitempty"`
+	CpuPercent        float64                `protobuf:"fixed64,4,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryPercent     float64                `protobuf:"fixed64,5,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	SelfCpuPercent    float64                `protobuf:"fixed64,6,opt,name=self_cpu_percent,json=selfCpuPercent,proto3" json:"self_cpu_percent,omitempty"`
+	SelfMemoryPercent float64                `protobuf:"fixed64,7,opt,name=self_memory_percent,json=selfMemoryPercent,proto3" json:"self_memory_percent,omitempty"`
+	CpuCount          uint64                 `protobuf:"varint,8,opt,name=cpu_count,json=cpuCount,proto3" json:"cpu_count,omitempty"`
+	BufferBytes       uint64                 `protobuf:"varint,9,opt,name=buffer_bytes,json=bufferBytes,proto3" json:"buffer_bytes,omitempty"`
+	// 停止文件存在，本周期未调整
+	KillSwitch bool `protobuf:"varint,10,opt,name=kill_switch,json=killSwitch,proto3" json:"kill_switch,omitempty"`
+	// 资源名称 → 决定（与调整日志的后缀相同，如 "0.6-增加"、"强制-减少"、"跳过"）
+	Decisions     map[string]string `protobuf:"bytes,11,rep,name=decisions,proto3" json:"decisions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sample) Reset() {
+	*x = Sample{}
+	mi := &file_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sample) ProtoMessage() {}
+
+func (x *Sample) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sample.ProtoReflect.Descriptor instead.
+func (*Sample) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Sample) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Sample) GetPeakUsage() int32 {
+	if x != nil {
+		return x.PeakUsage
+	}
+	return 0
+}
+
+func (x *Sample) GetExpectedUsage() float64 {
+	if x != nil {
+		return x.ExpectedUsage
+	}
+	return 0
+}
+
+func (x *Sample) GetCpuPercent() float64 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *Sample) GetMemoryPercent() float64 {
+	if x != nil {
+		return x.MemoryPercent
+	}
+	return 0
+}
+
+func (x *Sample) GetSelfCpuPercent() float64 {
+	if x != nil {
+		return x.SelfCpuPercent
+	}
+	return 0
+}
+
+func (x *Sample) GetSelfMemoryPercent() float64 {
+	if x != nil {
+		return x.SelfMemoryPercent
+	}
+	return 0
+}
+
+func (x *Sample) GetCpuCount() uint64 {
+	if x != nil {
+		return x.CpuCount
+	}
+	return 0
+}
+
+func (x *Sample) GetBufferBytes() uint64 {
+	if x != nil {
+		return x.BufferBytes
+	}
+	return 0
+}
+
+func (x *Sample) GetKillSwitch() bool {
+	if x != nil {
+		return x.KillSwitch
+	}
+	return false
+}
+
+func (x *Sample) GetDecisions() map[string]string {
+	if x != nil {
+		return x.Decisions
+	}
+	return nil
+}
+
+var File_agent_proto protoreflect.FileDescriptor
+
+const file_agent_proto_rawDesc = "" +
+	"\n" +
+	"\vagent.proto\x12\x13cpumembusy.agent.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x12\n" +
+	"\x10GetStatusRequest\"\x85\x04\n" +
+	"\x06Status\x12*\n" +
+	"\x11peak_usage_origin\x18\x01 \x01(\x05R\x0fpeakUsageOrigin\x12\x1d\n" +
+	"\n" +
+	"peak_usage\x18\x02 \x01(\x05R\tpeakUsage\x12%\n" +
+	"\x0eexpected_usage\x18\x03 \x01(\x01R\rexpectedUsage\x12\"\n" +
+	"\ris_night_time\x18\x04 \x01(\bR\visNightTime\x12\x1f\n" +
+	"\vcpu_percent\x18\x05 \x01(\x01R\n" +
+	"cpuPercent\x12%\n" +
+	"\x0ememory_percent\x18\x06 \x01(\x01R\rmemoryPercent\x12!\n" +
+	"\ftotal_memory\x18\a \x01(\x04R\vtotalMemory\x12.\n" +
+	"\x13buffer_memory_bytes\x18\b \x01(\x04R\x11bufferMemoryBytes\x12\x1b\n" +
+	"\tcpu_count\x18\t \x01(\x04R\bcpuCount\x12(\n" +
+	"\x10self_cpu_percent\x18\n" +
+	" \x01(\x01R\x0eselfCpuPercent\x12.\n" +
+	"\x13self_memory_percent\x18\v \x01(\x01R\x11selfMemoryPercent\x12\x18\n" +
+	"\amanaged\x18\f \x01(\bR\amanaged\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"[\n" +
+	"\x0eSetPeakRequest\x12*\n" +
+	"\x11peak_usage_origin\x18\x01 \x01(\x05R\x0fpeakUsageOrigin\x12\x1d\n" +
+	"\n" +
+	"peak_usage\x18\x02 \x01(\x05R\tpeakUsage\"G\n" +
+	"\x13WatchSamplesRequest\x120\n" +
+	"\x05since\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\"\x89\x04\n" +
+	"\x06Sample\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1d\n" +
+	"\n" +
+	"peak_usage\x18\x02 \x01(\x05R\tpeakUsage\x12%\n" +
+	"\x0eexpected_usage\x18\x03 \x01(\x01R\rexpectedUsage\x12\x1f\n" +
+	"\vcpu_percent\x18\x04 \x01(\x01R\n" +
+	"cpuPercent\x12%\n" +
+	"\x0ememory_percent\x18\x05 \x01(\x01R\rmemoryPercent\x12(\n" +
+	"\x10self_cpu_percent\x18\x06 \x01(\x01R\x0eselfCpuPercent\x12.\n" +
+	"\x13self_memory_percent\x18\a \x01(\x01R\x11selfMemoryPercent\x12\x1b\n" +
+	"\tcpu_count\x18\b \x01(\x04R\bcpuCount\x12!\n" +
+	"\fbuffer_bytes\x18\t \x01(\x04R\vbufferBytes\x12\x1f\n" +
+	"\vkill_switch\x18\n" +
+	" \x01(\bR\n" +
+	"killSwitch\x12H\n" +
+	"\tdecisions\x18\v \x03(\v2*.cpumembusy.agent.v1.Sample.DecisionsEntryR\tdecisions\x1a<\n" +
+	"\x0eDecisionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xfe\x01\n" +
+	"\x05Agent\x12O\n" +
+	"\tGetStatus\x12%.cpumembusy.agent.v1.GetStatusRequest\x1a\x1b.cpumembusy.agent.v1.Status\x12K\n" +
+	"\aSetPeak\x12#.cpumembusy.agent.v1.SetPeakRequest\x1a\x1b.cpumembusy.agent.v1.Status\x12W\n" +
+	"\fWatchSamples\x12(.cpumembusy.agent.v1.WatchSamplesRequest\x1a\x1b.cpumembusy.agent.v1.Sample0\x01B\x18Z\x16cpumembusy/pkg/agentpbb\x06proto3"
+
+var (
+	file_agent_proto_rawDescOnce sync.Once
+	file_agent_proto_rawDescData []byte
+)
+
+func file_agent_proto_rawDescGZIP() []byte {
+	file_agent_proto_rawDescOnce.Do(func() {
+		file_agent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_agent_proto_rawDesc), len(file_agent_proto_rawDesc)))
+	})
+	return file_agent_proto_rawDescData
+}
+
+var file_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_agent_proto_goTypes = []any{
+	(*GetStatusRequest)(nil),      // 0: cpumembusy.agent.v1.GetStatusRequest
+	(*Status)(nil),                // 1: cpumembusy.agent.v1.Status
+	(*SetPeakRequest)(nil),        // 2: cpumembusy.agent.v1.SetPeakRequest
+	(*WatchSamplesRequest)(nil),   // 3: cpumembusy.agent.v1.WatchSamplesRequest
+	(*Sample)(nil),                // 4: cpumembusy.agent.v1.Sample
+	nil,                           // 5: cpumembusy.agent.v1.Sample.DecisionsEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_agent_proto_depIdxs = []int32{
+	6, // 0: cpumembusy.agent.v1.Status.updated_at:type_name -> google.protobuf.Timestamp
+	6, // 1: cpumembusy.agent.v1.WatchSamplesRequest.since:type_name -> google.protobuf.Timestamp
+	6, // 2: cpumembusy.agent.v1.Sample.time:type_name -> google.protobuf.Timestamp
+	5, // 3: cpumembusy.agent.v1.Sample.decisions:type_name -> cpumembusy.agent.v1.Sample.DecisionsEntry
+	0, // 4: cpumembusy.agent.v1.Agent.GetStatus:input_type -> cpumembusy.agent.v1.GetStatusRequest
+	2, // 5: cpumembusy.agent.v1.Agent.SetPeak:input_type -> cpumembusy.agent.v1.SetPeakRequest
+	3, // 6: cpumembusy.agent.v1.Agent.WatchSamples:input_type -> cpumembusy.agent.v1.WatchSamplesRequest
+	1, // 7: cpumembusy.agent.v1.Agent.GetStatus:output_type -> cpumembusy.agent.v1.Status
+	1, // 8: cpumembusy.agent.v1.Agent.SetPeak:output_type -> cpumembusy.agent.v1.Status
+	4, // 9: cpumembusy.agent.v1.Agent.WatchSamples:output_type -> cpumembusy.agent.v1.Sample
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_agent_proto_init() }
+func file_agent_proto_init() {
+	if File_agent_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agent_proto_rawDesc), len(file_agent_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_agent_proto_goTypes,
+		DependencyIndexes: file_agent_proto_depIdxs,
+		MessageInfos:      file_agent_proto_msgTypes,
+	}.Build()
+	File_agent_proto = out.File
+	file_agent_proto_goTypes = nil
+	file_agent_proto_depIdxs = nil
+}
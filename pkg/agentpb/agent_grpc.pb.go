@@ -0,0 +1,219 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: agent.proto
+
+// agent 的 gRPC 接口：controller 通过它向每台主机下发峰值，监控工具可以订阅实时采样
+
+package agentpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Agent_GetStatus_FullMethodName    = "/cpumembusy.agent.v1.Agent/GetStatus"
+	Agent_SetPeak_FullMethodName      = "/cpumembusy.agent.v1.Agent/SetPeak"
+	Agent_WatchSamples_FullMethodName = "/cpumembusy.agent.v1.Agent/WatchSamples"
+)
+
+// AgentClient is the client API for Agent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Agent 每台主机上的 agent 提供的服务（AGENT_GRPC_LISTEN）
+// 设置 AGENT_READ_TOKEN / AGENT_ADMIN_TOKEN 时需要在 metadata 中携带 authorization: Bearer <token>，
+// SetPeak 需要管理令牌，其他方法只读令牌即可
+type AgentClient interface {
+	// GetStatus 返回最近一次监控周期的状态
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error)
+	// SetPeak 下发峰值设置，返回当前状态
+	// 超出范围时返回 INVALID_ARGUMENT，修改过于频繁（PEAK_UPDATE_COOLDOWN）时返回 RESOURCE_EXHAUSTED
+	SetPeak(ctx context.Context, in *SetPeakRequest, opts ...grpc.CallOption) (*Status, error)
+	// WatchSamples 实时推送每个监控周期的采样值和决定，客户端读取过慢时丢弃新的记录
+	WatchSamples(ctx context.Context, in *WatchSamplesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Sample], error)
+}
+
+type agentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAgentClient(cc grpc.ClientConnInterface) AgentClient {
+	return &agentClient{cc}
+}
+
+func (c *agentClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, Agent_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) SetPeak(ctx context.Context, in *SetPeakRequest, opts ...grpc.CallOption) (*Status, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Status)
+	err := c.cc.Invoke(ctx, Agent_SetPeak_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentClient) WatchSamples(ctx context.Context, in *WatchSamplesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Sample], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Agent_ServiceDesc.Streams[0], Agent_WatchSamples_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchSamplesRequest, Sample]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_WatchSamplesClient = grpc.ServerStreamingClient[Sample]
+
+// AgentServer is the server API for Agent service.
+// All implementations must embed UnimplementedAgentServer
+// for forward compatibility.
+//
+// Agent 每台主机上的 agent 提供的服务（AGENT_GRPC_LISTEN）
+// 设置 AGENT_READ_TOKEN / AGENT_ADMIN_TOKEN 时需要在 metadata 中携带 authorization: Bearer <token>，
+// SetPeak 需要管理令牌，其他方法只读令牌即可
+type AgentServer interface {
+	// GetStatus 返回最近一次监控周期的状态
+	GetStatus(context.Context, *GetStatusRequest) (*Status, error)
+	// SetPeak 下发峰值设置，返回当前状态
+	// 超出范围时返回 INVALID_ARGUMENT，修改过于频繁（PEAK_UPDATE_COOLDOWN）时返回 RESOURCE_EXHAUSTED
+	SetPeak(context.Context, *SetPeakRequest) (*Status, error)
+	// WatchSamples 实时推送每个监控周期的采样值和决定，客户端读取过慢时丢弃新的记录
+	WatchSamples(*WatchSamplesRequest, grpc.ServerStreamingServer[Sample]) error
+	mustEmbedUnimplementedAgentServer()
+}
+
+// UnimplementedAgentServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAgentServer struct{}
+
+func (UnimplementedAgentServer) GetStatus(context.Context, *GetStatusRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedAgentServer) SetPeak(context.Context, *SetPeakRequest) (*Status, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPeak not implemented")
+}
+func (UnimplementedAgentServer) WatchSamples(*WatchSamplesRequest, grpc.ServerStreamingServer[Sample]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchSamples not implemented")
+}
+func (UnimplementedAgentServer) mustEmbedUnimplementedAgentServer() {}
+func (UnimplementedAgentServer) testEmbeddedByValue()               {}
+
+// UnsafeAgentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentServer will
+// result in compilation errors.
+type UnsafeAgentServer interface {
+	mustEmbedUnimplementedAgentServer()
+}
+
+func RegisterAgentServer(s grpc.ServiceRegistrar, srv AgentServer) {
+	// If the following call pancis, it indicates UnimplementedAgentServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Agent_ServiceDesc, srv)
+}
+
+func _Agent_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_SetPeak_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPeakRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).SetPeak(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Agent_SetPeak_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).SetPeak(ctx, req.(*SetPeakRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Agent_WatchSamples_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchSamplesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServer).WatchSamples(m, &grpc.GenericServerStream[WatchSamplesRequest, Sample]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Agent_WatchSamplesServer = grpc.ServerStreamingServer[Sample]
+
+// Agent_ServiceDesc is the grpc.ServiceDesc for Agent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Agent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cpumembusy.agent.v1.Agent",
+	HandlerType: (*AgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _Agent_GetStatus_Handler,
+		},
+		{
+			MethodName: "SetPeak",
+			Handler:    _Agent_SetPeak_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchSamples",
+			Handler:       _Agent_WatchSamples_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "agent.proto",
+}
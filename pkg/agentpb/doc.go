@@ -0,0 +1,5 @@
+// Package agentpb agent 的 gRPC 服务定义（agent.proto）和生成的代码
+// controller 通过 AgentClient 向 agent 下发峰值，其他工具也可以直接使用生成的客户端
+package agentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agent.proto
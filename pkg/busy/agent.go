@@ -0,0 +1,280 @@
+package busy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// managedTimeout 收到 controller 下发后，在该时间内不再本地随机更新 peakUsage
+const managedTimeout = 10 * time.Minute
+
+// AgentStatus agent 当前状态（GET /status 返回）
+type AgentStatus struct {
+	PeakUsageOrigin    int               `json:"peak_usage_origin"`
+	PeakUsage          int               `json:"peak_usage"`
+	ExpectedUsage      float64           `json:"expected_usage"`
+	IsNightTime        bool              `json:"is_night_time"`
+	CPUPercent         float64           `json:"cpu_percent"`
+	MemoryPercent      float64           `json:"memory_percent"`
+	TotalMemory        uint64            `json:"total_memory"`
+	CurrentMemoryBytes uint64            `json:"current_memory_bytes"`
+	BufferMemoryBytes  uint64            `json:"buffer_memory_bytes"`
+	CPUCount           uint64            `json:"cpu_count"`
+	DiskPercent        float64           `json:"disk_percent"`
+	CPUTemp            float64           `json:"cpu_temp"`
+	CPUFreqMHz         float64           `json:"cpu_freq_mhz"`
+	StealPercent       float64           `json:"steal_percent"`
+	ReclaimScanRate    float64           `json:"reclaim_scan_per_sec"`   // 内核回收内存时每秒扫描的页数
+	DirectReclaimRate  float64           `json:"direct_reclaim_per_sec"` // 同步回收每秒扫描的页数
+	SelfCPUPercent     float64           `json:"self_cpu_percent"`
+	OtherCPUPercent    float64           `json:"other_cpu_percent"`
+	SelfMemoryPercent  float64           `json:"self_memory_percent"`
+	OtherMemoryPercent float64           `json:"other_memory_percent"`
+	SelfMemoryBytes    uint64            `json:"self_memory_bytes"`
+	LoadCPUPercent     float64           `json:"load_cpu_percent"`
+	OverheadCPUPercent float64           `json:"overhead_cpu_percent"`
+	GCCPUPercent       float64           `json:"gc_cpu_percent"`
+	OverheadMemory     uint64            `json:"overhead_memory_bytes"`
+	PerCPU             []float64         `json:"per_cpu"`
+	Managed            bool              `json:"managed"`
+	Budget             *BudgetStatus     `json:"budget,omitempty"`      // 每日资源预算的使用情况（没有设置预算时省略）
+	GridSignal         *GridSignalStatus `json:"grid_signal,omitempty"` // 电网碳强度 / 电价信号（没有设置 GRID_SIGNAL_URL 时省略）
+	CPUCredits         *CPUCreditStatus  `json:"cpu_credits,omitempty"` // 可突增性能实例的 CPU 积分（没有设置 CPU_CREDIT_FLOOR 时省略）
+	OOM                *OOMStatus        `json:"oom,omitempty"`         // OOM 事件的统计（没有设置 OOM_WATCH 时省略）
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+// PeakUpdate controller 下发的峰值设置（POST /peak 请求体）
+type PeakUpdate struct {
+	PeakUsageOrigin int `json:"peak_usage_origin"`
+	PeakUsage       int `json:"peak_usage"`
+}
+
+var (
+	agentStatus   AgentStatus  // 最近一次监控周期的状态
+	agentStatusMu sync.RWMutex // 保护 agentStatus
+	peakManagedAt time.Time    // 最近一次收到 controller 下发的时间（由 peakUsageMu 保护）
+)
+
+// startAgentServer 启动 agent 的 HTTP 接口（设置证书时使用 HTTPS，设置令牌时需要认证）
+// 监听非本机地址时必须设置令牌和证书（见 checkAgentExposure）
+func startAgentServer(addr string) (*http.Server, error) {
+	tlsConfig, err := agentTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var handler http.Handler = newAgentMux()
+	auth := newAgentAuth()
+	if err := checkAgentExposure(addr, auth, tlsConfig != nil); err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		handler = auth.wrap(handler)
+	}
+
+	server := &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
+	go func() {
+		logger.Info("agent 接口启动", "listen", addr, "tls", tlsConfig != nil, "auth", auth != nil)
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("agent 接口退出", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// newAgentMux agent 接口的路由（HTTP 接口和控制 socket 共用）
+func newAgentMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", handleStatus)
+	mux.HandleFunc("/peak", handlePeak)
+	mux.HandleFunc("/metrics", handleMetrics)
+	mux.HandleFunc("/fd", handleFD)
+	mux.HandleFunc("/threads", handleThreads)
+	mux.HandleFunc("/version", handleVersion)
+	mux.HandleFunc("/history", handleHistory)
+	mux.HandleFunc("/stream", handleStream)
+	mux.HandleFunc("/internals", handleInternals)
+	return mux
+}
+
+// handleVersion 返回版本和构建信息
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, GetBuildInfo())
+}
+
+// setAgentStatus 保存最近一次监控周期的状态
+func setAgentStatus(status AgentStatus) {
+	agentStatusMu.Lock()
+	defer agentStatusMu.Unlock()
+	agentStatus = status
+}
+
+// getAgentStatus 获取最近一次监控周期的状态
+func getAgentStatus() AgentStatus {
+	agentStatusMu.RLock()
+	defer agentStatusMu.RUnlock()
+	return agentStatus
+}
+
+// handleStatus 返回 agent 当前状态
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, getAgentStatus())
+}
+
+// handlePeak 接收 controller 下发的峰值设置
+func handlePeak(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var update PeakUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := applyPeakUpdate(update, httpCaller(r)); err != nil {
+		var limited *PeakRateLimitError
+		if errors.As(err, &limited) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(limited.RetryAfter.Seconds()+0.999)))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, getAgentStatus())
+}
+
+// FDUpdate 文件描述符控制器目标值（POST /fd 请求体，GET /fd 返回当前值）
+type FDUpdate struct {
+	FDCount    int `json:"fd_count"`
+	InodeCount int `json:"inode_count"`
+}
+
+// handleFD 查询或调整文件描述符控制器的目标值
+func handleFD(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var update FDUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := fdController.SetTargets(update.FDCount, update.InodeCount); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Info("文件描述符控制器目标值已更新", "fd_count", update.FDCount, "inode_count", update.InodeCount, "caller", httpCaller(r))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fds, inodes := fdController.GetCounts()
+	writeJSON(w, FDUpdate{FDCount: fds, InodeCount: inodes})
+}
+
+// ThreadUpdate 线程控制器目标值（POST /threads 请求体，GET /threads 返回当前值）
+type ThreadUpdate struct {
+	ThreadCount    int `json:"thread_count"`
+	GoroutineCount int `json:"goroutine_count"`
+}
+
+// handleThreads 查询或调整线程控制器的目标值
+func handleThreads(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var update ThreadUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := threadController.SetTargets(update.ThreadCount, update.GoroutineCount); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Info("线程控制器目标值已更新", "thread_count", update.ThreadCount, "goroutine_count", update.GoroutineCount, "caller", httpCaller(r))
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	threads, goroutines := threadController.GetCounts()
+	writeJSON(w, ThreadUpdate{ThreadCount: threads, GoroutineCount: goroutines})
+}
+
+// applyPeakUpdate 应用远程下发的峰值设置，caller 为调用方的身份（写入审计日志）
+// 实际改变峰值的请求受 peakLimiter 限制，与当前值相同的请求只刷新托管状态
+func applyPeakUpdate(update PeakUpdate, caller string) error {
+	if update.PeakUsageOrigin < 1 || update.PeakUsageOrigin > 100 {
+		return fmt.Errorf("peak_usage_origin 超出范围: %d", update.PeakUsageOrigin)
+	}
+	if update.PeakUsage < 1 || update.PeakUsage > 100 {
+		return fmt.Errorf("peak_usage 超出范围: %d", update.PeakUsage)
+	}
+
+	peakUsageMu.Lock()
+	defer peakUsageMu.Unlock()
+
+	oldPeakUsageOrigin, oldPeakUsage := peakUsageOrigin, peakUsage
+	newPeakUsageOrigin, newPeakUsage := max(update.PeakUsageOrigin, minPeakUsage), max(update.PeakUsage, minPeakUsage)
+	now := clock.Now()
+	if newPeakUsageOrigin == oldPeakUsageOrigin && newPeakUsage == oldPeakUsage {
+		peakManagedAt = now
+		logger.Debug("收到远程下发的 peakUsage，与当前值相同", "peak_usage", peakUsage, "caller", caller)
+		return nil
+	}
+	if err := peakLimiter.Allow(now); err != nil {
+		logger.Warn("拒绝远程修改 peakUsage", "caller", caller, "peak_usage_old", oldPeakUsage, "peak_usage_new", newPeakUsage, "error", err)
+		return err
+	}
+
+	peakUsageOrigin, peakUsage = newPeakUsageOrigin, newPeakUsage
+	peakManagedAt = now
+	logger.Info("远程修改 peakUsage",
+		"caller", caller,
+		"peak_usage_origin_old", oldPeakUsageOrigin,
+		"peak_usage_origin", peakUsageOrigin,
+		"peak_usage_old", oldPeakUsage,
+		"peak_usage_new", peakUsage)
+	emitEvent("peak_change", "caller", caller, "peak_usage_origin", peakUsageOrigin, "peak_usage_old", oldPeakUsage, "peak_usage_new", peakUsage)
+	return nil
+}
+
+// isPeakManaged 判断 peakUsage 当前是否由 controller 托管
+func isPeakManaged() bool {
+	peakUsageMu.RLock()
+	defer peakUsageMu.RUnlock()
+	return !peakManagedAt.IsZero() && clock.Now().Sub(peakManagedAt) < managedTimeout
+}
+
+// writeJSON 以 JSON 格式输出响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn("输出响应失败", "error", err)
+	}
+}
@@ -0,0 +1,176 @@
+package busy
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentAuth agent 接口的 bearer token 认证：只读令牌可以调用 GET / HEAD，管理令牌可以调用所有接口
+// 两个令牌都未设置时不认证（与之前的行为相同）；只设置了只读令牌时所有写入接口都被拒绝
+type agentAuth struct {
+	readToken  string
+	adminToken string
+}
+
+// newAgentAuth 从 AGENT_READ_TOKEN / AGENT_ADMIN_TOKEN 读取令牌（都未设置时返回 nil）
+func newAgentAuth() *agentAuth {
+	auth := &agentAuth{readToken: lookupEnv("AGENT_READ_TOKEN"), adminToken: lookupEnv("AGENT_ADMIN_TOKEN")}
+	if auth.readToken == "" && auth.adminToken == "" {
+		return nil
+	}
+	return auth
+}
+
+// wrap 在调用 next 之前校验令牌：缺少或错误的令牌返回 401，只读令牌调用写入接口返回 403
+func (a *agentAuth) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		admin := ok && tokenEqual(token, a.adminToken)
+		read := admin || ok && tokenEqual(token, a.readToken)
+		if !read {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cpumembusy"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !admin && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "forbidden: admin token required", http.StatusForbidden)
+			return
+		}
+		scope := "read"
+		if admin {
+			scope = "admin"
+		}
+		next.ServeHTTP(w, withAgentScope(r, scope))
+	})
+}
+
+// checkAgentExposure 监听非本机地址时要求认证，且令牌不能以明文 HTTP 传输：
+// 没有令牌时任何能访问该地址的人都可以调用 POST /peak、/fd、/threads，没有 TLS 时令牌可以被同一网络中的人截获
+// AGENT_INSECURE=1 时只记录警告（如只在受信任的内网中访问，或由反向代理负责 TLS）
+func checkAgentExposure(addr string, auth *agentAuth, useTLS bool) error {
+	if isLoopbackAddr(addr) {
+		return nil
+	}
+	var reason string
+	switch {
+	case auth == nil:
+		reason = "监听非本机地址时需要设置 AGENT_ADMIN_TOKEN"
+	case !useTLS:
+		reason = "监听非本机地址时令牌不能以明文 HTTP 传输，需要设置 AGENT_TLS_CERT / AGENT_TLS_KEY"
+	default:
+		return nil
+	}
+	if !getEnvBool("AGENT_INSECURE", false) {
+		return errors.New(reason + "（确实需要时设置 AGENT_INSECURE=1）")
+	}
+	logger.Warn("已设置 AGENT_INSECURE，agent 接口以不安全的方式监听", "listen", addr, "reason", reason)
+	return nil
+}
+
+// tokenEqual 以固定时间比较令牌（expected 为空时总是不相等）
+func tokenEqual(token, expected string) bool {
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// certReloader 从文件加载 TLS 证书，文件修改后在下一次握手时重新加载（证书轮换不需要重启）
+type certReloader struct {
+	certFile, keyFile string
+	mu                sync.Mutex
+	cert              *tls.Certificate
+	modTime           time.Time // 已加载的证书和私钥中较新的修改时间
+}
+
+// newCertReloader 加载证书，失败时返回错误（启动时就发现配置错误）
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	modTime, err := latestModTime(cr.certFile, cr.keyFile)
+	if err != nil {
+		if cr.cert != nil {
+			return cr.cert, nil // 轮换过程中文件暂时不存在时继续使用已加载的证书
+		}
+		return nil, err
+	}
+	if cr.cert != nil && modTime.Equal(cr.modTime) {
+		return cr.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		if cr.cert != nil {
+			logger.Warn("重新加载 TLS 证书失败，继续使用原证书", "cert", cr.certFile, "error", err)
+			cr.modTime = modTime
+			return cr.cert, nil
+		}
+		return nil, fmt.Errorf("加载 TLS 证书失败: %w", err)
+	}
+	if cr.cert != nil {
+		logger.Info("TLS 证书已重新加载", "cert", cr.certFile)
+	}
+	cr.cert, cr.modTime = &cert, modTime
+	return cr.cert, nil
+}
+
+// latestModTime 返回多个文件中最新的修改时间
+func latestModTime(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// agentTLSConfig 按 AGENT_TLS_CERT / AGENT_TLS_KEY 创建 agent 接口的 TLS 配置（都未设置时返回 nil）
+func agentTLSConfig() (*tls.Config, error) {
+	certFile, keyFile := lookupEnv("AGENT_TLS_CERT"), lookupEnv("AGENT_TLS_KEY")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("AGENT_TLS_CERT 和 AGENT_TLS_KEY 需要同时设置")
+	}
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: reloader.GetCertificate}, nil
+}
+
+// agentClientTLSConfig controller 连接 https:// agent 时使用的 TLS 配置：设置 AGENT_TLS_CA 时只信任该 CA
+func agentClientTLSConfig() (*tls.Config, error) {
+	path := lookupEnv("AGENT_TLS_CA")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s 中没有 PEM 格式的证书", path)
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool}, nil
+}
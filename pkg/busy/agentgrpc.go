@@ -0,0 +1,281 @@
+package busy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cpumembusy/pkg/agentpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// agentAdminMethods 需要管理令牌的 gRPC 方法（其他方法只读令牌即可）
+var agentAdminMethods = map[string]bool{
+	agentpb.Agent_SetPeak_FullMethodName: true,
+}
+
+// agentGRPCServer 实现 agentpb.AgentServer
+type agentGRPCServer struct {
+	agentpb.UnimplementedAgentServer
+}
+
+// startAgentGRPCServer 启动 agent 的 gRPC 接口，证书、令牌和监听非本机地址的要求与 HTTP 接口相同
+func startAgentGRPCServer(addr string) (*grpc.Server, error) {
+	tlsConfig, err := agentTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	auth := newAgentAuth()
+	if err := checkAgentExposure(addr, auth, tlsConfig != nil); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := newAgentGRPCServer(auth, opts...)
+	go func() {
+		logger.Info("agent gRPC 接口启动", "listen", ln.Addr().String(), "tls", tlsConfig != nil, "auth", auth != nil)
+		if err := server.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			logger.Error("agent gRPC 接口退出", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// newAgentGRPCServer 创建注册了 Agent 服务的 gRPC 服务端，auth 不为 nil 时校验令牌
+func newAgentGRPCServer(auth *agentAuth, opts ...grpc.ServerOption) *grpc.Server {
+	if auth != nil {
+		opts = append(opts, grpc.ChainUnaryInterceptor(auth.unaryInterceptor), grpc.ChainStreamInterceptor(auth.streamInterceptor))
+	}
+	server := grpc.NewServer(opts...)
+	agentpb.RegisterAgentServer(server, &agentGRPCServer{})
+	return server
+}
+
+// authorize 校验 metadata 中的令牌，返回令牌权限（admin 或 read）
+// 缺少或错误的令牌返回 UNAUTHENTICATED，只读令牌调用需要管理权限的方法返回 PERMISSION_DENIED
+func (a *agentAuth) authorize(ctx context.Context, method string) (string, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+	admin := tokenEqual(token, a.adminToken)
+	if !admin && !tokenEqual(token, a.readToken) {
+		return "", status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	if !admin && agentAdminMethods[method] {
+		return "", status.Error(codes.PermissionDenied, "admin token required")
+	}
+	if admin {
+		return "admin", nil
+	}
+	return "read", nil
+}
+
+func (a *agentAuth) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	scope, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, agentScopeKey{}, scope), req)
+}
+
+func (a *agentAuth) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// grpcCaller 审计日志中 gRPC 调用方的身份：远端地址、令牌权限（未启用认证时为 none）和 User-Agent
+func grpcCaller(ctx context.Context) string {
+	scope, _ := ctx.Value(agentScopeKey{}).(string)
+	if scope == "" {
+		scope = "none"
+	}
+	caller := "grpc"
+	if p, ok := peer.FromContext(ctx); ok {
+		caller += " " + p.Addr.String()
+	}
+	caller += " token=" + scope
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			caller += " agent=" + strings.ReplaceAll(ua[0], " ", "_")
+		}
+	}
+	return caller
+}
+
+func (s *agentGRPCServer) GetStatus(ctx context.Context, _ *agentpb.GetStatusRequest) (*agentpb.Status, error) {
+	return statusToProto(getAgentStatus()), nil
+}
+
+func (s *agentGRPCServer) SetPeak(ctx context.Context, req *agentpb.SetPeakRequest) (*agentpb.Status, error) {
+	update := PeakUpdate{PeakUsageOrigin: int(req.GetPeakUsageOrigin()), PeakUsage: int(req.GetPeakUsage())}
+	if err := applyPeakUpdate(update, grpcCaller(ctx)); err != nil {
+		var limited *PeakRateLimitError
+		if errors.As(err, &limited) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return statusToProto(getAgentStatus()), nil
+}
+
+// WatchSamples 与 GET /stream 相同：先订阅再补发 since 之后的历史记录，之后推送每个监控周期的记录
+func (s *agentGRPCServer) WatchSamples(req *agentpb.WatchSamplesRequest, stream agentpb.Agent_WatchSamplesServer) error {
+	ch := sampleStream.Subscribe()
+	defer sampleStream.Unsubscribe(ch)
+
+	var last time.Time
+	if req.GetSince() != nil {
+		for _, entry := range history.Since(req.GetSince().AsTime(), 0) {
+			if err := stream.Send(sampleToProto(entry)); err != nil {
+				return err
+			}
+			last = entry.Time
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case entry := <-ch:
+			// 补发历史时已经发送过的记录
+			if !entry.Time.After(last) {
+				continue
+			}
+			if err := stream.Send(sampleToProto(entry)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// statusToProto 转换为 gRPC 接口的状态
+func statusToProto(s AgentStatus) *agentpb.Status {
+	return &agentpb.Status{
+		PeakUsageOrigin:   int32(s.PeakUsageOrigin),
+		PeakUsage:         int32(s.PeakUsage),
+		ExpectedUsage:     s.ExpectedUsage,
+		IsNightTime:       s.IsNightTime,
+		CpuPercent:        s.CPUPercent,
+		MemoryPercent:     s.MemoryPercent,
+		TotalMemory:       s.TotalMemory,
+		BufferMemoryBytes: s.BufferMemoryBytes,
+		CpuCount:          s.CPUCount,
+		SelfCpuPercent:    s.SelfCPUPercent,
+		SelfMemoryPercent: s.SelfMemoryPercent,
+		Managed:           s.Managed,
+		UpdatedAt:         timestamppb.New(s.UpdatedAt),
+	}
+}
+
+// sampleToProto 转换为 gRPC 接口的采样记录
+func sampleToProto(e HistoryEntry) *agentpb.Sample {
+	return &agentpb.Sample{
+		Time:              timestamppb.New(e.Time),
+		PeakUsage:         int32(e.PeakUsage),
+		ExpectedUsage:     e.ExpectedUsage,
+		CpuPercent:        e.CPUPercent,
+		MemoryPercent:     e.MemoryPercent,
+		SelfCpuPercent:    e.SelfCPUPercent,
+		SelfMemoryPercent: e.SelfMemoryPercent,
+		CpuCount:          e.CPUCount,
+		BufferBytes:       e.BufferBytes,
+		KillSwitch:        e.KillSwitch,
+		Decisions:         e.Decisions,
+	}
+}
+
+// grpcPusher 通过 gRPC 向 agent 下发峰值（AGENT_TRANSPORT=grpc），每个 agent 复用一个连接
+type grpcPusher struct {
+	creds credentials.TransportCredentials
+	token string // AGENT_ADMIN_TOKEN
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn // agent 地址 → 连接
+}
+
+// newGRPCPusher 设置 AGENT_TLS_CA 时使用 TLS 并只信任该 CA，否则使用明文连接
+func newGRPCPusher() (*grpcPusher, error) {
+	creds := insecure.NewCredentials()
+	tlsConfig, err := agentClientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return &grpcPusher{creds: creds, token: lookupEnv("AGENT_ADMIN_TOKEN"), conns: make(map[string]*grpc.ClientConn)}, nil
+}
+
+// Push 向单个 agent 下发峰值设置
+func (gp *grpcPusher) Push(agent string, update PeakUpdate) error {
+	conn, err := gp.conn(agent)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+	if gp.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+gp.token)
+	}
+	_, err = agentpb.NewAgentClient(conn).SetPeak(ctx, &agentpb.SetPeakRequest{
+		PeakUsageOrigin: int32(update.PeakUsageOrigin),
+		PeakUsage:       int32(update.PeakUsage),
+	})
+	return err
+}
+
+// conn 返回 agent 的连接（第一次使用时创建）
+func (gp *grpcPusher) conn(agent string) (*grpc.ClientConn, error) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	if conn, ok := gp.conns[agent]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(agent, grpc.WithTransportCredentials(gp.creds), grpc.WithUserAgent("cpumembusy-controller/"+Version))
+	if err != nil {
+		return nil, err
+	}
+	gp.conns[agent] = conn
+	return conn, nil
+}
+
+// Retain 关闭不在 agent 列表中的连接（AGENTS_FILE 中删除的 agent）
+func (gp *grpcPusher) Retain(agents []string) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+	for agent, conn := range gp.conns {
+		if !slices.Contains(agents, agent) {
+			conn.Close()
+			delete(gp.conns, agent)
+		}
+	}
+}
+
+// Close 关闭所有连接
+func (gp *grpcPusher) Close() {
+	gp.Retain(nil)
+}
@@ -0,0 +1,177 @@
+package busy
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"cpumembusy/pkg/agentpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startTestAgentGRPC 在本机随机端口启动 agent 的 gRPC 服务端，并保存测试会修改的峰值状态，测试结束后恢复
+func startTestAgentGRPC(t *testing.T, auth *agentAuth) string {
+	t.Helper()
+	peakUsageMu.Lock()
+	oldOrigin, oldPeak, oldManaged := peakUsageOrigin, peakUsage, peakManagedAt
+	peakUsageOrigin, peakUsage = 50, 50
+	peakUsageMu.Unlock()
+	oldLimiter := peakLimiter
+	peakLimiter = &peakRateLimiter{}
+	t.Cleanup(func() {
+		peakUsageMu.Lock()
+		peakUsageOrigin, peakUsage, peakManagedAt = oldOrigin, oldPeak, oldManaged
+		peakUsageMu.Unlock()
+		peakLimiter = oldLimiter
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := newAgentGRPCServer(auth)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return ln.Addr().String()
+}
+
+// testPeakUsage 读取当前的 peakUsage
+func testPeakUsage() int {
+	peakUsageMu.RLock()
+	defer peakUsageMu.RUnlock()
+	return peakUsage
+}
+
+// dialTestAgent 创建连接测试服务端的客户端（生成的 AgentClient）
+func dialTestAgent(t *testing.T, addr string) agentpb.AgentClient {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return agentpb.NewAgentClient(conn)
+}
+
+func TestAgentGRPCSetPeak(t *testing.T) {
+	auth := &agentAuth{readToken: "reader", adminToken: "admin"}
+	tests := []struct {
+		name     string
+		token    string
+		origin   int32
+		peak     int32
+		wantCode codes.Code
+		wantPeak int
+	}{
+		{name: "admin", token: "admin", origin: 70, peak: 65, wantCode: codes.OK, wantPeak: 65},
+		{name: "read-token", token: "reader", origin: 70, peak: 65, wantCode: codes.PermissionDenied, wantPeak: 50},
+		{name: "wrong-token", token: "guess", origin: 70, peak: 65, wantCode: codes.Unauthenticated, wantPeak: 50},
+		{name: "no-token", origin: 70, peak: 65, wantCode: codes.Unauthenticated, wantPeak: 50},
+		{name: "out-of-range", token: "admin", origin: 70, peak: 101, wantCode: codes.InvalidArgument, wantPeak: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dialTestAgent(t, startTestAgentGRPC(t, auth))
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+			}
+			_, err := client.SetPeak(ctx, &agentpb.SetPeakRequest{PeakUsageOrigin: tt.origin, PeakUsage: tt.peak})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("SetPeak() code = %v (%v), want %v", code, err, tt.wantCode)
+			}
+			if got := testPeakUsage(); got != tt.wantPeak {
+				t.Errorf("peakUsage = %d, want %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+// TestAgentGRPCSetPeakRateLimited 修改过于频繁时返回 RESOURCE_EXHAUSTED，重复下发相同的值不受限制
+func TestAgentGRPCSetPeakRateLimited(t *testing.T) {
+	client := dialTestAgent(t, startTestAgentGRPC(t, nil))
+	peakLimiter.Configure(time.Hour, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i, tt := range []struct {
+		peak     int32
+		wantCode codes.Code
+	}{
+		{peak: 60, wantCode: codes.OK},
+		{peak: 60, wantCode: codes.OK},
+		{peak: 70, wantCode: codes.ResourceExhausted},
+	} {
+		_, err := client.SetPeak(ctx, &agentpb.SetPeakRequest{PeakUsageOrigin: 60, PeakUsage: tt.peak})
+		if code := status.Code(err); code != tt.wantCode {
+			t.Errorf("request %d: SetPeak() code = %v (%v), want %v", i+1, code, err, tt.wantCode)
+		}
+	}
+}
+
+// TestGRPCPusher controller 的下发使用生成的客户端，携带 AGENT_ADMIN_TOKEN
+func TestGRPCPusher(t *testing.T) {
+	t.Setenv("AGENT_ADMIN_TOKEN", "admin")
+	addr := startTestAgentGRPC(t, &agentAuth{adminToken: "admin"})
+	pusher, err := newPeakPusher("grpc")
+	if err != nil {
+		t.Fatalf("newPeakPusher() error: %v", err)
+	}
+	defer pusher.Close()
+
+	if err := pusher.Push(addr, PeakUpdate{PeakUsageOrigin: 80, PeakUsage: 75}); err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if got := testPeakUsage(); got != 75 {
+		t.Errorf("peakUsage = %d, want 75", got)
+	}
+	if !isPeakManaged() {
+		t.Errorf("isPeakManaged() = false after push, want true")
+	}
+}
+
+func TestAgentGRPCWatchSamples(t *testing.T) {
+	client := dialTestAgent(t, startTestAgentGRPC(t, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchSamples(ctx, &agentpb.WatchSamplesRequest{})
+	if err != nil {
+		t.Fatalf("WatchSamples() error: %v", err)
+	}
+
+	// 服务端订阅之前发布的记录不会推送，持续发布直到收到第一条
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 1; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sampleStream.Publish(HistoryEntry{
+					Time:          time.Unix(int64(i), 0),
+					PeakUsage:     60,
+					ExpectedUsage: 42.5,
+					CPUPercent:    40,
+					Decisions:     map[string]string{"CPU": "0.6-增加"},
+				})
+			}
+		}
+	}()
+
+	sample, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error: %v", err)
+	}
+	if sample.GetPeakUsage() != 60 || sample.GetExpectedUsage() != 42.5 || sample.GetCpuPercent() != 40 ||
+		sample.GetDecisions()["CPU"] != "0.6-增加" || sample.GetTime().AsTime().IsZero() {
+		t.Errorf("Recv() = %v", sample)
+	}
+}
@@ -0,0 +1,91 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPeakUpdateCooldown   = 30 * time.Second // 两次远程修改峰值的最小间隔
+	defaultPeakUpdateMaxPerHour = 20               // 每小时最多的远程修改次数
+)
+
+// PeakRateLimitError 远程修改峰值过于频繁（POST /peak 返回 429）
+type PeakRateLimitError struct {
+	RetryAfter time.Duration // 多久之后可以再次修改
+}
+
+func (e *PeakRateLimitError) Error() string {
+	return fmt.Sprintf("峰值修改过于频繁，%s 后重试", e.RetryAfter.Round(time.Second))
+}
+
+// peakRateLimiter 限制远程修改峰值的频率：两次修改至少间隔 cooldown，任意一小时内最多 maxPerHour 次
+// 只统计实际改变了峰值的请求，controller 定期重复下发相同的值不受限制
+// 防止失控的自动化脚本每秒改一次目标，使整个集群的负载来回震荡
+type peakRateLimiter struct {
+	mu         sync.Mutex
+	cooldown   time.Duration
+	maxPerHour int         // 0 表示不限制
+	changes    []time.Time // 最近一小时内的修改时间
+}
+
+var peakLimiter = &peakRateLimiter{cooldown: defaultPeakUpdateCooldown, maxPerHour: defaultPeakUpdateMaxPerHour}
+
+// Configure 设置修改间隔和每小时的次数上限（PEAK_UPDATE_COOLDOWN / PEAK_UPDATE_MAX_PER_HOUR）
+func (pl *peakRateLimiter) Configure(cooldown time.Duration, maxPerHour int) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.cooldown = max(cooldown, 0)
+	pl.maxPerHour = max(maxPerHour, 0)
+}
+
+// Allow 判断现在能否修改峰值，可以时记录本次修改
+func (pl *peakRateLimiter) Allow(now time.Time) error {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	recent := pl.changes[:0]
+	for _, t := range pl.changes {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	pl.changes = recent
+
+	if n := len(pl.changes); n > 0 {
+		if wait := pl.cooldown - now.Sub(pl.changes[n-1]); wait > 0 {
+			return &PeakRateLimitError{RetryAfter: wait}
+		}
+	}
+	if n := len(pl.changes); pl.maxPerHour > 0 && n >= pl.maxPerHour {
+		// 等到窗口内只剩 maxPerHour - 1 次修改
+		return &PeakRateLimitError{RetryAfter: time.Hour - now.Sub(pl.changes[n-pl.maxPerHour])}
+	}
+	pl.changes = append(pl.changes, now)
+	return nil
+}
+
+// agentScopeKey 请求 context 中保存令牌权限的 key
+type agentScopeKey struct{}
+
+// withAgentScope 在请求中记录调用方使用的令牌权限（admin 或 read）
+func withAgentScope(r *http.Request, scope string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), agentScopeKey{}, scope))
+}
+
+// httpCaller 审计日志中 HTTP 调用方的身份：远端地址、令牌权限（未启用认证时为 none）和 User-Agent
+func httpCaller(r *http.Request) string {
+	scope, _ := r.Context().Value(agentScopeKey{}).(string)
+	if scope == "" {
+		scope = "none"
+	}
+	caller := "http " + r.RemoteAddr + " token=" + scope
+	if ua := r.Header.Get("User-Agent"); ua != "" {
+		caller += " agent=" + strings.ReplaceAll(ua, " ", "_")
+	}
+	return caller
+}
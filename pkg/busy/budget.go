@@ -0,0 +1,191 @@
+package busy
+
+import (
+	"sync"
+	"time"
+)
+
+// BudgetStatus 当天资源预算的使用情况（GET /status 的 budget 字段）
+type BudgetStatus struct {
+	Day                     string    `json:"day"` // 预算日（UTC 日期）
+	CPUCoreHours            float64   `json:"cpu_core_hours,omitempty"`
+	CPUSpentCoreHours       float64   `json:"cpu_spent_core_hours"`
+	CPURemainingCoreHours   float64   `json:"cpu_remaining_core_hours,omitempty"`
+	CPUAllowedPercent       float64   `json:"cpu_allowed_percent,omitempty"` // 按计划本进程当前允许的 CPU 占用（占整机）
+	MemoryGiBHours          float64   `json:"memory_gib_hours,omitempty"`
+	MemorySpentGiBHours     float64   `json:"memory_spent_gib_hours"`
+	MemoryRemainingGiBHours float64   `json:"memory_remaining_gib_hours,omitempty"`
+	MemoryAllowedPercent    float64   `json:"memory_allowed_percent,omitempty"` // 按计划本进程当前允许的内存占用（占整机）
+	ResetAt                 time.Time `json:"reset_at"`
+}
+
+// DailyBudget 每日资源预算：每天（UTC）最多消耗多少 CPU 核心·小时和内存 GiB·小时
+// 每个周期按本进程实际的 CPU 和常驻内存累计消耗，剩余的预算按当天剩余时段的期望值（凌晨时段、DAY_WINDOWS、硬峰值）分配：
+// 本进程当前允许的消耗速率 = 剩余预算 × 当前期望值 / 剩余时段期望值的积分，期望值高的时段分到更多预算，
+// 预算充足时不影响调整，不足时降低本进程的目标，用完后本进程不再产生负载，UTC 零点重置
+type DailyBudget struct {
+	mu             sync.Mutex
+	cpuCoreHours   float64 // 每天的 CPU 预算（核心·小时，0 表示不限制）
+	memoryGiBHours float64 // 每天的内存预算（GiB·小时，0 表示不限制）
+
+	day           time.Time // 当前预算日（UTC 零点）
+	cpuSpent      float64   // 当天已消耗的 CPU（核心·小时）
+	memorySpent   float64   // 当天已消耗的内存（GiB·小时）
+	lastAt        time.Time // 上次累计的时间
+	cpuAllowed    float64   // 本进程当前允许的 CPU 占用（占整机的百分比）
+	memoryAllowed float64   // 本进程当前允许的内存占用（占整机的百分比）
+	exhausted     map[string]bool
+}
+
+var dailyBudget = &DailyBudget{}
+
+// Configure 设置每天的预算，从内存中的历史记录（HISTORY_FILE 恢复的记录）累计当天已经消耗的部分，避免重启后重新开始计算
+func (db *DailyBudget) Configure(cpuCoreHours, memoryGiBHours float64, stats *SystemStats) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.cpuCoreHours, db.memoryGiBHours = max(cpuCoreHours, 0), max(memoryGiBHours, 0)
+	db.reset(clock.Now())
+	if !db.enabled() {
+		return
+	}
+
+	var last time.Time
+	for _, entry := range history.Since(db.day, 0) {
+		if !last.IsZero() {
+			dt := min(entry.Time.Sub(last), trackingMaxGap).Hours()
+			db.cpuSpent += entry.SelfCPUPercent / 100 * float64(numCPU()) * dt
+			db.memorySpent += entry.SelfMemoryPercent / 100 * float64(stats.TotalMemory) / (1 << 30) * dt
+		}
+		last = entry.Time
+	}
+	logger.Info("已启用每日资源预算",
+		"cpu_core_hours", db.cpuCoreHours,
+		"memory_gib_hours", db.memoryGiBHours,
+		"cpu_spent_core_hours", db.cpuSpent,
+		"memory_spent_gib_hours", db.memorySpent)
+}
+
+// Enabled 是否设置了预算
+func (db *DailyBudget) Enabled() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.enabled()
+}
+
+func (db *DailyBudget) enabled() bool {
+	return db.cpuCoreHours > 0 || db.memoryGiBHours > 0
+}
+
+// reset 开始新的预算日
+func (db *DailyBudget) reset(now time.Time) {
+	db.day = now.UTC().Truncate(24 * time.Hour)
+	db.cpuSpent, db.memorySpent = 0, 0
+	db.lastAt = time.Time{}
+	db.exhausted = make(map[string]bool)
+}
+
+// Update 累计上个周期本进程的消耗，并按当天剩余时段的期望值重新分配剩余的预算（peak 为当前的峰值）
+func (db *DailyBudget) Update(now time.Time, stats *SystemStats, peak int) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !db.enabled() {
+		return
+	}
+
+	if day := now.UTC().Truncate(24 * time.Hour); !day.Equal(db.day) {
+		logger.Info("资源预算已重置", "day", day.Format(time.DateOnly),
+			"cpu_spent_core_hours", db.cpuSpent, "memory_spent_gib_hours", db.memorySpent)
+		db.reset(now)
+	}
+	if !db.lastAt.IsZero() {
+		// 长时间没有记录（如进程被挂起）时最多按 4 个周期计算
+		dt := min(now.Sub(db.lastAt), trackingMaxGap).Hours()
+		db.cpuSpent += stats.SelfCPUPercent / 100 * float64(numCPU()) * dt
+		db.memorySpent += float64(stats.SelfMemory) / (1 << 30) * dt
+	}
+	db.lastAt = now
+
+	share := budgetShare(now, db.day.Add(24*time.Hour), peak)
+	if db.cpuCoreHours > 0 {
+		remaining := db.remaining("CPU", db.cpuCoreHours, db.cpuSpent)
+		db.cpuAllowed = remaining * share / float64(numCPU()) * 100
+	}
+	if db.memoryGiBHours > 0 && stats.TotalMemory > 0 {
+		remaining := db.remaining("内存", db.memoryGiBHours, db.memorySpent)
+		db.memoryAllowed = remaining * share / (float64(stats.TotalMemory) / (1 << 30)) * 100
+	}
+}
+
+// remaining 剩余的预算，第一次用完时输出日志并发送 budget_exhausted 事件
+func (db *DailyBudget) remaining(resource string, budget, spent float64) float64 {
+	if spent < budget {
+		return budget - spent
+	}
+	if !db.exhausted[resource] {
+		db.exhausted[resource] = true
+		logger.Warn(resource+"的每日预算已用完，本进程不再产生负载，UTC 零点重置", "budget", budget, "spent", spent)
+		emitEvent("budget_exhausted", "resource", resource, "budget", budget, "spent", spent)
+	}
+	return 0
+}
+
+// budgetShare 当前时刻应该消耗剩余预算的比例（每小时）：当前期望值 / 剩余时段期望值的积分（百分比·小时）
+// 剩余时段的期望值都为 0 时返回 0
+func budgetShare(now, end time.Time, peak int) float64 {
+	current := calculateExpectedUsageAt(now, peak)
+	var integral float64
+	for t := now; t.Before(end); t = t.Add(time.Minute) {
+		integral += calculateExpectedUsageAt(t, peak) * min(end.Sub(t), time.Minute).Hours()
+	}
+	if integral <= 0 {
+		return 0
+	}
+	return current / integral
+}
+
+// CPUTarget 按预算限制后的 CPU 目标（与 scopedCPUPercent 相同的口径）：
+// 其他部分保持不变，本进程的部分不超过预算允许的占用
+func (db *DailyBudget) CPUTarget(stats *SystemStats, expectedUsage float64) float64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.cpuCoreHours <= 0 {
+		return expectedUsage
+	}
+	return min(expectedUsage, scopedCPUPercent(stats)-stats.SelfCPUPercent+db.cpuAllowed)
+}
+
+// MemoryTarget 按预算限制后的内存目标（与 scopedMemoryPercent 相同的口径）
+func (db *DailyBudget) MemoryTarget(stats *SystemStats, expectedUsage float64) float64 {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.memoryGiBHours <= 0 {
+		return expectedUsage
+	}
+	return min(expectedUsage, scopedMemoryPercent(stats)-stats.SelfMemoryPercent+db.memoryAllowed)
+}
+
+// Status 当天预算的使用情况，没有设置预算时返回 nil
+func (db *DailyBudget) Status() *BudgetStatus {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !db.enabled() {
+		return nil
+	}
+	status := &BudgetStatus{
+		Day:                 db.day.Format(time.DateOnly),
+		CPUSpentCoreHours:   db.cpuSpent,
+		MemorySpentGiBHours: db.memorySpent,
+		ResetAt:             db.day.Add(24 * time.Hour),
+	}
+	if db.cpuCoreHours > 0 {
+		status.CPUCoreHours = db.cpuCoreHours
+		status.CPURemainingCoreHours = max(db.cpuCoreHours-db.cpuSpent, 0)
+		status.CPUAllowedPercent = db.cpuAllowed
+	}
+	if db.memoryGiBHours > 0 {
+		status.MemoryGiBHours = db.memoryGiBHours
+		status.MemoryRemainingGiBHours = max(db.memoryGiBHours-db.memorySpent, 0)
+		status.MemoryAllowedPercent = db.memoryAllowed
+	}
+	return status
+}
@@ -0,0 +1,1696 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPeakUsage = 40
+	hardPeakLimit    = 70
+	minPeakUsage     = 5
+
+	monitorInterval   = 3 * time.Second // 监控和调整的周期
+	maxMonitorJitter  = 0.9             // 监控周期随机浮动比例的上限，保证间隔大于 0
+	peakUsageInterval = 5 * time.Minute // peakUsage 随机更新的间隔
+)
+
+// logger 包内使用的日志，默认使用 slog 输出到标准输出，可以通过 SetLogger 替换
+var logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	Level: slog.LevelInfo,
+}))
+
+// SetLogger 替换包内使用的日志（需在 Start 之前调用）
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+var (
+	thermalMaxC    float64         // CPU 温度上限（摄氏度，0 表示不限制）
+	cpuRefFreqMHz  float64         // 频率补偿的参考频率（MHz，0 表示不补偿）
+	cpuObjective   string          // CPU 控制目标：percent（CPU 使用率）、percentile（滑动窗口内 CPU 使用率的分位数）或 loadavg（1 分钟平均负载）
+	loadAvgTarget  float64         // loadavg 模式下的目标值（每核心平均负载，如 0.6）
+	cpuPerCore     bool            // 是否按核心独立调整 CPU 占用
+	coreTargets    map[int]float64 // 指定核心的期望占用值（未指定的核心使用整体期望值）
+	iowaitBackoff  float64         // iowait 超过该百分比时强制降低 CPU 占用（0 表示不检查）
+	reclaimBackoff float64         // 内存回收每秒扫描的页数超过该值时强制降低内存占用（0 表示不检查）
+	targetScope    string          // 期望值作用范围：system（整机占用）、self（本进程占用）或 sidecar（目标容器 + 本进程）
+	minUsage       float64         // 最低占用（%）：低于该值时强制增加，期望值也不低于该值（0 表示不限制）
+	cpuEnabled     bool            // 是否产生 CPU 负载（CPU_ENABLED）
+	memoryEnabled  bool            // 是否占用内存（MEMORY_ENABLED），关闭时完全不分配和访问内存缓冲区
+)
+
+var (
+	peakUsageOrigin int          // 原始的 peakUsage 值
+	peakUsage       int          // 当前浮动的 peakUsage 值
+	peakUsageMu     sync.RWMutex // 保护 peakUsage 的读写锁
+)
+
+// started 进程内是否已经启动过 Controller：各控制器和很多运行状态（如 CPU_IOWAIT_BUSY 的开关）都是进程级单例，
+// Stop 时不会重置，因此每个进程只能启动一次
+var started atomic.Bool
+
+// Controller 资源占用控制器：采集系统资源并按期望占用值调整 CPU、内存等资源的占用
+// 配置从环境变量读取（与命令行程序相同）。Controller 是进程级单例状态的启动句柄，不是可复用的类型：
+// 每个进程只能启动一次（停止后不能再次启动，第二个 Controller 的 Start 返回错误），见包文档
+type Controller struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	stops  []func() // Stop 时按相反顺序执行的清理函数
+
+	expectedUsageFn ExpectedUsageFunc // 期望占用值计算函数（nil 表示使用 TARGET_EXPR 或内置算法）
+
+	workloads []Workload // 通过 WORKLOADS 启用的负载模块
+
+	netBandwidthMbps int // 网络带宽上限（Mbps，0 表示不产生网络流量）
+	cswitchRate      int // 上下文切换速率上限（0 表示不产生上下文切换）
+
+	monitorJitter float64 // 监控周期的随机浮动比例（0 表示固定 3 秒）
+
+	gcForceInterval time.Duration // 强制 GC 的间隔（0 表示不强制 GC）
+}
+
+// NewController 创建资源占用控制器
+func NewController() *Controller {
+	return &Controller{}
+}
+
+// Start 读取配置，启动各控制器和主循环（主循环在后台运行）
+func (c *Controller) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		return fmt.Errorf("Controller 已经启动")
+	}
+	if !started.CompareAndSwap(false, true) {
+		return fmt.Errorf("每个进程只能启动一次 Controller（各控制器是进程级单例，停止后状态不会重置）")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	// 随机延迟启动：整批主机同时重启时错开开始产生负载的时间
+	if delay := startupDelay(); delay > 0 {
+		go c.delayedStart(ctx, delay)
+		return nil
+	}
+
+	stats := c.setup()
+	go c.run(ctx, stats)
+	return nil
+}
+
+// dropLoad 立即释放全部负载：暂停 CPU 工作协程，释放内存缓冲区并归还给系统，删除磁盘填充文件，其他负载的强度降为 0
+// 连接、文件描述符和线程不是持续的负载，保持不变
+func (c *Controller) dropLoad() {
+	cpuController.SetPaused(true)
+	cpuController.SetCount(initCount)
+	memoryController.Release()
+	debug.FreeOSMemory()
+	diskController.Release()
+	for _, workload := range c.workloads {
+		workload.SetIntensity(0)
+	}
+	if c.cswitchRate > 0 {
+		cswitchController.SetRate(0)
+	}
+	if netController.Enabled() {
+		netController.SetRate(0)
+	}
+	if gpuController.Enabled() {
+		gpuController.Idle()
+	}
+}
+
+// startupDelay 在 [0, STARTUP_DELAY_MAX] 内随机生成启动延迟
+func startupDelay() time.Duration {
+	maxDelay := getEnvDuration("STARTUP_DELAY_MAX", 0)
+	if maxDelay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(maxDelay) + 1))
+}
+
+// delayedStart 等待 delay 后再读取配置并启动（等待期间 Stop 会直接返回）
+// setup 持有锁执行，与同时到来的 Stop 互斥：Stop 要么在 setup 之前取消（不再启动），要么等 setup 注册完清理函数
+func (c *Controller) delayedStart(ctx context.Context, delay time.Duration) {
+	logger.Info("延迟启动", "delay", delay, "start_at", time.Now().Add(delay).Format(time.RFC3339))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		close(c.done)
+		return
+	case <-timer.C:
+	}
+	c.mu.Lock()
+	if ctx.Err() != nil {
+		c.mu.Unlock()
+		close(c.done)
+		return
+	}
+	stats := c.setup()
+	c.mu.Unlock()
+	c.run(ctx, stats)
+}
+
+// Stop 停止主循环和各控制器，释放占用的资源
+// 等待主循环退出时不持有锁（延迟启动的 setup 需要持有锁）
+func (c *Controller) Stop() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+
+	logger.Info("开始停止资源占用")
+	cancel()
+	<-done
+
+	c.mu.Lock()
+	stops := c.stops
+	c.stops = nil
+	c.mu.Unlock()
+	for i := len(stops) - 1; i >= 0; i-- {
+		stops[i]()
+	}
+	logger.Info("资源占用已停止")
+}
+
+// SetTarget 设置峰值（与 controller 通过 POST /peak 下发的效果相同）
+func (c *Controller) SetTarget(peakUsageOrigin, peakUsage int) error {
+	return applyPeakUpdate(PeakUpdate{PeakUsageOrigin: peakUsageOrigin, PeakUsage: peakUsage}, "api SetTarget")
+}
+
+// Snapshot 获取最近一次监控周期的状态
+func (c *Controller) Snapshot() AgentStatus {
+	return getAgentStatus()
+}
+
+// SetExpectedUsageFunc 设置自定义的期望占用值计算函数，替代内置的凌晨时段算法（需在 Start 之前调用）
+func (c *Controller) SetExpectedUsageFunc(fn ExpectedUsageFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expectedUsageFn = fn
+}
+
+// onStop 注册 Stop 时执行的清理函数（调用方需持有锁）
+func (c *Controller) onStop(stop func()) {
+	c.stops = append(c.stops, stop)
+}
+
+// setup 读取环境变量，初始化并启动各控制器，返回初始的系统资源信息（调用方需持有锁）
+func (c *Controller) setup() *SystemStats {
+	// 主机信息（主机名、实例 ID、HOST_LABELS）附加到之后的每条日志
+	loadHostMeta()
+	c.onStop(enrichLogger())
+	logPreset()
+
+	// 读取环境变量
+	peakUsageOrigin = getPeakUsage()
+	peakUsage = peakUsageOrigin
+	loadCurveConfig()
+	info := GetBuildInfo()
+	logger.Info("程序启动",
+		"version", info.Version,
+		"commit", info.Commit,
+		"go_version", info.GoVersion,
+		"stats_backends", info.StatsBackends,
+		"peak_usage_origin", peakUsageOrigin,
+		"peak_usage", peakUsage,
+		"night_hard_peak", nightHardPeak,
+		"day_hard_peak", dayHardPeak,
+		"gomaxprocs", runtime.GOMAXPROCS(0))
+
+	// 初始化系统资源监控
+	procRoot = getEnvString("PROC_ROOT", "/proc")
+	backends := getEnvList("STATS_BACKENDS")
+	if len(backends) == 0 {
+		backends = defaultStatsBackends
+	}
+	if err := statsChain.Configure(backends); err != nil {
+		logger.Warn("STATS_BACKENDS 无效，只使用 procfs", "error", err)
+	}
+	if getEnvBool("CGROUP_LIMITS", false) {
+		if err := cgroupLimits.Enable(); err != nil {
+			logger.Warn("无法跟踪 cgroup 限制，使用整机资源", "error", err)
+		} else {
+			logger.Info("已启用 cgroup 限制跟踪", "path", cgroupLimits.Path())
+		}
+	}
+	if getEnvBool("CPU_HOTPLUG", true) {
+		if err := cpuTopology.Enable(); err != nil {
+			logger.Info("不跟踪 CPU 热插拔", "reason", err)
+		}
+	}
+	stats, err := GetSystemStats()
+	if err != nil {
+		logger.Warn("初始化系统资源监控失败，使用保守策略", "error", err)
+		stats = &SystemStats{}
+	} else {
+		memoryController.SetTotalMemory(stats.TotalMemory)
+		logger.Info("系统资源初始化成功",
+			"total_memory_gb", stats.TotalMemory/(1024*1024*1024),
+			"cpu_cores", numCPU())
+	}
+
+	// 模块开关：部分主机不允许占用内存，但仍需要 CPU 负载（或相反）
+	cpuEnabled = getEnvBool("CPU_ENABLED", true)
+	memoryEnabled = getEnvBool("MEMORY_ENABLED", true)
+	if !cpuEnabled || !memoryEnabled {
+		logger.Info("部分模块已关闭", "cpu_enabled", cpuEnabled, "memory_enabled", memoryEnabled)
+	}
+
+	// 把自身限制在独立的 cgroup 中，作为硬峰值之外的兜底保护
+	if path := lookupEnv("CGROUP_PATH"); path != "" {
+		if err := confineToCgroup(path, stats.TotalMemory); err != nil {
+			logger.Warn("cgroup 自我限制失败，继续运行", "path", path, "error", err)
+		} else if cgroupLimits.Enabled() {
+			// 进程已经移入新的 cgroup，限制跟踪改为跟踪新的 cgroup
+			if err := cgroupLimits.Enable(); err != nil {
+				logger.Warn("无法跟踪新 cgroup 的限制，使用整机资源", "error", err)
+			} else {
+				logger.Info("cgroup 限制跟踪已切换到新的 cgroup", "path", cgroupLimits.Path())
+			}
+		}
+	}
+
+	// 内存块大小：默认固定 1MB，设置浮动比例后每次分配的大小随机变化
+	memoryController.SetBlockSize(uint64(getEnvInt("MEMORY_BLOCK_KB", defaultBlockSize/1024))*1024, getEnvFloat("MEMORY_BLOCK_JITTER", 0))
+	if backend := getEnvString("MEMORY_BACKEND", "heap"); backend != "heap" {
+		alloc, err := newMemoryAllocator(backend, getEnvString("MEMORY_TMPFS_DIR", "/dev/shm"))
+		if err != nil {
+			logger.Warn("初始化内存后端失败，使用堆内存", "backend", backend, "error", err)
+		} else {
+			memoryController.SetAllocator(alloc)
+			logger.Info("内存后端已启用", "backend", backend)
+		}
+	}
+	if split := lookupEnv("MEMORY_NUMA_SPLIT"); split != "" {
+		// 按比例把内存分配到各个 NUMA 节点（只支持堆内存后端）
+		if backend := getEnvString("MEMORY_BACKEND", "heap"); backend != "heap" {
+			logger.Warn("MEMORY_NUMA_SPLIT 只支持堆内存后端，忽略", "backend", backend)
+		} else if na, err := newNUMAAllocator(split); err != nil {
+			logger.Warn("MEMORY_NUMA_SPLIT 无效，不绑定节点", "split", split, "error", err)
+		} else {
+			memoryController.SetAllocator(na.allocate)
+			logger.Info("内存按 NUMA 节点分配", "split", split)
+		}
+	}
+	if pattern := getEnvString("MEMORY_ACCESS_PATTERN", "none"); pattern != "none" && memoryEnabled {
+		if err := memoryController.StartAccess(pattern, getEnvFloat("MEMORY_ACCESS_MBPS", 100), getEnvFloat("MEMORY_ACCESS_WRITE_RATIO", 0.5)); err != nil {
+			logger.Warn("启动内存访问失败", "pattern", pattern, "error", err)
+		} else {
+			c.onStop(memoryController.StopAccess)
+			logger.Info("内存访问已启用", "pattern", pattern)
+		}
+	}
+	if err := memoryController.SetAccounting(getEnvString("MEMORY_ACCOUNTING", "rss")); err != nil {
+		logger.Warn("MEMORY_ACCOUNTING 无效，使用默认值", "error", err, "default", "rss")
+		memoryController.SetAccounting("rss")
+	}
+	if getEnvBool("MEMORY_FRAGMENT", false) {
+		memoryController.SetFragment(true)
+		logger.Info("内存碎片化模式已启用")
+	}
+
+	// 停止文件：文件存在期间释放全部负载
+	if path := lookupEnv("KILL_SWITCH_FILE"); path != "" {
+		killSwitch.Start(path)
+		c.onStop(func() {
+			killSwitch.Stop()
+			cpuController.SetPaused(false)
+		})
+		logger.Info("停止文件监视已启用", "path", path)
+	}
+
+	// 抢占通知：Spot / 抢占式实例收到回收通知时释放全部负载，不再恢复
+	if getEnvBool("SPOT_WATCH", false) {
+		provider := getEnvString("SPOT_PROVIDER", "auto")
+		if provider != "auto" && provider != "aws" && provider != "gcp" && provider != "azure" {
+			logger.Warn("SPOT_PROVIDER 无效，使用默认值", "value", provider, "default", "auto")
+			provider = "auto"
+		}
+		preemptionWatcher.Start(provider, getEnvDuration("SPOT_POLL_INTERVAL", defaultSpotPollInterval))
+		c.onStop(preemptionWatcher.Stop)
+	}
+
+	// OOM 检测：同机有进程被 OOM killer 杀死时释放部分内存缓冲区，一段时间内不再增加内存占用
+	if getEnvBool("OOM_WATCH", false) && memoryEnabled {
+		oomWatcher.Start(getEnvFloat("OOM_RELEASE_PERCENT", defaultOOMReleasePercent), getEnvDuration("OOM_HOLD", defaultOOMHold),
+			lookupEnv("OOM_WEBHOOK"), stats.TotalMemory)
+		c.onStop(oomWatcher.Stop)
+	}
+
+	// GC 设置：GC 百分比和强制 GC 间隔
+	applyGCPercent()
+	c.gcForceInterval = max(getEnvDuration("GC_FORCE_INTERVAL", defaultGCForceInterval), 0)
+	if c.gcForceInterval == 0 {
+		logger.Info("已关闭定时强制 GC")
+	}
+
+	// 设置内存 rlimit，作为独立于控制循环的内核级保护
+	if getEnvBool("RLIMIT_MEMORY", false) {
+		limit, err := applyMemoryRlimits(stats.TotalMemory)
+		if err != nil {
+			logger.Warn("设置内存 rlimit 失败，继续运行", "error", err)
+		} else {
+			memoryController.SetLimit(limit)
+		}
+	}
+
+	// 初始化磁盘控制器
+	if dir := lookupEnv("DISK_PATH"); dir != "" {
+		if err := diskController.Init(dir, getEnvString("DISK_FILL_MODE", "fallocate")); err != nil {
+			logger.Warn("初始化磁盘控制器失败，不调整磁盘占用", "dir", dir, "error", err)
+		} else {
+			target := min(max(getEnvFloat("DISK_TARGET_PERCENT", 0), 0), 100)
+			diskController.SetTarget(target)
+			logger.Info("磁盘控制器已启用", "dir", dir, "target_percent", target)
+			c.onStop(diskController.Stop)
+		}
+	}
+
+	// 启动网络控制器：带宽随期望占用值变化，与 CPU/内存曲线保持一致
+	c.netBandwidthMbps = getEnvInt("NET_BANDWIDTH_MBPS", 0)
+	if c.netBandwidthMbps > 0 {
+		proto := getEnvString("NET_PROTO", "udp")
+		if err := netController.Start(proto, lookupEnv("NET_PEER"), lookupEnv("NET_LISTEN")); err != nil {
+			logger.Warn("启动网络控制器失败，不产生网络流量", "error", err)
+		} else {
+			c.onStop(netController.Stop)
+			logger.Info("网络控制器已启用", "proto", proto, "bandwidth_mbps", c.netBandwidthMbps)
+		}
+	}
+
+	// 启动连接控制器：维持一定数量的 TCP 长连接
+	if connCount := getEnvInt("CONN_COUNT", 0); connCount > 0 {
+		churn := getEnvFloat("CONN_CHURN", 0.1)
+		if err := connController.Start(connCount, churn, lookupEnv("CONN_PEER"), lookupEnv("CONN_LISTEN")); err != nil {
+			logger.Warn("启动连接控制器失败，不维持 TCP 连接", "error", err)
+		} else {
+			c.onStop(connController.Stop)
+			logger.Info("连接控制器已启用", "conn_count", connCount, "churn_per_minute", churn)
+		}
+	}
+
+	// 启动文件描述符控制器：维持一定数量的打开文件描述符和临时 inode
+	fdCount, inodeCount := getEnvInt("FD_COUNT", 0), getEnvInt("INODE_COUNT", 0)
+	if fdCount > 0 || inodeCount > 0 {
+		dir := getEnvString("FD_DIR", filepath.Join(os.TempDir(), "cpumembusy-fd"))
+		if err := fdController.Init(dir); err != nil {
+			logger.Warn("初始化文件描述符控制器失败", "dir", dir, "error", err)
+		} else {
+			c.onStop(fdController.Stop)
+			if err := fdController.SetTargets(fdCount, inodeCount); err != nil {
+				logger.Warn("文件描述符控制器未能达到目标值", "error", err)
+			}
+			logger.Info("文件描述符控制器已启用", "dir", dir, "fd_count", fdCount, "inode_count", inodeCount)
+		}
+	}
+
+	// 启动线程控制器：维持一定数量的空闲线程和协程
+	threadCount, goroutineCount := getEnvInt("THREAD_COUNT", 0), getEnvInt("GOROUTINE_COUNT", 0)
+	if threadCount > 0 || goroutineCount > 0 {
+		if err := threadController.SetTargets(threadCount, goroutineCount); err != nil {
+			logger.Warn("启动线程控制器失败", "error", err)
+		} else {
+			c.onStop(threadController.Stop)
+			logger.Info("线程控制器已启用", "thread_count", threadCount, "goroutine_count", goroutineCount)
+		}
+	}
+
+	// 启动上下文切换控制器：产生真实的自愿上下文切换，切换速率随期望占用值变化
+	c.monitorJitter = min(max(getEnvFloat("MONITOR_JITTER", 0), 0), maxMonitorJitter)
+	cpuControl = getEnvString("CPU_CONTROL", "probability")
+	if cpuControl != "probability" && cpuControl != "closedloop" {
+		logger.Warn("CPU_CONTROL 无效，使用默认值", "value", cpuControl, "default", "probability")
+		cpuControl = "probability"
+	}
+	closedLoopGain = min(max(getEnvFloat("CPU_CONTROL_GAIN", 0.5), 0.05), 1)
+	gcCompensation = getEnvBool("CPU_GC_COMPENSATION", false)
+	trackingStats.SetBand(getEnvFloat("TRACK_BAND", 2))
+	history.SetDuration(getEnvDuration("HISTORY_DURATION", defaultHistoryDuration))
+	logMode := getEnvString("LOG_MODE", "all")
+	if logMode != "all" && logMode != "changes" {
+		logger.Warn("LOG_MODE 无效，使用默认值", "value", logMode, "default", "all")
+		logMode = "all"
+	}
+	cycleLog.Configure(logMode, getEnvFloat("LOG_CHANGE_DELTA", defaultLogChangeDelta), getEnvDuration("LOG_HEARTBEAT", defaultLogHeartbeat))
+	if path := lookupEnv("HISTORY_FILE"); path != "" && history.Capacity() > 0 {
+		if err := historyFile.Open(path); err != nil {
+			logger.Warn("打开历史文件失败，只在内存中保留记录", "path", path, "error", err)
+		} else {
+			c.onStop(historyFile.Close)
+		}
+	}
+	// 每日资源预算：需在恢复历史记录之后配置，累计当天已经消耗的部分
+	dailyBudget.Configure(getEnvFloat("CPU_BUDGET_CORE_HOURS", 0), getEnvFloat("MEMORY_BUDGET_GIB_HOURS", 0), stats)
+	// CPU 积分：可突增性能实例上保留一部分积分，避免填充负载耗尽积分后真实业务被限制到基准性能
+	if lookupEnv("CPU_CREDIT_FLOOR") != "" && cpuEnabled {
+		configureCPUCredits()
+		c.onStop(cpuCredits.Stop)
+	}
+	stallDetector.Configure(getEnvInt("STALL_CYCLES", defaultStallCycles), getEnvFloat("STALL_MIN_GAP", defaultStallMinGap), lookupEnv("STALL_WEBHOOK"))
+	c.cswitchRate = getEnvInt("CSWITCH_RATE", 0)
+	if c.cswitchRate > 0 {
+		pairs := getEnvInt("CSWITCH_PAIRS", 4)
+		cswitchController.Start(pairs, 0)
+		c.onStop(cswitchController.Stop)
+		logger.Info("上下文切换控制器已启用", "rate", c.cswitchRate, "pairs", pairs)
+	}
+
+	// 启动 GPU 控制器：通过外部 helper 程序产生 GPU 负载
+	if helper := lookupEnv("GPU_HELPER"); helper != "" {
+		if err := gpuController.Start(helper, getEnvString("GPU_QUERY_CMD", defaultGPUQueryCmd)); err != nil {
+			logger.Warn("启动 GPU 控制器失败，不产生 GPU 负载", "error", err)
+		} else {
+			c.onStop(gpuController.Stop)
+			logger.Info("GPU 控制器已启用", "helper", helper)
+		}
+	}
+
+	// 事件发布：把调整和阈值事件发布到 NATS / Kafka / 文件，供分析平台与故障时间线关联
+	if sinks, err := newEventSinks(); err != nil {
+		logger.Warn("事件发布配置无效，不发布事件", "error", err)
+	} else if len(sinks) > 0 {
+		eventPublisher = startEventPublisher(sinks)
+		c.onStop(func() {
+			ep := eventPublisher
+			eventPublisher = nil
+			ep.Stop()
+		})
+		names := make([]string, len(sinks))
+		for i, sink := range sinks {
+			names[i] = sink.Name()
+		}
+		logger.Info("事件发布已启用", "sinks", names)
+	}
+
+	// 远程修改峰值（agent 接口、MQTT、配置中心、SetTarget）的频率限制
+	peakLimiter.Configure(getEnvDuration("PEAK_UPDATE_COOLDOWN", defaultPeakUpdateCooldown), getEnvInt("PEAK_UPDATE_MAX_PER_HOUR", defaultPeakUpdateMaxPerHour))
+
+	// 实时信号微调峰值（没有 HTTP 接口时在 shell 中使用）
+	if step := getEnvInt("SIGNAL_NUDGE_STEP", defaultNudgeStep); step > 0 {
+		c.onStop(startSignalNudge(step))
+	}
+
+	// 控制 socket：本机的 top 子命令通过它查看内部状态，不需要开放网络端口
+	if path := lookupEnv("CONTROL_SOCKET"); path != "" {
+		if server, err := startControlSocket(path); err != nil {
+			logger.Warn("控制 socket 启动失败", "path", path, "error", err)
+		} else {
+			c.onStop(func() { server.Close(); os.Remove(path) })
+		}
+	}
+
+	// 启动 agent 接口（HTTP 和 gRPC），供 controller 统一下发峰值
+	if addr := lookupEnv("AGENT_LISTEN"); addr != "" {
+		if server, err := startAgentServer(addr); err != nil {
+			logger.Warn("agent 接口启动失败", "listen", addr, "error", err)
+		} else {
+			c.onStop(func() { server.Close() })
+		}
+	}
+	if addr := lookupEnv("AGENT_GRPC_LISTEN"); addr != "" {
+		if server, err := startAgentGRPCServer(addr); err != nil {
+			logger.Warn("agent gRPC 接口启动失败", "listen", addr, "error", err)
+		} else {
+			c.onStop(server.Stop)
+		}
+	}
+
+	// MQTT：订阅命令主题（设置峰值、暂停、恢复）并定期发布遥测，用于没有入站 HTTP 访问的设备
+	if cfg, ok := loadMQTTConfig(); ok {
+		client := startMQTTClient(cfg)
+		c.onStop(func() {
+			client.Stop()
+			killSwitch.SetRemote(false)
+			cpuController.SetPaused(false)
+		})
+	}
+
+	// 配置存储：监视 etcd / Consul 中的配置文档，整个集群的峰值修改几秒内生效
+	if store, desc, err := newConfigStore(); err != nil {
+		logger.Warn("配置存储无效，不监视", "error", err)
+	} else if store != nil {
+		watcher := startConfigWatcher(store, desc)
+		c.onStop(func() {
+			watcher.Stop()
+			cpuController.SetPaused(false)
+		})
+	}
+
+	// 节点配置：以 DaemonSet 运行时从挂载的 ConfigMap 中按节点名或标签选择本节点的目标
+	if store, desc := newNodeProfileStore(); store != nil {
+		watcher := startConfigWatcher(store, desc)
+		c.onStop(func() {
+			watcher.Stop()
+			cpuController.SetPaused(false)
+		})
+	}
+
+	// 降低自身调度优先级，让真实业务优先使用 CPU
+	if value := lookupEnv("NICE"); value != "" {
+		nice := getEnvInt("NICE", 0)
+		if err := setProcessNice(nice); err != nil {
+			logger.Warn("设置 nice 值失败", "nice", nice, "error", err)
+		} else {
+			logger.Info("已设置 nice 值", "nice", nice)
+		}
+	}
+	thermalMaxC = getEnvFloat("THERMAL_MAX_C", 0)
+	cpuExcludeSteal = getEnvBool("CPU_EXCLUDE_STEAL", false)
+	switch mode := getEnvString("IOWAIT_MODE", "idle"); mode {
+	case "idle":
+	case "busy":
+		cpuIOWaitBusy = true
+	case "backoff":
+		iowaitBackoff = getEnvFloat("IOWAIT_BACKOFF_PERCENT", 20)
+	default:
+		logger.Warn("IOWAIT_MODE 无效，使用默认值", "value", mode, "default", "idle")
+	}
+	reclaimBackoff = getEnvFloat("MEMORY_RECLAIM_BACKOFF", 0)
+	cpuObjective = getEnvString("CPU_OBJECTIVE", "percent")
+	if cpuObjective != "percent" && cpuObjective != "percentile" && cpuObjective != "loadavg" {
+		logger.Warn("CPU_OBJECTIVE 无效，使用默认值", "value", cpuObjective, "default", "percent")
+		cpuObjective = "percent"
+	}
+	percentileObjective.Configure(getEnvFloat("OBJECTIVE_PERCENTILE", defaultObjectivePercentile), getEnvDuration("PERCENTILE_WINDOW", defaultPercentileWindow))
+	loadAvgTarget = getEnvFloat("LOADAVG_TARGET", 0.6)
+	if value := lookupEnv("CORE_TARGETS"); value != "" {
+		targets, err := parseIntFloatMap(value)
+		if err != nil {
+			logger.Warn("CORE_TARGETS 无效，忽略", "value", value, "error", err)
+		} else {
+			coreTargets = targets
+		}
+	}
+	cpuPerCore = getEnvBool("CPU_PER_CORE", false) || len(coreTargets) > 0
+	targetScope = getEnvString("TARGET_SCOPE", "system")
+	if targetScope != "system" && targetScope != "self" && targetScope != "sidecar" {
+		logger.Warn("TARGET_SCOPE 无效，使用默认值", "value", targetScope, "default", "system")
+		targetScope = "system"
+	}
+	if targetScope == "sidecar" {
+		target := lookupEnv("SIDECAR_TARGET")
+		if err := sidecarTracker.Init(target); err != nil {
+			logger.Error("初始化 sidecar 跟踪失败，使用整机模式", "target", target, "error", err)
+			targetScope = "system"
+		} else {
+			logger.Info("已启用 sidecar 模式", "target", target, "cgroup", sidecarTracker.Path())
+		}
+	}
+	if targetScope != "system" && (cpuObjective == "loadavg" || cpuPerCore) {
+		// loadavg 和按核心调整都只能观测整机，与本进程模式冲突
+		logger.Warn("TARGET_SCOPE 不是 system 时忽略 CPU_OBJECTIVE=loadavg 和按核心调整", "target_scope", targetScope)
+		cpuObjective = "percent"
+		cpuPerCore = false
+	}
+	cpuController.SetPinned(cpuPerCore)
+	if getEnvBool("CPU_FREQ_COMPENSATE", false) {
+		// 参考频率：优先使用最大频率，否则使用启动时的频率
+		if freq, err := readCPUMaxFrequency(); err == nil {
+			cpuRefFreqMHz = freq
+		} else {
+			cpuRefFreqMHz = stats.CPUFreqMHz
+		}
+		logger.Info("已启用 CPU 频率补偿", "ref_freq_mhz", cpuRefFreqMHz)
+	}
+	cpuController.SetSchedIdle(getEnvBool("WORKER_SCHED_IDLE", false))
+	cpuController.SetStallTimeout(getEnvDuration("WORKER_STALL_TIMEOUT", defaultWorkerStallTimeout))
+	cpuController.SetSleep(getEnvDuration("CPU_SLEEP", sleepTime), getEnvBool("CPU_SLEEP_ADAPTIVE", false))
+	cpuController.SetSleepJitter(getEnvFloat("CPU_SLEEP_JITTER", 0))
+	cpuController.SetSpread(getEnvFloat("CPU_WORKER_SPREAD", 0))
+	if err := cpuController.SetIdleMode(getEnvString("CPU_IDLE_MODE", "sleep")); err != nil {
+		logger.Warn("CPU_IDLE_MODE 无效，使用 sleep", "error", err)
+		cpuController.SetIdleMode("sleep")
+	}
+	if workers := getEnvInt("CPU_WORKERS", 0); workers > 0 {
+		// 工作协程数量与核心数解耦：每个协程最多占满一个核心，整机 CPU 占用上限为 workers / 核心数
+		cpuController.SetWorkerCount(workers)
+		if reachable := float64(workers) / float64(numCPU()) * 100; reachable < maxHardPeak() {
+			logger.Warn("CPU 工作协程数较少，整机 CPU 占用可能达不到期望值", "cpu_workers", workers, "cpu_cores", numCPU(), "max_percent", reachable)
+		}
+	}
+	kernel := getEnvString("CPU_KERNEL", "int")
+	if err := cpuController.SetKernel(kernel); err != nil {
+		logger.Warn("计算内核设置无效，使用默认内核", "error", err)
+		kernel = "int"
+	}
+	if kernel == "power" {
+		logger.Info("功耗内核", "isa", powerBurnISA())
+	}
+
+	// 根据校准结果（CALIBRATION_FILE 或 CALIBRATION_DIR 中本机机型的文件）设置初始计算次数，启动后更快接近期望值
+	if cpuEnabled {
+		c.applyCalibration(kernel)
+	}
+
+	// 计算次数模型：只用于按使用率的整体调整
+	cpuCountModel = nil
+	if path := lookupEnv("COUNT_MODEL_FILE"); path != "" && cpuEnabled {
+		if cpuObjective != "loadavg" && !cpuPerCore {
+			workers := getEnvInt("CPU_WORKERS", 0)
+			if workers <= 0 {
+				workers = numCPU()
+			}
+			cpuCountModel = loadCountModel(path, kernel, workers)
+			c.onStop(cpuCountModel.save)
+		} else {
+			logger.Warn("CPU_OBJECTIVE=loadavg 或按核心调整时不使用计算次数模型", "path", path)
+		}
+	}
+
+	// 期望占用值计算：优先使用嵌入方设置的函数，其次是 TARGET_EXPR 表达式，最后是内置算法
+	if c.expectedUsageFn == nil {
+		c.expectedUsageFn = configuredExpectedUsage()
+	}
+
+	// 启动可插拔的负载模块
+	c.workloads = nil
+	for _, name := range getEnvList("WORKLOADS") {
+		workload, err := newWorkload(name)
+		if err != nil {
+			logger.Warn("创建负载模块失败", "error", err)
+			continue
+		}
+		if err := workload.Start(); err != nil {
+			logger.Warn("启动负载模块失败", "workload", name, "error", err)
+			continue
+		}
+		c.workloads = append(c.workloads, workload)
+		c.onStop(workload.Stop)
+		logger.Info("负载模块已启用", "workload", name)
+	}
+
+	// 短时 CPU 突发
+	if rate := getEnvFloat("CPU_SPIKE_RATE", 0); rate > 0 && cpuEnabled {
+		minDur := getEnvDuration("CPU_SPIKE_MIN", time.Second)
+		maxDur := getEnvDuration("CPU_SPIKE_MAX", 10*time.Second)
+		percent := getEnvFloat("CPU_SPIKE_PERCENT", 20)
+		spikeGenerator.Start(rate, minDur, maxDur, percent)
+		c.onStop(spikeGenerator.Stop)
+		logger.Info("CPU 突发已启用", "rate_per_hour", rate, "min", minDur, "max", maxDur, "percent", percent)
+	}
+
+	// 电网信号：碳强度或电价较高的时段按比例降低期望值
+	if url := lookupEnv("GRID_SIGNAL_URL"); url != "" {
+		gridSignal.Start(GridSignalConfig{
+			URL:         url,
+			Field:       lookupEnv("GRID_SIGNAL_FIELD"),
+			Token:       lookupEnv("GRID_SIGNAL_TOKEN"),
+			TokenHeader: getEnvString("GRID_SIGNAL_TOKEN_HEADER", "Authorization"),
+			Low:         getEnvFloat("GRID_SIGNAL_LOW", 0),
+			High:        getEnvFloat("GRID_SIGNAL_HIGH", 0),
+			MinFactor:   getEnvFloat("GRID_MIN_FACTOR", defaultGridMinFactor),
+			Interval:    getEnvDuration("GRID_SIGNAL_INTERVAL", defaultGridInterval),
+			MaxAge:      getEnvDuration("GRID_SIGNAL_MAX_AGE", defaultGridMaxAge),
+		})
+		c.onStop(gridSignal.Stop)
+	}
+
+	// 策略脚本：每个周期根据观测值计算期望值和调整概率
+	loadConfiguredPolicy()
+
+	// 启动 CPU 控制器
+	if cpuEnabled {
+		cpuController.Start()
+		c.onStop(cpuController.Stop)
+	}
+	c.onStop(memoryController.Release)
+
+	return stats
+}
+
+// applyCalibration 读取校准文件，按当前期望值设置 CPU 工作协程的初始计算次数（调用方需持有锁）
+func (c *Controller) applyCalibration(kernel string) {
+	cal, path, err := resolveCalibration()
+	if err != nil {
+		logger.Warn("读取校准文件失败，使用默认初始值", "path", path, "error", err)
+		return
+	}
+	if cal == nil {
+		return
+	}
+	if cal.CPUCores != numCPU() {
+		logger.Warn("校准文件的核心数与本机不一致，结果可能不准确", "calibration_cores", cal.CPUCores, "cpu_cores", numCPU())
+	}
+	if cal.MaxCPUPercent > 0 && cal.MaxCPUPercent < maxHardPeak() {
+		logger.Warn("本机可达到的最大 CPU 使用率低于硬峰值", "max_cpu_percent", cal.MaxCPUPercent, "hard_peak", maxHardPeak())
+	}
+	if mbps := getEnvFloat("MEMORY_ACCESS_MBPS", 100); cal.MemoryBandwidthMBps > 0 && getEnvString("MEMORY_ACCESS_PATTERN", "none") != "none" && mbps > cal.MemoryBandwidthMBps {
+		logger.Warn("MEMORY_ACCESS_MBPS 超过校准时测得的内存带宽，实际速率达不到设置值", "memory_access_mbps", mbps, "memory_bandwidth_mbps", cal.MemoryBandwidthMBps)
+	}
+
+	expectedUsage := calculateExpectedUsage(peakUsage)
+	count, ok := cal.initialCount(kernel, expectedUsage, cpuController.SleepTime())
+	if !ok {
+		logger.Warn("校准文件中没有该计算内核的结果，使用默认初始值", "kernel", kernel)
+		return
+	}
+	cpuController.SetCount(count)
+	logger.Info("已根据校准结果设置初始计算次数", "path", path, "kernel", kernel, "expected_usage", expectedUsage, "cpu_count", count)
+}
+
+// run 主循环：定期采集系统资源并调整占用，ctx 取消时退出
+// 主循环 panic 时记录并在等待后重新进入（连续 panic 时等待时间加倍，最长 1 分钟；正常运行超过 1 分钟后恢复为 3 秒），
+// 避免一次意外的 panic 使负载停在当时的水平而没有任何调整
+func (c *Controller) run(ctx context.Context, stats *SystemStats) {
+	defer close(c.done)
+	delay := monitorInterval
+	for start := time.Now(); c.runLoop(ctx, stats); start = time.Now() {
+		if time.Since(start) > maxLoopRestartDelay {
+			delay = monitorInterval
+		}
+		logger.Warn("主循环 panic，等待后重新进入", "delay", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxLoopRestartDelay)
+	}
+}
+
+// loop 主循环的一次运行
+func (c *Controller) loop(ctx context.Context, stats *SystemStats) {
+	monitorTicker := clock.NewTicker(monitorInterval)
+	defer monitorTicker.Stop()
+
+	// 定时强制 GC（GC_FORCE_INTERVAL=0 时 gcC 为 nil，永远不会触发）
+	var gcC <-chan time.Time
+	if c.gcForceInterval > 0 {
+		gcTicker := clock.NewTicker(c.gcForceInterval)
+		defer gcTicker.Stop()
+		gcC = gcTicker.C()
+	}
+
+	// 每小时输出一次跟踪误差统计
+	trackingTicker := clock.NewTicker(time.Hour)
+	defer trackingTicker.Stop()
+
+	// 每 5 分钟更新一次 peakUsage
+	peakUsageTicker := clock.NewTicker(peakUsageInterval)
+	defer peakUsageTicker.Stop()
+	nextPeakUpdateAt.Store(clock.Now().Add(peakUsageInterval).UnixNano())
+
+	lastStats := stats
+	statsFrozen := false // 所有资源信息来源都不可用，冻结调整
+	killC := killSwitch.Changes()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case active := <-killC:
+			// 停止文件出现或被远程暂停时立即释放全部负载，恢复后由控制循环从空闲状态重新调整
+			if active {
+				logger.Warn("进入停止状态，释放全部负载并保持空闲", killSwitch.Reason()...)
+				emitEvent("kill_switch", append([]any{"active", true}, killSwitch.Reason()...)...)
+				c.dropLoad()
+			} else {
+				logger.Info("停止状态解除，恢复调整", killSwitch.Reason()...)
+				emitEvent("kill_switch", append([]any{"active", false}, killSwitch.Reason()...)...)
+				cpuController.SetPaused(false)
+			}
+
+		case <-gcC:
+			// 每隔 GC_FORCE_INTERVAL 触发 GC
+			runtime.GC()
+			logger.Info("触发垃圾回收")
+
+		case <-trackingTicker.C():
+			logTracking(clock.Now())
+
+		case <-peakUsageTicker.C():
+			// 每 5 分钟更新一次 peakUsage（由 controller 托管时跳过）
+			// 同时轮换各 CPU 工作协程的强度（CPU_WORKER_SPREAD）
+			nextPeakUpdateAt.Store(clock.Now().Add(peakUsageInterval).UnixNano())
+			cpuController.ReshuffleWeights()
+			if cpuCountModel != nil {
+				cpuCountModel.save()
+			}
+			if isPeakManaged() {
+				continue
+			}
+			updatePeakUsage()
+
+		case <-monitorTicker.C():
+			// 获取系统资源信息
+			currentStats, err := GetSystemStats()
+			if err != nil {
+				// 没有可信的数据时冻结调整：不增加也不减少，保持当前负载，直到某个来源恢复
+				if !statsFrozen {
+					logger.Warn("获取系统资源信息失败，冻结调整", "error", err)
+					emitEvent("stats_frozen", "error", err.Error())
+				}
+				statsFrozen = true
+				currentStats = lastStats
+			} else {
+				if statsFrozen {
+					logger.Info("系统资源信息恢复，继续调整", "backend", statsChain.Active())
+					statsFrozen = false
+				}
+				currentStats.GCCPUPercent, currentStats.GCCPUAvgPercent = gcTracker.Sample()
+				rescaleMemory(lastStats.TotalMemory, currentStats.TotalMemory)
+				if oldCores, newCores, changed := cpuTopology.Refresh(); changed {
+					applyCPUCountChange(oldCores, newCores)
+				}
+				selfOverhead.Sample(currentStats, memoryController.GetBufferMemory())
+				reclaimMonitor.Sample(currentStats)
+				oomWatcher.Observe(currentStats)
+				lastStats = currentStats
+			}
+
+			// 获取当前的 peakUsage（加读锁）
+			peakUsageMu.RLock()
+			currentPeakUsage := peakUsage
+			currentPeakUsageOrigin := peakUsageOrigin
+			peakUsageMu.RUnlock()
+
+			// 计算期望占用值
+			expectedUsage := evalExpectedUsage(c.expectedUsageFn, currentPeakUsage, currentStats)
+			if policyScript != nil {
+				expectedUsage = runPolicyScript(clock.Now().UTC(), currentPeakUsage, currentStats, expectedUsage)
+			}
+			// 电网信号：碳强度或电价较高时按系数降低，不低于 MIN_USAGE
+			if scaled := gridSignal.Scale(expectedUsage); scaled != expectedUsage {
+				expectedUsage = max(scaled, min(minUsage, expectedUsage))
+			}
+			isNightTime := isNightTime()
+
+			// 每日资源预算：累计上个周期的消耗（停止文件存在期间也累计），重新分配剩余的预算
+			dailyBudget.Update(clock.Now(), currentStats, currentPeakUsage)
+			cpuCredits.Update(clock.Now(), currentStats)
+
+			// 打印资源监控信息（LOG_MODE=changes 时暂存到周期结束，没有变化时丢弃）
+			cycleLog.Begin(cycleValues{
+				cpu:        currentStats.CPUPercent,
+				memory:     currentStats.MemoryPercent,
+				selfCPU:    currentStats.SelfCPUPercent,
+				selfMemory: currentStats.SelfMemoryPercent,
+				expected:   expectedUsage,
+				peak:       currentPeakUsage,
+				paused:     killSwitch.Active(),
+			})
+			cycleLog.Info("系统资源监控",
+				"cpu_percent", currentStats.CPUPercent,
+				"memory_percent", currentStats.MemoryPercent,
+				"self_cpu_percent", currentStats.SelfCPUPercent,
+				"other_cpu_percent", currentStats.OtherCPUPercent,
+				"self_memory_percent", currentStats.SelfMemoryPercent,
+				"other_memory_percent", currentStats.OtherMemoryPercent,
+				"self_rss_mb", currentStats.SelfMemory/(1024*1024),
+				"self_swap_mb", currentStats.SelfSwap/(1024*1024),
+				"sidecar_cpu_percent", currentStats.SidecarCPUPercent,
+				"sidecar_memory_percent", currentStats.SidecarMemoryPercent,
+				"expected_usage", expectedUsage,
+				"is_night_time", isNightTime,
+				"current_memory_mb", memoryController.GetCurrentMemory()/(1024*1024),
+				"buffer_memory_mb", memoryController.GetBufferMemory()/(1024*1024),
+				"cpu_count", cpuController.GetCount(),
+				"cpu_temp", currentStats.CPUTemp,
+				"cpu_freq_mhz", currentStats.CPUFreqMHz,
+				"steal_percent", currentStats.StealPercent,
+				"iowait_percent", currentStats.IOWaitPercent,
+				"reclaim_scan_per_sec", currentStats.ReclaimScanRate,
+				"direct_reclaim_per_sec", currentStats.DirectReclaimRate,
+				"load1", currentStats.Load1,
+				"cpu_workers", cpuController.GetWorkers(),
+				"cpu_spike_percent", spikeGenerator.Current(),
+				"kill_switch", killSwitch.Active(),
+				"gc_cpu_percent", currentStats.GCCPUPercent,
+				"gc_cpu_avg_percent", currentStats.GCCPUAvgPercent,
+				"load_cpu_percent", currentStats.LoadCPUPercent,
+				"overhead_cpu_percent", currentStats.OverheadCPUPercent,
+				"overhead_memory_mb", currentStats.OverheadMemory/(1024*1024),
+				"disk_percent", currentStats.DiskPercent,
+				"current_disk_mb", diskController.GetCurrentBytes()/(1024*1024),
+				"net_rate_kbps", netController.GetRate()*8/1000,
+				"net_sent_mb", netController.GetSent()/(1024*1024),
+				"conn_count", connController.GetCount())
+			if budget := dailyBudget.Status(); budget != nil {
+				cycleLog.Info("资源预算",
+					"cpu_spent_core_hours", budget.CPUSpentCoreHours,
+					"cpu_remaining_core_hours", budget.CPURemainingCoreHours,
+					"cpu_allowed_percent", budget.CPUAllowedPercent,
+					"memory_spent_gib_hours", budget.MemorySpentGiBHours,
+					"memory_remaining_gib_hours", budget.MemoryRemainingGiBHours,
+					"memory_allowed_percent", budget.MemoryAllowedPercent)
+			}
+			if credits := cpuCredits.Status(); credits != nil {
+				cycleLog.Info("CPU 积分",
+					"balance", credits.Balance,
+					"floor", credits.Floor,
+					"allowed_percent", credits.AllowedPercent,
+					"source", credits.Source)
+			}
+
+			setAgentStatus(AgentStatus{
+				PeakUsageOrigin:    currentPeakUsageOrigin,
+				PeakUsage:          currentPeakUsage,
+				ExpectedUsage:      expectedUsage,
+				IsNightTime:        isNightTime,
+				CPUPercent:         currentStats.CPUPercent,
+				MemoryPercent:      currentStats.MemoryPercent,
+				TotalMemory:        currentStats.TotalMemory,
+				CurrentMemoryBytes: memoryController.GetCurrentMemory(),
+				BufferMemoryBytes:  memoryController.GetBufferMemory(),
+				CPUCount:           cpuController.GetCount(),
+				DiskPercent:        currentStats.DiskPercent,
+				CPUTemp:            currentStats.CPUTemp,
+				CPUFreqMHz:         currentStats.CPUFreqMHz,
+				StealPercent:       currentStats.StealPercent,
+				ReclaimScanRate:    currentStats.ReclaimScanRate,
+				DirectReclaimRate:  currentStats.DirectReclaimRate,
+				SelfCPUPercent:     currentStats.SelfCPUPercent,
+				OtherCPUPercent:    currentStats.OtherCPUPercent,
+				SelfMemoryPercent:  currentStats.SelfMemoryPercent,
+				OtherMemoryPercent: currentStats.OtherMemoryPercent,
+				SelfMemoryBytes:    currentStats.SelfMemory,
+				LoadCPUPercent:     currentStats.LoadCPUPercent,
+				OverheadCPUPercent: currentStats.OverheadCPUPercent,
+				GCCPUPercent:       currentStats.GCCPUPercent,
+				OverheadMemory:     currentStats.OverheadMemory,
+				PerCPU:             currentStats.PerCPU,
+				Managed:            isPeakManaged(),
+				Budget:             dailyBudget.Status(),
+				GridSignal:         gridSignal.Status(),
+				CPUCredits:         cpuCredits.Status(),
+				OOM:                oomWatcher.Status(),
+				UpdatedAt:          clock.Now(),
+			})
+
+			// 随机间隔：设置 MONITOR_JITTER 时重设下一个周期的间隔（不阻塞循环），
+			// 避免固定 3 秒的调整在细粒度监控中形成梳状图案
+			if c.monitorJitter > 0 {
+				monitorTicker.Reset(jitteredMonitorInterval(c.monitorJitter))
+			}
+
+			// 记录到 /history（本周期的调整决定在调整之后填入）
+			entry := HistoryEntry{
+				Time:              clock.Now(),
+				PeakUsage:         currentPeakUsage,
+				ExpectedUsage:     expectedUsage,
+				CPUPercent:        currentStats.CPUPercent,
+				MemoryPercent:     currentStats.MemoryPercent,
+				SelfCPUPercent:    currentStats.SelfCPUPercent,
+				SelfMemoryPercent: currentStats.SelfMemoryPercent,
+				CPUCount:          cpuController.GetCount(),
+				BufferBytes:       memoryController.GetBufferMemory(),
+			}
+
+			// 停止文件存在期间保持空闲，不调整
+			if killSwitch.Active() {
+				entry.KillSwitch = true
+				recordSample(entry)
+				cycleLog.End(false)
+				continue
+			}
+
+			// 资源信息不可用期间保持当前负载，不调整
+			if statsFrozen {
+				recordSample(entry)
+				cycleLog.End(false)
+				continue
+			}
+
+			// 记录跟踪误差（与控制使用相同的占用值）
+			trackingStats.Add(clock.Now(), scopedCPUPercent(currentStats), scopedMemoryPercent(currentStats), expectedUsage)
+
+			// 执行资源调整
+			cpuController.AdaptSleep()
+			adjustResources(currentStats, expectedUsage)
+			decisions, actions := takeDecisions()
+			entry.Decisions = decisions
+			recordSample(entry)
+			emitAdjustmentEvent(entry, actions)
+			cycleLog.End(executedActions(actions))
+
+			// 调整负载模块的强度：CPU 超过硬峰值时降为 0（强制降低只减少 CPU 工作协程的计算次数，
+			// 负载模块和上下文切换同样占用 CPU，继续按期望值运行会使整机停留在硬峰值之上）
+			intensity := expectedUsage
+			if currentStats.CPUPercent > hardPeak() {
+				intensity = 0
+			}
+			for _, workload := range c.workloads {
+				workload.SetIntensity(intensity)
+			}
+
+			// 调整上下文切换速率：最大速率 × 强度
+			if c.cswitchRate > 0 {
+				cswitchController.SetRate(uint64(float64(c.cswitchRate) * intensity / 100))
+			}
+
+			// 调整网络发送速率：带宽上限 × 期望占用值
+			if netController.Enabled() {
+				netController.SetRate(uint64(float64(c.netBandwidthMbps) * 1000 * 1000 / 8 * expectedUsage / 100))
+			}
+		}
+	}
+}
+
+// getPeakUsage 从环境变量获取峰值使用率
+func getPeakUsage() int {
+	// 读取 P 或 p 环境变量（不区分大小写），未设置时使用预设中的值
+	value := lookupEnv("P")
+
+	if value == "" {
+		return defaultPeakUsage
+	}
+
+	peakUsage, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn("环境变量 P 值无效，使用默认值", "value", value, "default", defaultPeakUsage)
+		return defaultPeakUsage
+	}
+
+	// 验证范围
+	if peakUsage < 1 || peakUsage > 100 {
+		logger.Warn("环境变量 P 值超出范围，使用默认值", "value", peakUsage, "default", defaultPeakUsage)
+		return defaultPeakUsage
+	}
+
+	// 如果设置值过低，使用最小值
+	if peakUsage < minPeakUsage {
+		logger.Warn("环境变量 P 值过低，使用最小值", "value", peakUsage, "min", minPeakUsage)
+		return minPeakUsage
+	}
+
+	return peakUsage
+}
+
+// jitteredMonitorInterval 在 monitorInterval × (1 ± jitter) 内随机生成下一个监控周期
+func jitteredMonitorInterval(jitter float64) time.Duration {
+	return time.Duration(float64(monitorInterval) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// isNightTime 判断是否是凌晨时段（UTC 16:00-20:00）
+func isNightTime() bool {
+	return isNightTimeAt(clock.Now())
+}
+
+// isNightTimeAt 判断指定时间是否是凌晨时段（UTC 16:00-20:00）
+func isNightTimeAt(t time.Time) bool {
+	hour := t.UTC().Hour()
+	return hour >= 16 && hour < 20
+}
+
+// calculateExpectedUsage 计算当前的期望占用值
+func calculateExpectedUsage(userPeakUsage int) float64 {
+	return calculateExpectedUsageAt(clock.Now(), userPeakUsage)
+}
+
+// calculateExpectedUsageAt 计算指定时间的期望占用值
+func calculateExpectedUsageAt(t time.Time, userPeakUsage int) float64 {
+	var expectedUsage float64
+
+	if isNightTimeAt(t) {
+		// 凌晨时段：期望占用 = min(用户设置值, 70%)
+		expectedUsage = float64(userPeakUsage)
+	} else {
+		// 其他时段：期望占用 = min(用户设置值 * 白天系数, 70%)，白天系数默认 0.8，可以按时段设置
+		expectedUsage = float64(userPeakUsage) * dayFactorAt(t)
+	}
+
+	// 硬峰值限制：任何时段都不能超过该时段的硬峰值（默认 70%）
+	if limit := hardPeakAt(t); expectedUsage > limit {
+		expectedUsage = limit
+	}
+
+	return expectedUsage
+}
+
+// adjustResources 调整资源占用
+func adjustResources(stats *SystemStats, expectedUsage float64) {
+	// 调整内存
+	if memoryEnabled {
+		adjustMemory(stats, expectedUsage)
+	}
+
+	// 调整 CPU
+	if cpuEnabled {
+		if spikeGenerator.Current() == 0 {
+			// 突发之外的整机占用决定下一次突发的上限
+			spikeGenerator.SetHeadroom(stats.CPUPercent)
+		}
+		adjustCPU(stats, expectedUsage)
+	}
+
+	// 调整磁盘
+	if diskController.Enabled() {
+		adjustDisk(stats, expectedUsage)
+	}
+
+	// 调整 GPU
+	if gpuController.Enabled() {
+		adjustGPU(expectedUsage)
+	}
+}
+
+// adjustMemory 调整内存占用
+func adjustMemory(stats *SystemStats, expectedUsage float64) {
+	currentPercent := stats.MemoryPercent
+	if targetScope != "system" {
+		currentPercent = scopedMemoryPercent(stats)
+	}
+
+	// 内存回收检查：内核已经在频繁回收内存时不再增加内存占用，早于使用率达到硬峰值回退，避免拖慢同机的其他服务
+	if reclaimPressure(stats) {
+		logger.Warn("内存回收速率过高，强制降低", "reclaim_scan_per_sec", stats.ReclaimScanRate,
+			"direct_reclaim_per_sec", stats.DirectReclaimRate, "reclaim_backoff", reclaimBackoff)
+		emitEvent("reclaim", "resource", "内存", "reclaim_scan_per_sec", stats.ReclaimScanRate,
+			"direct_reclaim_per_sec", stats.DirectReclaimRate, "reclaim_backoff", reclaimBackoff)
+		forceDecrease("内存", currentPercent, memoryController.AdjustMemoryRandom)
+		return
+	}
+
+	if targetScope != "system" {
+		// 本进程 / sidecar 模式：整机占用的硬峰值仍然生效
+		if limit := hardPeak(); stats.MemoryPercent > limit {
+			logger.Warn("内存占用超过硬峰值，强制降低", "current_percent", stats.MemoryPercent, "hard_peak", limit)
+			emitEvent("hard_peak", "resource", "内存", "current_percent", stats.MemoryPercent, "hard_peak", limit)
+			forceDecrease("内存", currentPercent, memoryController.AdjustMemoryRandom)
+			return
+		}
+	}
+	expectedUsage = dailyBudget.MemoryTarget(stats, expectedUsage)
+	adjust := memoryController.AdjustMemoryRandom
+	if oomWatcher.Holding() {
+		// 发生 OOM 后的一段时间内只降低、不增加内存占用（包括低于 MIN_USAGE 时的强制增加）
+		adjust = func(shouldIncrease bool) (bool, bool, uint64) {
+			if shouldIncrease {
+				return false, false, 0
+			}
+			return memoryController.AdjustMemoryRandom(false)
+		}
+	}
+	adjustByStrategy("内存", currentPercent, expectedUsage, adjust)
+}
+
+// scopedCPUPercent 按 TARGET_SCOPE 计算参与控制的 CPU 使用率
+// sidecar 模式下为目标容器与本进程之和，使 "应用 + 填充" 一起跟随期望曲线
+func scopedCPUPercent(stats *SystemStats) float64 {
+	switch targetScope {
+	case "self":
+		return stats.SelfCPUPercent
+	case "sidecar":
+		return stats.SidecarCPUPercent + stats.SelfCPUPercent
+	default:
+		return stats.CPUPercent
+	}
+}
+
+// scopedMemoryPercent 按 TARGET_SCOPE 计算参与控制的内存使用率
+func scopedMemoryPercent(stats *SystemStats) float64 {
+	switch targetScope {
+	case "self":
+		return stats.SelfMemoryPercent
+	case "sidecar":
+		return stats.SidecarMemoryPercent + stats.SelfMemoryPercent
+	default:
+		return stats.MemoryPercent
+	}
+}
+
+// adjustCPU 调整 CPU 占用
+func adjustCPU(stats *SystemStats, expectedUsage float64) {
+	// 分位数模式：记录每个周期的占用（包括突发和强制调整的周期，与看板的统计一致）
+	if cpuObjective == "percentile" {
+		percentileObjective.Observe(clock.Now(), scopedCPUPercent(stats))
+	}
+
+	// 温度检查：超过温度上限时强制降低，避免设备过热降频或关机
+	if thermalMaxC > 0 && stats.CPUTemp > thermalMaxC {
+		logger.Warn("CPU 温度超过上限，强制降低", "cpu_temp", stats.CPUTemp, "thermal_max", thermalMaxC)
+		emitEvent("thermal", "resource", "CPU", "cpu_temp", stats.CPUTemp, "thermal_max", thermalMaxC)
+		forceDecrease("CPU", stats.CPUPercent, cpuController.AdjustCountRandom)
+		return
+	}
+
+	// iowait 检查：磁盘已经饱和时不再增加 CPU 负载
+	if iowaitBackoff > 0 && stats.IOWaitPercent > iowaitBackoff {
+		logger.Warn("CPU iowait 过高，强制降低", "iowait_percent", stats.IOWaitPercent, "iowait_backoff", iowaitBackoff)
+		emitEvent("iowait", "resource", "CPU", "iowait_percent", stats.IOWaitPercent, "iowait_backoff", iowaitBackoff)
+		forceDecrease("CPU", stats.CPUPercent, cpuController.AdjustCountRandom)
+		return
+	}
+
+	// 短时突发期间不调整：突发叠加在期望曲线之上，不应被控制循环抵消
+	if spike := spikeGenerator.Current(); spike > 0 {
+		if stats.CPUPercent <= hardPeak() {
+			return
+		}
+		logger.Warn("CPU 突发期间超过硬峰值，按正常流程调整", "current_percent", stats.CPUPercent, "spike_percent", spike)
+	}
+
+	// 硬峰值检查：按整机的实际占用判断（各种模式都生效），
+	// 下面的 GC 补偿和频率补偿只用于跟踪期望值：GC 尖峰同样占用了 CPU，降频时等效使用率偏低，都不能用来绕过硬峰值
+	limit := hardPeak()
+	if stats.CPUPercent > limit {
+		logger.Warn("CPU占用超过硬峰值，强制降低", "current_percent", stats.CPUPercent, "hard_peak", limit)
+		emitEvent("hard_peak", "resource", "CPU", "current_percent", stats.CPUPercent, "hard_peak", limit)
+		switch {
+		case cpuObjective == "loadavg":
+			forceDecrease("CPU", stats.CPUPercent, cpuController.AdjustWorkersRandom)
+		case cpuControl == "closedloop" || cpuCountModel != nil:
+			forceDecreaseCount(stats, stats.CPUPercent, limit)
+		default:
+			forceDecrease("CPU", stats.CPUPercent, cpuController.AdjustCountRandom)
+		}
+		return
+	}
+	// 整机实际占用距离硬峰值的余量，闭环调整和模型跳转一步最多用完该余量
+	headroom := limit - stats.CPUPercent
+
+	// GC 补偿：扣除本周期 GC 占用相对平均值的波动
+	stats = gcCompensatedStats(stats)
+
+	// 本进程 / sidecar 模式按对应的占用跟踪期望值
+	currentPercent := scopedCPUPercent(stats)
+	if cpuRefFreqMHz > 0 && stats.CPUFreqMHz > 0 {
+		// 频率补偿：把使用率换算为参考频率下的等效使用率，
+		// 降频时等效使用率降低，控制器会增加负载，使实际完成的计算量保持稳定
+		currentPercent = currentPercent * stats.CPUFreqMHz / cpuRefFreqMHz
+	}
+
+	if cpuObjective == "loadavg" {
+		adjustLoadAvg(stats)
+		return
+	}
+
+	// 每日资源预算：本进程的部分不超过预算当前允许的占用
+	expectedUsage = dailyBudget.CPUTarget(stats, expectedUsage)
+	// CPU 积分：整机占用不超过积分当前允许的占用
+	expectedUsage = cpuCredits.CPUTarget(stats, expectedUsage)
+
+	if cpuPerCore {
+		adjustPerCore(stats, expectedUsage)
+		return
+	}
+
+	// 分位数模式：按窗口内的波动降低瞬时目标，使窗口内的分位数接近期望值
+	if cpuObjective == "percentile" {
+		expectedUsage = percentileObjective.Setpoint(expectedUsage)
+	}
+
+	// 计算次数模型：记录上个周期的观测值，期望值变化较大时直接跳到模型给出的计算次数
+	if cpuCountModel != nil {
+		cpuCountModel.observe(cpuController.GetCount(), stats.SelfCPUPercent)
+		if cpuCountModel.jump(stats, expectedUsage, headroom) {
+			return
+		}
+	}
+
+	if cpuControl == "closedloop" {
+		adjustClosedLoop(stats, currentPercent, expectedUsage, headroom)
+		return
+	}
+
+	adjustByStrategy("CPU", currentPercent, expectedUsage, cpuController.AdjustCountRandom)
+}
+
+// adjustPerCore 按核心独立调整：每个核心的使用率只由绑定到该核心的工作协程调整
+func adjustPerCore(stats *SystemStats, expectedUsage float64) {
+	for core := 0; core < len(stats.PerCPU) && core < numCPU(); core++ {
+		target := expectedUsage
+		if t, ok := coreTargets[core]; ok {
+			target = min(t, hardPeak())
+		}
+		adjustByStrategy(fmt.Sprintf("CPU%d", core), stats.PerCPU[core], target, func(shouldIncrease bool) (bool, bool, uint64) {
+			return cpuController.AdjustCoreRandom(core, shouldIncrease)
+		})
+	}
+}
+
+// adjustLoadAvg loadavg 模式：通过增减工作协程数量，使 1 分钟平均负载维持在 LOADAVG_TARGET × 核心数附近
+// CPU 使用率的硬峰值由 adjustCPU 检查
+func adjustLoadAvg(stats *SystemStats) {
+	// 换算为每核心负载的百分比，复用相同的概率算法
+	currentPercent := stats.Load1 / float64(numCPU()) * 100
+	targetPercent := loadAvgTarget * 100
+	diff := currentPercent - targetPercent
+
+	if !shouldAdjust(calculateAdjustProbability(abs(diff))) {
+		cycleLog.Info("负载-" + formatPercent(currentPercent) + "-跳过")
+		recordDecision("负载", "", "跳过")
+		return
+	}
+
+	increaseProb := calculateDirectionProbability(diff, targetPercent)
+	success, increased, workers := cpuController.AdjustWorkersRandom(rand.Float64() < increaseProb)
+	if success {
+		action := "减少"
+		if increased {
+			action = "增加"
+		}
+		cycleLog.Info("负载-"+formatPercent(currentPercent)+"-"+formatProbability(increaseProb)+"-"+action, "workers", workers)
+		recordDecision("负载", formatProbability(increaseProb), action)
+	}
+}
+
+// adjustDisk 调整磁盘占用
+// 不做 MIN_USAGE 的强制增加：MIN_USAGE 是 CPU / 内存的下限，磁盘使用率低于它时不应因此填充磁盘
+func adjustDisk(stats *SystemStats, expectedUsage float64) {
+	adjustTowards("磁盘", stats.DiskPercent, diskController.Target(expectedUsage), 0, diskController.AdjustDiskRandom)
+}
+
+// adjustGPU 调整 GPU 计算强度和显存占用
+func adjustGPU(expectedUsage float64) {
+	gpuStats, err := gpuController.Stats()
+	if err != nil {
+		logger.Warn("获取 GPU 信息失败，跳过本次调整", "error", err)
+		return
+	}
+	adjustByStrategy("GPU", gpuStats.UtilPercent, expectedUsage, gpuController.AdjustIntensityRandom)
+	adjustByStrategy("显存", gpuStats.MemoryPercent, expectedUsage, gpuController.AdjustMemoryRandom)
+}
+
+// adjustByStrategy 按 CONTROL_STRATEGY 选择的控制策略调整一类资源的占用
+// name: 资源名称（用于日志）；adjust: 执行调整的函数，参数为 true=增加，false=减少
+func adjustByStrategy(name string, currentPercent, expectedUsage float64, adjust func(shouldIncrease bool) (bool, bool, uint64)) {
+	adjustTowards(name, currentPercent, expectedUsage, minUsage, adjust)
+}
+
+// adjustTowards 与 adjustByStrategy 相同，floor 为强制增加的下限（0 表示不检查）
+func adjustTowards(name string, currentPercent, expectedUsage, floor float64, adjust func(shouldIncrease bool) (bool, bool, uint64)) {
+	stallDetector.Observe(name, currentPercent, expectedUsage)
+
+	// 硬峰值检查：如果超过当前时段的硬峰值（默认 70%），必须强制降低（安全机制）
+	if limit := hardPeak(); currentPercent > limit {
+		logger.Warn(name+"占用超过硬峰值，强制降低", "current_percent", currentPercent, "hard_peak", limit)
+		emitEvent("hard_peak", "resource", name, "current_percent", currentPercent, "hard_peak", limit)
+		forceDecrease(name, currentPercent, adjust)
+		return
+	}
+
+	// 最低占用检查：低于 MIN_USAGE 时强制增加（与硬峰值对称）
+	if currentPercent < floor {
+		logger.Warn(name+"占用低于最低值，强制增加", "current_percent", currentPercent, "min_usage", floor)
+		emitEvent("min_usage", "resource", name, "current_percent", currentPercent, "min_usage", floor)
+		forceIncrease(name, currentPercent, adjust)
+		return
+	}
+
+	strategy := controlStrategy
+	if strategy == nil {
+		strategy = probabilityWalk{}
+	}
+	decision := strategy.Decide(name, currentPercent, expectedUsage)
+	if decision.Steps == 0 {
+		// 格式化：资源-当前占用%-跳过
+		cycleLog.Info(name + "-" + formatPercent(currentPercent) + "-跳过")
+		recordDecision(name, "", "跳过")
+		return
+	}
+
+	// 执行调整
+	shouldIncrease, steps := decision.Steps > 0, decision.Steps
+	if steps < 0 {
+		steps = -steps
+	}
+	success, increased := false, shouldIncrease
+	for i := 0; i < steps; i++ {
+		ok, inc, _ := adjust(shouldIncrease)
+		if !ok {
+			break
+		}
+		success, increased = true, inc
+	}
+	if success {
+		action := "减少"
+		if increased {
+			action = "增加"
+		}
+		// 格式化：资源-当前占用%-决策依据（如增加概率）-实际动作
+		cycleLog.Info(name + "-" + formatPercent(currentPercent) + "-" + decision.Label + "-" + action)
+		recordDecision(name, decision.Label, action)
+	}
+}
+
+// forceDecrease 强制减少一类资源的占用（不随机）
+func forceDecrease(name string, currentPercent float64, adjust func(shouldIncrease bool) (bool, bool, uint64)) {
+	success, _, _ := adjust(false)
+	if success {
+		// 格式化：资源-当前占用%-强制-减少
+		cycleLog.Info(name + "-" + formatPercent(currentPercent) + "-强制-减少")
+		recordDecision(name, "强制", "减少")
+	}
+}
+
+// forceIncrease 强制增加占用（低于最低占用时使用）
+func forceIncrease(name string, currentPercent float64, adjust func(shouldIncrease bool) (bool, bool, uint64)) {
+	success, _, _ := adjust(true)
+	if success {
+		// 格式化：资源-当前占用%-强制-增加
+		cycleLog.Info(name + "-" + formatPercent(currentPercent) + "-强制-增加")
+		recordDecision(name, "强制", "增加")
+	}
+}
+
+// loadCurveConfig 读取期望曲线相关的配置：peakUsage 随机波动、白天系数、硬峰值和最低占用
+func loadCurveConfig() {
+	loadPeakWalk()
+	loadHardPeaks()
+	loadDayFactors()
+	loadMinUsage()
+	loadDecisionTable()
+	loadStrategy()
+}
+
+// loadMinUsage 从环境变量读取最低占用，不能超过两个时段中较低的硬峰值
+func loadMinUsage() {
+	minUsage = getEnvFloat("MIN_USAGE", 0)
+	if limit := min(nightHardPeak, dayHardPeak); minUsage < 0 || minUsage >= limit {
+		logger.Warn("MIN_USAGE 超出范围，不限制最低占用", "value", minUsage, "hard_peak", limit)
+		minUsage = 0
+	}
+}
+
+// calculateAdjustProbability 计算是否执行调整的概率
+func calculateAdjustProbability(diff float64) float64 {
+	if decisionTable != nil {
+		if prob, ok := lookupProb(decisionTable.Adjust, diff); ok {
+			return prob
+		}
+	}
+	if diff > 5 {
+		return 0.90 // 90%
+	} else if diff >= 2 {
+		return 0.70 // 70%
+	} else {
+		return 0.60 // 60%
+	}
+}
+
+// calculateDirectionProbability 计算上涨（增加占用）的概率
+// diff: 当前值 - 期望值（正数表示当前 > 期望，负数表示当前 < 期望）
+// expectedUsage: 期望值
+// 返回：上涨的概率（0.0 - 1.0）
+func calculateDirectionProbability(diff, expectedUsage float64) float64 {
+	absDiff := abs(diff)
+
+	// 概率表给出朝期望值方向调整的概率，当前 > 期望时换算为上涨概率
+	if decisionTable != nil {
+		if prob, ok := lookupProb(decisionTable.Toward, absDiff); ok {
+			if diff < 0 {
+				return prob
+			}
+			return 1 - prob
+		}
+	}
+
+	if diff < 0 {
+		// 当前 < 期望，应该上涨（增加占用）
+		// 差值越大，上涨概率越大
+		if absDiff > 50 {
+			return 0.90 // 差70%，上涨概率90%
+		} else if absDiff > 20 {
+			return 0.80 // 差20-50%，上涨概率80%
+		} else if absDiff > 10 {
+			return 0.70 // 差10-20%，上涨概率70%
+		} else if absDiff > 5 {
+			return 0.65 // 差5-10%，上涨概率65%
+		} else if absDiff >= 2 {
+			return 0.60 // 差2-5%，上涨概率60%
+		} else {
+			return 0.55 // 差<2%，上涨概率55%（接近期望值）
+		}
+	} else {
+		// 当前 > 期望，应该下跌（减少占用）
+		// 差值越大，下跌概率越大（上涨概率越小）
+		if absDiff > 50 {
+			return 0.10 // 差70%，上涨概率10%（下跌概率90%）
+		} else if absDiff > 20 {
+			return 0.20 // 差20-50%，上涨概率20%（下跌概率80%）
+		} else if absDiff > 10 {
+			return 0.30 // 差10-20%，上涨概率30%（下跌概率70%）
+		} else if absDiff > 5 {
+			return 0.35 // 差5-10%，上涨概率35%（下跌概率65%）
+		} else if absDiff >= 2 {
+			return 0.40 // 差2-5%，上涨概率40%（下跌概率60%）
+		} else {
+			return 0.45 // 差<2%，上涨概率45%（下跌概率55%，接近期望值）
+		}
+	}
+}
+
+// shouldAdjust 根据概率决定是否调整
+func shouldAdjust(probability float64) bool {
+	return rand.Float64() < probability
+}
+
+// abs 计算绝对值
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// formatPercent 格式化百分比，保留1位小数
+func formatPercent(p float64) string {
+	return fmt.Sprintf("%.1f%%", p)
+}
+
+// formatProbability 格式化概率，保留1位小数（0.0-1.0）
+func formatProbability(p float64) string {
+	return fmt.Sprintf("%.1f", p)
+}
+
+// updatePeakUsage 每 5 分钟更新一次 peakUsage
+// 新值范围：rand[0.2 * peakUsage_origin, peakUsage_origin]
+func updatePeakUsage() {
+	peakUsageMu.Lock()
+	defer peakUsageMu.Unlock()
+
+	// 保存旧值用于日志
+	oldPeakUsage := peakUsage
+	var oldState string
+	if peakStates != nil {
+		oldState = peakStates.current
+	}
+
+	peakUsage = nextPeakUsage(peakUsageOrigin, oldPeakUsage)
+
+	if peakStates != nil {
+		if peakStates.current != oldState {
+			logger.Info("负载状态切换", "from", oldState, "to", peakStates.current, "dwell", peakStates.remaining)
+		}
+		low, high := peakUsageRange(peakUsageOrigin)
+		logger.Info("peakUsage 更新",
+			"peak_usage_origin", peakUsageOrigin,
+			"peak_usage_old", oldPeakUsage,
+			"peak_usage_new", peakUsage,
+			"state", peakStates.current,
+			"range", fmt.Sprintf("[%d, %d]", low, high))
+		return
+	}
+
+	logger.Info("peakUsage 更新",
+		"peak_usage_origin", peakUsageOrigin,
+		"peak_usage_old", oldPeakUsage,
+		"peak_usage_new", peakUsage,
+		"range", fmt.Sprintf("[%.1f, %d]", float64(peakUsageOrigin)*peakWalk.lowFactor, peakUsageOrigin))
+}
+
+// peakWalkConfig peakUsage 随机波动的参数
+type peakWalkConfig struct {
+	lowFactor    float64 // 范围下限 = lowFactor * origin
+	distribution string  // uniform（在范围内均匀分布）或 normal（以 meanFactor * origin 为中心的正态分布）
+	meanFactor   float64 // normal 分布的中心 = meanFactor * origin
+	stddevFactor float64 // normal 分布的标准差 = stddevFactor * origin
+	maxStep      int     // 每次更新的最大变化量（百分点，0 表示不限制）
+}
+
+// peakWalk 默认：在 [0.2 * origin, origin] 内均匀分布，变化量不限
+var peakWalk = peakWalkConfig{lowFactor: 0.2, distribution: "uniform", meanFactor: 0.6, stddevFactor: 0.2}
+
+// loadPeakWalk 从环境变量读取 peakUsage 随机波动的参数
+func loadPeakWalk() {
+	low := min(max(getEnvFloat("PEAK_LOW_FACTOR", 0.2), 0), 1)
+	peakWalk = peakWalkConfig{
+		lowFactor:    low,
+		distribution: getEnvString("PEAK_DISTRIBUTION", "uniform"),
+		meanFactor:   min(max(getEnvFloat("PEAK_MEAN_FACTOR", (1+low)/2), low), 1),
+		stddevFactor: max(getEnvFloat("PEAK_STDDEV_FACTOR", 0.2), 0),
+		maxStep:      max(getEnvInt("PEAK_MAX_STEP", 0), 0),
+	}
+	if peakWalk.distribution != "uniform" && peakWalk.distribution != "normal" {
+		logger.Warn("PEAK_DISTRIBUTION 无效，使用默认值", "value", peakWalk.distribution, "default", "uniform")
+		peakWalk.distribution = "uniform"
+	}
+
+	peakStates = nil
+	if path := lookupEnv("PEAK_STATES_FILE"); path != "" {
+		sm, err := loadPeakStates(path)
+		if err != nil {
+			logger.Warn("加载负载状态文件失败，使用随机波动", "path", path, "error", err)
+			return
+		}
+		peakStates = sm
+		logger.Info("已加载负载状态文件", "path", path, "states", len(sm.states), "initial", sm.current)
+	}
+}
+
+// peakUsageRange peakUsage 随机波动的范围：[PEAK_LOW_FACTOR * origin, origin]（不低于最小值）
+// 使用状态机时为所有状态范围的并集
+func peakUsageRange(origin int) (int, int) {
+	if peakStates != nil {
+		low, high := peakStates.bounds()
+		return max(int(float64(origin)*low), minPeakUsage), max(int(float64(origin)*high), minPeakUsage)
+	}
+	return max(int(float64(origin)*peakWalk.lowFactor), minPeakUsage), origin
+}
+
+// nextPeakUsage 在 [PEAK_LOW_FACTOR * origin, origin] 范围内随机生成新的 peakUsage
+// 按 PEAK_DISTRIBUTION 选择分布，设置 PEAK_MAX_STEP 时与上一次的值 prev 相差不超过该值
+// 设置 PEAK_STATES_FILE 时由状态机推进一个更新周期，在当前状态的范围内生成
+func nextPeakUsage(origin, prev int) int {
+	// 计算范围：PEAK_LOW_FACTOR * peakUsage_origin 到 peakUsage_origin
+	minValue := float64(origin) * peakWalk.lowFactor
+	maxValue := float64(origin)
+
+	// 生成随机值
+	var newValue float64
+	if peakStates != nil {
+		peakStates.advance(peakUsageInterval)
+		newValue = float64(origin) * peakStates.factor()
+		minValue = 0
+	} else if peakWalk.distribution == "normal" {
+		newValue = float64(origin) * (peakWalk.meanFactor + rand.NormFloat64()*peakWalk.stddevFactor)
+		newValue = min(max(newValue, minValue), maxValue)
+	} else {
+		newValue = minValue + rand.Float64()*(maxValue-minValue)
+	}
+	if step := float64(peakWalk.maxStep); step > 0 {
+		newValue = min(max(newValue, float64(prev)-step), float64(prev)+step)
+	}
+
+	// 转换为整数，并确保不小于最小值
+	value := int(newValue)
+	if value < int(minValue) {
+		value = int(minValue)
+	}
+	if value < minPeakUsage {
+		value = minPeakUsage
+	}
+	return value
+}
@@ -0,0 +1,276 @@
+package busy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	calibrateKernelDuration = 500 * time.Millisecond // 每个计算内核的测量时长
+	calibrateLoadDuration   = 2 * time.Second        // 最大负载的测量时长
+	calibrateMemoryMB       = 256                    // 内存分配测量的总量（MB）
+	calibrateCopyMB         = 64                     // 内存带宽测量的缓冲区大小（MB，大于常见的 LLC）
+	defaultCalibrationFile  = "cpumembusy-calibration.json"
+	defaultCalibrationName  = "default" // CALIBRATION_DIR 中没有当前机型的文件时使用 default.json
+)
+
+// Calibration 校准结果（calibrate 子命令写入 CALIBRATION_FILE 或 CALIBRATION_DIR，agent 启动时读取）
+type Calibration struct {
+	CreatedAt           time.Time          `json:"created_at"`
+	InstanceType        string             `json:"instance_type,omitempty"` // 校准时的云主机机型（不在云上时为空）
+	CPUCores            int                `json:"cpu_cores"`
+	KernelItersPerMs    map[string]float64 `json:"kernel_iters_per_ms"` // 单个工作协程每毫秒的迭代次数
+	MemoryAllocMBps     float64            `json:"memory_alloc_mb_per_sec"`
+	MemoryBandwidthMBps float64            `json:"memory_bandwidth_mb_per_sec,omitempty"` // 单个协程复制内存的速度
+	GCDurationMs        float64            `json:"gc_duration_ms"`                        // 释放 calibrateMemoryMB 后一次 GC 的耗时
+	MaxCPUPercent       float64            `json:"max_cpu_percent"`                       // 所有核心满负载时的整机 CPU 使用率
+}
+
+// detectInstanceType 本机的机型：优先使用 INSTANCE_TYPE，其次查询云厂商元数据（CLOUD_METADATA 关闭或不在云上时为空）
+func detectInstanceType() string {
+	if value := lookupEnv("INSTANCE_TYPE"); value != "" {
+		return value
+	}
+	if !getEnvBool("CLOUD_METADATA", true) {
+		return ""
+	}
+	instanceType, provider := fetchInstanceType()
+	if instanceType != "" {
+		logger.Info("已从云厂商元数据获取机型", "provider", provider, "instance_type", instanceType)
+	}
+	return instanceType
+}
+
+// CalibrationOutputPath calibrate 子命令写入的文件：CALIBRATION_FILE，其次是 CALIBRATION_DIR 中以本机机型命名的文件
+// （不在云上时为 default.json），都没有设置时为当前目录下的 cpumembusy-calibration.json
+func CalibrationOutputPath() string {
+	if path := lookupEnv("CALIBRATION_FILE"); path != "" {
+		return path
+	}
+	if dir := lookupEnv("CALIBRATION_DIR"); dir != "" {
+		name := detectInstanceType()
+		if name == "" {
+			name = defaultCalibrationName
+		}
+		return calibrationProfilePath(dir, name)
+	}
+	return defaultCalibrationFile
+}
+
+// calibrationProfilePath CALIBRATION_DIR 中机型对应的文件（机型中的路径分隔符被去掉，不会指向目录之外）
+func calibrationProfilePath(dir, instanceType string) string {
+	return filepath.Join(dir, filepath.Base(filepath.Clean("/"+instanceType))+".json")
+}
+
+// resolveCalibration 按配置读取校准结果：CALIBRATION_FILE 优先，其次在 CALIBRATION_DIR 中按机型选择，
+// 没有该机型的文件时使用 default.json；都没有设置时返回 nil
+// 同一个镜像部署到不同机型的主机上时，每台主机自动使用与自己机型对应的校准结果
+func resolveCalibration() (*Calibration, string, error) {
+	if path := lookupEnv("CALIBRATION_FILE"); path != "" {
+		cal, err := loadCalibration(path)
+		return cal, path, err
+	}
+	dir := lookupEnv("CALIBRATION_DIR")
+	if dir == "" {
+		return nil, "", nil
+	}
+
+	instanceType := detectInstanceType()
+	var candidates []string
+	if instanceType != "" {
+		candidates = append(candidates, calibrationProfilePath(dir, instanceType))
+	}
+	candidates = append(candidates, calibrationProfilePath(dir, defaultCalibrationName))
+	for _, path := range candidates {
+		cal, err := loadCalibration(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil && instanceType != "" && cal.InstanceType != "" && cal.InstanceType != instanceType {
+			logger.Warn("校准文件的机型与本机不一致，结果可能不准确", "path", path, "calibration_instance_type", cal.InstanceType, "instance_type", instanceType)
+		}
+		return cal, path, err
+	}
+	return nil, "", fmt.Errorf("%s 中没有机型 %q 的校准文件，也没有 %s.json", dir, instanceType, defaultCalibrationName)
+}
+
+// Calibrate 测量本机的计算速度、内存分配速度和可达到的最大负载，结果写入 path（calibrate 子命令）
+func Calibrate(w io.Writer, path string) error {
+	cal := Calibration{
+		CreatedAt:        time.Now().UTC(),
+		InstanceType:     detectInstanceType(),
+		CPUCores:         runtime.NumCPU(),
+		KernelItersPerMs: make(map[string]float64),
+	}
+	if cal.InstanceType != "" {
+		fmt.Fprintf(w, "机型              %s\n", cal.InstanceType)
+	}
+
+	for _, name := range cpuKernelNames() {
+		rate, err := measureKernel(name, calibrateKernelDuration)
+		if err != nil {
+			return err
+		}
+		cal.KernelItersPerMs[name] = rate
+		fmt.Fprintf(w, "计算内核 %-10s %.0f 次/ms\n", name, rate)
+	}
+
+	cal.MemoryAllocMBps, cal.GCDurationMs = measureMemory(calibrateMemoryMB)
+	fmt.Fprintf(w, "内存分配速度      %.0f MB/s\n", cal.MemoryAllocMBps)
+	fmt.Fprintf(w, "GC 耗时           %.1f ms（释放 %d MB 后）\n", cal.GCDurationMs, calibrateMemoryMB)
+	cal.MemoryBandwidthMBps = measureMemoryBandwidth(calibrateCopyMB, calibrateKernelDuration)
+	fmt.Fprintf(w, "内存带宽          %.0f MB/s（单协程复制）\n", cal.MemoryBandwidthMBps)
+
+	maxPercent, err := measureMaxLoad(calibrateLoadDuration)
+	if err != nil {
+		return fmt.Errorf("测量最大负载失败: %w", err)
+	}
+	cal.MaxCPUPercent = maxPercent
+	fmt.Fprintf(w, "最大 CPU 使用率   %.1f%%\n", cal.MaxCPUPercent)
+
+	data, err := json.MarshalIndent(cal, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入校准文件失败: %w", err)
+	}
+	fmt.Fprintf(w, "校准结果已写入 %s\n", path)
+	return nil
+}
+
+// loadCalibration 读取校准文件
+func loadCalibration(path string) (*Calibration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cal Calibration
+	if err := json.Unmarshal(data, &cal); err != nil {
+		return nil, fmt.Errorf("校准文件格式错误: %w", err)
+	}
+	return &cal, nil
+}
+
+// initialCount 根据校准结果计算达到期望占用值所需的计算次数
+// 每个核心一个工作协程，每 count 次计算 sleep 一次：占用 = 计算时间 / (计算时间 + sleep)
+func (cal *Calibration) initialCount(kernel string, expectedUsage float64, sleep time.Duration) (uint64, bool) {
+	rate, ok := cal.KernelItersPerMs[kernel]
+	if !ok || rate <= 0 || expectedUsage <= 0 {
+		return 0, false
+	}
+	duty := min(expectedUsage/100, 0.99)
+	busyMs := duty / (1 - duty) * float64(sleep) / float64(time.Millisecond)
+	return max(uint64(rate*busyMs), 1), true
+}
+
+// measureKernel 测量计算内核在工作协程循环中每毫秒的迭代次数
+func measureKernel(name string, d time.Duration) (float64, error) {
+	kernel, err := newCPUKernel(name)
+	if err != nil {
+		return 0, err
+	}
+	// 预热：部分内核（如 data）在首次迭代时才分配工作集，不计入测量
+	kernel.run(0, 1)
+
+	// 与 cpuWorker 的循环结构一致：按批迭代，批与批之间检查退出信号
+	done := make(chan struct{})
+	time.AfterFunc(d, func() { close(done) })
+	start := time.Now()
+	var counter uint64
+	for {
+		select {
+		case <-done:
+			return float64(counter) / float64(time.Since(start).Milliseconds()), nil
+		default:
+		}
+		kernel.run(counter+1, cpuBatchSize)
+		counter += cpuBatchSize
+		if counter%initCount < cpuBatchSize {
+			runtime.Gosched()
+		}
+	}
+}
+
+// measureMemory 测量分配并写入 mb 个 1MB 块的速度（MB/s），以及释放后一次 GC 的耗时（ms）
+func measureMemory(mb int) (float64, float64) {
+	blockSize := 1024 * 1024
+	start := time.Now()
+	buffer := make([][]byte, 0, mb)
+	for i := 0; i < mb; i++ {
+		buf := make([]byte, blockSize)
+		for j := range buf {
+			buf[j] = byte(j % 256)
+		}
+		buffer = append(buffer, buf)
+	}
+	allocRate := float64(mb) / time.Since(start).Seconds()
+
+	runtime.KeepAlive(buffer)
+	buffer = nil
+	start = time.Now()
+	runtime.GC()
+	return allocRate, float64(time.Since(start).Microseconds()) / 1000
+}
+
+// measureMemoryBandwidth 在两个 mb 大小的缓冲区之间反复复制 d，返回每秒复制的 MB 数
+func measureMemoryBandwidth(mb int, d time.Duration) float64 {
+	src := make([]byte, mb*1024*1024)
+	dst := make([]byte, len(src))
+	for i := range src {
+		src[i] = byte(i)
+	}
+	copy(dst, src) // 预热：使两个缓冲区都已映射到物理内存
+
+	start := time.Now()
+	copies := 0
+	for time.Since(start) < d {
+		copy(dst, src)
+		copies++
+	}
+	runtime.KeepAlive(dst)
+	return float64(copies*mb) / time.Since(start).Seconds()
+}
+
+// measureMaxLoad 所有核心满负载运行 d，返回整机 CPU 使用率
+func measureMaxLoad(d time.Duration) (float64, error) {
+	if _, err := GetSystemStats(); err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			kernel := &intKernel{}
+			var counter uint64
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					counter++
+					kernel.step(counter)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(d)
+	stats, err := GetSystemStats()
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return 0, err
+	}
+	return stats.CPUPercent, nil
+}
@@ -0,0 +1,74 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// cgroupCPUPeriod cpu.max 的调度周期（微秒）
+const cgroupCPUPeriod = 100000
+
+// confineToCgroup 创建（或加入）cgroup v2，并按硬峰值设置 cpu.max 和 memory.max
+// 即使控制算法出现 bug，内核也会保证本程序的占用不超过硬峰值
+func confineToCgroup(path string, totalMemory uint64) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("创建 cgroup 失败: %w", err)
+	}
+
+	// 尝试在父 cgroup 中启用 cpu 和 memory 控制器（已启用或无权限时忽略错误）
+	_ = writeCgroupFile(filepath.Join(filepath.Dir(path), "cgroup.subtree_control"), "+cpu +memory")
+
+	// 按两个时段中较高的硬峰值设置：cgroup 的上限不随时间变化
+	limit := maxHardPeak()
+	if err := setCgroupCPUMax(path); err != nil {
+		return err
+	}
+
+	if totalMemory > 0 {
+		if err := setCgroupMemoryMax(path, totalMemory); err != nil {
+			return err
+		}
+	}
+
+	// 把整个进程（所有线程）移入该 cgroup
+	pid := strconv.Itoa(os.Getpid())
+	if err := writeCgroupFile(filepath.Join(path, "cgroup.procs"), pid); err != nil {
+		return fmt.Errorf("加入 cgroup 失败: %w", err)
+	}
+
+	logger.Info("已加入 cgroup", "path", path, "cpu_cores", numCPU(), "memory_max_mb", uint64(float64(totalMemory)*limit/100)/(1024*1024))
+	return nil
+}
+
+// setCgroupCPUMax 按当前核心数和硬峰值设置 cpu.max（核心数变化后重新设置）
+func setCgroupCPUMax(path string) error {
+	quota := uint64(float64(uint64(numCPU())*cgroupCPUPeriod) * maxHardPeak() / 100)
+	cpuMax := fmt.Sprintf("%d %d", quota, cgroupCPUPeriod)
+	if err := writeCgroupFile(filepath.Join(path, "cpu.max"), cpuMax); err != nil {
+		return fmt.Errorf("设置 cpu.max 失败: %w", err)
+	}
+	return nil
+}
+
+// setCgroupMemoryMax 按总内存和硬峰值设置 memory.max（总内存变化后重新设置）
+func setCgroupMemoryMax(path string, totalMemory uint64) error {
+	memoryMax := strconv.FormatUint(uint64(float64(totalMemory)*maxHardPeak()/100), 10)
+	if err := writeCgroupFile(filepath.Join(path, "memory.max"), memoryMax); err != nil {
+		return fmt.Errorf("设置 memory.max 失败: %w", err)
+	}
+	return nil
+}
+
+// writeCgroupFile 写入 cgroup 控制文件（文件不存在时报错，不会创建普通文件）
+func writeCgroupFile(path, value string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(value)
+	return err
+}
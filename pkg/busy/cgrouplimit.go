@@ -0,0 +1,158 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CgroupLimits 跟踪本进程所在 cgroup（v2）的 memory.max 和 cpu.max，每个监控周期重新读取
+// 容器有限制时以限制作为 "整机"：内存使用率 = memory.current / memory.max，CPU 使用率 = cgroup 的 CPU 时间 / 配额，
+// Kubernetes 原地调整资源或 VPA 修改限制后，期望值和调整步长随之变化
+type CgroupLimits struct {
+	mu        sync.Mutex
+	enabled   bool
+	dir       string  // 本进程所在的 cgroup 目录
+	memoryMax uint64  // memory.max（字节，0 表示不限制）
+	cpuCores  float64 // cpu.max 换算的核心数（0 表示不限制）
+	lastUsage uint64  // 上次读取的 cpu.stat usage_usec
+	lastTime  time.Time
+}
+
+var cgroupLimits = &CgroupLimits{}
+
+// Enable 定位本进程所在的 cgroup 并开始跟踪限制，同时记录 CPU 时间的基准，
+// 使第一次 Apply 就能按配额计算 CPU 使用率（而不是报告 0%）
+// 进程移入其他 cgroup（CGROUP_PATH）后需要再次调用，重新定位目录并丢弃旧 cgroup 的基准
+func (cl *CgroupLimits) Enable() error {
+	dir, err := selfCgroupDir()
+	if err != nil {
+		return err
+	}
+	usage, err := readCgroupCPUUsage(dir)
+	if err != nil {
+		return fmt.Errorf("cgroup 不可用: %w", err)
+	}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.enabled = true
+	cl.dir = dir
+	cl.memoryMax, cl.cpuCores = 0, 0
+	cl.lastUsage, cl.lastTime = usage, clock.Now()
+	return nil
+}
+
+// Enabled 是否在跟踪 cgroup 限制
+func (cl *CgroupLimits) Enabled() bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.enabled
+}
+
+// Path 本进程所在的 cgroup 目录
+func (cl *CgroupLimits) Path() string {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.dir
+}
+
+// Apply 重新读取限制，把 stats 中的整机数据换算为相对 cgroup 限制的数据
+// 需在内存和 CPU 信息之后、本进程占用之前调用（本进程的占比使用换算后的总量）
+func (cl *CgroupLimits) Apply(stats *SystemStats) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if !cl.enabled {
+		return nil
+	}
+
+	memoryMax, cpuCores, err := readCgroupLimits(cl.dir)
+	if err != nil {
+		return err
+	}
+	if memoryMax != cl.memoryMax || cpuCores != cl.cpuCores {
+		logger.Info("cgroup 限制变化",
+			"path", cl.dir,
+			"memory_max_mb_old", cl.memoryMax/(1024*1024),
+			"memory_max_mb_new", memoryMax/(1024*1024),
+			"cpu_cores_old", cl.cpuCores,
+			"cpu_cores_new", cpuCores)
+		cl.memoryMax, cl.cpuCores = memoryMax, cpuCores
+	}
+
+	// 内存：限制小于整机内存时以限制为总量
+	if memoryMax > 0 && memoryMax < stats.TotalMemory {
+		current, err := readCgroupUint(filepath.Join(cl.dir, "memory.current"))
+		if err != nil {
+			return err
+		}
+		stats.TotalMemory = memoryMax
+		stats.UsedMemory = min(current, memoryMax)
+		stats.MemoryPercent = float64(stats.UsedMemory) / float64(memoryMax) * 100
+	}
+
+	// CPU：配额小于核心数时以配额为总量，本进程的占比同样按配额换算
+	usage, err := readCgroupCPUUsage(cl.dir)
+	if err != nil {
+		return err
+	}
+	now := clock.Now()
+	cores := float64(numCPU())
+	if cpuCores > 0 && cpuCores < cores {
+		var cpuPercent float64
+		if !cl.lastTime.IsZero() && usage >= cl.lastUsage {
+			if elapsed := now.Sub(cl.lastTime).Microseconds(); elapsed > 0 {
+				cpuPercent = float64(usage-cl.lastUsage) / (float64(elapsed) * cpuCores) * 100
+			}
+		}
+		stats.CPUPercent = min(cpuPercent, 100)
+		stats.SelfCPUPercent = min(stats.SelfCPUPercent*cores/cpuCores, stats.CPUPercent)
+	}
+	cl.lastUsage, cl.lastTime = usage, now
+	return nil
+}
+
+// selfCgroupDir 从 /proc/self/cgroup 读取本进程所在的 cgroup v2 目录
+func selfCgroupDir() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(cgroupRoot, path), nil
+		}
+	}
+	return "", fmt.Errorf("没有找到 cgroup v2 的路径（只支持 cgroup v2）")
+}
+
+// readCgroupLimits 读取 memory.max 和 cpu.max（max 或文件不存在时为 0，表示不限制）
+func readCgroupLimits(dir string) (memoryMax uint64, cpuCores float64, err error) {
+	if data, err := os.ReadFile(filepath.Join(dir, "memory.max")); err == nil {
+		if value := strings.TrimSpace(string(data)); value != "max" {
+			if memoryMax, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return 0, 0, fmt.Errorf("解析 memory.max 失败: %w", err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dir, "cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 != nil || err2 != nil || period <= 0 {
+				return 0, 0, fmt.Errorf("解析 cpu.max 失败: %q", strings.TrimSpace(string(data)))
+			}
+			cpuCores = quota / period
+		}
+	} else if !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+	return memoryMax, cpuCores, nil
+}
@@ -0,0 +1,138 @@
+package busy
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间来源：控制循环通过它获取当前时间和创建定时器，模拟时替换为可以快进的时钟
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker 周期定时器
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration) // 停止后以新的周期 d 重新开始计时
+}
+
+// clock 包内使用的时间来源，默认为系统时钟
+var clock Clock = realClock{}
+
+// SetClock 替换包内使用的时间来源（需在 Start 之前调用，nil 表示恢复系统时钟）
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clock = c
+}
+
+// realClock 系统时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+// realTicker 包装 time.Ticker
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time { return rt.t.C }
+
+func (rt realTicker) Stop() { rt.t.Stop() }
+
+func (rt realTicker) Reset(d time.Duration) { rt.t.Reset(d) }
+
+// fakeClock 模拟时钟：只在调用 Advance 时前进，到期的定时器按时间顺序触发
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // 创建定时器时通知 BlockUntil
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// fakeTicker 模拟时钟的定时器（与 time.Ticker 一样，接收方来不及读取时丢弃多余的触发）
+type fakeTicker struct {
+	clock   *fakeClock
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+}
+
+// newFakeClock 创建从 start 开始的模拟时钟
+func newFakeClock(start time.Time) *fakeClock {
+	fc := &fakeClock{now: start}
+	fc.cond = sync.NewCond(&fc.mu)
+	return fc
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) NewTicker(d time.Duration) Ticker {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	ft := &fakeTicker{clock: fc, c: make(chan time.Time, 1), period: d, next: fc.now.Add(d)}
+	fc.tickers = append(fc.tickers, ft)
+	fc.cond.Broadcast()
+	return ft
+}
+
+// BlockUntil 等待至少创建了 n 个定时器（在其他协程创建定时器之前快进，定时器不会触发）
+func (fc *fakeClock) BlockUntil(n int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for len(fc.tickers) < n {
+		fc.cond.Wait()
+	}
+}
+
+// Advance 时钟前进 d，依次触发期间到期的定时器
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	end := fc.now.Add(d)
+	for {
+		var earliest *fakeTicker
+		for _, ft := range fc.tickers {
+			if !ft.stopped && !ft.next.After(end) && (earliest == nil || ft.next.Before(earliest.next)) {
+				earliest = ft
+			}
+		}
+		if earliest == nil {
+			break
+		}
+		fc.now = earliest.next
+		select {
+		case earliest.c <- fc.now:
+		default:
+		}
+		earliest.next = earliest.next.Add(earliest.period)
+	}
+	fc.now = end
+}
+
+func (ft *fakeTicker) C() <-chan time.Time { return ft.c }
+
+func (ft *fakeTicker) Stop() {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	ft.stopped = true
+}
+
+func (ft *fakeTicker) Reset(d time.Duration) {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	ft.period = d
+	ft.next = ft.clock.now.Add(d)
+	ft.stopped = false
+}
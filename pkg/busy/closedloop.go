@@ -0,0 +1,101 @@
+package busy
+
+import (
+	"math"
+)
+
+const (
+	closedLoopDeadband = 0.5 // 本进程 CPU 与目标相差不到该值（百分点）时不调整
+	closedLoopMaxRatio = 10  // 每个周期计算次数最多变化的倍数，避免测量异常时大幅跳变
+)
+
+var (
+	cpuControl     string  // CPU 调整方式：probability（按概率逐步调整）或 closedloop（按本进程 CPU 时间闭环调整）
+	closedLoopGain float64 // 闭环调整的增益（0-1，1 表示一步调到模型给出的值）
+)
+
+// selfCPUTarget 按 TARGET_SCOPE 把期望值换算为本进程的 CPU 目标：整机模式下扣除其他进程的占用
+func selfCPUTarget(stats *SystemStats, expectedUsage float64) float64 {
+	switch targetScope {
+	case "self":
+		return expectedUsage
+	case "sidecar":
+		return expectedUsage - stats.SidecarCPUPercent
+	default:
+		return expectedUsage - stats.OtherCPUPercent
+	}
+}
+
+// adjustClosedLoop 闭环调整：用上个周期本进程实际消耗的 CPU 时间（/proc/self/stat）推算计算次数与使用率的关系，
+// 直接计算达到目标所需的计算次数，收敛速度与机器性能无关
+// headroom 为整机实际占用距离硬峰值的余量，目标不超过本进程占用 + 余量，避免一步跳过硬峰值
+func adjustClosedLoop(stats *SystemStats, currentPercent, expectedUsage, headroom float64) {
+	stallDetector.Observe("CPU", currentPercent, expectedUsage)
+
+	// 硬峰值和最低占用检查与概率调整相同
+	if limit := hardPeak(); currentPercent > limit {
+		logger.Warn("CPU占用超过硬峰值，强制降低", "current_percent", currentPercent, "hard_peak", limit)
+		emitEvent("hard_peak", "resource", "CPU", "current_percent", currentPercent, "hard_peak", limit)
+		forceDecreaseCount(stats, currentPercent, limit)
+		return
+	}
+
+	self := stats.SelfCPUPercent
+	target := min(selfCPUTarget(stats, max(expectedUsage, minUsage)), self+headroom-closedLoopDeadband)
+	if abs(target-self) < closedLoopDeadband {
+		cycleLog.Info("CPU-" + formatPercent(currentPercent) + "-闭环-跳过")
+		recordDecision("CPU", "闭环", "跳过")
+		return
+	}
+
+	ratio, ok := countRatio(self, target, closedLoopGain)
+	if !ok {
+		return
+	}
+	oldCount := cpuController.GetCount()
+	newCount := max(uint64(float64(oldCount)*ratio), 1)
+	cpuController.SetCount(newCount)
+
+	action := "减少"
+	if newCount > oldCount {
+		action = "增加"
+	}
+	cycleLog.Info("CPU-"+formatPercent(currentPercent)+"-闭环-"+action,
+		"self_percent", self, "self_target", target, "count_old", oldCount, "count_new", newCount)
+	recordDecision("CPU", "闭环", action)
+}
+
+// countRatio 本进程 CPU 占用从 self 调到 target 时计算次数需要变化的倍数（限制在 closedLoopMaxRatio 倍以内）
+// 模型：每个工作协程的占空比 d = busy / (busy + sleep)，busy 与计算次数成正比，
+// 所以新计算次数 = 当前计算次数 × (d目标 / (1 - d目标)) / (d实际 / (1 - d实际))，gain 为对数空间的步长（1 表示一步到位）
+func countRatio(self, target, gain float64) (float64, bool) {
+	// 工作协程全部满载时本进程能达到的 CPU 占用
+	capacity := float64(cpuController.GetWorkers()) / float64(numCPU()) * 100
+	if capacity <= 0 {
+		return 0, false
+	}
+	odds := func(percent float64) float64 {
+		d := min(max(percent/capacity, 0.001), 0.99)
+		return d / (1 - d)
+	}
+	ratio := math.Pow(odds(target)/odds(self), gain)
+	return min(max(ratio, 1.0/closedLoopMaxRatio), closedLoopMaxRatio), true
+}
+
+// forceDecreaseCount 超过硬峰值时按超出的幅度一次性降低计算次数（闭环调整和模型跳转使用）：
+// 这两种方式一个周期可以把计算次数放大数倍，forceDecrease 每个周期只降低 0.1%，
+// 超调后需要上千个周期才能回到硬峰值以下；超出的部分来自其他进程时最多降低到 1/closedLoopMaxRatio
+func forceDecreaseCount(stats *SystemStats, currentPercent, limit float64) {
+	self := stats.SelfCPUPercent
+	ratio, ok := countRatio(self, self-(currentPercent-limit)-closedLoopDeadband, 1)
+	if !ok || ratio >= 1 {
+		forceDecrease("CPU", currentPercent, cpuController.AdjustCountRandom)
+		return
+	}
+	oldCount := cpuController.GetCount()
+	newCount := max(uint64(float64(oldCount)*ratio), 1)
+	cpuController.SetCount(newCount)
+	// 格式化：资源-当前占用%-强制-减少
+	cycleLog.Info("CPU-"+formatPercent(currentPercent)+"-强制-减少", "count_old", oldCount, "count_new", newCount)
+	recordDecision("CPU", "强制", "减少")
+}
@@ -0,0 +1,147 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lookupEnv 读取环境变量，未设置时使用预设（PRESET）中的值
+func lookupEnv(name string) string {
+	if value := envValue(name); value != "" {
+		return value
+	}
+	return presetValue(name)
+}
+
+// envValue 读取环境变量，先读大写名称，再读小写名称（不区分大小写），不考虑预设
+func envValue(name string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		value = os.Getenv(strings.ToLower(name))
+	}
+	return strings.TrimSpace(value)
+}
+
+// getEnvString 读取字符串环境变量，未设置时返回默认值
+func getEnvString(name, def string) string {
+	value := lookupEnv(name)
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// getEnvInt 读取整数环境变量，未设置或无效时返回默认值
+func getEnvInt(name string, def int) int {
+	value := lookupEnv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn("环境变量值无效，使用默认值", "name", name, "value", value, "default", def)
+		return def
+	}
+	return n
+}
+
+// getEnvFloat 读取浮点数环境变量，未设置或无效时返回默认值
+func getEnvFloat(name string, def float64) float64 {
+	value := lookupEnv(name)
+	if value == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logger.Warn("环境变量值无效，使用默认值", "name", name, "value", value, "default", def)
+		return def
+	}
+	return f
+}
+
+// getEnvBool 读取布尔环境变量（1/true/yes/on），未设置或无效时返回默认值
+func getEnvBool(name string, def bool) bool {
+	value := lookupEnv(name)
+	if value == "" {
+		return def
+	}
+
+	b, ok := parseBool(value)
+	if !ok {
+		logger.Warn("环境变量值无效，使用默认值", "name", name, "value", value, "default", def)
+		return def
+	}
+	return b
+}
+
+// parseBool 解析布尔值（1/true/yes/on、0/false/no/off，不区分大小写）
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "on":
+		return true, true
+	case "0", "false", "no", "off":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// getEnvDuration 读取时长环境变量（如 30s、5m），未设置或无效时返回默认值
+func getEnvDuration(name string, def time.Duration) time.Duration {
+	value := lookupEnv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		logger.Warn("环境变量值无效，使用默认值", "name", name, "value", value, "default", def)
+		return def
+	}
+	return d
+}
+
+// getEnvList 读取逗号分隔的列表环境变量，忽略空项
+func getEnvList(name string) []string {
+	return splitList(lookupEnv(name))
+}
+
+// splitList 按逗号或换行拆分字符串，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '\n'
+	}) {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseIntFloatMap 解析 "键:值" 逗号分隔的映射（如 "0:10,1:50"）
+func parseIntFloatMap(value string) (map[int]float64, error) {
+	result := make(map[int]float64)
+	for _, item := range splitList(value) {
+		k, v, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("格式错误: %q（应为 键:值）", item)
+		}
+		key, err := strconv.Atoi(strings.TrimSpace(k))
+		if err != nil {
+			return nil, fmt.Errorf("键无效: %q", k)
+		}
+		val, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("值无效: %q", v)
+		}
+		result[key] = val
+	}
+	return result, nil
+}
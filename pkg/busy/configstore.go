@@ -0,0 +1,324 @@
+package busy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConfigStoreKey = "cpumembusy/config"
+	configStoreWait       = time.Minute      // 一次长轮询 / watch 的最长等待时间，超时后重新读取（同时刷新托管状态）
+	configStoreTimeout    = 10 * time.Second // 普通请求的超时
+)
+
+// ConfigDocument etcd / Consul 中保存的配置文档（JSON），整个集群共用一个 key
+//
+//	{"peak_usage_origin": 60, "peak_usage": 45, "paused": false}
+type ConfigDocument struct {
+	PeakUsageOrigin int   `json:"peak_usage_origin"` // 省略时等于 peak_usage
+	PeakUsage       int   `json:"peak_usage"`        // 省略或为 0 时不修改峰值
+	Paused          *bool `json:"paused"`            // true 时与停止文件相同，省略时不修改
+}
+
+// validateConfigDocument 校验配置文档的取值范围（0 表示不修改）
+func validateConfigDocument(doc ConfigDocument) error {
+	if doc.PeakUsage < 0 || doc.PeakUsage > 100 {
+		return fmt.Errorf("peak_usage 超出范围: %d", doc.PeakUsage)
+	}
+	if doc.PeakUsageOrigin < 0 || doc.PeakUsageOrigin > 100 {
+		return fmt.Errorf("peak_usage_origin 超出范围: %d", doc.PeakUsageOrigin)
+	}
+	return nil
+}
+
+// configStore 配置存储：阻塞到 key 的版本与 lastIndex 不同或超过 configStoreWait 为止，返回当前的值
+type configStore interface {
+	Watch(ctx context.Context, lastIndex uint64) (value []byte, found bool, index uint64, err error)
+}
+
+// ConfigWatcher 监视 etcd 或 Consul 中的配置文档，修改后几秒内生效
+// 与 controller 下发相同，峰值在 10 分钟内没有刷新时恢复本地更新；每次长轮询结束都会重新应用文档以刷新托管状态
+type ConfigWatcher struct {
+	store  configStore
+	desc   string // 用于日志：存储类型、地址和 key
+	paused bool   // 是否由配置文档暂停
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newConfigStore 按 CONFIG_STORE 创建配置存储（未设置时返回 nil）
+func newConfigStore() (configStore, string, error) {
+	kind := lookupEnv("CONFIG_STORE")
+	key := getEnvString("CONFIG_STORE_KEY", defaultConfigStoreKey)
+	token := lookupEnv("CONFIG_STORE_TOKEN")
+	var defaultAddr string
+	switch kind {
+	case "":
+		return nil, "", nil
+	case "etcd":
+		defaultAddr = "http://127.0.0.1:2379"
+	case "consul":
+		defaultAddr = "http://127.0.0.1:8500"
+	default:
+		return nil, "", fmt.Errorf("未知的配置存储: %s（可选: etcd、consul）", kind)
+	}
+	addr := strings.TrimRight(getEnvString("CONFIG_STORE_ADDR", defaultAddr), "/")
+	if err := checkURL(addr); err != nil {
+		return nil, "", fmt.Errorf("CONFIG_STORE_ADDR 无效: %w", err)
+	}
+	desc := kind + " " + addr + " " + key
+	client := &http.Client{Timeout: configStoreWait + configStoreTimeout}
+	if kind == "etcd" {
+		return &etcdStore{addr: addr, key: key, token: token, client: client}, desc, nil
+	}
+	return &consulStore{addr: addr, key: key, token: token, client: client}, desc, nil
+}
+
+// startConfigWatcher 开始监视配置文档（后台运行，失败时自动重试）
+func startConfigWatcher(store configStore, desc string) *ConfigWatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	cw := &ConfigWatcher{store: store, desc: desc, cancel: cancel}
+	cw.wg.Add(1)
+	go cw.loop(ctx)
+	return cw
+}
+
+// Stop 停止监视（由配置文档暂停的状态同时解除）
+func (cw *ConfigWatcher) Stop() {
+	cw.cancel()
+	cw.wg.Wait()
+	if cw.paused {
+		killSwitch.SetRemote(false)
+	}
+}
+
+// loop 反复长轮询配置文档，出错时按指数退避重试
+func (cw *ConfigWatcher) loop(ctx context.Context) {
+	defer cw.wg.Done()
+	logger.Info("配置存储监视已启用", "store", cw.desc)
+
+	var index uint64
+	missing := false
+	backoff := time.Second
+	for {
+		value, found, newIndex, err := cw.store.Watch(ctx, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logger.Warn("读取配置存储失败，稍后重试", "store", cw.desc, "error", err, "retry_in", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, mqttMaxBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		changed := newIndex != index
+		index = newIndex
+		if !found {
+			if !missing {
+				logger.Warn("配置存储中没有配置文档，保持当前配置", "store", cw.desc)
+				missing = true
+			}
+			if cw.paused {
+				cw.paused = false
+				killSwitch.SetRemote(false)
+			}
+			continue
+		}
+		missing = false
+		if err := cw.apply(value); err != nil {
+			var limited *PeakRateLimitError
+			if errors.As(err, &limited) {
+				continue // 已记录日志，下一次长轮询结束时重试
+			}
+			if changed {
+				logger.Warn("配置文档无效，保持当前配置", "store", cw.desc, "error", err)
+			}
+			continue
+		}
+		if changed {
+			logger.Info("配置文档已更新", "store", cw.desc, "index", index)
+		}
+	}
+}
+
+// apply 应用配置文档
+func (cw *ConfigWatcher) apply(value []byte) error {
+	var doc ConfigDocument
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return err
+	}
+	if doc.Paused != nil && *doc.Paused != cw.paused {
+		cw.paused = *doc.Paused
+		killSwitch.SetRemote(cw.paused)
+	}
+	if doc.PeakUsage != 0 {
+		if doc.PeakUsageOrigin == 0 {
+			doc.PeakUsageOrigin = doc.PeakUsage
+		}
+		return applyPeakUpdate(PeakUpdate{PeakUsageOrigin: doc.PeakUsageOrigin, PeakUsage: doc.PeakUsage}, cw.desc)
+	}
+	return nil
+}
+
+// consulStore Consul KV：使用阻塞查询（?index=&wait=）监视 key
+type consulStore struct {
+	addr, key, token string
+	client           *http.Client
+}
+
+func (cs *consulStore) Watch(ctx context.Context, lastIndex uint64) ([]byte, bool, uint64, error) {
+	query := url.Values{"raw": {""}, "wait": {configStoreWait.String()}}
+	if lastIndex > 0 {
+		query.Set("index", strconv.FormatUint(lastIndex, 10))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cs.addr+"/v1/kv/"+cs.key+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	if cs.token != "" {
+		req.Header.Set("X-Consul-Token", cs.token)
+	}
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	defer resp.Body.Close()
+
+	// X-Consul-Index 变小时（如 Consul 重建）从头开始
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if index < lastIndex {
+		index = 0
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		value, err := io.ReadAll(resp.Body)
+		return value, true, index, err
+	case http.StatusNotFound:
+		return nil, false, index, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, false, 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// etcdStore etcd v3：通过 gRPC 网关的 JSON 接口读取 key（/v3/kv/range），没有变化时用 /v3/watch 等待
+type etcdStore struct {
+	addr, key, token string
+	client           *http.Client
+}
+
+// etcdRangeResponse /v3/kv/range 的响应（int64 以字符串表示，bytes 以 base64 表示）
+type etcdRangeResponse struct {
+	Header struct {
+		Revision int64 `json:"revision,string"`
+	} `json:"header"`
+	Kvs []struct {
+		Value       []byte `json:"value"` // []byte 在 JSON 中编码为 base64，与网关的格式一致
+		ModRevision int64  `json:"mod_revision,string"`
+	} `json:"kvs"`
+}
+
+func (es *etcdStore) Watch(ctx context.Context, lastIndex uint64) ([]byte, bool, uint64, error) {
+	value, found, index, revision, err := es.get(ctx)
+	if err != nil || index != lastIndex {
+		return value, found, index, err
+	}
+
+	// 没有变化：从下一个版本开始 watch，直到 key 有事件或超时
+	watchCtx, cancel := context.WithTimeout(ctx, configStoreWait)
+	defer cancel()
+	if err := es.wait(watchCtx, revision+1); err != nil && watchCtx.Err() == nil {
+		return nil, false, 0, err
+	}
+	if ctx.Err() != nil {
+		return nil, false, 0, ctx.Err()
+	}
+	value, found, index, _, err = es.get(ctx)
+	return value, found, index, err
+}
+
+// get 读取 key 的当前值，index 为 key 的 mod_revision（不存在时为 0），revision 为集群的当前版本
+func (es *etcdStore) get(ctx context.Context) (value []byte, found bool, index uint64, revision int64, err error) {
+	var resp etcdRangeResponse
+	if err := es.post(ctx, "/v3/kv/range", map[string]any{"key": []byte(es.key)}, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&resp)
+	}); err != nil {
+		return nil, false, 0, 0, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, false, 0, resp.Header.Revision, nil
+	}
+	kv := resp.Kvs[0]
+	return kv.Value, true, uint64(kv.ModRevision), resp.Header.Revision, nil
+}
+
+// wait 在 key 上 watch，收到第一个事件时返回
+func (es *etcdStore) wait(ctx context.Context, startRevision int64) error {
+	request := map[string]any{"create_request": map[string]any{
+		"key":            []byte(es.key),
+		"start_revision": strconv.FormatInt(startRevision, 10),
+	}}
+	return es.post(ctx, "/v3/watch", request, func(body io.Reader) error {
+		decoder := json.NewDecoder(body)
+		for {
+			var msg struct {
+				Result struct {
+					Events []json.RawMessage `json:"events"`
+				} `json:"result"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := decoder.Decode(&msg); err != nil {
+				return err
+			}
+			if msg.Error != nil {
+				return errors.New(msg.Error.Message)
+			}
+			if len(msg.Result.Events) > 0 {
+				return nil
+			}
+		}
+	})
+}
+
+// post 以 JSON 调用 etcd 的 gRPC 网关，由 read 处理响应体
+func (es *etcdStore) post(ctx context.Context, path string, request any, read func(io.Reader) error) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, es.addr+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if es.token != "" {
+		req.Header.Set("Authorization", es.token)
+	}
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return read(resp.Body)
+}
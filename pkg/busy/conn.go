@@ -0,0 +1,167 @@
+package busy
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	connTickInterval = 1 * time.Second // 连接维护间隔
+	connDialPerTick  = 100             // 每次维护最多新建的连接数，避免瞬间建连风暴
+)
+
+// ConnController 连接控制器：维持一定数量的 TCP 长连接，并按比例周期性断开重连
+type ConnController struct {
+	mu     sync.Mutex
+	peer   string     // 连接目标地址
+	target int        // 目标连接数
+	churn  float64    // 每分钟断开重连的连接比例（0.0 - 1.0）
+	conns  []net.Conn // 当前持有的连接
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	ln     net.Listener // 内置接收端
+}
+
+var connController = &ConnController{}
+
+// Start 启动连接维护
+// peer 为空时连接到回环地址上的内置接收端；listen 不为空时额外启动接收端，供其他实例连接
+func (cc *ConnController) Start(target int, churn float64, peer, listen string) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.ctx != nil {
+		// 已经启动
+		return nil
+	}
+
+	if peer == "" && listen == "" {
+		listen = "127.0.0.1:0"
+	}
+	if listen != "" {
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return err
+		}
+		go holdConnections(ln)
+		cc.ln = ln
+		if peer == "" {
+			peer = ln.Addr().String()
+		}
+	}
+
+	cc.peer = peer
+	cc.target = target
+	cc.churn = churn
+	cc.ctx, cc.cancel = context.WithCancel(context.Background())
+
+	cc.wg.Add(1)
+	go cc.maintain()
+	return nil
+}
+
+// Stop 停止连接维护并关闭所有连接
+func (cc *ConnController) Stop() {
+	cc.mu.Lock()
+	cancel := cc.cancel
+	cc.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	cc.wg.Wait()
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for _, conn := range cc.conns {
+		conn.Close()
+	}
+	cc.conns = nil
+	if cc.ln != nil {
+		cc.ln.Close()
+		cc.ln = nil
+	}
+	cc.ctx = nil
+	cc.cancel = nil
+}
+
+// SetTarget 设置目标连接数
+func (cc *ConnController) SetTarget(target int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.target = max(target, 0)
+}
+
+// GetCount 获取当前持有的连接数
+func (cc *ConnController) GetCount() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return len(cc.conns)
+}
+
+// maintain 连接维护协程：按比例断开连接，并补齐到目标连接数
+func (cc *ConnController) maintain() {
+	defer cc.wg.Done()
+
+	ticker := time.NewTicker(connTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cc.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cc.mu.Lock()
+		// 断开重连：每个连接在本次维护中被断开的概率 = 每分钟比例 / 60
+		closeProb := cc.churn * connTickInterval.Seconds() / 60
+		kept := cc.conns[:0]
+		for i, conn := range cc.conns {
+			if i >= cc.target || rand.Float64() < closeProb {
+				conn.Close()
+				continue
+			}
+			kept = append(kept, conn)
+		}
+		cc.conns = kept
+		need := min(cc.target-len(cc.conns), connDialPerTick)
+		peer := cc.peer
+		cc.mu.Unlock()
+
+		// 建连不持有锁，避免阻塞查询
+		var dialed []net.Conn
+		for i := 0; i < need; i++ {
+			conn, err := net.DialTimeout("tcp", peer, time.Second)
+			if err != nil {
+				logger.Warn("建立 TCP 连接失败", "peer", peer, "error", err)
+				break
+			}
+			dialed = append(dialed, conn)
+		}
+
+		cc.mu.Lock()
+		cc.conns = append(cc.conns, dialed...)
+		cc.mu.Unlock()
+	}
+}
+
+// holdConnections 内置接收端：接受连接并保持，直到对端关闭
+func holdConnections(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}()
+	}
+}
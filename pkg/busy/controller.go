@@ -0,0 +1,256 @@
+package busy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPushInterval = 30 * time.Second // 默认下发间隔
+	pushTimeout         = 5 * time.Second  // 单个 agent 下发超时
+)
+
+// RunController 运行 controller 子命令：统一计算 peakUsage 并下发给所有 agent，ctx 取消时返回
+func RunController(ctx context.Context) {
+	peakUsageOrigin = getPeakUsage()
+	peakUsage = peakUsageOrigin
+	loadCurveConfig()
+	pushInterval := getEnvDuration("PUSH_INTERVAL", defaultPushInterval)
+	if pushInterval <= 0 {
+		pushInterval = defaultPushInterval
+	}
+
+	transport := getEnvString("AGENT_TRANSPORT", "grpc")
+
+	info := GetBuildInfo()
+	logger.Info("controller 启动",
+		"version", info.Version,
+		"commit", info.Commit,
+		"go_version", info.GoVersion,
+		"peak_usage_origin", peakUsageOrigin,
+		"push_interval", pushInterval,
+		"transport", transport,
+		"agents", len(loadAgents()))
+
+	pusher, err := newPeakPusher(transport)
+	if err != nil {
+		logger.Error("controller 无法下发", "error", err)
+		return
+	}
+	defer pusher.Close()
+
+	pushTicker := clock.NewTicker(pushInterval)
+	defer pushTicker.Stop()
+
+	// 每 5 分钟更新一次 peakUsage，所有 agent 共用同一条曲线
+	peakUsageTicker := clock.NewTicker(peakUsageInterval)
+	defer peakUsageTicker.Stop()
+
+	pushToAgents(pusher)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("controller 退出")
+			return
+
+		case <-peakUsageTicker.C():
+			reloadTargetFile()
+			updatePeakUsage()
+			pushToAgents(pusher)
+
+		case <-pushTicker.C():
+			reloadTargetFile()
+			pushToAgents(pusher)
+		}
+	}
+}
+
+// loadAgents 读取 agent 地址列表：AGENTS 环境变量 + AGENTS_FILE 文件（每次下发时重新读取）
+func loadAgents() []string {
+	agents := getEnvList("AGENTS")
+
+	if path := lookupEnv("AGENTS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warn("读取 agent 列表文件失败", "path", path, "error", err)
+		} else {
+			for _, line := range strings.Split(string(data), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				agents = append(agents, line)
+			}
+		}
+	}
+
+	return agents
+}
+
+// reloadTargetFile 从 TARGET_FILE 重新读取峰值设置
+// 修改该文件即可把新的峰值下发给整个集群，无需逐台修改环境变量
+func reloadTargetFile() {
+	path := lookupEnv("TARGET_FILE")
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Warn("读取峰值文件失败", "path", path, "error", err)
+		return
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || value < 1 || value > 100 {
+		logger.Warn("峰值文件内容无效，忽略", "path", path, "value", strings.TrimSpace(string(data)))
+		return
+	}
+	value = max(value, minPeakUsage)
+
+	peakUsageMu.Lock()
+	defer peakUsageMu.Unlock()
+
+	if value == peakUsageOrigin {
+		return
+	}
+
+	logger.Info("峰值文件变化", "peak_usage_origin_old", peakUsageOrigin, "peak_usage_origin_new", value)
+	peakUsageOrigin = value
+	peakUsage = value
+}
+
+// peakPusher 向 agent 下发峰值设置的方式（AGENT_TRANSPORT）
+type peakPusher interface {
+	Push(agent string, update PeakUpdate) error
+	Retain(agents []string) // 释放不在 agent 列表中的 agent 的连接
+	Close()
+}
+
+// newPeakPusher 按 AGENT_TRANSPORT 创建下发方式：grpc（默认，agent 的 AGENT_GRPC_LISTEN）或 http（agent 的 AGENT_LISTEN）
+func newPeakPusher(transport string) (peakPusher, error) {
+	switch transport {
+	case "grpc":
+		return newGRPCPusher()
+	case "http":
+		return newHTTPPusher(), nil
+	default:
+		return nil, fmt.Errorf("AGENT_TRANSPORT 无效: %s（可选: grpc、http）", transport)
+	}
+}
+
+// httpPusher 通过 HTTP 接口（POST /peak）向 agent 下发峰值
+type httpPusher struct {
+	client *http.Client
+}
+
+func newHTTPPusher() *httpPusher {
+	client := &http.Client{Timeout: pushTimeout}
+	if tlsConfig, err := agentClientTLSConfig(); err != nil {
+		logger.Warn("AGENT_TLS_CA 无效，使用系统的 CA", "error", err)
+	} else if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	return &httpPusher{client: client}
+}
+
+func (hp *httpPusher) Push(agent string, update PeakUpdate) error {
+	return pushPeak(hp.client, agent, update)
+}
+
+func (hp *httpPusher) Retain([]string) {}
+
+func (hp *httpPusher) Close() {
+	hp.client.CloseIdleConnections()
+}
+
+// pushToAgents 并发地把当前 peakUsage 下发给所有 agent
+func pushToAgents(pusher peakPusher) {
+	peakUsageMu.RLock()
+	update := PeakUpdate{
+		PeakUsageOrigin: peakUsageOrigin,
+		PeakUsage:       peakUsage,
+	}
+	peakUsageMu.RUnlock()
+
+	agents := loadAgents()
+	pusher.Retain(agents)
+	if len(agents) == 0 {
+		logger.Warn("没有配置 agent，跳过下发")
+		return
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	for _, agent := range agents {
+		wg.Add(1)
+		go func(agent string) {
+			defer wg.Done()
+			if err := pusher.Push(agent, update); err != nil {
+				logger.Warn("下发 agent 失败", "agent", agent, "error", err)
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(agent)
+	}
+	wg.Wait()
+
+	logger.Info("下发 peakUsage 完成",
+		"peak_usage_origin", update.PeakUsageOrigin,
+		"peak_usage", update.PeakUsage,
+		"agents", len(agents),
+		"failed", failed)
+}
+
+// pushPeak 通过 HTTP 接口向单个 agent 下发峰值设置
+func pushPeak(client *http.Client, agent string, update PeakUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, agentURL(agent, "/peak"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := lookupEnv("AGENT_ADMIN_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// agentURL 拼接 agent 接口地址，agent 可以是 host:port 或完整 URL（设置 AGENT_TLS_CA 时 host:port 默认使用 https）
+func agentURL(agent, path string) string {
+	if !strings.HasPrefix(agent, "http://") && !strings.HasPrefix(agent, "https://") {
+		scheme := "http://"
+		if lookupEnv("AGENT_TLS_CA") != "" {
+			scheme = "https://"
+		}
+		agent = scheme + agent
+	}
+	return strings.TrimSuffix(agent, "/") + path
+}
@@ -0,0 +1,101 @@
+package busy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// nextPeakUpdateAt 下一次随机更新 peakUsage 的时间（UnixNano，由主循环设置）
+var nextPeakUpdateAt atomic.Int64
+
+// Internals 运行中的内部状态（GET /internals 返回，top 子命令显示）
+type Internals struct {
+	Status            AgentStatus       `json:"status"`
+	Paused            bool              `json:"paused"`
+	BufferBlocks      int               `json:"buffer_blocks"`
+	BufferBytes       uint64            `json:"buffer_bytes"`
+	BlockMinBytes     uint64            `json:"block_min_bytes"`
+	BlockMaxBytes     uint64            `json:"block_max_bytes"`
+	BufferLimitBytes  uint64            `json:"buffer_limit_bytes"` // 0 表示不限制
+	Workers           []WorkerInternals `json:"workers"`
+	CPUSleep          time.Duration     `json:"cpu_sleep_ns"`
+	NextPeakUpdate    time.Time         `json:"next_peak_update"`              // 下一次随机更新 peakUsage 的时间（托管时跳过）
+	NextTransition    time.Time         `json:"next_transition,omitzero"`      // 下一次按时段变化期望值的时间（24 小时内没有变化时为零值）
+	NextExpectedUsage float64           `json:"next_expected_usage,omitempty"` // 变化后的期望值
+}
+
+// getInternals 汇总各控制器的内部状态
+func getInternals() Internals {
+	status := getAgentStatus()
+	blocks, minSize, maxSize, limit := memoryController.BlockStats()
+	internals := Internals{
+		Status:           status,
+		Paused:           killSwitch.Active(),
+		BufferBlocks:     blocks,
+		BufferBytes:      memoryController.GetBufferMemory(),
+		BlockMinBytes:    minSize,
+		BlockMaxBytes:    maxSize,
+		BufferLimitBytes: limit,
+		Workers:          cpuController.WorkerStats(),
+		CPUSleep:         cpuController.SleepTime(),
+		NextPeakUpdate:   time.Unix(0, nextPeakUpdateAt.Load()),
+	}
+	internals.NextTransition, internals.NextExpectedUsage = nextScheduleTransition(clock.Now(), status.PeakUsage)
+	return internals
+}
+
+// nextScheduleTransition 在 24 小时内按分钟查找期望值因时段（凌晨时段、DAY_WINDOWS、白天 / 凌晨硬峰值）变化的时间
+// 峰值保持 peak 不变，不包括 TARGET_EXPR 和 POLICY_SCRIPT；没有变化时返回零值
+func nextScheduleTransition(now time.Time, peak int) (time.Time, float64) {
+	current := calculateExpectedUsageAt(now, peak)
+	t := now.Truncate(time.Minute)
+	for range 24 * 60 {
+		t = t.Add(time.Minute)
+		if next := calculateExpectedUsageAt(t, peak); next != current {
+			return t, next
+		}
+	}
+	return time.Time{}, 0
+}
+
+// handleInternals 返回内部状态
+func handleInternals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, getInternals())
+}
+
+// startControlSocket 在 unix socket 上提供与 agent 接口相同的路由（不需要令牌，访问权限由文件权限 0600 控制）
+// 已经存在的 socket 文件（上次异常退出留下的）先删除
+func startControlSocket(path string) (*http.Server, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New("路径已存在且不是 socket")
+		}
+		os.Remove(path)
+	}
+	// 在创建时就设置权限：先 Listen 再 chmod 时，socket 在这段时间内按 umask 的权限提供不需要认证的接口
+	// umask 对整个进程生效，只在 Listen 期间修改
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{Handler: newAgentMux()}
+	go func() {
+		logger.Info("控制 socket 启动", "path", path)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("控制 socket 退出", "error", err)
+		}
+	}()
+	return server, nil
+}
@@ -0,0 +1,150 @@
+package busy
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	countModelAlpha     = 0.2 // 同一个使用率区间内新观测值的权重（对数空间的指数移动平均）
+	countModelJump      = 2   // 期望值变化超过该值（百分点）时按模型直接跳到对应的计算次数
+	countModelNeighbors = 3   // 只有一侧有观测值时，允许使用的最远区间（百分点）
+)
+
+// countModel 计算次数与本进程 CPU 占用之间的经验模型：按本进程 CPU 占用的整数百分比分区，
+// 每个区间记录观测到该占用时计算次数的平均值（对数空间），目标变化时插值得到所需的计算次数，
+// 直接跳到附近而不是每个周期 0.1% 地逐步逼近；持久化到 COUNT_MODEL_FILE，重启后继续使用
+type countModel struct {
+	Kernel    string          `json:"kernel"`
+	Workers   int             `json:"workers"`
+	CPUCores  int             `json:"cpu_cores"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Buckets   map[int]float64 `json:"buckets"` // 本进程 CPU 占用（%）→ ln(计算次数) 的平均值
+	Samples   map[int]int     `json:"samples"` // 各区间的观测次数
+
+	path         string
+	lastExpected float64 // 上次跳转时的期望值
+}
+
+// cpuCountModel 启用 COUNT_MODEL_FILE 时的模型（nil 表示不使用，只在主循环中读写）
+var cpuCountModel *countModel
+
+// loadCountModel 读取模型文件；文件不存在，或计算内核、工作协程数、核心数与本机不一致时从空模型开始
+func loadCountModel(path, kernel string, workers int) *countModel {
+	fresh := &countModel{Kernel: kernel, Workers: workers, CPUCores: numCPU(),
+		Buckets: make(map[int]float64), Samples: make(map[int]int), path: path, lastExpected: -1}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("读取计算次数模型失败，从空模型开始", "path", path, "error", err)
+		}
+		return fresh
+	}
+	var m countModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		logger.Warn("解析计算次数模型失败，从空模型开始", "path", path, "error", err)
+		return fresh
+	}
+	if m.Kernel != kernel || m.Workers != workers || m.CPUCores != numCPU() {
+		logger.Warn("计算次数模型与当前配置不一致，从空模型开始", "path", path,
+			"model_kernel", m.Kernel, "model_workers", m.Workers, "model_cores", m.CPUCores)
+		return fresh
+	}
+	if m.Buckets == nil || m.Samples == nil {
+		return fresh
+	}
+	m.path, m.lastExpected = path, -1
+	logger.Info("已加载计算次数模型", "path", path, "buckets", len(m.Buckets))
+	return &m
+}
+
+// save 写入模型文件（先写临时文件再重命名，避免中途退出留下不完整的文件）
+func (m *countModel) save() {
+	m.UpdatedAt = clock.Now()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		logger.Warn("保存计算次数模型失败", "path", m.path, "error", err)
+		return
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		logger.Warn("保存计算次数模型失败", "path", m.path, "error", err)
+	}
+}
+
+// reset 核心数或工作协程数变化后清空模型（原有的观测值不再适用）
+func (m *countModel) reset(workers int) {
+	m.Workers, m.CPUCores = workers, numCPU()
+	m.Buckets, m.Samples = make(map[int]float64), make(map[int]int)
+	m.lastExpected = -1
+}
+
+// observe 记录上个周期的计算次数和本进程实际的 CPU 占用
+func (m *countModel) observe(count uint64, selfPercent float64) {
+	bucket := int(math.Round(selfPercent))
+	if count == 0 || bucket <= 0 || bucket >= 100 {
+		return
+	}
+	value := math.Log(float64(count))
+	if m.Samples[bucket] == 0 {
+		m.Buckets[bucket] = value
+	} else {
+		m.Buckets[bucket] += countModelAlpha * (value - m.Buckets[bucket])
+	}
+	m.Samples[bucket]++
+}
+
+// lookup 估算达到本进程 CPU 占用 target 所需的计算次数：在两侧最近的区间之间插值，
+// 只有一侧有观测值时使用 countModelNeighbors 以内的区间
+func (m *countModel) lookup(target float64) (uint64, bool) {
+	lo, hi := -1, -1
+	for bucket := range m.Buckets {
+		if float64(bucket) <= target && (lo < 0 || bucket > lo) {
+			lo = bucket
+		}
+		if float64(bucket) >= target && (hi < 0 || bucket < hi) {
+			hi = bucket
+		}
+	}
+
+	var value float64
+	switch {
+	case lo >= 0 && hi >= 0 && lo != hi:
+		w := (target - float64(lo)) / float64(hi-lo)
+		value = m.Buckets[lo] + w*(m.Buckets[hi]-m.Buckets[lo])
+	case lo >= 0 && target-float64(lo) <= countModelNeighbors:
+		value = m.Buckets[lo]
+	case hi >= 0 && float64(hi)-target <= countModelNeighbors:
+		value = m.Buckets[hi]
+	default:
+		return 0, false
+	}
+	return max(uint64(math.Exp(value)), 1), true
+}
+
+// jump 期望值变化较大时按模型直接设置计算次数，返回是否已经调整
+// headroom 为整机实际占用距离硬峰值的余量，跳转的目标不超过本进程占用 + 余量
+func (m *countModel) jump(stats *SystemStats, expectedUsage, headroom float64) bool {
+	if m.lastExpected >= 0 && abs(expectedUsage-m.lastExpected) < countModelJump {
+		return false
+	}
+	m.lastExpected = expectedUsage
+
+	target := min(selfCPUTarget(stats, max(expectedUsage, minUsage)), stats.SelfCPUPercent+headroom-closedLoopDeadband)
+	count, ok := m.lookup(target)
+	if !ok {
+		return false
+	}
+	oldCount := cpuController.GetCount()
+	cpuController.SetCount(count)
+	cycleLog.Info("CPU-"+formatPercent(stats.CPUPercent)+"-模型-跳转",
+		"self_target", target, "count_old", oldCount, "count_new", count)
+	recordDecision("CPU", "模型", "跳转")
+	return true
+}
@@ -0,0 +1,576 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// CPUController CPU 控制器
+type CPUController struct {
+	mu      sync.Mutex // 用于保护 ctx、cancel 和 workers
+	count   uint64     // 每次 sleep 前执行的计算次数（使用 atomic 保护）
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	workers []*cpuWorkerState // 当前运行的工作协程
+	nextID  int               // 下一个工作协程的编号
+
+	schedIdle  bool    // 工作协程是否以 SCHED_IDLE 策略运行
+	kernel     string  // 计算内核名称
+	pinned     bool    // 工作协程是否绑定到各自的 CPU 核心（按核心独立调整）
+	numWorkers int     // 启动时的工作协程数量（0 表示每个核心一个）
+	jitter     float64 // sleep 时长的随机浮动比例（0-1，0 表示固定 1ms）
+	spread     float64 // 各协程强度的分散程度（0-1，0 表示所有协程相同）
+	idleMode   string  // 占空比中空闲部分的实现方式：sleep、nanosleep、spin 或 yield
+
+	sleep      atomic.Int64 // 每轮 sleep 的时长（纳秒，0 表示 sleepTime）
+	baseSleep  time.Duration
+	adaptive   bool         // 是否根据实际 sleep 时长自动调整 sleep 的长度
+	sleepReqNs atomic.Int64 // 自适应模式下统计周期内请求的 sleep 总时长
+	sleepActNs atomic.Int64 // 自适应模式下统计周期内实际的 sleep 总时长
+
+	paused atomic.Bool // 暂停时工作协程不做计算（停止文件存在期间）
+
+	stallTimeout    time.Duration      // 工作协程多长时间没有进展时重新启动（0 表示只重启 panic 退出的协程）
+	superviseCancel context.CancelFunc // 停止 supervisor
+	superviseDone   chan struct{}      // supervisor 退出时关闭
+}
+
+// cpuWorkerState 单个工作协程的状态
+type cpuWorkerState struct {
+	cancel context.CancelFunc
+	core   int           // 绑定的 CPU 核心（-1 表示不绑定）
+	count  uint64        // 独立的计算次数（0 表示使用全局 count，使用 atomic 保护）
+	weight atomic.Uint64 // 计算次数的倍数（math.Float64bits，0 表示 1 倍），使各协程的强度不同
+
+	progress atomic.Uint64 // 心跳：每完成一批计算（或暂停期间每次检查）加 1，supervisor 据此判断协程是否卡住
+	exited   atomic.Bool   // 协程是否已经退出
+	released atomic.Bool   // 是否已经调用过 wg.Done（协程退出或被 supervisor 放弃时）
+}
+
+const (
+	sleepTime        = 1 * time.Millisecond   // 默认 sleep 时间：1ms
+	minSleepTime     = 100 * time.Microsecond // CPU_SLEEP 的下限
+	maxSleepTime     = 100 * time.Millisecond // CPU_SLEEP 的上限
+	maxAdaptiveSleep = 20 * time.Millisecond  // 自适应 sleep 时长的上限
+	initCount        = 10000                  // 初始计算次数
+	cpuBatchSize     = 4096                   // 每批迭代次数：批与批之间检查退出信号和 count 的变化
+	pausedPoll       = 100 * time.Millisecond // 暂停期间工作协程检查恢复的间隔
+)
+
+var cpuController = &CPUController{
+	count:  initCount, // 初始值：10000
+	kernel: "int",
+}
+
+// Start 启动 CPU 占用协程
+func (cc *CPUController) Start() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.ctx != nil {
+		// 已经启动
+		return
+	}
+
+	cc.ctx, cc.cancel = context.WithCancel(context.Background())
+
+	// 默认每个核心启动一个协程
+	numWorkers := cc.numWorkers
+	if numWorkers <= 0 {
+		numWorkers = numCPU()
+	}
+
+	for i := 0; i < numWorkers; i++ {
+		cc.addWorker()
+	}
+
+	ctx, cancel := context.WithCancel(cc.ctx)
+	cc.superviseCancel, cc.superviseDone = cancel, make(chan struct{})
+	go cc.supervise(ctx, cc.stallTimeout, cc.superviseDone)
+}
+
+// addWorker 启动一个工作协程（调用方需持有锁）
+// 绑核模式下第 i 个工作协程绑定到第 i 个核心，并使用独立的计算次数
+func (cc *CPUController) addWorker() {
+	w := &cpuWorkerState{core: -1}
+	if cc.pinned && len(cc.workers) < numCPU() {
+		w.core = len(cc.workers)
+		w.count = atomic.LoadUint64(&cc.count)
+	}
+	cc.assignWeight(w)
+	cc.workers = append(cc.workers, w)
+	cc.launch(w)
+}
+
+// launch 启动工作协程（调用方需持有锁）
+func (cc *CPUController) launch(w *cpuWorkerState) {
+	ctx, cancel := context.WithCancel(cc.ctx)
+	w.cancel = cancel
+	cc.wg.Add(1)
+	go cc.cpuWorker(ctx, cc.nextID, w)
+	cc.nextID++
+}
+
+// removeWorker 停止最后启动的一个工作协程（调用方需持有锁）
+func (cc *CPUController) removeWorker() {
+	last := len(cc.workers) - 1
+	cc.workers[last].cancel()
+	cc.workers = cc.workers[:last]
+}
+
+// SetWorkers 调整工作协程数量
+func (cc *CPUController) SetWorkers(n int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.ctx == nil {
+		return
+	}
+	for len(cc.workers) < n {
+		cc.addWorker()
+	}
+	for len(cc.workers) > max(n, 0) {
+		cc.removeWorker()
+	}
+}
+
+// Rescale 核心数变化后按比例调整工作协程数量（绑核模式下每个核心一个），返回新的数量和是否变化
+// 通过 CPU_WORKERS 指定了数量时不调整
+func (cc *CPUController) Rescale(oldCores, newCores int) (int, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.ctx == nil || cc.numWorkers > 0 || oldCores <= 0 {
+		return len(cc.workers), false
+	}
+	n := newCores
+	if !cc.pinned {
+		n = max(int(math.Round(float64(len(cc.workers))*float64(newCores)/float64(oldCores))), 1)
+	}
+	if n == len(cc.workers) {
+		return n, false
+	}
+	for len(cc.workers) < n {
+		cc.addWorker()
+	}
+	for len(cc.workers) > n {
+		cc.removeWorker()
+	}
+	return n, true
+}
+
+// GetWorkers 获取当前工作协程数量
+func (cc *CPUController) GetWorkers() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return len(cc.workers)
+}
+
+// AdjustWorkersRandom 根据随机方向调整工作协程数量（每次增减 1 个）
+// shouldIncrease: true=增加，false=减少
+// 返回：是否成功调整，调整的方向（true=增加，false=减少），新的工作协程数量
+func (cc *CPUController) AdjustWorkersRandom(shouldIncrease bool) (bool, bool, uint64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.ctx == nil {
+		return false, shouldIncrease, 0
+	}
+
+	// 工作协程数量上限：CPU 核心数的 2 倍
+	maxWorkers := numCPU() * 2
+	if shouldIncrease && len(cc.workers) < maxWorkers {
+		cc.addWorker()
+	} else if !shouldIncrease && len(cc.workers) > 0 {
+		cc.removeWorker()
+	}
+	return true, shouldIncrease, uint64(len(cc.workers))
+}
+
+// Stop 停止 CPU 占用协程
+func (cc *CPUController) Stop() {
+	// supervisor 需要持有锁，先在不持有锁时停止它
+	cc.mu.Lock()
+	cancel, done := cc.superviseCancel, cc.superviseDone
+	cc.superviseCancel, cc.superviseDone = nil, nil
+	cc.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.cancel != nil {
+		cc.cancel()
+		cc.wg.Wait()
+		cc.ctx = nil
+		cc.cancel = nil
+		cc.workers = nil
+	}
+}
+
+// SetStallTimeout 设置工作协程多长时间没有进展时重新启动（需在 Start 之前调用）
+func (cc *CPUController) SetStallTimeout(d time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.stallTimeout = max(d, 0)
+}
+
+// SetSchedIdle 设置工作协程是否以 SCHED_IDLE 策略运行（需在 Start 之前调用）
+func (cc *CPUController) SetSchedIdle(enabled bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.schedIdle = enabled
+}
+
+// SetKernel 设置工作协程使用的计算内核（需在 Start 之前调用）
+func (cc *CPUController) SetKernel(name string) error {
+	if _, err := newCPUKernel(name); err != nil {
+		return err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.kernel = name
+	return nil
+}
+
+// SetWorkerCount 设置启动时的工作协程数量，与核心数无关（需在 Start 之前调用，0 表示每个核心一个）
+func (cc *CPUController) SetWorkerCount(n int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.numWorkers = max(n, 0)
+}
+
+// SetSleepJitter 设置 sleep 时长的随机浮动比例（0-1，需在 Start 之前调用）
+func (cc *CPUController) SetSleepJitter(jitter float64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.jitter = min(max(jitter, 0), 1)
+}
+
+// sleepDuration 本次 sleep 的时长：在 [1ms × (1 - jitter), 1ms × (1 + jitter)] 内均匀分布
+func (cc *CPUController) sleepDuration() time.Duration {
+	sleep := cc.SleepTime()
+	if cc.jitter == 0 {
+		return sleep
+	}
+	return time.Duration(float64(sleep) * (1 + cc.jitter*(2*rand.Float64()-1)))
+}
+
+// SetSleep 设置每轮 sleep 的时长；adaptive 为 true 时以 d 为下限，根据实际 sleep 时长自动调整（需在 Start 之前调用）
+func (cc *CPUController) SetSleep(d time.Duration, adaptive bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	d = min(max(d, minSleepTime), maxSleepTime)
+	cc.baseSleep = d
+	cc.adaptive = adaptive
+	cc.sleep.Store(int64(d))
+}
+
+// SleepTime 当前每轮 sleep 的时长
+func (cc *CPUController) SleepTime() time.Duration {
+	if d := cc.sleep.Load(); d > 0 {
+		return time.Duration(d)
+	}
+	return sleepTime
+}
+
+// AdaptSleep 自适应 sleep 时长（每个监控周期调用一次）：
+// 定时器精度较粗时实际 sleep 明显长于请求值，占空比被量化，此时加倍 sleep 时长使误差占比变小；
+// 实际与请求值接近时逐步减半回到 CPU_SLEEP。改变 sleep 时长时按比例调整计算次数，保持占空比不变
+func (cc *CPUController) AdaptSleep() {
+	if !cc.adaptive {
+		return
+	}
+	req, act := cc.sleepReqNs.Swap(0), cc.sleepActNs.Swap(0)
+	if req <= 0 {
+		return
+	}
+	ratio := float64(act) / float64(req)
+	old := cc.SleepTime()
+	var next time.Duration
+	switch {
+	case ratio > 1.25 && old < maxAdaptiveSleep:
+		next = min(old*2, maxAdaptiveSleep)
+	case ratio < 1.05 && old > cc.baseSleep:
+		next = max(old/2, cc.baseSleep)
+	default:
+		return
+	}
+
+	scale := float64(next) / float64(old)
+	cc.sleep.Store(int64(next))
+	atomic.StoreUint64(&cc.count, max(uint64(float64(atomic.LoadUint64(&cc.count))*scale), 1))
+	cc.mu.Lock()
+	for _, w := range cc.workers {
+		if count := atomic.LoadUint64(&w.count); count > 0 {
+			atomic.StoreUint64(&w.count, max(uint64(float64(count)*scale), 1))
+		}
+	}
+	cc.mu.Unlock()
+	logger.Info("调整 sleep 时长", "old", old, "new", next, "actual_ratio", ratio)
+}
+
+// SetIdleMode 设置占空比中空闲部分的实现方式（需在 Start 之前调用）
+func (cc *CPUController) SetIdleMode(mode string) error {
+	switch mode {
+	case "sleep", "nanosleep", "spin", "yield":
+	default:
+		return fmt.Errorf("未知的空闲方式: %s（可选：sleep, nanosleep, spin, yield）", mode)
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.idleMode = mode
+	return nil
+}
+
+// idle 空闲 d
+// sleep：Go 定时器（默认）；nanosleep：直接调用 nanosleep 阻塞当前线程；
+// spin：执行 PAUSE 指令自旋等待，使用率计为满载但功耗较低；yield：调用 runtime.Gosched 自旋等待
+func (cc *CPUController) idle(d time.Duration) {
+	switch cc.idleMode {
+	case "nanosleep":
+		ts := syscall.NsecToTimespec(int64(d))
+		syscall.Nanosleep(&ts, nil)
+	case "spin":
+		for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+			for i := 0; i < 64; i++ {
+				cpuPause()
+			}
+		}
+	case "yield":
+		for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+			runtime.Gosched()
+		}
+	default:
+		time.Sleep(d)
+	}
+}
+
+// SetSpread 设置各协程强度的分散程度（0-1，需在 Start 之前调用）
+// 每个协程的计算次数 = 全局计算次数 × 倍数，倍数在 [1 - spread, 1 + spread] 内随机，spread 为 1 时部分协程接近空闲、部分接近满载
+func (cc *CPUController) SetSpread(spread float64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.spread = min(max(spread, 0), 1)
+}
+
+// ReshuffleWeights 重新随机分配各协程的强度倍数，使高负载在协程之间轮换
+func (cc *CPUController) ReshuffleWeights() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for _, w := range cc.workers {
+		cc.assignWeight(w)
+	}
+}
+
+// assignWeight 随机分配一个协程的强度倍数（调用方需持有锁；绑核模式下各核心独立调整，不分散）
+func (cc *CPUController) assignWeight(w *cpuWorkerState) {
+	if cc.spread == 0 || w.core >= 0 {
+		w.weight.Store(0)
+		return
+	}
+	weight := 1 + cc.spread*(2*rand.Float64()-1)
+	w.weight.Store(math.Float64bits(max(weight, 0.01)))
+}
+
+// SetPinned 设置工作协程是否绑定到各自的 CPU 核心（需在 Start 之前调用）
+func (cc *CPUController) SetPinned(enabled bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.pinned = enabled
+}
+
+// cpuWorker CPU 工作协程
+func (cc *CPUController) cpuWorker(ctx context.Context, id int, w *cpuWorkerState) {
+	// panic 时只结束本协程，由 supervisor 重新启动
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic("cpu_worker", r)
+		}
+		w.exited.Store(true)
+		cc.release(w)
+	}()
+
+	// 需要修改线程属性时独占线程
+	// 协程退出时不解锁，线程随之销毁，不会把这些属性带给其他协程
+	if cc.schedIdle || w.core >= 0 {
+		runtime.LockOSThread()
+	}
+	if cc.schedIdle {
+		// SCHED_IDLE：真实业务始终可以抢占
+		if err := setThreadSchedIdle(); err != nil {
+			logger.Warn("设置 SCHED_IDLE 失败", "worker", id, "error", err)
+		}
+	}
+	if w.core >= 0 {
+		if err := setThreadAffinity(w.core); err != nil {
+			logger.Warn("绑定 CPU 核心失败", "worker", id, "core", w.core, "error", err)
+		}
+	}
+
+	kernel, err := newCPUKernel(cc.kernel)
+	if err != nil {
+		kernel = &intKernel{}
+	}
+
+	// 随机错开各协程的相位，避免所有协程在同一时刻 sleep，整体使用率在亚秒级别更平滑
+	var counter, done uint64 // counter：总迭代次数，done：本轮（距上次 sleep）已完成的迭代次数
+	if count := cc.workerCount(w); count > 1 {
+		done = uint64(rand.Int63n(int64(count)))
+	}
+
+	// 简单的计算密集型任务
+	// 每批最多 cpuBatchSize 次迭代，批与批之间才检查退出信号和读取 count，整批迭代只调用一次计算内核（cpuKernel.run），热循环中没有接口调用、原子操作和 select
+	// roundStart：本轮计算的开始时间，每轮结束时把计算时间计入 loadCPUNs（区分有意的负载和自身开销）
+	roundStart := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		w.progress.Add(1)
+		if cc.paused.Load() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pausedPoll):
+			}
+			roundStart = time.Now()
+			continue
+		}
+
+		count := cc.workerCount(w)
+		n := min(cpuBatchSize, count-min(done, count))
+		kernel.run(counter+1, n)
+		counter += n
+		done += n
+
+		if done >= count {
+			loadCPUNs.Add(int64(time.Since(roundStart)))
+			// 每 count 次计算后 sleep 1ms（可以通过 CPU_SLEEP 设置；设置浮动比例时随机浮动，平均值不变）
+			sleep := cc.sleepDuration()
+			if cc.adaptive {
+				start := time.Now()
+				cc.idle(sleep)
+				cc.sleepReqNs.Add(int64(sleep))
+				cc.sleepActNs.Add(int64(time.Since(start)))
+			} else {
+				cc.idle(sleep)
+			}
+			done = 0
+			roundStart = time.Now()
+		}
+	}
+}
+
+// workerCount 工作协程当前的计算次数（使用 atomic 读取，无需加锁）：有独立计算次数时优先使用，再乘以强度倍数
+func (cc *CPUController) workerCount(w *cpuWorkerState) uint64 {
+	count := atomic.LoadUint64(&w.count)
+	if count == 0 {
+		count = atomic.LoadUint64(&cc.count)
+	}
+	if weight := w.weight.Load(); weight != 0 {
+		count = uint64(float64(count) * math.Float64frombits(weight))
+	}
+	return max(count, 1)
+}
+
+// WorkerInternals 单个工作协程的状态（GET /internals 返回）
+type WorkerInternals struct {
+	Core   int     `json:"core"`   // 绑定的 CPU 核心（-1 表示不绑定）
+	Count  uint64  `json:"count"`  // 每次 sleep 前实际执行的计算次数
+	Weight float64 `json:"weight"` // 计算次数的倍数（CPU_WORKER_SPREAD）
+}
+
+// WorkerStats 返回各工作协程当前的强度
+func (cc *CPUController) WorkerStats() []WorkerInternals {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	stats := make([]WorkerInternals, len(cc.workers))
+	for i, w := range cc.workers {
+		weight := 1.0
+		if bits := w.weight.Load(); bits != 0 {
+			weight = math.Float64frombits(bits)
+		}
+		stats[i] = WorkerInternals{Core: w.core, Count: cc.workerCount(w), Weight: weight}
+	}
+	return stats
+}
+
+// AdjustCountRandom 根据随机方向调整计算次数
+// shouldIncrease: true=增加占用（增加 count），false=减少占用（减少 count）
+// 返回：是否成功调整，调整的方向（true=增加占用，false=减少占用），新的 count 值
+func (cc *CPUController) AdjustCountRandom(shouldIncrease bool) (bool, bool, uint64) {
+	newCount := scaleCount(atomic.LoadUint64(&cc.count), shouldIncrease)
+
+	// 使用 atomic 写入新值
+	atomic.StoreUint64(&cc.count, newCount)
+	return true, shouldIncrease, newCount
+}
+
+// AdjustCoreRandom 根据随机方向调整绑定到指定核心的工作协程的计算次数
+// 返回：是否成功调整，调整的方向（true=增加占用，false=减少占用），新的 count 值
+func (cc *CPUController) AdjustCoreRandom(core int, shouldIncrease bool) (bool, bool, uint64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	for _, w := range cc.workers {
+		if w.core != core {
+			continue
+		}
+		newCount := scaleCount(atomic.LoadUint64(&w.count), shouldIncrease)
+		atomic.StoreUint64(&w.count, newCount)
+		return true, shouldIncrease, newCount
+	}
+	return false, shouldIncrease, 0
+}
+
+// scaleCount 按 0.1% 的步长增加或减少计算次数
+func scaleCount(currentCount uint64, shouldIncrease bool) uint64 {
+	var newCount uint64
+
+	if shouldIncrease {
+		// 增加 CPU 占用，增加 count
+		// count = count * (1 + 0.1%) = count * 1.001
+		newCount = uint64(float64(currentCount) * 1.001)
+	} else {
+		// 减少 CPU 占用，减少 count
+		// count = count * (1 - 0.1%) = count * 0.999
+		newCount = uint64(float64(currentCount) * 0.999)
+		// 确保 count 不会小于 1
+		if newCount < 1 {
+			newCount = 1
+		}
+	}
+
+	return newCount
+}
+
+// SetCount 设置计算次数（如根据校准结果设置初始值）
+func (cc *CPUController) SetCount(count uint64) {
+	atomic.StoreUint64(&cc.count, max(count, 1))
+}
+
+// SetPaused 暂停或恢复所有工作协程的计算
+func (cc *CPUController) SetPaused(paused bool) {
+	cc.paused.Store(paused)
+}
+
+// GetCount 获取当前计算次数
+func (cc *CPUController) GetCount() uint64 {
+	return atomic.LoadUint64(&cc.count)
+}
@@ -0,0 +1,83 @@
+package busy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// readCPUFrequency 读取所有 CPU 核心的平均当前频率（MHz）
+// 优先读取 cpufreq（scaling_cur_freq），没有 cpufreq 驱动时从 /proc/cpuinfo 读取
+func readCPUFrequency() (float64, error) {
+	if freq, err := averageCPUFreqFile("scaling_cur_freq"); err == nil {
+		return freq, nil
+	}
+	return readCPUInfoMHz()
+}
+
+// readCPUMaxFrequency 读取所有 CPU 核心的平均最大频率（MHz），作为频率补偿的参考值
+func readCPUMaxFrequency() (float64, error) {
+	return averageCPUFreqFile("cpuinfo_max_freq")
+}
+
+// averageCPUFreqFile 读取所有核心 cpufreq 目录下指定文件（单位 kHz）的平均值，返回 MHz
+func averageCPUFreqFile(name string) (float64, error) {
+	paths, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/" + name)
+
+	var (
+		sum   float64
+		count int
+	)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		khz, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+		if err != nil {
+			continue
+		}
+		sum += khz / 1000
+		count++
+	}
+
+	if count == 0 {
+		return 0, fmt.Errorf("无法读取 cpufreq/%s", name)
+	}
+	return sum / float64(count), nil
+}
+
+// readCPUInfoMHz 从 /proc/cpuinfo 读取所有核心的平均频率（MHz）
+func readCPUInfoMHz() (float64, error) {
+	file, err := os.Open(procPath("cpuinfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var (
+		sum   float64
+		count int
+	)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "cpu MHz" {
+			continue
+		}
+		mhz, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		sum += mhz
+		count++
+	}
+
+	if count == 0 {
+		return 0, fmt.Errorf("/proc/cpuinfo 中没有频率信息")
+	}
+	return sum / float64(count), nil
+}
@@ -0,0 +1,4 @@
+package busy
+
+// cpuPause 执行一次 PAUSE 指令：告诉 CPU 当前在自旋等待，降低功耗并让出超线程的执行资源
+func cpuPause()
@@ -0,0 +1,6 @@
+//go:build !amd64
+
+package busy
+
+// cpuPause 其他架构上没有实现 PAUSE，自旋等待时不做任何事
+func cpuPause() {}
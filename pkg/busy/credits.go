@@ -0,0 +1,229 @@
+package busy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCreditHorizon     = time.Hour       // 默认在多长时间内用完高于下限的积分
+	defaultCreditURLInterval = 5 * time.Minute // 默认读取外部积分余额的间隔（CloudWatch 的 CPUCreditBalance 每 5 分钟更新一次）
+	creditStealPercent       = 10              // steal 达到该值时可能是积分耗尽后被限制到基准性能
+	creditStealCycles        = 5               // steal 连续多少个周期达到 creditStealPercent 时认为积分已经耗尽
+)
+
+// burstableBaselines 可突增性能实例（AWS T 系列）每个 vCPU 的基准性能（百分比），按机型大小
+var burstableBaselines = map[string]map[string]float64{
+	"t2":  {"nano": 5, "micro": 10, "small": 20, "medium": 20, "large": 30, "xlarge": 22.5, "2xlarge": 17},
+	"t3":  {"nano": 5, "micro": 10, "small": 20, "medium": 20, "large": 30, "xlarge": 40, "2xlarge": 40},
+	"t3a": {"nano": 5, "micro": 10, "small": 20, "medium": 20, "large": 30, "xlarge": 40, "2xlarge": 40},
+	"t4g": {"nano": 5, "micro": 10, "small": 20, "medium": 20, "large": 30, "xlarge": 40, "2xlarge": 40},
+}
+
+// burstableBaseline 机型（如 t3.large）的基准性能，不是 T 系列时返回 false
+func burstableBaseline(instanceType string) (float64, bool) {
+	family, size, ok := strings.Cut(instanceType, ".")
+	if !ok {
+		return 0, false
+	}
+	baseline, ok := burstableBaselines[family][size]
+	return baseline, ok
+}
+
+// configureCPUCredits 按环境变量启用 CPU 积分控制：基准性能使用 CPU_CREDIT_BASELINE，未设置时按机型查表
+func configureCPUCredits() {
+	baseline := getEnvFloat("CPU_CREDIT_BASELINE", 0)
+	if baseline <= 0 {
+		instanceType := detectInstanceType()
+		var ok bool
+		if baseline, ok = burstableBaseline(instanceType); !ok {
+			logger.Warn("无法确定基准性能（不是 T 系列机型或无法获取机型），不启用 CPU 积分控制，可以设置 CPU_CREDIT_BASELINE",
+				"instance_type", instanceType)
+			return
+		}
+		logger.Info("按机型确定基准性能", "instance_type", instanceType, "baseline_percent", baseline)
+	}
+	floor := getEnvFloat("CPU_CREDIT_FLOOR", 0)
+	cpuCredits.Configure(baseline, floor, getEnvFloat("CPU_CREDIT_INITIAL", floor), getEnvDuration("CPU_CREDIT_HORIZON", defaultCreditHorizon))
+	cpuCredits.StartSync(lookupEnv("CPU_CREDIT_URL"), lookupEnv("CPU_CREDIT_FIELD"), getEnvDuration("CPU_CREDIT_URL_INTERVAL", defaultCreditURLInterval))
+}
+
+// CPUCreditStatus CPU 积分的估算（GET /status 的 cpu_credits 字段）
+type CPUCreditStatus struct {
+	Balance         float64   `json:"balance"`          // 当前积分余额（1 积分 = 1 个 vCPU 满载 1 分钟）
+	Floor           float64   `json:"floor"`            // 保留的积分下限
+	BaselinePercent float64   `json:"baseline_percent"` // 基准性能（占整机）
+	AllowedPercent  float64   `json:"allowed_percent"`  // 当前允许的整机 CPU 占用
+	Source          string    `json:"source"`           // 余额的来源：estimate（本地估算）或 url（外部接口）
+	SyncedAt        time.Time `json:"synced_at,omitzero"`
+}
+
+// CPUCredits 可突增性能实例的 CPU 积分：整机 CPU 低于基准性能时积累积分，高于基准性能时消耗积分，
+// 积分耗尽后（标准模式）整机被限制到基准性能，真实业务也随之变慢（无限模式则开始额外计费）
+// 每个周期按整机 CPU 占用估算余额（可以定期用外部接口返回的实际余额校正），
+// 高于下限的积分在 horizon 内均匀使用：允许的整机占用 = 基准性能 + 高于下限的积分 / horizon，余额低于下限时低于基准性能以恢复积分
+type CPUCredits struct {
+	mu       sync.Mutex
+	enabled  bool
+	baseline float64       // 基准性能（占整机的百分比）
+	floor    float64       // 积分下限
+	maxBal   float64       // 最多积累的积分（24 小时的积分收入）
+	horizon  time.Duration // 在多长时间内用完高于下限的积分
+	balance  float64
+	source   string
+	syncedAt time.Time
+	lastAt   time.Time
+	steal    int  // steal 连续达到 creditStealPercent 的周期数
+	low      bool // 余额是否低于下限（只在变化时记录）
+
+	url    string
+	field  string
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var cpuCredits = &CPUCredits{}
+
+// Configure 设置基准性能（每个 vCPU 的百分比）、积分下限、初始余额和 horizon
+func (cc *CPUCredits) Configure(baseline, floor, initial float64, horizon time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.enabled = true
+	cc.baseline = min(max(baseline, 0), 100)
+	cc.maxBal = cc.baseline / 100 * float64(numCPU()) * 60 * 24
+	cc.floor = min(max(floor, 0), cc.maxBal)
+	cc.horizon = max(horizon, time.Minute)
+	cc.balance = min(max(initial, 0), cc.maxBal)
+	cc.source = "estimate"
+	cc.lastAt, cc.steal, cc.low = time.Time{}, 0, false
+	logger.Info("已启用 CPU 积分控制", "baseline_percent", cc.baseline, "floor", cc.floor,
+		"initial_balance", cc.balance, "max_balance", cc.maxBal, "horizon", cc.horizon)
+}
+
+// StartSync 定期从外部接口（如转发 CloudWatch CPUCreditBalance 的服务）读取实际余额，校正本地估算
+func (cc *CPUCredits) StartSync(url, field string, interval time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.cancel != nil || !cc.enabled || url == "" {
+		return
+	}
+	cc.url, cc.field = url, field
+	ctx, cancel := context.WithCancel(context.Background())
+	cc.cancel = cancel
+	cc.wg.Add(1)
+	go cc.sync(ctx, max(interval, time.Minute))
+}
+
+// Stop 停止读取外部余额
+func (cc *CPUCredits) Stop() {
+	cc.mu.Lock()
+	cancel := cc.cancel
+	cc.cancel = nil
+	cc.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		cc.wg.Wait()
+	}
+}
+
+// sync 按间隔读取外部余额，失败时继续使用本地估算
+func (cc *CPUCredits) sync(ctx context.Context, interval time.Duration) {
+	defer cc.wg.Done()
+	client := &http.Client{Timeout: gridSignalTimeout}
+	cfg := GridSignalConfig{URL: cc.url, Field: cc.field}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		balance, err := fetchGridSignal(ctx, client, cfg)
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				logger.Warn("读取 CPU 积分余额失败，继续使用本地估算", "url", cc.url, "error", err)
+			}
+		default:
+			cc.mu.Lock()
+			estimate := cc.balance
+			cc.balance, cc.source, cc.syncedAt = max(balance, 0), "url", clock.Now()
+			cc.mu.Unlock()
+			logger.Info("CPU 积分余额已校正", "balance", balance, "estimate", estimate)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update 按上个周期的整机 CPU 占用累计积分的收入和消耗，steal 持续较高时认为积分已经耗尽
+func (cc *CPUCredits) Update(now time.Time, stats *SystemStats) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if !cc.enabled {
+		return
+	}
+	if !cc.lastAt.IsZero() {
+		dt := min(now.Sub(cc.lastAt), trackingMaxGap).Hours()
+		// steal 时间没有实际运行，不消耗积分
+		used := max(stats.CPUPercent-stats.StealPercent, 0)
+		cc.balance = min(max(cc.balance+(cc.baseline-used)/100*float64(numCPU())*60*dt, 0), cc.maxBal)
+	}
+	cc.lastAt = now
+
+	// 积分耗尽后宿主机把实例限制在基准性能，表现为持续的 steal 时间
+	if stats.StealPercent >= creditStealPercent {
+		if cc.steal++; cc.steal == creditStealCycles && cc.balance > 0 {
+			logger.Warn("steal 持续较高，CPU 积分可能已经耗尽，估算余额重置为 0", "steal_percent", stats.StealPercent, "estimate", cc.balance)
+			cc.balance, cc.source = 0, "estimate"
+		}
+	} else {
+		cc.steal = 0
+	}
+
+	if low := cc.balance <= cc.floor; low != cc.low {
+		cc.low = low
+		if low {
+			logger.Warn("CPU 积分余额低于下限，整机 CPU 限制在基准性能以下", "balance", cc.balance, "floor", cc.floor, "baseline_percent", cc.baseline)
+			emitEvent("cpu_credit_low", "resource", "CPU", "balance", cc.balance, "floor", cc.floor)
+		} else {
+			logger.Info("CPU 积分余额恢复到下限以上", "balance", cc.balance, "floor", cc.floor)
+		}
+	}
+}
+
+// allowed 当前允许的整机 CPU 占用：基准性能 + 高于下限的积分在 horizon 内均匀使用的部分
+func (cc *CPUCredits) allowed() float64 {
+	extra := (cc.balance - cc.floor) / (cc.horizon.Hours() * float64(numCPU()) * 60) * 100
+	return min(max(cc.baseline+extra, 0), 100)
+}
+
+// CPUTarget 按积分限制后的 CPU 目标（与 scopedCPUPercent 相同的口径）：整机占用不超过当前允许的占用
+func (cc *CPUCredits) CPUTarget(stats *SystemStats, expectedUsage float64) float64 {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if !cc.enabled {
+		return expectedUsage
+	}
+	return min(expectedUsage, scopedCPUPercent(stats)-stats.CPUPercent+cc.allowed())
+}
+
+// Status 当前的积分估算，没有启用时返回 nil
+func (cc *CPUCredits) Status() *CPUCreditStatus {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if !cc.enabled {
+		return nil
+	}
+	return &CPUCreditStatus{
+		Balance:         cc.balance,
+		Floor:           cc.floor,
+		BaselinePercent: cc.baseline,
+		AllowedPercent:  cc.allowed(),
+		Source:          cc.source,
+		SyncedAt:        cc.syncedAt,
+	}
+}
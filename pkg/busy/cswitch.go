@@ -0,0 +1,171 @@
+package busy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cswitchTickInterval 上下文切换负载的节拍：每 10ms 执行一批往返
+const cswitchTickInterval = 10 * time.Millisecond
+
+// CSwitchController 上下文切换控制器：成对的独占线程通过 channel 互相唤醒，产生真实的自愿上下文切换
+// 每次往返至少让两个线程各阻塞/唤醒一次，Go 调度器的唤醒路径还会带来额外切换，
+// 因此每次往返实际产生的切换次数通过 /proc/self/task 统计校准
+type CSwitchController struct {
+	mu     sync.Mutex
+	rate   uint64 // 目标往返次数（次/秒，使用 atomic 保护）
+	pairs  int    // 线程对数
+	trips  uint64 // 累计往返次数（使用 atomic 保护）
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	switchesPerTrip float64 // 每次往返实际产生的上下文切换次数（校准值）
+	lastSwitches    uint64  // 上次校准时本进程的累计上下文切换次数
+	lastTrips       uint64  // 上次校准时的累计往返次数
+}
+
+var cswitchController = &CSwitchController{}
+
+// Start 启动指定对数的 ping-pong 线程
+func (sc *CSwitchController) Start(pairs int, rate uint64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.ctx != nil {
+		// 已经启动
+		return
+	}
+
+	sc.pairs = max(pairs, 1)
+	sc.switchesPerTrip = 2
+	atomic.StoreUint64(&sc.rate, rate)
+	sc.ctx, sc.cancel = context.WithCancel(context.Background())
+
+	for i := 0; i < sc.pairs; i++ {
+		ping := make(chan struct{})
+		pong := make(chan struct{})
+		sc.wg.Add(2)
+		go sc.pinger(ping, pong)
+		go sc.ponger(ping, pong)
+	}
+}
+
+// Stop 停止所有 ping-pong 线程
+func (sc *CSwitchController) Stop() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.cancel != nil {
+		sc.cancel()
+		sc.wg.Wait()
+		sc.ctx = nil
+		sc.cancel = nil
+	}
+}
+
+// SetRate 设置目标上下文切换次数（次/秒），并根据上一周期的实际切换次数校准
+func (sc *CSwitchController) SetRate(rate uint64) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.calibrate()
+	atomic.StoreUint64(&sc.rate, uint64(float64(rate)/sc.switchesPerTrip))
+}
+
+// calibrate 统计本进程的上下文切换次数，更新每次往返实际产生的切换次数
+func (sc *CSwitchController) calibrate() {
+	switches, err := readSelfContextSwitches()
+	if err != nil {
+		return
+	}
+	trips := atomic.LoadUint64(&sc.trips)
+
+	// 往返次数太少时统计不准确，保持原值
+	if sc.lastSwitches > 0 && trips-sc.lastTrips >= 100 && switches > sc.lastSwitches {
+		measured := float64(switches-sc.lastSwitches) / float64(trips-sc.lastTrips)
+		// 指数平滑，避免其他线程的切换造成抖动
+		sc.switchesPerTrip = max(sc.switchesPerTrip*0.7+measured*0.3, 1)
+	}
+	sc.lastSwitches = switches
+	sc.lastTrips = trips
+}
+
+// GetTrips 获取累计往返次数
+func (sc *CSwitchController) GetTrips() uint64 {
+	return atomic.LoadUint64(&sc.trips)
+}
+
+// pinger 发起方：按节拍发起一批往返
+func (sc *CSwitchController) pinger(ping, pong chan struct{}) {
+	defer sc.wg.Done()
+	defer close(ping)
+	runtime.LockOSThread()
+
+	ticker := time.NewTicker(cswitchTickInterval)
+	defer ticker.Stop()
+
+	var budget float64 // 当前节拍可执行的往返次数
+	for {
+		select {
+		case <-sc.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// 每对线程分摊总往返速率
+		perPair := float64(atomic.LoadUint64(&sc.rate)) / float64(sc.pairs)
+		budget += perPair * cswitchTickInterval.Seconds()
+
+		for ; budget >= 1; budget-- {
+			ping <- struct{}{}
+			<-pong
+			atomic.AddUint64(&sc.trips, 1)
+		}
+	}
+}
+
+// ponger 响应方：收到 ping 后立即回复 pong，ping 关闭后退出
+func (sc *CSwitchController) ponger(ping, pong chan struct{}) {
+	defer sc.wg.Done()
+	runtime.LockOSThread()
+
+	for range ping {
+		pong <- struct{}{}
+	}
+}
+
+// readSelfContextSwitches 统计本进程所有线程的累计上下文切换次数（自愿 + 非自愿）
+func readSelfContextSwitches() (uint64, error) {
+	paths, err := filepath.Glob("/proc/self/task/*/status")
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// 线程可能已经退出
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			if fields[0] == "voluntary_ctxt_switches:" || fields[0] == "nonvoluntary_ctxt_switches:" {
+				n, _ := strconv.ParseUint(fields[1], 10, 64)
+				total += n
+			}
+		}
+	}
+	return total, nil
+}
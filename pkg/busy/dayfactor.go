@@ -0,0 +1,86 @@
+package busy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDayFactor 凌晨时段以外期望占用相对 peakUsage 的系数
+const defaultDayFactor = 0.8
+
+// dayWindow 白天的一个时段及其系数（UTC 小时，左闭右开，start > end 表示跨零点）
+type dayWindow struct {
+	start, end float64
+	factor     float64
+}
+
+var (
+	dayFactor  = defaultDayFactor // 未被 DAY_WINDOWS 覆盖的时段使用的系数
+	dayWindows []dayWindow        // 按时段设置的系数（先匹配的优先）
+)
+
+// loadDayFactors 从环境变量读取白天系数
+func loadDayFactors() {
+	dayFactor = getEnvFloat("DAY_FACTOR", defaultDayFactor)
+	if dayFactor < 0 || dayFactor > 1 {
+		logger.Warn("DAY_FACTOR 超出范围，使用默认值", "value", dayFactor, "default", defaultDayFactor)
+		dayFactor = defaultDayFactor
+	}
+	dayWindows = nil
+	if value := lookupEnv("DAY_WINDOWS"); value != "" {
+		windows, err := parseDayWindows(value)
+		if err != nil {
+			logger.Warn("DAY_WINDOWS 无效，忽略", "value", value, "error", err)
+			return
+		}
+		dayWindows = windows
+	}
+}
+
+// parseDayWindows 解析 "开始-结束=系数" 的逗号分隔列表（UTC 小时，可以有小数），如 "0-8=0.6,8-16=0.9"
+func parseDayWindows(value string) ([]dayWindow, error) {
+	var windows []dayWindow
+	for _, item := range splitList(value) {
+		span, f, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("格式错误: %q（应为 开始-结束=系数）", item)
+		}
+		s, e, ok := strings.Cut(span, "-")
+		if !ok {
+			return nil, fmt.Errorf("时段格式错误: %q（应为 开始-结束）", span)
+		}
+		start, err1 := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		end, err2 := strconv.ParseFloat(strings.TrimSpace(e), 64)
+		if err1 != nil || err2 != nil || start < 0 || start > 24 || end < 0 || end > 24 || start == end {
+			return nil, fmt.Errorf("时段无效: %q（小时应在 0-24 之间且开始不等于结束）", span)
+		}
+		factor, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || factor < 0 || factor > 1 {
+			return nil, fmt.Errorf("系数无效: %q（应在 0-1 之间）", f)
+		}
+		windows = append(windows, dayWindow{start: start, end: end, factor: factor})
+	}
+	return windows, nil
+}
+
+// contains 判断小时数是否在时段内
+func (w dayWindow) contains(hour float64) bool {
+	if w.start < w.end {
+		return hour >= w.start && hour < w.end
+	}
+	return hour >= w.start || hour < w.end
+}
+
+// dayFactorAt 返回指定时间的白天系数：先匹配 DAY_WINDOWS，再使用 DAY_FACTOR
+func dayFactorAt(t time.Time) float64 {
+	t = t.UTC()
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	for _, w := range dayWindows {
+		if w.contains(hour) {
+			return w.factor
+		}
+	}
+	return dayFactor
+}
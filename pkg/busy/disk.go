@@ -0,0 +1,280 @@
+package busy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+const (
+	diskFilePrefix = "cpumembusy-fill-" // 填充文件名前缀
+	diskChunkSize  = 1024 * 1024        // write 模式下每次写入 1MB
+)
+
+// DiskController 磁盘控制器：通过创建/删除填充文件调整文件系统占用
+type DiskController struct {
+	mu         sync.Mutex
+	dir        string     // 填充文件所在目录
+	mode       string     // 填充方式：fallocate（预分配真实块）或 write（写入数据）
+	files      []diskFile // 已创建的填充文件
+	totalBytes uint64     // 文件系统总容量
+	seq        int        // 填充文件序号
+	target     float64    // 固定的目标使用率（DISK_TARGET_PERCENT，0 表示跟随期望占用值）
+
+	// write 模式在后台写入填充文件（写入和 fsync 可能需要数秒，不能阻塞监控循环），同一时间只写一个文件
+	writeCancel context.CancelFunc // 正在写入的文件（nil 表示没有）
+	wg          sync.WaitGroup
+}
+
+// diskFile 填充文件
+type diskFile struct {
+	path string
+	size uint64
+}
+
+var diskController = &DiskController{}
+
+// errDiskWriting write 模式下上一个填充文件还在后台写入
+var errDiskWriting = errors.New("上一个填充文件还在写入")
+
+// SetTarget 设置固定的目标使用率（0 表示跟随期望占用值）
+func (dc *DiskController) SetTarget(percent float64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.target = percent
+}
+
+// Target 本周期的目标使用率：设置了固定目标时为该值，否则为期望占用值，都不超过当前时段的硬峰值
+func (dc *DiskController) Target(expectedUsage float64) float64 {
+	dc.mu.Lock()
+	target := dc.target
+	dc.mu.Unlock()
+	if target <= 0 {
+		target = expectedUsage
+	}
+	return min(target, hardPeak())
+}
+
+// Init 初始化磁盘控制器，清理上次运行遗留的填充文件
+func (dc *DiskController) Init(dir, mode string) error {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if mode != "fallocate" && mode != "write" {
+		return fmt.Errorf("未知的磁盘填充方式: %s", mode)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, diskFilePrefix+"*"))
+	for _, path := range leftovers {
+		os.Remove(path)
+	}
+
+	_, total, err := getDiskUsage(dir)
+	if err != nil {
+		return err
+	}
+
+	dc.dir = dir
+	dc.mode = mode
+	dc.totalBytes = total
+	return nil
+}
+
+// Enabled 磁盘控制器是否已启用
+func (dc *DiskController) Enabled() bool {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.dir != ""
+}
+
+// Dir 填充文件所在目录
+func (dc *DiskController) Dir() string {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.dir
+}
+
+// AdjustDiskRandom 根据随机方向调整磁盘占用
+// shouldIncrease: true=增加，false=减少
+// 返回：是否成功调整，调整的方向（true=增加，false=减少），当前填充文件总字节数
+func (dc *DiskController) AdjustDiskRandom(shouldIncrease bool) (bool, bool, uint64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if shouldIncrease {
+		// 每次增加 0.1% 的文件系统容量，至少 1MB
+		size := max(dc.totalBytes/1000, diskChunkSize)
+		if err := dc.createFile(size); errors.Is(err, errDiskWriting) {
+			return false, shouldIncrease, dc.getCurrentBytes()
+		} else if err != nil {
+			logger.Warn("创建磁盘填充文件失败", "dir", dc.dir, "size_mb", size/(1024*1024), "error", err)
+			return false, shouldIncrease, dc.getCurrentBytes()
+		}
+	} else {
+		// 正在后台写入时先取消写入（未完成的文件会被删除）
+		if dc.writeCancel != nil {
+			dc.writeCancel()
+			dc.writeCancel = nil
+			return true, shouldIncrease, dc.getCurrentBytes()
+		}
+		// 从最后创建的文件开始删除
+		if len(dc.files) == 0 {
+			return true, shouldIncrease, 0
+		}
+		last := dc.files[len(dc.files)-1]
+		if err := os.Remove(last.path); err != nil && !os.IsNotExist(err) {
+			logger.Warn("删除磁盘填充文件失败", "path", last.path, "error", err)
+			return false, shouldIncrease, dc.getCurrentBytes()
+		}
+		dc.files = dc.files[:len(dc.files)-1]
+	}
+
+	return true, shouldIncrease, dc.getCurrentBytes()
+}
+
+// createFile 创建指定大小的填充文件；write 模式在后台写入，上一个文件还没有写完时返回错误
+func (dc *DiskController) createFile(size uint64) error {
+	if dc.writeCancel != nil {
+		return errDiskWriting
+	}
+	dc.seq++
+	path := filepath.Join(dc.dir, fmt.Sprintf("%s%06d", diskFilePrefix, dc.seq))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if dc.mode == "write" {
+		ctx, cancel := context.WithCancel(context.Background())
+		dc.writeCancel = cancel
+		dc.wg.Add(1)
+		go dc.writeFile(ctx, cancel, file, diskFile{path: path, size: size})
+		return nil
+	}
+
+	// 预分配真实的磁盘块，不需要实际写入数据
+	defer file.Close()
+	if err := syscall.Fallocate(int(file.Fd()), 0, 0, int64(size)); err != nil {
+		os.Remove(path)
+		return err
+	}
+	dc.files = append(dc.files, diskFile{path: path, size: size})
+	return nil
+}
+
+// writeFile 在后台写入填充文件，完成后加入已创建的文件；失败或被取消时删除未完成的文件
+func (dc *DiskController) writeFile(ctx context.Context, cancel context.CancelFunc, file *os.File, f diskFile) {
+	defer dc.wg.Done()
+	err := writeFiller(ctx, file, f.size)
+	file.Close()
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	// 被取消时 writeCancel 已经由调用方清空
+	if ctx.Err() == nil {
+		dc.writeCancel = nil
+	}
+	cancel()
+	if err != nil {
+		os.Remove(f.path)
+		if ctx.Err() == nil {
+			logger.Warn("写入磁盘填充文件失败", "path", f.path, "size_mb", f.size/(1024*1024), "error", err)
+		}
+		return
+	}
+	dc.files = append(dc.files, f)
+}
+
+// writeFiller 向文件写入 size 字节的数据（每写入 1MB 检查一次是否被取消）
+func writeFiller(ctx context.Context, file *os.File, size uint64) error {
+	buf := make([]byte, diskChunkSize)
+	for j := range buf {
+		buf[j] = byte(j % 256)
+	}
+
+	for written := uint64(0); written < size; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := min(uint64(len(buf)), size-written)
+		if _, err := file.Write(buf[:n]); err != nil {
+			return err
+		}
+		written += n
+	}
+	return file.Sync()
+}
+
+// Release 删除全部填充文件（取消正在后台写入的文件），之后仍可以继续调整
+func (dc *DiskController) Release() {
+	dc.mu.Lock()
+	if dc.writeCancel != nil {
+		dc.writeCancel()
+		dc.writeCancel = nil
+	}
+	dc.mu.Unlock()
+	dc.wg.Wait()
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if len(dc.files) == 0 {
+		return
+	}
+	released := dc.getCurrentBytes()
+	for _, f := range dc.files {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			logger.Warn("删除磁盘填充文件失败", "path", f.path, "error", err)
+		}
+	}
+	dc.files = nil
+	logger.Info("已删除磁盘填充文件", "dir", dc.dir, "released_mb", released/(1024*1024))
+}
+
+// Stop 删除全部填充文件并停用磁盘控制器
+func (dc *DiskController) Stop() {
+	dc.Release()
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.dir = ""
+}
+
+// getCurrentBytes 获取当前填充文件的总字节数
+func (dc *DiskController) getCurrentBytes() uint64 {
+	var total uint64
+	for _, f := range dc.files {
+		total += f.size
+	}
+	return total
+}
+
+// GetCurrentBytes 获取当前填充文件的总字节数
+func (dc *DiskController) GetCurrentBytes() uint64 {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.getCurrentBytes()
+}
+
+// getDiskUsage 获取路径所在文件系统的使用率（与 df 的计算方式一致）和总容量
+func getDiskUsage(path string) (float64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	blockSize := uint64(st.Bsize)
+	used := (st.Blocks - st.Bfree) * blockSize
+	usable := used + st.Bavail*blockSize
+	if usable == 0 {
+		return 0, 0, fmt.Errorf("无法获取文件系统容量")
+	}
+
+	return float64(used) / float64(usable) * 100, st.Blocks * blockSize, nil
+}
@@ -0,0 +1,9 @@
+// Package busy 按期望占用曲线产生 CPU、内存、磁盘、网络等资源的负载，并通过 agent / controller 接口远程调整
+//
+// 包内的状态是进程级单例：CPU、内存、磁盘等控制器、峰值、硬峰值、控制策略和大部分运行时配置都是包级变量，
+// Controller 只是启动和停止这些单例的句柄，而不是可以创建多个实例的类型：
+//
+//   - 每个进程只能 Start 一次 Controller；停止后不能重新启动，也不能再创建第二个 Controller 启动（Start 返回错误）
+//   - 需要多个独立实例或重新启动时，应运行多个进程
+//   - SetLogger、SetClock、RegisterWorkload、RegisterStrategy 修改的同样是进程级的设置
+package busy
@@ -0,0 +1,421 @@
+package busy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultEventsTopic = "cpumembusy.events"
+	eventQueueSize     = 1024             // 等待发布的事件数上限，超过时丢弃新的事件（不阻塞主循环）
+	eventBatchSize     = 100              // 一次发布的最多事件数
+	eventTimeout       = 10 * time.Second // 连接和发布的超时
+	eventRetryInterval = 5 * time.Second  // 连接失败后多久内不再重试（期间的事件丢弃）
+)
+
+// eventSink 事件的发布目标，每次发布一批 JSON 编码的事件
+type eventSink interface {
+	Name() string
+	Publish(events [][]byte) error
+	Close()
+}
+
+// EventPublisher 把调整和阈值事件以 JSON 发布到 NATS 或 Kafka（通过 REST Proxy），便于在分析平台中与真实故障的时间线关联
+// 事件在后台异步发布，发布失败只记录日志；队列已满时丢弃新的事件
+type EventPublisher struct {
+	sinks   []eventSink
+	queue   chan []byte
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+var eventPublisher *EventPublisher // nil 表示不发布事件
+
+// newEventSinks 按 EVENTS_FILE / EVENTS_NATS_URL / EVENTS_KAFKA_REST_URL 创建发布目标
+func newEventSinks() ([]eventSink, error) {
+	topic := getEnvString("EVENTS_TOPIC", defaultEventsTopic)
+	var sinks []eventSink
+	if value := lookupEnv("EVENTS_FILE"); value != "" {
+		sink, err := newFileSink(value)
+		if err != nil {
+			return nil, fmt.Errorf("EVENTS_FILE 无效: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if value := lookupEnv("EVENTS_NATS_URL"); value != "" {
+		sink, err := newNATSSink(value, topic)
+		if err != nil {
+			return nil, fmt.Errorf("EVENTS_NATS_URL 无效: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+	if value := lookupEnv("EVENTS_KAFKA_REST_URL"); value != "" {
+		if err := checkURL(value); err != nil {
+			return nil, fmt.Errorf("EVENTS_KAFKA_REST_URL 无效: %w", err)
+		}
+		sinks = append(sinks, &kafkaRESTSink{
+			url:    strings.TrimRight(value, "/") + "/topics/" + url.PathEscape(topic),
+			client: &http.Client{Timeout: eventTimeout},
+		})
+	}
+	return sinks, nil
+}
+
+// startEventPublisher 开始在后台发布事件
+func startEventPublisher(sinks []eventSink) *EventPublisher {
+	ep := &EventPublisher{
+		sinks: sinks,
+		queue: make(chan []byte, eventQueueSize),
+		done:  make(chan struct{}),
+	}
+	go ep.loop()
+	return ep
+}
+
+// Stop 发布队列中剩余的事件后关闭连接
+func (ep *EventPublisher) Stop() {
+	close(ep.queue)
+	<-ep.done
+	for _, sink := range ep.sinks {
+		sink.Close()
+	}
+	if n := ep.dropped.Load(); n > 0 {
+		logger.Warn("部分事件因队列已满被丢弃", "dropped", n)
+	}
+}
+
+// loop 按批次取出事件并发布到所有目标
+func (ep *EventPublisher) loop() {
+	defer close(ep.done)
+	for data := range ep.queue {
+		batch := [][]byte{data}
+	drain:
+		for len(batch) < eventBatchSize {
+			select {
+			case data, ok := <-ep.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, data)
+			default:
+				break drain
+			}
+		}
+		for _, sink := range ep.sinks {
+			if err := sink.Publish(batch); err != nil {
+				logger.Warn("发布事件失败", "sink", sink.Name(), "events", len(batch), "error", err)
+			}
+		}
+	}
+}
+
+// emitEvent 发布一个事件，fields 为 键, 值 交替的字段（与 logger 相同）
+// 每个事件都包含 type、time、hostname；resource 字段转换为英文名称（见 resourceKey）；未启用事件发布时不做任何事
+func emitEvent(eventType string, fields ...any) {
+	ep := eventPublisher
+	if ep == nil {
+		return
+	}
+	event := map[string]any{
+		"type":     eventType,
+		"time":     clock.Now().UTC(),
+		"hostname": hostname(),
+	}
+	for key, value := range hostMeta.EventFields() {
+		event[key] = value
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if key, ok := fields[i].(string); ok {
+			event[key] = fields[i+1]
+			if name, ok := fields[i+1].(string); ok && key == "resource" {
+				event[key] = resourceKey(name)
+			}
+		}
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Warn("事件编码失败", "type", eventType, "error", err)
+		return
+	}
+	select {
+	case ep.queue <- data:
+	default:
+		ep.dropped.Add(1)
+	}
+}
+
+// emitAdjustmentEvent 本周期有实际调整时发布 adjustment 事件（全部跳过时不发布）
+// decisions 与 /history 相同（中文的资源名称和决定），actions 是相同决定的机器可读形式
+func emitAdjustmentEvent(entry HistoryEntry, actions []DecisionAction) {
+	for _, action := range entry.Decisions {
+		if !strings.HasSuffix(action, "跳过") {
+			emitEvent("adjustment",
+				"peak_usage", entry.PeakUsage,
+				"expected_usage", entry.ExpectedUsage,
+				"cpu_percent", entry.CPUPercent,
+				"memory_percent", entry.MemoryPercent,
+				"self_cpu_percent", entry.SelfCPUPercent,
+				"self_memory_percent", entry.SelfMemoryPercent,
+				"decisions", entry.Decisions,
+				"actions", actions)
+			return
+		}
+	}
+}
+
+// fileSink 把事件逐行写入文件或继承的文件描述符（JSON Lines），与给人看的日志分开，下游解析不需要处理日志中的中文拼接字符串
+type fileSink struct {
+	name string
+	file *os.File
+}
+
+// newFileSink 打开 path（追加写入）；fd:N 表示使用启动时继承的文件描述符 N（如 fd:3，由 systemd 或 shell 的 3>events.jsonl 打开）
+func newFileSink(value string) (*fileSink, error) {
+	if fd, ok, err := parseEventsFD(value); ok {
+		if err != nil {
+			return nil, err
+		}
+		return &fileSink{name: value, file: os.NewFile(uintptr(fd), value)}, nil
+	}
+	file, err := os.OpenFile(value, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{name: value, file: file}, nil
+}
+
+// parseEventsFD 解析 fd:N 形式的 EVENTS_FILE（不是该形式时 ok 为 false），并检查文件描述符已经打开
+func parseEventsFD(value string) (fd int, ok bool, err error) {
+	fdValue, ok := strings.CutPrefix(value, "fd:")
+	if !ok {
+		return 0, false, nil
+	}
+	fd, err = strconv.Atoi(fdValue)
+	if err != nil || fd < 0 {
+		return 0, true, fmt.Errorf("文件描述符无效: %s", fdValue)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return 0, true, fmt.Errorf("文件描述符 %d 未打开: %w", fd, err)
+	}
+	return fd, true, nil
+}
+
+func (s *fileSink) Name() string { return "file " + s.name }
+
+// Publish 一次写入一批事件，每个事件一行
+func (s *fileSink) Publish(events [][]byte) error {
+	var buf bytes.Buffer
+	for _, data := range events {
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	_, err := s.file.Write(buf.Bytes())
+	return err
+}
+
+func (s *fileSink) Close() {
+	s.file.Close()
+}
+
+// natsSink NATS 核心协议（PUB），连接断开后在下一次发布时重连
+type natsSink struct {
+	addr     string
+	subject  string
+	connect  []byte // CONNECT 命令（包含认证信息）
+	mu       sync.Mutex
+	conn     net.Conn
+	failedAt time.Time // 上次连接失败的时间
+}
+
+// newNATSSink 解析 nats://[user:pass@|token@]host:port
+func newNATSSink(rawURL, subject string) (*natsSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" || u.Host == "" {
+		return nil, errors.New("应为 nats://host:port")
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	options := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "cpumembusy-" + hostname(),
+		"lang":     "go",
+		"version":  GetBuildInfo().Version,
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			options["user"], options["pass"] = u.User.Username(), pass
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+	return &natsSink{
+		addr:    addr,
+		subject: subject,
+		connect: append(append([]byte("CONNECT "), connect...), "\r\n"...),
+	}, nil
+}
+
+func (ns *natsSink) Name() string { return "nats://" + ns.addr }
+
+func (ns *natsSink) Publish(events [][]byte) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if ns.conn == nil {
+		if time.Since(ns.failedAt) < eventRetryInterval {
+			return errors.New("连接失败，稍后重试")
+		}
+		if err := ns.dial(); err != nil {
+			ns.failedAt = time.Now()
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, data := range events {
+		fmt.Fprintf(&buf, "PUB %s %d\r\n", ns.subject, len(data))
+		buf.Write(data)
+		buf.WriteString("\r\n")
+	}
+	ns.conn.SetWriteDeadline(time.Now().Add(eventTimeout))
+	if _, err := ns.conn.Write(buf.Bytes()); err != nil {
+		ns.conn.Close()
+		ns.conn = nil
+		return err
+	}
+	return nil
+}
+
+// dial 连接服务器并完成握手（INFO → CONNECT → PING/PONG），之后在后台回复服务器的 PING（调用方持有锁）
+func (ns *natsSink) dial() error {
+	conn, err := net.DialTimeout("tcp", ns.addr, eventTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(eventTimeout))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("握手失败: %q %v", strings.TrimSpace(line), err)
+	}
+	if _, err := conn.Write(append(ns.connect, "PING\r\n"...)); err != nil {
+		conn.Close()
+		return err
+	}
+	line, err = r.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "PONG" {
+		conn.Close()
+		return fmt.Errorf("握手失败: %q %v", strings.TrimSpace(line), err)
+	}
+	conn.SetDeadline(time.Time{})
+	ns.conn = conn
+	logger.Info("已连接 NATS", "addr", ns.addr, "subject", ns.subject)
+	go ns.readLoop(conn, r)
+	return nil
+}
+
+// readLoop 回复服务器的 PING，连接出错时关闭（下一次发布时重连）
+func (ns *natsSink) readLoop(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		switch line = strings.TrimSpace(line); {
+		case line == "PING":
+			ns.mu.Lock()
+			conn.SetWriteDeadline(time.Now().Add(eventTimeout))
+			_, err = conn.Write([]byte("PONG\r\n"))
+			ns.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			logger.Warn("NATS 返回错误", "addr", ns.addr, "error", line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	conn.Close()
+	if ns.conn == conn {
+		ns.conn = nil
+	}
+}
+
+func (ns *natsSink) Close() {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.conn != nil {
+		ns.conn.Close()
+		ns.conn = nil
+	}
+}
+
+// kafkaRESTSink 通过 Kafka REST Proxy（v2 API）发布到 Kafka 主题，消息的 key 为主机名
+type kafkaRESTSink struct {
+	url    string
+	client *http.Client
+}
+
+func (ks *kafkaRESTSink) Name() string { return ks.url }
+
+func (ks *kafkaRESTSink) Publish(events [][]byte) error {
+	type record struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	records := make([]record, len(events))
+	for i, data := range events {
+		records[i] = record{Key: hostname(), Value: data}
+	}
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ks.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (ks *kafkaRESTSink) Close() {}
@@ -0,0 +1,168 @@
+package busy
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportTimeout 从 agent 读取历史记录的超时
+const exportTimeout = 30 * time.Second
+
+// ExportOptions export 子命令的参数
+type ExportOptions struct {
+	Since  string // RFC3339 时间，或 24h 这类时长表示最近一段时间
+	Format string // csv 或 json
+	Agent  string // 运行中的 agent 接口地址（空表示不从 agent 读取）
+	File   string // agent 的历史文件（HISTORY_FILE，空表示不读取）
+}
+
+// DefaultExportOptions 按 AGENT_LISTEN 和 HISTORY_FILE 生成默认参数：导出本机 agent 最近 24 小时的记录
+func DefaultExportOptions() ExportOptions {
+	opts := ExportOptions{Since: "24h", Format: "csv", File: lookupEnv("HISTORY_FILE")}
+	if addr := lookupEnv("AGENT_LISTEN"); addr != "" {
+		host, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			if host == "" || net.ParseIP(host).IsUnspecified() {
+				host = "127.0.0.1"
+			}
+			opts.Agent = net.JoinHostPort(host, port)
+			if lookupEnv("AGENT_TLS_CERT") != "" {
+				opts.Agent = "https://" + opts.Agent
+			}
+		}
+	}
+	return opts
+}
+
+// Export 导出历史记录：优先从运行中的 agent 读取（GET /history），agent 无法访问时读取历史文件
+// 返回记录的来源，用于提示
+func Export(w io.Writer, opts ExportOptions) (string, error) {
+	if opts.Format != "csv" && opts.Format != "json" {
+		return "", fmt.Errorf("不支持的格式: %s（可选 csv、json）", opts.Format)
+	}
+	since, err := parseSince(opts.Since)
+	if err != nil {
+		return "", err
+	}
+	if opts.Agent == "" && opts.File == "" {
+		return "", errors.New("没有可以读取的来源：设置 AGENT_LISTEN / --agent 或 HISTORY_FILE / --file")
+	}
+
+	var entries []HistoryEntry
+	var source string
+	var agentErr error
+	if opts.Agent != "" {
+		entries, agentErr = fetchHistory(opts.Agent, opts.Since)
+		source = "agent " + opts.Agent
+	}
+	if opts.Agent == "" || agentErr != nil {
+		if opts.File == "" {
+			return "", agentErr
+		}
+		var fileErr error
+		if entries, fileErr = readHistoryFile(opts.File, since); fileErr != nil {
+			return "", errors.Join(agentErr, fileErr)
+		}
+		source = "file " + opts.File
+		if agentErr != nil {
+			source += fmt.Sprintf("（agent 无法访问: %v）", agentErr)
+		}
+	}
+
+	if opts.Format == "json" {
+		if entries == nil {
+			entries = []HistoryEntry{}
+		}
+		return source, json.NewEncoder(w).Encode(entries)
+	}
+	return source, writeHistoryCSV(w, entries)
+}
+
+// fetchHistory 从 agent 读取 since 之后的记录（使用 AGENT_READ_TOKEN 或 AGENT_ADMIN_TOKEN 认证）
+func fetchHistory(agent, since string) ([]HistoryEntry, error) {
+	client := &http.Client{Timeout: exportTimeout}
+	tlsConfig, err := agentClientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+
+	req, err := http.NewRequest(http.MethodGet, agentURL(agent, "/history?since="+url.QueryEscape(since)), nil)
+	if err != nil {
+		return nil, err
+	}
+	token := lookupEnv("AGENT_READ_TOKEN")
+	if token == "" {
+		token = lookupEnv("AGENT_ADMIN_TOKEN")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("agent 返回 %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var entries []HistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("解析 agent 返回的记录失败: %w", err)
+	}
+	return entries, nil
+}
+
+// writeHistoryCSV 以 CSV 格式输出记录，每个周期一行；decisions 列为按资源名称排序的 名称=决定，以空格分隔
+func writeHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "peak_usage", "expected_usage", "cpu_percent", "memory_percent",
+		"self_cpu_percent", "self_memory_percent", "cpu_count", "buffer_bytes", "kill_switch", "decisions"})
+	percent := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, entry := range entries {
+		cw.Write([]string{
+			entry.Time.Format(time.RFC3339),
+			strconv.Itoa(entry.PeakUsage),
+			percent(entry.ExpectedUsage),
+			percent(entry.CPUPercent),
+			percent(entry.MemoryPercent),
+			percent(entry.SelfCPUPercent),
+			percent(entry.SelfMemoryPercent),
+			strconv.FormatUint(entry.CPUCount, 10),
+			strconv.FormatUint(entry.BufferBytes, 10),
+			strconv.FormatBool(entry.KillSwitch),
+			joinDecisions(entry.Decisions),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// joinDecisions 按资源名称排序，输出 名称=决定，以空格分隔
+func joinDecisions(decisions map[string]string) string {
+	names := make([]string, 0, len(decisions))
+	for name := range decisions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + decisions[name]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,311 @@
+package busy
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprFunc 编译后的表达式：根据变量计算结果
+type exprFunc func(env map[string]float64) float64
+
+// exprBuiltins 表达式中可用的函数（参数个数，-1 表示至少 1 个）
+var exprBuiltins = map[string]struct {
+	args int
+	fn   func(args []float64) float64
+}{
+	"min":   {-1, func(a []float64) float64 { return slices.Min(a) }},
+	"max":   {-1, func(a []float64) float64 { return slices.Max(a) }},
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"clamp": {3, func(a []float64) float64 { return math.Min(math.Max(a[0], a[1]), a[2]) }},
+	"rand":  {0, func(a []float64) float64 { return rand.Float64() }},
+}
+
+// compileExpr 编译表达式，vars 为允许使用的变量名
+// 支持数字、变量、函数调用、四则运算和取余、比较（结果为 1 或 0）、&& || !、三元运算符 a ? b : c
+func compileExpr(src string, vars []string) (exprFunc, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, vars: vars}
+	fn, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("表达式在 %q 处有多余内容", p.tokens[p.pos])
+	}
+	return fn, nil
+}
+
+// tokenizeExpr 把表达式拆分为数字、标识符和运算符
+func tokenizeExpr(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			if i+1 < len(src) && slices.Contains([]string{"<=", ">=", "==", "!=", "&&", "||"}, src[i:i+2]) {
+				tokens = append(tokens, src[i:i+2])
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("+-*/%<>!?:(),", c) {
+				return nil, fmt.Errorf("表达式中有无效字符 %q", c)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser 递归下降解析器
+type exprParser struct {
+	tokens []string
+	pos    int
+	vars   []string
+}
+
+// peek 查看当前 token（没有时返回空字符串）
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// accept 当前 token 为 tok 时消费它并返回 true
+func (p *exprParser) accept(tok string) bool {
+	if p.peek() == tok {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect 消费指定的 token，不匹配时报错
+func (p *exprParser) expect(tok string) error {
+	if !p.accept(tok) {
+		return fmt.Errorf("表达式中缺少 %q", tok)
+	}
+	return nil
+}
+
+// parseExpr 三元运算符：cond ? a : b
+func (p *exprParser) parseExpr() (exprFunc, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	a, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return func(env map[string]float64) float64 {
+		if cond(env) != 0 {
+			return a(env)
+		}
+		return b(env)
+	}, nil
+}
+
+// exprLevels 二元运算符，按优先级从低到高排列
+var exprLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// parseBinary 解析第 level 级及更高优先级的二元运算
+func (p *exprParser) parseBinary(level int) (exprFunc, error) {
+	if level == len(exprLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for slices.Contains(exprLevels[level], p.peek()) {
+		op := p.peek()
+		p.pos++
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryOp(op, left, right)
+	}
+	return left, nil
+}
+
+// binaryOp 组合二元运算
+func binaryOp(op string, a, b exprFunc) exprFunc {
+	boolean := func(v bool) float64 {
+		if v {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case "||":
+		return func(env map[string]float64) float64 { return boolean(a(env) != 0 || b(env) != 0) }
+	case "&&":
+		return func(env map[string]float64) float64 { return boolean(a(env) != 0 && b(env) != 0) }
+	case "==":
+		return func(env map[string]float64) float64 { return boolean(a(env) == b(env)) }
+	case "!=":
+		return func(env map[string]float64) float64 { return boolean(a(env) != b(env)) }
+	case "<":
+		return func(env map[string]float64) float64 { return boolean(a(env) < b(env)) }
+	case "<=":
+		return func(env map[string]float64) float64 { return boolean(a(env) <= b(env)) }
+	case ">":
+		return func(env map[string]float64) float64 { return boolean(a(env) > b(env)) }
+	case ">=":
+		return func(env map[string]float64) float64 { return boolean(a(env) >= b(env)) }
+	case "+":
+		return func(env map[string]float64) float64 { return a(env) + b(env) }
+	case "-":
+		return func(env map[string]float64) float64 { return a(env) - b(env) }
+	case "*":
+		return func(env map[string]float64) float64 { return a(env) * b(env) }
+	case "/":
+		return func(env map[string]float64) float64 { return a(env) / b(env) }
+	default: // "%"
+		return func(env map[string]float64) float64 { return math.Mod(a(env), b(env)) }
+	}
+}
+
+// parseUnary 一元运算符：-x、!x
+func (p *exprParser) parseUnary() (exprFunc, error) {
+	if p.accept("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env map[string]float64) float64 { return -x(env) }, nil
+	}
+	if p.accept("!") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env map[string]float64) float64 {
+			if x(env) == 0 {
+				return 1
+			}
+			return 0
+		}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary 数字、变量、函数调用或括号
+func (p *exprParser) parsePrimary() (exprFunc, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("表达式不完整")
+	case tok == "(":
+		p.pos++
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+		p.pos++
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的数字 %q", tok)
+		}
+		return func(map[string]float64) float64 { return v }, nil
+	case unicode.IsLetter(rune(tok[0])) || tok[0] == '_':
+		p.pos++
+		if p.accept("(") {
+			return p.parseCall(tok)
+		}
+		if !slices.Contains(p.vars, tok) {
+			return nil, fmt.Errorf("未知变量 %q（可用变量: %s）", tok, strings.Join(p.vars, ", "))
+		}
+		return func(env map[string]float64) float64 { return env[tok] }, nil
+	default:
+		return nil, fmt.Errorf("表达式在 %q 处有语法错误", tok)
+	}
+}
+
+// parseCall 函数调用（左括号已消费）
+func (p *exprParser) parseCall(name string) (exprFunc, error) {
+	builtin, ok := exprBuiltins[name]
+	if !ok {
+		return nil, fmt.Errorf("未知函数 %q", name)
+	}
+
+	var args []exprFunc
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if (builtin.args >= 0 && len(args) != builtin.args) || (builtin.args < 0 && len(args) == 0) {
+		return nil, fmt.Errorf("函数 %s 的参数个数错误: %d", name, len(args))
+	}
+
+	return func(env map[string]float64) float64 {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			values[i] = arg(env)
+		}
+		return builtin.fn(values)
+	}, nil
+}
@@ -0,0 +1,123 @@
+package busy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCompileExpr(t *testing.T) {
+	vars := []string{"x", "y"}
+	env := map[string]float64{"x": 4, "y": -2}
+	tests := []struct {
+		src     string
+		want    float64
+		wantErr bool
+	}{
+		// 优先级和结合性
+		{src: "1 + 2 * 3", want: 7},
+		{src: "(1 + 2) * 3", want: 9},
+		{src: "10 - 4 - 3", want: 3},
+		{src: "8 / 4 / 2", want: 1},
+		{src: "7 % 3 * 2", want: 2},
+		{src: "1 + 2 < 4", want: 1},
+		{src: "1 < 2 == 1", want: 1},
+		{src: "0 || 1 && 0", want: 0},
+		{src: "1 || 0 && 0", want: 1},
+		{src: "0 ? 1 : 0 ? 2 : 3", want: 3},
+		{src: "x > 3 ? x * 10 : y", want: 40},
+		// 一元运算符
+		{src: "-2 * 3", want: -6},
+		{src: "- -3", want: 3},
+		{src: "2 - -3", want: 5},
+		{src: "-x + y", want: -6},
+		{src: "!0 + 1", want: 2},
+		{src: "!x", want: 0},
+		// 函数
+		{src: "min(x, y, 3)", want: -2},
+		{src: "max(x)", want: 4},
+		{src: "clamp(x * 100, 0, 70)", want: 70},
+		{src: "pow(2, 10)", want: 1024},
+		{src: "abs(y) + floor(1.7) + ceil(1.2) + round(2.5)", want: 8},
+		// 参数个数错误
+		{src: "abs(1, 2)", wantErr: true},
+		{src: "pow(2)", wantErr: true},
+		{src: "clamp(1, 2)", wantErr: true},
+		{src: "min()", wantErr: true},
+		{src: "rand(1)", wantErr: true},
+		// 语法错误
+		{src: "nope(1)", wantErr: true},
+		{src: "z + 1", wantErr: true},
+		{src: "1 +", wantErr: true},
+		{src: "(1 + 2", wantErr: true},
+		{src: "1 2", wantErr: true},
+		{src: "1 ? 2", wantErr: true},
+		{src: "1.2.3", wantErr: true},
+		{src: "x $ y", wantErr: true},
+		{src: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			fn, err := compileExpr(tt.src, vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("compileExpr(%q) = nil error, want error", tt.src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compileExpr(%q) error: %v", tt.src, err)
+			}
+			if got := fn(env); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileExprNonFinite(t *testing.T) {
+	tests := []struct {
+		src   string
+		check func(float64) bool
+	}{
+		{"1 / 0", func(v float64) bool { return math.IsInf(v, 1) }},
+		{"-1 / 0", func(v float64) bool { return math.IsInf(v, -1) }},
+		{"0 / 0", math.IsNaN},
+		{"5 % 0", math.IsNaN},
+		{"sqrt(-1)", math.IsNaN},
+	}
+	for _, tt := range tests {
+		fn, err := compileExpr(tt.src, nil)
+		if err != nil {
+			t.Fatalf("compileExpr(%q) error: %v", tt.src, err)
+		}
+		if got := fn(nil); !tt.check(got) {
+			t.Errorf("%s = %v", tt.src, got)
+		}
+	}
+}
+
+// TestEvalExpectedUsageClamp TARGET_EXPR 的结果限制在 [MIN_USAGE, 硬峰值] 内，NaN / Inf 时改用内置算法
+func TestEvalExpectedUsageClamp(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	builtin := min(max(calculateExpectedUsageAt(now, 50), minUsage), hardPeakAt(now))
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"peak * 0.5", 25},
+		{"peak * 2", hardPeakAt(now)},
+		{"-peak", minUsage},
+		{"peak / 0", builtin},
+		{"0 / 0", builtin},
+	}
+	for _, tt := range tests {
+		fn, err := newExprExpectedUsage(tt.src)
+		if err != nil {
+			t.Fatalf("newExprExpectedUsage(%q) error: %v", tt.src, err)
+		}
+		if got := evalExpectedUsageAt(fn, now, 50, &SystemStats{}); got != tt.want {
+			t.Errorf("%s: evalExpectedUsageAt() = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
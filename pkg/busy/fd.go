@@ -0,0 +1,124 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FDController 文件描述符控制器：维持一定数量的打开文件描述符和临时 inode
+type FDController struct {
+	mu          sync.Mutex
+	dir         string     // 临时 inode 所在目录
+	files       []*os.File // 持有的文件描述符
+	inodes      []string   // 已创建的临时文件
+	fdTarget    int        // 目标文件描述符数
+	inodeTarget int        // 目标 inode 数
+	seq         int        // 临时文件序号
+}
+
+var fdController = &FDController{}
+
+// Init 初始化临时 inode 目录，清理上次运行遗留的文件
+func (fc *FDController) Init(dir string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	fc.dir = dir
+	return nil
+}
+
+// Enabled 文件描述符控制器是否已启用
+func (fc *FDController) Enabled() bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.dir != ""
+}
+
+// SetTargets 设置目标文件描述符数和 inode 数，并立即调整到目标值
+func (fc *FDController) SetTargets(fdTarget, inodeTarget int) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if fc.dir == "" {
+		return fmt.Errorf("文件描述符控制器未启用")
+	}
+	if fdTarget < 0 || inodeTarget < 0 {
+		return fmt.Errorf("目标值不能为负数")
+	}
+
+	fc.fdTarget = fdTarget
+	fc.inodeTarget = inodeTarget
+
+	if err := fc.adjustFDs(); err != nil {
+		return err
+	}
+	return fc.adjustInodes()
+}
+
+// GetCounts 获取当前持有的文件描述符数和 inode 数
+func (fc *FDController) GetCounts() (int, int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return len(fc.files), len(fc.inodes)
+}
+
+// Stop 关闭所有文件描述符并删除临时目录
+func (fc *FDController) Stop() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	for _, f := range fc.files {
+		f.Close()
+	}
+	fc.files = nil
+	fc.inodes = nil
+	if fc.dir != "" {
+		os.RemoveAll(fc.dir)
+	}
+}
+
+// adjustFDs 打开或关闭 /dev/null，使持有的文件描述符数达到目标值
+func (fc *FDController) adjustFDs() error {
+	for len(fc.files) < fc.fdTarget {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			// 达到 RLIMIT_NOFILE 等限制时停止，保留已打开的部分
+			return fmt.Errorf("打开文件描述符失败（当前 %d 个）: %w", len(fc.files), err)
+		}
+		fc.files = append(fc.files, f)
+	}
+	for len(fc.files) > fc.fdTarget {
+		last := len(fc.files) - 1
+		fc.files[last].Close()
+		fc.files = fc.files[:last]
+	}
+	return nil
+}
+
+// adjustInodes 创建或删除空文件，使临时 inode 数达到目标值
+func (fc *FDController) adjustInodes() error {
+	for len(fc.inodes) < fc.inodeTarget {
+		fc.seq++
+		path := filepath.Join(fc.dir, fmt.Sprintf("inode-%08d", fc.seq))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("创建临时文件失败（当前 %d 个）: %w", len(fc.inodes), err)
+		}
+		f.Close()
+		fc.inodes = append(fc.inodes, path)
+	}
+	for len(fc.inodes) > fc.inodeTarget {
+		last := len(fc.inodes) - 1
+		os.Remove(fc.inodes[last])
+		fc.inodes = fc.inodes[:last]
+	}
+	return nil
+}
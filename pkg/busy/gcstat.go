@@ -0,0 +1,89 @@
+package busy
+
+import (
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+const (
+	defaultGCForceInterval = time.Minute // 默认的强制 GC 间隔
+
+	gcCPUMetric = "/cpu/classes/gc/total:cpu-seconds" // GC 累计消耗的 CPU 时间
+	gcCPUAlpha  = 0.1                                 // GC CPU 占用平均值的平滑系数
+)
+
+// gcCPUTracker 统计 Go 运行时 GC 消耗的 CPU（占整机的百分比）：每个周期的值和指数移动平均
+type gcCPUTracker struct {
+	read        func() (float64, bool) // 累计的 GC CPU 时间（秒），nil 表示从运行时读取（模拟时替换）
+	lastSeconds float64
+	lastTime    time.Time
+	avg         float64
+	sample      []metrics.Sample
+}
+
+var gcTracker = &gcCPUTracker{sample: []metrics.Sample{{Name: gcCPUMetric}}}
+
+// Sample 返回上次调用以来 GC 的 CPU 占用，以及平均值
+func (t *gcCPUTracker) Sample() (float64, float64) {
+	seconds, ok := t.seconds()
+	if !ok {
+		return 0, 0
+	}
+	now := clock.Now()
+	if t.lastTime.IsZero() {
+		t.lastSeconds, t.lastTime = seconds, now
+		return 0, 0
+	}
+
+	elapsed := now.Sub(t.lastTime).Seconds() * float64(numCPU())
+	var current float64
+	if elapsed > 0 {
+		current = min((seconds-t.lastSeconds)/elapsed*100, 100)
+	}
+	t.lastSeconds, t.lastTime = seconds, now
+	t.avg += gcCPUAlpha * (current - t.avg)
+	return current, t.avg
+}
+
+// seconds 读取 GC 累计消耗的 CPU 时间（秒）
+func (t *gcCPUTracker) seconds() (float64, bool) {
+	if t.read != nil {
+		return t.read()
+	}
+	metrics.Read(t.sample)
+	if t.sample[0].Value.Kind() != metrics.KindFloat64 {
+		return 0, false
+	}
+	return t.sample[0].Value.Float64(), true
+}
+
+// gcCompensation 为 true 时（CPU_GC_COMPENSATION），CPU 调整使用扣除 GC 波动后的使用率
+var gcCompensation bool
+
+// gcCompensatedStats 把本周期 GC 的 CPU 占用替换为平均值：
+// 工作协程的预算 = 期望值 - GC 平均占用，强制 GC 带来的短时尖峰不会被控制循环当作偏差去"纠正"
+func gcCompensatedStats(stats *SystemStats) *SystemStats {
+	if !gcCompensation {
+		return stats
+	}
+	excess := stats.GCCPUPercent - stats.GCCPUAvgPercent
+	compensated := *stats
+	compensated.CPUPercent = max(stats.CPUPercent-excess, 0)
+	compensated.SelfCPUPercent = max(stats.SelfCPUPercent-excess, 0)
+	return &compensated
+}
+
+// applyGCPercent 设置了 GC_PERCENT 时调用 debug.SetGCPercent（覆盖 GOGC），-1 表示关闭自动 GC
+func applyGCPercent() {
+	if lookupEnv("GC_PERCENT") == "" {
+		return
+	}
+	percent := getEnvInt("GC_PERCENT", 100)
+	if percent < -1 {
+		logger.Warn("GC_PERCENT 无效，不修改 GC 设置", "value", percent)
+		return
+	}
+	previous := debug.SetGCPercent(percent)
+	logger.Info("已设置 GC 百分比", "gc_percent", percent, "previous", previous)
+}
@@ -0,0 +1,247 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gpuStepPercent     = 1.0              // GPU 每次调整的步长（计算强度百分比 / 显存总量百分比）
+	gpuQueryTimeout    = 5 * time.Second  // 查询 GPU 状态的超时时间
+	gpuHelperRestart   = 10 * time.Second // helper 退出后重启的间隔
+	defaultGPUQueryCmd = "nvidia-smi --query-gpu=utilization.gpu,memory.used,memory.total --format=csv,noheader,nounits"
+)
+
+// GPUStats GPU 资源统计（多张卡取平均值/总和）
+type GPUStats struct {
+	UtilPercent   float64 // GPU 使用率百分比
+	MemoryPercent float64 // 显存使用率百分比
+	MemoryTotalMB uint64  // 显存总量（MB）
+}
+
+// GPUController GPU 控制器：通过外部 helper 程序产生 GPU 负载
+// helper 从标准输入逐行读取 "<计算强度百分比> <显存占用MB>"，并保持对应的 GPU 占用
+type GPUController struct {
+	mu          sync.Mutex
+	helper      string  // helper 命令
+	queryCmd    string  // 查询 GPU 状态的命令
+	intensity   float64 // 当前计算强度（0-100）
+	memoryMB    uint64  // 当前显存占用（MB）
+	memoryTotal uint64  // 显存总量（MB）
+	stdin       io.WriteCloser
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+var gpuController = &GPUController{}
+
+// Start 启动 helper 进程
+func (gc *GPUController) Start(helper, queryCmd string) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.ctx != nil {
+		// 已经启动
+		return nil
+	}
+
+	gc.helper = helper
+	gc.queryCmd = queryCmd
+	gc.ctx, gc.cancel = context.WithCancel(context.Background())
+
+	stats, err := queryGPUStats(queryCmd)
+	if err != nil {
+		gc.cancel()
+		gc.ctx = nil
+		return fmt.Errorf("查询 GPU 状态失败: %w", err)
+	}
+	gc.memoryTotal = stats.MemoryTotalMB
+
+	go gc.superviseHelper()
+	return nil
+}
+
+// Stop 停止 helper 进程
+func (gc *GPUController) Stop() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.cancel != nil {
+		gc.cancel()
+		gc.ctx = nil
+		gc.cancel = nil
+	}
+}
+
+// Enabled GPU 控制器是否已启动
+func (gc *GPUController) Enabled() bool {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.ctx != nil
+}
+
+// Stats 查询当前 GPU 状态
+func (gc *GPUController) Stats() (*GPUStats, error) {
+	gc.mu.Lock()
+	queryCmd := gc.queryCmd
+	gc.mu.Unlock()
+	return queryGPUStats(queryCmd)
+}
+
+// AdjustIntensityRandom 根据随机方向调整 GPU 计算强度
+// 返回：是否成功调整，调整的方向（true=增加，false=减少），新的计算强度
+func (gc *GPUController) AdjustIntensityRandom(shouldIncrease bool) (bool, bool, uint64) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if shouldIncrease {
+		gc.intensity = min(gc.intensity+gpuStepPercent, 100)
+	} else {
+		gc.intensity = max(gc.intensity-gpuStepPercent, 0)
+	}
+	gc.sendTarget()
+	return true, shouldIncrease, uint64(gc.intensity)
+}
+
+// AdjustMemoryRandom 根据随机方向调整显存占用
+// 返回：是否成功调整，调整的方向（true=增加，false=减少），新的显存占用（MB）
+func (gc *GPUController) AdjustMemoryRandom(shouldIncrease bool) (bool, bool, uint64) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	step := uint64(float64(gc.memoryTotal) * gpuStepPercent / 100)
+	if shouldIncrease {
+		gc.memoryMB = min(gc.memoryMB+step, gc.memoryTotal)
+	} else if gc.memoryMB > step {
+		gc.memoryMB -= step
+	} else {
+		gc.memoryMB = 0
+	}
+	gc.sendTarget()
+	return true, shouldIncrease, gc.memoryMB
+}
+
+// Idle 把计算强度和显存占用都降为 0
+func (gc *GPUController) Idle() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.intensity, gc.memoryMB = 0, 0
+	gc.sendTarget()
+}
+
+// GetTargets 获取当前计算强度和显存占用（MB）
+func (gc *GPUController) GetTargets() (float64, uint64) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.intensity, gc.memoryMB
+}
+
+// sendTarget 把当前目标发送给 helper（调用方需持有锁）
+func (gc *GPUController) sendTarget() {
+	if gc.stdin == nil {
+		return
+	}
+	if _, err := fmt.Fprintf(gc.stdin, "%.0f %d\n", gc.intensity, gc.memoryMB); err != nil {
+		logger.Warn("发送 GPU 目标失败", "error", err)
+	}
+}
+
+// superviseHelper 运行 helper 进程，退出后自动重启
+func (gc *GPUController) superviseHelper() {
+	for {
+		gc.mu.Lock()
+		ctx := gc.ctx
+		gc.mu.Unlock()
+		if ctx == nil {
+			return
+		}
+
+		if err := gc.runHelper(ctx); err != nil {
+			logger.Warn("GPU helper 退出", "helper", gc.helper, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(gpuHelperRestart):
+		}
+	}
+}
+
+// runHelper 启动一次 helper 进程并等待其退出
+func (gc *GPUController) runHelper(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", gc.helper)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	gc.mu.Lock()
+	gc.stdin = stdin
+	gc.sendTarget()
+	gc.mu.Unlock()
+
+	err = cmd.Wait()
+
+	gc.mu.Lock()
+	gc.stdin = nil
+	gc.mu.Unlock()
+	return err
+}
+
+// queryGPUStats 执行查询命令获取 GPU 状态
+// 输出格式与 nvidia-smi --query-gpu=utilization.gpu,memory.used,memory.total --format=csv,noheader,nounits 相同，每张卡一行
+func queryGPUStats(queryCmd string) (*GPUStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gpuQueryTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "sh", "-c", queryCmd).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		utilSum   float64
+		usedMB    uint64
+		totalMB   uint64
+		gpuNumber int
+	)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 3 {
+			continue
+		}
+		util, err1 := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+		used, err2 := strconv.ParseUint(strings.TrimSpace(fields[1]), 10, 64)
+		total, err3 := strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		utilSum += util
+		usedMB += used
+		totalMB += total
+		gpuNumber++
+	}
+
+	if gpuNumber == 0 || totalMB == 0 {
+		return nil, fmt.Errorf("无法解析 GPU 状态: %q", strings.TrimSpace(string(out)))
+	}
+
+	return &GPUStats{
+		UtilPercent:   utilSum / float64(gpuNumber),
+		MemoryPercent: float64(usedMB) / float64(totalMB) * 100,
+		MemoryTotalMB: totalMB,
+	}, nil
+}
@@ -0,0 +1,251 @@
+package busy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGridInterval  = 15 * time.Minute // 默认每隔多久读取一次电网信号
+	defaultGridMaxAge    = time.Hour        // 默认超过多久没有读取成功时不再降低期望值
+	defaultGridMinFactor = 0.5              // 默认信号达到上限时的期望值系数
+	gridSignalTimeout    = 10 * time.Second // 单次请求的超时
+)
+
+// GridSignalConfig 电网信号的来源和换算方式
+type GridSignalConfig struct {
+	URL         string        // 返回 JSON（或纯数字）的 HTTP 接口
+	Field       string        // JSON 中数值的路径，以 "." 分隔，数组使用下标（如 data.0.intensity.forecast），空表示整个响应是数字
+	Token       string        // 认证令牌（可以为空）
+	TokenHeader string        // 令牌所在的请求头：Authorization 时为 "Bearer <token>"，其他请求头（如 auth-token）直接使用令牌
+	Low, High   float64       // 信号不超过 Low 时不降低，达到 High 时降到 MinFactor，中间线性插值
+	MinFactor   float64       // 信号达到 High 时的期望值系数（0-1）
+	Interval    time.Duration // 读取间隔
+	MaxAge      time.Duration // 超过该时长没有读取成功时系数恢复为 1（接口故障时不持续压低负载）
+}
+
+// GridSignalStatus 最近一次读取的电网信号（GET /status 的 grid_signal 字段）
+type GridSignalStatus struct {
+	Value     float64   `json:"value"`
+	Factor    float64   `json:"factor"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"` // 最近一次读取成功的时间（零值表示还没有成功过）
+}
+
+// GridSignal 碳强度 / 电价感知：定期从配置的接口读取电网碳强度或现货电价，在碳排放高或电价贵的时段按比例降低期望值，
+// 用于可持续性报告的测试环境
+type GridSignal struct {
+	mu        sync.Mutex
+	cfg       GridSignalConfig
+	client    *http.Client
+	value     float64
+	factor    float64
+	updatedAt time.Time
+	stale     bool // 是否已经因为超过 MaxAge 恢复为 1（只记录一次日志）
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+var gridSignal = &GridSignal{factor: 1}
+
+// Start 开始定期读取电网信号（立即读取一次）
+func (gs *GridSignal) Start(cfg GridSignalConfig) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.cancel != nil || cfg.URL == "" {
+		return
+	}
+	if cfg.High <= cfg.Low {
+		logger.Warn("GRID_SIGNAL_HIGH 应大于 GRID_SIGNAL_LOW，电网信号不会降低期望值", "low", cfg.Low, "high", cfg.High)
+	}
+	cfg.MinFactor = min(max(cfg.MinFactor, 0), 1)
+	cfg.Interval = max(cfg.Interval, time.Minute)
+	gs.cfg = cfg
+	gs.client = &http.Client{Timeout: gridSignalTimeout}
+	gs.factor, gs.updatedAt, gs.stale = 1, time.Time{}, false
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gs.cancel = cancel
+	gs.wg.Add(1)
+	go gs.run(ctx)
+	logger.Info("已启用电网信号", "url", cfg.URL, "field", cfg.Field, "low", cfg.Low, "high", cfg.High,
+		"min_factor", cfg.MinFactor, "interval", cfg.Interval)
+}
+
+// Stop 停止读取，系数恢复为 1
+func (gs *GridSignal) Stop() {
+	gs.mu.Lock()
+	cancel := gs.cancel
+	gs.cancel = nil
+	gs.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		gs.wg.Wait()
+	}
+	gs.mu.Lock()
+	gs.factor = 1
+	gs.mu.Unlock()
+}
+
+// run 按间隔读取信号
+func (gs *GridSignal) run(ctx context.Context) {
+	defer gs.wg.Done()
+	ticker := time.NewTicker(gs.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		gs.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh 读取一次信号并更新系数，失败时保留上次的系数（直到超过 MaxAge）
+func (gs *GridSignal) refresh(ctx context.Context) {
+	value, err := fetchGridSignal(ctx, gs.client, gs.cfg)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("读取电网信号失败，保留上次的系数", "url", gs.cfg.URL, "error", err)
+		}
+		return
+	}
+
+	gs.mu.Lock()
+	previous := gs.factor
+	gs.value, gs.factor, gs.updatedAt, gs.stale = value, gridFactor(value, gs.cfg), clock.Now(), false
+	factor := gs.factor
+	gs.mu.Unlock()
+
+	logger.Info("电网信号更新", "value", value, "factor", factor)
+	if factor != previous {
+		emitEvent("grid_signal", "value", value, "factor", factor, "previous_factor", previous)
+	}
+}
+
+// gridFactor 按信号值计算期望值系数：不超过 Low 时为 1，达到 High 时为 MinFactor，中间线性插值
+func gridFactor(value float64, cfg GridSignalConfig) float64 {
+	if cfg.High <= cfg.Low {
+		return 1
+	}
+	ratio := min(max((value-cfg.Low)/(cfg.High-cfg.Low), 0), 1)
+	return 1 - (1-cfg.MinFactor)*ratio
+}
+
+// Scale 按当前系数缩放期望值；没有启用或超过 MaxAge 没有读取成功时不缩放
+func (gs *GridSignal) Scale(expectedUsage float64) float64 {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.cancel == nil || gs.factor == 1 {
+		return expectedUsage
+	}
+	if gs.cfg.MaxAge > 0 && clock.Now().Sub(gs.updatedAt) > gs.cfg.MaxAge {
+		if !gs.stale {
+			gs.stale = true
+			logger.Warn("电网信号长时间没有更新，不再降低期望值", "updated_at", gs.updatedAt, "max_age", gs.cfg.MaxAge)
+		}
+		return expectedUsage
+	}
+	return expectedUsage * gs.factor
+}
+
+// Status 最近一次读取的信号，没有启用时返回 nil
+func (gs *GridSignal) Status() *GridSignalStatus {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.cancel == nil {
+		return nil
+	}
+	factor := gs.factor
+	if gs.stale {
+		factor = 1
+	}
+	return &GridSignalStatus{Value: gs.value, Factor: factor, UpdatedAt: gs.updatedAt}
+}
+
+// fetchGridSignal 请求接口并按 Field 读取数值
+func fetchGridSignal(ctx context.Context, client *http.Client, cfg GridSignalConfig) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, gridSignalTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if cfg.Token != "" {
+		header, token := cfg.TokenHeader, cfg.Token
+		if header == "" {
+			header = "Authorization"
+		}
+		if strings.EqualFold(header, "Authorization") {
+			token = "Bearer " + token
+		}
+		req.Header.Set(header, token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("接口返回 %s: %s", resp.Status, strings.TrimSpace(string(body[:min(len(body), 200)])))
+	}
+	return parseGridSignal(body, cfg.Field)
+}
+
+// parseGridSignal 从响应中读取数值：field 为空时整个响应是数字，否则按 "." 分隔的路径逐层读取（数组使用下标）
+func parseGridSignal(body []byte, field string) (float64, error) {
+	if field == "" {
+		return strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return 0, fmt.Errorf("解析 JSON 失败: %w", err)
+	}
+	for _, key := range strings.Split(field, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return 0, fmt.Errorf("字段 %s 不存在", field)
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return 0, fmt.Errorf("字段 %s 的下标 %s 无效", field, key)
+			}
+			value = v[i]
+		default:
+			return 0, fmt.Errorf("字段 %s 不存在", field)
+		}
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		// 部分接口以字符串返回数值
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("字段 %s 不是数值: %q", field, v)
+		}
+		return f, nil
+	case nil:
+		return 0, fmt.Errorf("字段 %s 为空", field)
+	default:
+		return 0, fmt.Errorf("字段 %s 不是数值", field)
+	}
+}
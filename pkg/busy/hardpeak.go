@@ -0,0 +1,50 @@
+package busy
+
+import "time"
+
+var (
+	nightHardPeak float64 = hardPeakLimit // 凌晨时段的硬峰值（%）
+	dayHardPeak   float64 = hardPeakLimit // 其他时段的硬峰值（%）
+)
+
+// envHardPeaks 从环境变量读取凌晨时段和其他时段的硬峰值（默认都为 70%）
+func envHardPeaks() (float64, float64) {
+	return getEnvFloat("NIGHT_HARD_PEAK", hardPeakLimit), getEnvFloat("DAY_HARD_PEAK", hardPeakLimit)
+}
+
+// loadHardPeaks 读取硬峰值，超出 [1, 100] 的值使用默认值，高于默认值时警告（安全上限被提高）
+func loadHardPeaks() {
+	nightHardPeak, dayHardPeak = envHardPeaks()
+	if nightHardPeak < 1 || nightHardPeak > 100 {
+		logger.Warn("NIGHT_HARD_PEAK 超出范围，使用默认值", "value", nightHardPeak, "default", hardPeakLimit)
+		nightHardPeak = hardPeakLimit
+	}
+	if dayHardPeak < 1 || dayHardPeak > 100 {
+		logger.Warn("DAY_HARD_PEAK 超出范围，使用默认值", "value", dayHardPeak, "default", hardPeakLimit)
+		dayHardPeak = hardPeakLimit
+	}
+	if nightHardPeak > hardPeakLimit {
+		logger.Warn("NIGHT_HARD_PEAK 高于默认值，凌晨时段的安全上限被提高", "value", nightHardPeak, "default", hardPeakLimit)
+	}
+	if dayHardPeak > hardPeakLimit {
+		logger.Warn("DAY_HARD_PEAK 高于默认值，其他时段的安全上限被提高", "value", dayHardPeak, "default", hardPeakLimit)
+	}
+}
+
+// hardPeakAt 返回指定时间的硬峰值
+func hardPeakAt(t time.Time) float64 {
+	if isNightTimeAt(t) {
+		return nightHardPeak
+	}
+	return dayHardPeak
+}
+
+// hardPeak 返回当前的硬峰值
+func hardPeak() float64 {
+	return hardPeakAt(clock.Now())
+}
+
+// maxHardPeak 两个时段中较高的硬峰值，用于 cgroup、rlimit 等不随时间变化的上限
+func maxHardPeak() float64 {
+	return max(nightHardPeak, dayHardPeak)
+}
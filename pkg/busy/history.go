@@ -0,0 +1,203 @@
+package busy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHistoryDuration 默认保留的历史时长
+const defaultHistoryDuration = 3 * time.Hour
+
+// HistoryEntry 一个监控周期的采样值和各资源的调整决定（GET /history 返回）
+type HistoryEntry struct {
+	Time              time.Time         `json:"time"`
+	PeakUsage         int               `json:"peak_usage"`
+	ExpectedUsage     float64           `json:"expected_usage"`
+	CPUPercent        float64           `json:"cpu_percent"`
+	MemoryPercent     float64           `json:"memory_percent"`
+	SelfCPUPercent    float64           `json:"self_cpu_percent"`
+	SelfMemoryPercent float64           `json:"self_memory_percent"`
+	CPUCount          uint64            `json:"cpu_count"`
+	BufferBytes       uint64            `json:"buffer_bytes"`
+	KillSwitch        bool              `json:"kill_switch,omitempty"` // 停止文件存在，本周期未调整
+	Decisions         map[string]string `json:"decisions,omitempty"`   // 资源名称 → 决定（与调整日志的后缀相同，如 "0.6-增加"、"强制-减少"、"跳过"）
+}
+
+// History 最近一段时间的采样值和决定（环形缓冲区）
+type History struct {
+	mu      sync.RWMutex
+	entries []HistoryEntry
+	next    int  // 下一个写入位置
+	full    bool // 缓冲区是否已经写满一轮
+}
+
+var history = &History{}
+
+// DecisionAction 一类资源本周期的调整决定（机器可读的形式，adjustment 事件的 actions 字段）
+type DecisionAction struct {
+	Resource    string  `json:"resource"`              // 资源的英文名称（见 resourceKey）
+	Action      string  `json:"action"`                // increase、decrease、skip 或 jump（计算次数模型直接跳转）
+	Basis       string  `json:"basis,omitempty"`       // probability、forced、closed_loop、model 或 strategy（CONTROL_STRATEGY 的其他策略）
+	Probability float64 `json:"probability,omitempty"` // basis 为 probability 时的增加概率
+	Label       string  `json:"label"`                 // 与调整日志的后缀相同（如 "0.6-增加"）
+}
+
+var (
+	pendingDecisions map[string]string // 本周期各资源的调整决定（只在主循环中读写）
+	pendingActions   []DecisionAction  // 与 pendingDecisions 相同的决定（机器可读）
+)
+
+// decisionActions 调整日志中的动作 → 机器可读的动作
+var decisionActions = map[string]string{"增加": "increase", "减少": "decrease", "跳过": "skip", "跳转": "jump"}
+
+// decisionBases 调整日志中的决策依据 → 机器可读的依据（概率和其他策略的标签另外处理）
+var decisionBases = map[string]string{"强制": "forced", "闭环": "closed_loop", "模型": "model"}
+
+// recordDecision 记录本周期一类资源的调整决定：basis 为决策依据（如增加概率、"强制"，跳过时为空），action 为动作（增加、减少、跳过、跳转）
+func recordDecision(name, basis, action string) {
+	if pendingDecisions == nil {
+		pendingDecisions = make(map[string]string)
+	}
+	label := action
+	if basis != "" {
+		label = basis + "-" + action
+	}
+	pendingDecisions[name] = label
+
+	da := DecisionAction{Resource: resourceKey(name), Action: decisionActions[action], Label: label}
+	if p, err := strconv.ParseFloat(basis, 64); err == nil {
+		da.Basis, da.Probability = "probability", p
+	} else if key, ok := decisionBases[basis]; ok {
+		da.Basis = key
+	} else if basis != "" {
+		da.Basis = "strategy"
+	}
+	pendingActions = append(pendingActions, da)
+}
+
+// takeDecisions 取出并清空本周期的调整决定
+func takeDecisions() (map[string]string, []DecisionAction) {
+	decisions, actions := pendingDecisions, pendingActions
+	pendingDecisions, pendingActions = nil, nil
+	return decisions, actions
+}
+
+// resourceNames 日志中的资源名称 → 事件中的英文名称
+var resourceNames = map[string]string{"CPU": "cpu", "内存": "memory", "磁盘": "disk", "GPU": "gpu", "显存": "gpu_memory", "负载": "load"}
+
+// resourceKey 资源的英文名称：cpu、memory、disk、gpu、gpu_memory、load，按核心调整时为 cpu0、cpu1 等
+func resourceKey(name string) string {
+	if key, ok := resourceNames[name]; ok {
+		return key
+	}
+	if core, ok := strings.CutPrefix(name, "CPU"); ok {
+		return "cpu" + core
+	}
+	return name
+}
+
+// SetDuration 按保留时长和监控周期设置缓冲区大小（清空已有记录，0 表示不记录）
+func (h *History) SetDuration(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = make([]HistoryEntry, max(int(d/monitorInterval), 0))
+	h.next, h.full = 0, false
+}
+
+// Capacity 缓冲区最多保留的记录数
+func (h *History) Capacity() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.entries)
+}
+
+// Duration 缓冲区保留的时长
+func (h *History) Duration() time.Duration {
+	return time.Duration(h.Capacity()) * monitorInterval
+}
+
+// Add 追加一条记录，缓冲区满时覆盖最旧的记录
+func (h *History) Add(entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.entries) == 0 {
+		return
+	}
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Since 按时间顺序返回 since 之后的记录，最多 limit 条（取最新的，limit <= 0 表示不限制）
+func (h *History) Since(since time.Time, limit int) []HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var ordered []HistoryEntry
+	if h.full {
+		ordered = append(ordered, h.entries[h.next:]...)
+	}
+	ordered = append(ordered, h.entries[:h.next]...)
+
+	start := 0
+	for start < len(ordered) && !ordered[start].Time.After(since) {
+		start++
+	}
+	ordered = ordered[start:]
+	if limit > 0 && len(ordered) > limit {
+		ordered = ordered[len(ordered)-limit:]
+	}
+	return ordered
+}
+
+// recordSample 保存一个监控周期的记录并推送给 /stream 的订阅者
+func recordSample(entry HistoryEntry) {
+	history.Add(entry)
+	historyFile.Append(entry)
+	sampleStream.Publish(entry)
+}
+
+// parseSince 解析 since 参数：RFC3339 时间，或 10m 这类时长表示最近一段时间（空字符串为零值）
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return clock.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since: %s", value)
+}
+
+// handleHistory 返回最近的采样值和决定
+// 参数：since（RFC3339 时间，或 10m 这类时长表示最近一段时间）、limit（最多返回的条数）
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	since, err := parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	writeJSON(w, history.Since(since, limit))
+}
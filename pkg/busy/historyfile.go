@@ -0,0 +1,129 @@
+package busy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// HistoryFile 把每个监控周期的记录追加到文件（每行一个 JSON），进程重启后恢复 GET /history 的内容，
+// 进程没有运行时 export 子命令也可以直接读取；追加的行数达到内存缓冲区的大小时按缓冲区重写文件，文件大小不超过约两倍的 HISTORY_DURATION
+type HistoryFile struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	appended int // 上次重写之后追加的行数
+}
+
+var historyFile = &HistoryFile{}
+
+// Open 从文件恢复 HISTORY_DURATION 内的记录并开始追加（需在 history.SetDuration 之后调用）
+func (hf *HistoryFile) Open(path string) error {
+	hf.mu.Lock()
+	defer hf.mu.Unlock()
+
+	entries, err := readHistoryFile(path, clock.Now().Add(-history.Duration()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, entry := range entries {
+		history.Add(entry)
+	}
+	hf.path = path
+	if err := hf.rewrite(); err != nil {
+		hf.path = ""
+		return err
+	}
+	logger.Info("已从历史文件恢复记录", "path", path, "entries", len(history.Since(time.Time{}, 0)))
+	return nil
+}
+
+// Append 追加一条记录，写入失败时停止写入（只记录一次日志）
+func (hf *HistoryFile) Append(entry HistoryEntry) {
+	hf.mu.Lock()
+	defer hf.mu.Unlock()
+	if hf.file == nil {
+		return
+	}
+
+	var err error
+	if hf.appended >= history.Capacity() {
+		err = hf.rewrite()
+	} else {
+		var data []byte
+		if data, err = json.Marshal(entry); err == nil {
+			_, err = hf.file.Write(append(data, '\n'))
+			hf.appended++
+		}
+	}
+	if err != nil {
+		logger.Warn("写入历史文件失败，停止写入", "path", hf.path, "error", err)
+		hf.close()
+	}
+}
+
+// Close 关闭文件
+func (hf *HistoryFile) Close() {
+	hf.mu.Lock()
+	defer hf.mu.Unlock()
+	hf.close()
+}
+
+func (hf *HistoryFile) close() {
+	if hf.file != nil {
+		hf.file.Close()
+		hf.file = nil
+	}
+}
+
+// rewrite 按内存缓冲区重写文件（先写临时文件再重命名），然后重新打开文件继续追加（调用方需持有锁）
+func (hf *HistoryFile) rewrite() error {
+	hf.close()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range history.Since(time.Time{}, 0) {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	tmp := hf.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, hf.path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(hf.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	hf.file, hf.appended = file, 0
+	return nil
+}
+
+// readHistoryFile 读取历史文件中 since 之后的记录（跳过无法解析的行，如中途退出时写了一半的最后一行）
+func readHistoryFile(path string, since time.Time) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if entry.Time.After(since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,288 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cloudMetadataTimeout 查询云厂商元数据服务的总超时（不在云上时连接通常会一直等到超时，启动最多延迟这么久）
+const cloudMetadataTimeout = time.Second
+
+// hostLabel 用户指定的主机标签（HOST_LABELS）
+type hostLabel struct {
+	key, value string
+}
+
+// HostMeta 附加到每条日志、每个指标和每个事件上的主机信息，聚合整个集群的数据时不需要在外部重新打标签
+type HostMeta struct {
+	mu         sync.RWMutex
+	instanceID string
+	labels     []hostLabel
+}
+
+var hostMeta = &HostMeta{}
+
+// labelKeyPattern Prometheus 标签名的格式
+var labelKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels 内置的标签，HOST_LABELS 不能覆盖
+var reservedLabels = map[string]bool{"hostname": true, "instance_id": true, "resource": true, "window": true, "backend": true}
+
+// parseHostLabels 解析 "key=value,key=value"（如 cluster=staging,region=eu），标签名需要符合 Prometheus 的格式
+func parseHostLabels(value string) ([]hostLabel, error) {
+	var labels []hostLabel
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || !labelKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("标签格式错误: %q（应为 key=value，key 只能包含字母、数字和下划线）", part)
+		}
+		if reservedLabels[key] || seen[key] {
+			return nil, fmt.Errorf("标签 %s 重复或与内置的标签冲突", key)
+		}
+		seen[key] = true
+		labels = append(labels, hostLabel{key: key, value: val})
+	}
+	return labels, nil
+}
+
+// Configure 设置实例 ID 和标签
+func (hm *HostMeta) Configure(instanceID string, labels []hostLabel) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	hm.instanceID, hm.labels = instanceID, labels
+}
+
+// LogAttrs 附加到日志上的字段：hostname、instance_id（没有时省略）和各标签
+func (hm *HostMeta) LogAttrs() []any {
+	hm.mu.RLock()
+	defer hm.mu.RUnlock()
+	attrs := []any{"hostname", hostname()}
+	if hm.instanceID != "" {
+		attrs = append(attrs, "instance_id", hm.instanceID)
+	}
+	for _, label := range hm.labels {
+		attrs = append(attrs, label.key, label.value)
+	}
+	return attrs
+}
+
+// MetricLabels Prometheus 格式的标签（不含花括号），如 hostname="node-a",instance_id="i-0abc",cluster="staging"
+func (hm *HostMeta) MetricLabels() string {
+	attrs := hm.LogAttrs()
+	parts := make([]string, 0, len(attrs)/2)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", attrs[i], attrs[i+1]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// EventFields 附加到事件上的字段：instance_id 和 labels（hostname 已经包含在每个事件中）
+func (hm *HostMeta) EventFields() map[string]any {
+	hm.mu.RLock()
+	defer hm.mu.RUnlock()
+	fields := make(map[string]any)
+	if hm.instanceID != "" {
+		fields["instance_id"] = hm.instanceID
+	}
+	if len(hm.labels) > 0 {
+		labels := make(map[string]string, len(hm.labels))
+		for _, label := range hm.labels {
+			labels[label.key] = label.value
+		}
+		fields["labels"] = labels
+	}
+	return fields
+}
+
+// withMetricLabels 把主机标签加到指标的标签前面：labels 为已有的标签（不含花括号，可以为空）
+func withMetricLabels(labels string) string {
+	host := hostMeta.MetricLabels()
+	if labels == "" {
+		return "{" + host + "}"
+	}
+	return "{" + host + "," + labels + "}"
+}
+
+// enrichLogger 在 logger 上附加主机信息，返回恢复原来日志的函数（期间 logger 被替换过时不恢复）
+func enrichLogger() func() {
+	saved := logger
+	enriched := logger.With(hostMeta.LogAttrs()...)
+	logger = enriched
+	return func() {
+		if logger == enriched {
+			logger = saved
+		}
+	}
+}
+
+// cloudMetadataSource 一个云厂商的元数据（实例 ID、机型）查询方式
+type cloudMetadataSource struct {
+	name     string
+	url      string
+	headers  map[string]string
+	token    func(ctx context.Context, client *http.Client) (string, string, error) // 返回需要额外设置的请求头（AWS IMDSv2）
+	basename bool                                                                   // 只取响应中最后一个 "/" 之后的部分（GCP 返回完整的资源路径）
+}
+
+// cloudMetadataSources AWS（IMDSv2）、GCP、Azure 的实例 ID
+var cloudMetadataSources = []cloudMetadataSource{
+	{
+		name:  "aws",
+		url:   "http://169.254.169.254/latest/meta-data/instance-id",
+		token: awsMetadataToken,
+	},
+	{
+		name:    "gcp",
+		url:     "http://metadata.google.internal/computeMetadata/v1/instance/id",
+		headers: map[string]string{"Metadata-Flavor": "Google"},
+	},
+	{
+		name:    "azure",
+		url:     "http://169.254.169.254/metadata/instance/compute/vmId?api-version=2021-02-01&format=text",
+		headers: map[string]string{"Metadata": "true"},
+	},
+}
+
+// instanceTypeSources AWS（IMDSv2）、GCP、阿里云的机型
+var instanceTypeSources = []cloudMetadataSource{
+	{
+		name:  "aws",
+		url:   "http://169.254.169.254/latest/meta-data/instance-type",
+		token: awsMetadataToken,
+	},
+	{
+		name:     "gcp",
+		url:      "http://metadata.google.internal/computeMetadata/v1/instance/machine-type",
+		headers:  map[string]string{"Metadata-Flavor": "Google"},
+		basename: true,
+	},
+	{
+		name: "aliyun",
+		url:  "http://100.100.100.200/latest/meta-data/instance/instance-type",
+	},
+}
+
+// awsMetadataToken 获取 IMDSv2 的会话令牌
+func awsMetadataToken(ctx context.Context, client *http.Client) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "http://169.254.169.254/latest/api/token", nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := readMetadata(client, req)
+	return "X-aws-ec2-metadata-token", token, err
+}
+
+// fetchInstanceID 同时查询各云厂商的元数据服务，返回第一个成功的实例 ID 和厂商（都失败时返回空字符串）
+func fetchInstanceID() (string, string) {
+	return fetchCloudMetadata(cloudMetadataSources)
+}
+
+// fetchInstanceType 同时查询各云厂商的元数据服务，返回第一个成功的机型和厂商（都失败时返回空字符串）
+func fetchInstanceType() (string, string) {
+	return fetchCloudMetadata(instanceTypeSources)
+}
+
+// fetchCloudMetadata 同时查询各来源，返回第一个成功的值和厂商
+func fetchCloudMetadata(sources []cloudMetadataSource) (string, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cloudMetadataTimeout)
+	defer cancel()
+	// 元数据服务只能直接访问，不使用 HTTP_PROXY
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+
+	type result struct{ id, provider string }
+	results := make(chan result, len(sources))
+	for _, source := range sources {
+		go func() {
+			id, err := source.fetch(ctx, client)
+			if err != nil {
+				id = ""
+			}
+			results <- result{id, source.name}
+		}()
+	}
+	for range sources {
+		if r := <-results; r.id != "" {
+			return r.id, r.provider
+		}
+	}
+	return "", ""
+}
+
+// fetch 读取元数据的值
+func (s cloudMetadataSource) fetch(ctx context.Context, client *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	if s.token != nil {
+		key, token, err := s.token(ctx, client)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(key, token)
+	}
+	value, err := readMetadata(client, req)
+	if err != nil {
+		return "", err
+	}
+	if s.basename {
+		value = value[strings.LastIndex(value, "/")+1:]
+	}
+	if !metadataValuePattern.MatchString(value) {
+		return "", fmt.Errorf("%s 返回的值格式不正确", req.URL.Host)
+	}
+	return value, nil
+}
+
+// metadataValuePattern 实例 ID、机型的格式（排除代理或错误页面返回的 HTML 等内容）
+var metadataValuePattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// readMetadata 发送请求并读取简短的文本响应
+func readMetadata(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s 返回 %s", req.URL.Host, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// loadHostMeta 按 INSTANCE_ID / CLOUD_METADATA / HOST_LABELS 设置主机信息
+func loadHostMeta() {
+	labels, err := parseHostLabels(lookupEnv("HOST_LABELS"))
+	if err != nil {
+		logger.Warn("HOST_LABELS 无效，忽略", "error", err)
+		labels = nil
+	}
+	instanceID := lookupEnv("INSTANCE_ID")
+	if instanceID == "" && getEnvBool("CLOUD_METADATA", true) {
+		var provider string
+		if instanceID, provider = fetchInstanceID(); instanceID != "" {
+			logger.Info("已从云厂商元数据获取实例 ID", "provider", provider, "instance_id", instanceID)
+		}
+	}
+	hostMeta.Configure(instanceID, labels)
+}
@@ -0,0 +1,158 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// cpuOnlinePath 在线 CPU 核心的列表（如 "0-3,6"）
+const cpuOnlinePath = "/sys/devices/system/cpu/online"
+
+// CPUTopology 跟踪在线的 CPU 核心数：runtime.NumCPU 只在启动时读取一次，虚拟机热插拔 vCPU 后不会变化，
+// 以核心数为基准的计算（工作协程数、loadavg 换算、闭环容量、cgroup 配额等）都通过 numCPU() 读取当前值
+type CPUTopology struct {
+	count   atomic.Int64 // 当前的核心数（0 表示未跟踪，使用 runtime.NumCPU）
+	enabled bool
+}
+
+var cpuTopology = &CPUTopology{}
+
+// numCPU 当前可用的 CPU 核心数（至少为 1）
+func numCPU() int {
+	if n := cpuTopology.count.Load(); n > 0 {
+		return int(n)
+	}
+	return max(runtime.NumCPU(), 1)
+}
+
+// Enable 开始跟踪在线核心数
+// 进程只能使用部分核心时（CPU 亲和性或 cpuset）在线核心数不代表可用核心数，不跟踪
+func (ct *CPUTopology) Enable() error {
+	online, err := readOnlineCPUs()
+	if err != nil {
+		return err
+	}
+	if online != runtime.NumCPU() {
+		return fmt.Errorf("进程只能使用 %d 个核心（共 %d 个在线核心，受 CPU 亲和性或 cpuset 限制）", runtime.NumCPU(), online)
+	}
+	ct.enabled = true
+	ct.count.Store(int64(online))
+	return nil
+}
+
+// Refresh 重新读取在线核心数，返回之前和当前的核心数，以及是否变化（未启用或读取失败时不变）
+func (ct *CPUTopology) Refresh() (int, int, bool) {
+	old := numCPU()
+	if !ct.enabled {
+		return old, old, false
+	}
+	online, err := readOnlineCPUs()
+	if err != nil || online == old {
+		return old, old, false
+	}
+	ct.count.Store(int64(online))
+	return old, online, true
+}
+
+// readOnlineCPUs 读取在线的核心数
+func readOnlineCPUs() (int, error) {
+	data, err := os.ReadFile(cpuOnlinePath)
+	if err != nil {
+		return 0, err
+	}
+	return countCPUList(strings.TrimSpace(string(data)))
+}
+
+// countCPUList 统计 CPU 列表（如 "0-3,6"）中的核心数
+func countCPUList(list string) (int, error) {
+	count := 0
+	for _, part := range strings.Split(list, ",") {
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return 0, fmt.Errorf("CPU 列表格式错误: %q", list)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return 0, fmt.Errorf("CPU 列表格式错误: %q", list)
+			}
+		}
+		count += end - start + 1
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("CPU 列表为空")
+	}
+	return count, nil
+}
+
+// rescaleMemory 总内存变化后（内存热插拔、virtio-balloon、cgroup 限制变化）调整内存步长，
+// 并按新的总量重新设置 CGROUP_PATH 的 memory.max；内存缓冲区超过新总量的硬峰值时立即释放超出的部分，
+// 不等待每个周期 0.1% 的调整（总量缩小后逐步释放可能来不及，被内核 OOM）
+func rescaleMemory(oldTotal, newTotal uint64) {
+	if newTotal == 0 || newTotal == oldTotal {
+		return
+	}
+	memoryController.SetTotalMemory(newTotal)
+	logger.Info("总内存变化，调整内存步长", "total_memory_mb_old", oldTotal/(1024*1024), "total_memory_mb_new", newTotal/(1024*1024))
+	if oldTotal == 0 {
+		return // 启动时没有读取到总内存
+	}
+	emitEvent("memory_resize", "total_memory_mb_old", oldTotal/(1024*1024), "total_memory_mb_new", newTotal/(1024*1024))
+
+	// 跟踪 cgroup 限制时总内存就是限制本身，按它设置 memory.max 会逐次缩小
+	if path := lookupEnv("CGROUP_PATH"); path != "" && cgroupLimits.Path() == "" {
+		if err := setCgroupMemoryMax(path, newTotal); err != nil {
+			logger.Warn("更新 cgroup 的 memory.max 失败", "path", path, "error", err)
+		}
+	}
+	if newTotal > oldTotal {
+		return
+	}
+
+	// rlimit 只收紧：总内存增加后保持启动时的上限
+	if getEnvBool("RLIMIT_MEMORY", false) {
+		if limit, err := applyMemoryRlimits(newTotal); err != nil {
+			logger.Warn("更新内存 rlimit 失败", "error", err)
+		} else {
+			memoryController.SetLimit(limit)
+		}
+	}
+	limit := uint64(float64(newTotal) * maxHardPeak() / 100)
+	if released := memoryController.ShrinkTo(limit); released > 0 {
+		debug.FreeOSMemory()
+		logger.Warn("总内存缩小，立即释放超出硬峰值的内存缓冲区", "released_mb", released/(1024*1024), "limit_mb", limit/(1024*1024))
+	}
+}
+
+// applyCPUCountChange 核心数变化后调整 GOMAXPROCS、工作协程数、计算次数模型和 cgroup 的 CPU 配额
+// 期望值本身是百分比，整机 CPU 使用率由 /proc/stat 按在线核心统计，不需要换算
+func applyCPUCountChange(oldCount, newCount int) {
+	logger.Warn("在线 CPU 核心数变化", "cpu_cores_old", oldCount, "cpu_cores_new", newCount)
+	emitEvent("cpu_hotplug", "cpu_cores_old", oldCount, "cpu_cores_new", newCount)
+
+	// GOMAXPROCS 未通过环境变量指定时随核心数变化（Go 运行时只在启动时设置）
+	if lookupEnv("GOMAXPROCS") == "" {
+		runtime.GOMAXPROCS(newCount)
+	}
+	if workers, changed := cpuController.Rescale(oldCount, newCount); changed {
+		logger.Info("按核心数调整工作协程数量", "cpu_workers", workers)
+	}
+	if cpuCountModel != nil {
+		cpuCountModel.reset(cpuController.GetWorkers())
+		logger.Info("核心数变化，计算次数模型从空模型开始", "path", cpuCountModel.path)
+	}
+	if path := lookupEnv("CGROUP_PATH"); path != "" {
+		if err := setCgroupCPUMax(path); err != nil {
+			logger.Warn("更新 cgroup 的 cpu.max 失败", "path", path, "error", err)
+		}
+	}
+}
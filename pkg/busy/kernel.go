@@ -0,0 +1,151 @@
+package busy
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// cpuKernel CPU 工作协程执行的计算：run 连续执行 n 次迭代，迭代序号从 from 开始
+// 每批迭代只有一次接口调用，批内调用的是具体类型的 step（可以内联），热循环中没有动态派发
+type cpuKernel interface {
+	run(from, n uint64)
+}
+
+// cpuKernels 可选的计算内核，键为 CPU_KERNEL 的取值
+var cpuKernels = map[string]func() cpuKernel{
+	"int":     func() cpuKernel { return &intKernel{} },
+	"syscall": newSyscallKernel,
+	"power":   newPowerKernel,
+	"data":    newDataKernel,
+	"regex":   newRegexKernel,
+	"memcpy":  func() cpuKernel { return &memcpyKernel{} },
+	"hash":    func() cpuKernel { return &hashKernel{} },
+}
+
+// newCPUKernel 根据名称创建计算内核，包含 ":" 时按加权混合解析（如 "int:50,memcpy:30,hash:20"）
+func newCPUKernel(name string) (cpuKernel, error) {
+	if strings.Contains(name, ":") {
+		return newMixKernel(name)
+	}
+	factory, ok := cpuKernels[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的计算内核: %s（可选：%s）", name, strings.Join(cpuKernelNames(), ", "))
+	}
+	return factory(), nil
+}
+
+// cpuKernelNames 返回所有可选的计算内核名称
+func cpuKernelNames() []string {
+	names := make([]string, 0, len(cpuKernels))
+	for name := range cpuKernels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// intKernel 简单的整数累加，纯用户态计算
+type intKernel struct {
+	sum uint64
+}
+
+func (k *intKernel) run(from, n uint64) {
+	for i := from; i < from+n; i++ {
+		k.step(i)
+	}
+}
+
+func (k *intKernel) step(i uint64) {
+	k.sum += i
+}
+
+// memcpyBlockSize memcpy 内核每次迭代复制的字节数
+const memcpyBlockSize = 4096
+
+// memcpyKernel 在两个缓冲区之间来回复制，主要消耗内存带宽（perf 中体现为 memmove）
+type memcpyKernel struct {
+	src, dst []byte
+}
+
+func (k *memcpyKernel) run(from, n uint64) {
+	for i := from; i < from+n; i++ {
+		k.step(i)
+	}
+}
+
+func (k *memcpyKernel) step(i uint64) {
+	if k.src == nil {
+		k.src, k.dst = make([]byte, memcpyBlockSize), make([]byte, memcpyBlockSize)
+	}
+	k.src[i%memcpyBlockSize] = byte(i)
+	copy(k.dst, k.src)
+	k.src, k.dst = k.dst, k.src
+}
+
+// hashKernel 每次迭代计算一次 64 字节的 SHA-256
+type hashKernel struct {
+	block [64]byte
+}
+
+func (k *hashKernel) run(from, n uint64) {
+	for i := from; i < from+n; i++ {
+		k.step(i)
+	}
+}
+
+func (k *hashKernel) step(i uint64) {
+	k.block[0] = byte(i)
+	sum := sha256.Sum256(k.block[:])
+	copy(k.block[:], sum[:])
+}
+
+// syscallEvery 系统调用内核中每隔多少次迭代执行一次系统调用
+const syscallEvery = 64
+
+// syscallKernel 整数累加中穿插系统调用（getpid / clock_gettime / read /dev/zero），
+// 使产生的 CPU 占用有一部分体现为 %sys，接近以系统调用为主的真实服务
+type syscallKernel struct {
+	sum uint64
+	fd  int
+	buf []byte
+	ts  syscall.Timespec
+}
+
+func newSyscallKernel() cpuKernel {
+	k := &syscallKernel{fd: -1, buf: make([]byte, 4096)}
+	if fd, err := syscall.Open("/dev/zero", syscall.O_RDONLY, 0); err == nil {
+		k.fd = fd
+	}
+	return k
+}
+
+func (k *syscallKernel) run(from, n uint64) {
+	for i := from; i < from+n; i++ {
+		k.step(i)
+	}
+}
+
+func (k *syscallKernel) step(i uint64) {
+	k.sum += i
+	if i%syscallEvery != 0 {
+		return
+	}
+
+	switch (i / syscallEvery) % 3 {
+	case 0:
+		syscall.Getpid()
+	case 1:
+		// 直接发起系统调用，绕过 vDSO
+		syscall.RawSyscall(syscall.SYS_CLOCK_GETTIME, 1, uintptr(unsafe.Pointer(&k.ts)), 0) // CLOCK_MONOTONIC
+	case 2:
+		if k.fd >= 0 {
+			syscall.Read(k.fd, k.buf)
+		} else {
+			syscall.Getppid()
+		}
+	}
+}
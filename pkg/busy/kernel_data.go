@@ -0,0 +1,96 @@
+package busy
+
+import (
+	"slices"
+)
+
+const (
+	defaultKernelWorkingSetKB = 1024 // 数据结构内核默认的工作集大小
+	dataSortWindow            = 32   // 排序操作每次排序的元素个数
+	mapEntryBytes             = 48   // map 中每个元素的大致内存占用（键、值和桶开销）
+)
+
+// dataKernel 在可配置大小的工作集上反复执行 map 增删查、有序索引二分查找和小批量排序，
+// 产生分支多、对缓存敏感的 CPU 负载，比整数累加更接近业务逻辑
+// 工作集由 CPU_KERNEL_WORKSET_KB 设置，一半用于 map，一半用于有序索引；首次迭代时才分配
+type dataKernel struct {
+	workingSet uint64
+	table      map[uint64]uint64
+	tableKeys  uint64   // map 的键空间：键空间是元素数的 2 倍，增删后大小在目标附近波动
+	index      []uint64 // 有序索引（相当于 B 树的叶子层）
+	window     []uint64
+	rng        uint64
+	sum        uint64
+}
+
+func newDataKernel() cpuKernel {
+	return &dataKernel{
+		workingSet: uint64(max(getEnvInt("CPU_KERNEL_WORKSET_KB", defaultKernelWorkingSetKB), 1)) * 1024,
+		rng:        0x9E3779B97F4A7C15,
+	}
+}
+
+// next xorshift64 伪随机数，比 math/rand 开销小且每个协程独立
+func (k *dataKernel) next() uint64 {
+	k.rng ^= k.rng << 13
+	k.rng ^= k.rng >> 7
+	k.rng ^= k.rng << 17
+	return k.rng
+}
+
+func (k *dataKernel) init() {
+	entries := max(k.workingSet/2/mapEntryBytes, 1)
+	k.tableKeys = entries * 2
+	k.table = make(map[uint64]uint64, entries)
+	for uint64(len(k.table)) < entries {
+		k.table[k.next()%k.tableKeys] = k.next()
+	}
+
+	k.index = make([]uint64, max(k.workingSet/2/8, dataSortWindow))
+	for j := range k.index {
+		k.index[j] = k.next()
+	}
+	slices.Sort(k.index)
+	k.window = make([]uint64, dataSortWindow)
+}
+
+func (k *dataKernel) run(from, n uint64) {
+	for i := from; i < from+n; i++ {
+		k.step(i)
+	}
+}
+
+func (k *dataKernel) step(i uint64) {
+	if k.table == nil {
+		k.init()
+	}
+
+	r := k.next()
+	switch i % 4 {
+	case 0:
+		// map：存在则删除，不存在则插入
+		key := r % k.tableKeys
+		if v, ok := k.table[key]; ok {
+			k.sum += v
+			delete(k.table, key)
+		} else {
+			k.table[key] = r
+		}
+	case 1, 2:
+		// 有序索引：二分查找，命中位置附近的元素
+		pos, found := slices.BinarySearch(k.index, r)
+		if found || pos < len(k.index) {
+			k.sum += k.index[min(pos, len(k.index)-1)]
+		}
+	case 3:
+		// 小批量排序：从索引随机位置取一段，打乱后排序
+		start := int(r % uint64(len(k.index)-dataSortWindow+1))
+		copy(k.window, k.index[start:start+dataSortWindow])
+		for j := len(k.window) - 1; j > 0; j-- {
+			n := int(k.next() % uint64(j+1))
+			k.window[j], k.window[n] = k.window[n], k.window[j]
+		}
+		slices.Sort(k.window)
+		k.sum += k.window[0]
+	}
+}
@@ -0,0 +1,89 @@
+package busy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mixSegment 混合内核连续执行同一个子内核的迭代次数，之后按时间占比选择下一个子内核
+const mixSegment = 256
+
+// mixKernel 按权重混合多个计算内核：权重是各子内核占用的 CPU 时间比例（而不是迭代次数），
+// 使 perf 中的热点分布与被替代的应用一致
+type mixKernel struct {
+	kernels []cpuKernel
+	weights []float64       // 归一化后的权重
+	spent   []time.Duration // 各子内核累计的执行时间
+	total   time.Duration
+	current int
+	start   time.Time
+}
+
+// newMixKernel 解析 "名称:权重,名称:权重"，子内核不能再是混合内核
+func newMixKernel(spec string) (cpuKernel, error) {
+	k := &mixKernel{}
+	var sum float64
+	for _, item := range splitList(spec) {
+		name, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("格式错误: %q（应为 内核:权重）", item)
+		}
+		name = strings.TrimSpace(name)
+		factory, ok := cpuKernels[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的计算内核: %s（可选：%s）", name, strings.Join(cpuKernelNames(), ", "))
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("内核 %s 的权重无效: %q", name, value)
+		}
+		k.kernels = append(k.kernels, factory())
+		k.weights = append(k.weights, weight)
+		sum += weight
+	}
+	if len(k.kernels) == 0 {
+		return nil, fmt.Errorf("没有指定计算内核")
+	}
+	for j := range k.weights {
+		k.weights[j] /= sum
+	}
+	k.spent = make([]time.Duration, len(k.kernels))
+	return k, nil
+}
+
+// run 按 mixSegment 分段，每段整体交给当前的子内核执行
+func (k *mixKernel) run(from, n uint64) {
+	for end := from + n; from < end; {
+		if from%mixSegment == 0 {
+			k.next()
+		}
+		m := min(end-from, mixSegment-from%mixSegment)
+		k.kernels[k.current].run(from, m)
+		from += m
+	}
+}
+
+// next 记录当前子内核的执行时间，选择时间占比最低于权重的子内核
+func (k *mixKernel) next() {
+	now := time.Now()
+	if !k.start.IsZero() {
+		elapsed := now.Sub(k.start)
+		k.spent[k.current] += elapsed
+		k.total += elapsed
+	}
+	k.start = now
+
+	best, bestDeficit := 0, -1.0
+	for j, weight := range k.weights {
+		deficit := weight
+		if k.total > 0 {
+			deficit -= float64(k.spent[j]) / float64(k.total)
+		}
+		if deficit > bestDeficit {
+			best, bestDeficit = j, deficit
+		}
+	}
+	k.current = best
+}
@@ -0,0 +1,124 @@
+package busy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// defaultRegexPatterns 默认的正则表达式：日志解析和 WAF 规则中常见的写法
+var defaultRegexPatterns = []string{
+	`^(\S+) \S+ \S+ \[([^\]]+)\] "(GET|POST|PUT|DELETE) ([^ ]+) HTTP/1\.[01]" (\d{3}) (\d+)`,
+	`(?i)(union\s+select|or\s+1\s*=\s*1|sleep\s*\(\s*\d+\s*\))`,
+	`(?i)<script[^>]*>|javascript:|on(load|error|click)\s*=`,
+	`\.\./|%2e%2e%2f|/etc/passwd`,
+	`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`,
+	`(?i)user-agent:\s*(curl|wget|python-requests|sqlmap)`,
+	`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+}
+
+// defaultRegexCorpus 默认的匹配文本：模拟访问日志，其中少量为攻击请求
+var defaultRegexCorpus = []string{
+	`10.0.3.17 - - [16/Oct/2026:10:21:07 +0800] "GET /api/v1/orders?page=2&size=20 HTTP/1.1" 200 5123 "-" "Mozilla/5.0 (X11; Linux x86_64)"`,
+	`10.0.8.201 - - [16/Oct/2026:10:21:07 +0800] "POST /api/v1/login HTTP/1.1" 401 87 "-" "okhttp/4.9.3" user=alice@example.com`,
+	`192.168.1.55 - - [16/Oct/2026:10:21:08 +0800] "GET /static/js/app.4f2a9c.js HTTP/1.1" 304 0 "https://shop.example.com/" "Mozilla/5.0"`,
+	`172.16.4.9 - - [16/Oct/2026:10:21:08 +0800] "GET /search?q=1%27%20or%201=1-- HTTP/1.1" 403 153 "-" "sqlmap/1.7"`,
+	`10.0.3.17 - - [16/Oct/2026:10:21:09 +0800] "PUT /api/v1/cart/8812 HTTP/1.1" 200 412 "-" "Mozilla/5.0 (Macintosh)"`,
+	`203.0.113.42 - - [16/Oct/2026:10:21:09 +0800] "GET /download?file=../../../../etc/passwd HTTP/1.0" 400 0 "-" "curl/8.4.0"`,
+	`10.0.9.3 - - [16/Oct/2026:10:21:10 +0800] "DELETE /api/v1/session HTTP/1.1" 204 0 "-" "python-requests/2.31"`,
+	`198.51.100.7 - - [16/Oct/2026:10:21:10 +0800] "POST /comment HTTP/1.1" 200 64 "-" "Mozilla/5.0" body=<script>alert(1)</script>`,
+}
+
+// regexKernel 用一组正则表达式轮流匹配一组文本，近似日志解析和 WAF 类服务的 CPU 负载
+// 正则由 CPU_KERNEL_REGEX_FILE 设置，文本由 CPU_KERNEL_CORPUS_FILE 设置（每行一条），未设置时使用内置的访问日志规则
+type regexKernel struct {
+	patterns []*regexp.Regexp
+	corpus   []string
+	sum      uint64
+}
+
+func newRegexKernel() cpuKernel {
+	k := &regexKernel{}
+	patterns, corpus, err := loadRegexKernelConfig()
+	if err != nil {
+		// 配置在 check 中校验，这里退回内置规则
+		logger.Warn("正则内核配置无效，使用内置规则", "error", err)
+		patterns, corpus, _ = compileRegexKernel(defaultRegexPatterns, defaultRegexCorpus)
+	}
+	k.patterns, k.corpus = patterns, corpus
+	return k
+}
+
+func (k *regexKernel) run(from, n uint64) {
+	for i := from; i < from+n; i++ {
+		k.step(i)
+	}
+}
+
+func (k *regexKernel) step(i uint64) {
+	pattern := k.patterns[i%uint64(len(k.patterns))]
+	text := k.corpus[(i/uint64(len(k.patterns)))%uint64(len(k.corpus))]
+	if loc := pattern.FindStringSubmatchIndex(text); loc != nil {
+		k.sum += uint64(loc[1])
+	}
+}
+
+// loadRegexKernelConfig 读取 CPU_KERNEL_REGEX_FILE 和 CPU_KERNEL_CORPUS_FILE
+func loadRegexKernelConfig() ([]*regexp.Regexp, []string, error) {
+	patterns, corpus := defaultRegexPatterns, defaultRegexCorpus
+	if path := lookupEnv("CPU_KERNEL_REGEX_FILE"); path != "" {
+		lines, err := readLines(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		patterns = lines
+	}
+	if path := lookupEnv("CPU_KERNEL_CORPUS_FILE"); path != "" {
+		lines, err := readLines(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		corpus = lines
+	}
+	return compileRegexKernel(patterns, corpus)
+}
+
+// compileRegexKernel 编译正则表达式，两者都不能为空
+func compileRegexKernel(patterns, corpus []string) ([]*regexp.Regexp, []string, error) {
+	if len(patterns) == 0 {
+		return nil, nil, fmt.Errorf("没有正则表达式")
+	}
+	if len(corpus) == 0 {
+		return nil, nil, fmt.Errorf("没有匹配文本")
+	}
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("正则表达式 %q 无效: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, corpus, nil
+}
+
+// readLines 读取文件中的非空行
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,128 @@
+package busy
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// killSwitchInterval 检查停止文件的间隔
+const killSwitchInterval = time.Second
+
+// KillSwitch 停止文件：文件出现时立即释放全部负载并保持空闲，文件删除后恢复调整
+// 运维在故障期间不需要操作进程管理器，touch 一个文件即可让本程序静默
+// 远程暂停（如 MQTT 的 pause 命令）与停止文件效果相同，两者任一生效即保持空闲
+// 收到云厂商的抢占通知后同样保持空闲，且不再恢复（实例即将被回收）
+type KillSwitch struct {
+	mu        sync.Mutex
+	path      string
+	file      atomic.Bool // 停止文件是否存在
+	remote    atomic.Bool // 是否被远程暂停
+	preempted atomic.Bool // 是否收到了抢占通知（不会恢复）
+	active    atomic.Bool // 停止文件存在、被远程暂停或收到了抢占通知
+	notifyMu  sync.Mutex  // 串行化状态变化的通知
+	changes   chan bool   // 状态变化（true=进入停止状态，false=恢复），由主循环处理
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+var killSwitch = &KillSwitch{changes: make(chan bool, 1)}
+
+// Start 开始监视 path（已经存在时立即生效）
+func (ks *KillSwitch) Start(path string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.cancel != nil || path == "" {
+		return
+	}
+	ks.path = path
+	ctx, cancel := context.WithCancel(context.Background())
+	ks.cancel = cancel
+	ks.poll()
+	ks.wg.Add(1)
+	go ks.watch(ctx)
+}
+
+// Stop 停止监视（状态恢复为未触发）
+func (ks *KillSwitch) Stop() {
+	ks.mu.Lock()
+	cancel := ks.cancel
+	ks.cancel = nil
+	ks.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		ks.wg.Wait()
+		ks.file.Store(false)
+		ks.active.Store(ks.remote.Load() || ks.preempted.Load())
+	}
+}
+
+// SetRemote 远程暂停或恢复
+func (ks *KillSwitch) SetRemote(paused bool) {
+	ks.remote.Store(paused)
+	ks.notify()
+}
+
+// SetPreempted 收到抢占通知：进入停止状态，之后远程恢复和删除停止文件都不再解除
+func (ks *KillSwitch) SetPreempted() {
+	ks.preempted.Store(true)
+	ks.notify()
+}
+
+// Active 是否处于停止状态（停止文件存在、被远程暂停或收到了抢占通知）
+func (ks *KillSwitch) Active() bool {
+	return ks.active.Load()
+}
+
+// Reason 停止状态的来源（用于日志）
+func (ks *KillSwitch) Reason() []any {
+	return []any{"file", ks.path, "file_exists", ks.file.Load(), "remote_paused", ks.remote.Load(), "preempted", ks.preempted.Load()}
+}
+
+// Changes 状态变化通知
+func (ks *KillSwitch) Changes() <-chan bool {
+	return ks.changes
+}
+
+// watch 定期检查停止文件是否存在
+func (ks *KillSwitch) watch(ctx context.Context) {
+	defer ks.wg.Done()
+	ticker := time.NewTicker(killSwitchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ks.poll()
+		}
+	}
+}
+
+// poll 检查一次停止文件
+func (ks *KillSwitch) poll() {
+	_, err := os.Stat(ks.path)
+	ks.file.Store(err == nil)
+	ks.notify()
+}
+
+// notify 重新计算停止状态，变化时通知主循环
+func (ks *KillSwitch) notify() {
+	ks.notifyMu.Lock()
+	defer ks.notifyMu.Unlock()
+
+	active := ks.file.Load() || ks.remote.Load() || ks.preempted.Load()
+	if ks.active.Swap(active) == active {
+		return
+	}
+	// 只保留最新的状态：主循环来不及处理时丢弃旧通知
+	select {
+	case <-ks.changes:
+	default:
+	}
+	ks.changes <- active
+}
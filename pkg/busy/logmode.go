@@ -0,0 +1,110 @@
+package busy
+
+import (
+	"time"
+)
+
+const (
+	defaultLogChangeDelta = 1.0             // 变化日志模式下视为变化的占用差（百分点）
+	defaultLogHeartbeat   = 5 * time.Minute // 变化日志模式下没有变化时至少每隔多久输出一次
+)
+
+// cycleValues 判断监控周期是否变化时比较的值
+type cycleValues struct {
+	cpu, memory, selfCPU, selfMemory, expected float64
+	peak                                       int
+	paused                                     bool
+}
+
+// cycleLogRecord 暂存的一条日志
+type cycleLogRecord struct {
+	msg  string
+	args []any
+}
+
+// CycleLogger 每个监控周期的资源监控日志和调整日志（LOG_MODE=changes 时只在变化时输出）
+// 收敛之后每个周期的日志几乎相同，变化日志模式把一个周期的日志暂存到周期结束：
+// 占用或期望值的变化超过 LOG_CHANGE_DELTA、峰值或暂停状态变化、实际执行了调整，或距离上次输出超过 LOG_HEARTBEAT 时才输出，否则丢弃
+// 只在主循环中使用；警告和其他日志不经过这里，总是立即输出
+type CycleLogger struct {
+	changesOnly bool
+	delta       float64
+	heartbeat   time.Duration // 0 表示没有变化时一直不输出
+
+	pending    []cycleLogRecord
+	current    cycleValues
+	last       cycleValues // 上次输出的周期的值
+	lastAt     time.Time   // 上次输出的时间（零值表示还没有输出过）
+	suppressed int         // 上次输出之后省略的周期数
+}
+
+var cycleLog = &CycleLogger{}
+
+// Configure 设置日志模式（all 或 changes）、变化阈值和心跳间隔
+func (cl *CycleLogger) Configure(mode string, delta float64, heartbeat time.Duration) {
+	cl.changesOnly = mode == "changes"
+	cl.delta, cl.heartbeat = max(delta, 0), max(heartbeat, 0)
+	cl.pending, cl.lastAt, cl.suppressed = nil, time.Time{}, 0
+}
+
+// Begin 开始一个监控周期
+func (cl *CycleLogger) Begin(values cycleValues) {
+	cl.current = values
+	cl.pending = cl.pending[:0]
+}
+
+// Info 输出或暂存一条周期内的日志
+func (cl *CycleLogger) Info(msg string, args ...any) {
+	if !cl.changesOnly {
+		logger.Info(msg, args...)
+		return
+	}
+	cl.pending = append(cl.pending, cycleLogRecord{msg: msg, args: args})
+}
+
+// End 结束监控周期：executed 表示本周期实际执行了调整
+// 输出时第一条日志（资源监控）附带 suppressed_cycles：上次输出之后省略的周期数
+func (cl *CycleLogger) End(executed bool) {
+	if !cl.changesOnly {
+		return
+	}
+	now := clock.Now()
+	if !executed && !cl.lastAt.IsZero() && !cl.changed() && (cl.heartbeat == 0 || now.Sub(cl.lastAt) < cl.heartbeat) {
+		cl.suppressed++
+		cl.pending = cl.pending[:0]
+		return
+	}
+	for i, record := range cl.pending {
+		args := record.args
+		if i == 0 && cl.suppressed > 0 {
+			args = append(args, "suppressed_cycles", cl.suppressed)
+		}
+		logger.Info(record.msg, args...)
+	}
+	cl.pending = cl.pending[:0]
+	cl.last, cl.lastAt, cl.suppressed = cl.current, now, 0
+}
+
+// changed 本周期与上次输出的周期相比是否有变化
+func (cl *CycleLogger) changed() bool {
+	cur, last := cl.current, cl.last
+	if cur.peak != last.peak || cur.paused != last.paused {
+		return true
+	}
+	for _, d := range []float64{cur.cpu - last.cpu, cur.memory - last.memory, cur.selfCPU - last.selfCPU, cur.selfMemory - last.selfMemory, cur.expected - last.expected} {
+		if abs(d) > cl.delta {
+			return true
+		}
+	}
+	return false
+}
+
+// executedActions 本周期是否实际执行了调整（不是全部跳过）
+func executedActions(actions []DecisionAction) bool {
+	for _, action := range actions {
+		if action.Action != "skip" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package busy
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// MemoryController 内存控制器
+type MemoryController struct {
+	mu          sync.RWMutex
+	buffer      []memoryBlock   // 内存缓冲区
+	heldBytes   uint64          // 内存缓冲区的总字节数
+	totalMemory uint64          // 整机总内存
+	limitBytes  uint64          // 内存缓冲区允许占用的上限（0 表示不限制）
+	backoffTill time.Time       // 分配失败或达到上限后，在此之前不再增加内存
+	blockSize   uint64          // 每次分配的块大小（0 表示使用默认值）
+	blockJitter float64         // 块大小的随机浮动比例（0-1，0 表示固定大小）
+	fragment    bool            // 碎片化模式：混合大小的块，随机顺序释放
+	alloc       memoryAllocator // 内存分配方式（nil 表示堆内存）
+	stopAccess  func()          // 停止内存访问协程（nil 表示未启动）
+	accounting  string          // 本程序内存占用的统计方式：rss（VmRSS + VmSwap）或 buffer（缓冲区长度）
+}
+
+const (
+	allocBackoff     = 1 * time.Minute // 内存分配达到上限后，暂停增加内存的时长
+	defaultBlockSize = 1024 * 1024     // 默认块大小：1MB
+	minBlockSize     = 4096            // 最小块大小：一个内存页
+)
+
+var memoryController = &MemoryController{}
+
+// AdjustMemoryRandom 根据随机方向调整内存占用
+// shouldIncrease: true=增加，false=减少
+// 返回：是否成功调整，调整的方向（true=增加，false=减少），调整的字节数
+func (mc *MemoryController) AdjustMemoryRandom(shouldIncrease bool) (bool, bool, uint64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	// 分配达到上限后退避一段时间，期间不再增加内存
+	if shouldIncrease && clock.Now().Before(mc.backoffTill) {
+		return false, shouldIncrease, mc.getCurrentProgramMemory()
+	}
+
+	// 计算当前程序占用的内存
+	currentProgramBytes := mc.getCurrentProgramMemory()
+
+	// 计算需要调整的字节数（0.1% 的整机内存）
+	adjustBytes := mc.totalMemory / 1000 // 0.1% = 1/1000
+
+	var targetProgramBytes uint64
+
+	if shouldIncrease {
+		// 增加内存
+		targetProgramBytes = currentProgramBytes + adjustBytes
+	} else {
+		// 减少内存
+		if currentProgramBytes > adjustBytes {
+			targetProgramBytes = currentProgramBytes - adjustBytes
+		} else {
+			targetProgramBytes = 0
+		}
+	}
+
+	// 执行调整
+	actualBytes := mc.adjustTo(targetProgramBytes)
+	return true, shouldIncrease, actualBytes
+}
+
+// adjustTo 调整内存到目标大小
+func (mc *MemoryController) adjustTo(targetBytes uint64) uint64 {
+	currentBytes := mc.getCurrentProgramMemory()
+
+	if targetBytes > currentBytes {
+		// 增加内存
+		needBytes := targetBytes - currentBytes
+		mc.allocateMemory(needBytes)
+	} else if targetBytes < currentBytes {
+		// 减少内存
+		releaseBytes := currentBytes - targetBytes
+		mc.releaseMemory(releaseBytes)
+	}
+
+	return mc.getCurrentProgramMemory()
+}
+
+// nextBlockSize 下一次分配的块大小：在 [blockSize × (1 - jitter), blockSize × (1 + jitter)] 内随机，使 RSS 的增长不是整齐的阶梯
+func (mc *MemoryController) nextBlockSize() uint64 {
+	size := mc.blockSize
+	if size == 0 {
+		size = defaultBlockSize
+	}
+	if mc.fragment {
+		// 碎片化模式：在 [最小块, 4 × 块大小] 内按对数均匀分布取值，小块多、大块少
+		lo, hi := math.Log(minBlockSize), math.Log(float64(max(size*4, minBlockSize)))
+		return uint64(math.Exp(lo + rand.Float64()*(hi-lo)))
+	}
+	if mc.blockJitter > 0 {
+		size = uint64(float64(size) * (1 + mc.blockJitter*(2*rand.Float64()-1)))
+	}
+	return max(size, minBlockSize)
+}
+
+// allocateMemory 分配内存（按块分配，直到分配量不小于 bytes）
+func (mc *MemoryController) allocateMemory(bytes uint64) {
+	for allocated := uint64(0); allocated < bytes; {
+		blockSize := mc.nextBlockSize()
+
+		// 内存缓冲区达到上限时停止分配，避免触发内核 rlimit 导致 Go 运行时直接崩溃
+		// 上限只针对缓冲区（rlimit 中已经为运行时预留了空间），与缓冲区的大小比较，不包括常驻内存中的其他部分
+		if mc.limitBytes > 0 && mc.heldBytes+blockSize > mc.limitBytes {
+			mc.backoffTill = clock.Now().Add(allocBackoff)
+			logger.Warn("内存缓冲区达到上限，暂停增加内存",
+				"buffer_mb", mc.heldBytes/(1024*1024),
+				"limit_mb", mc.limitBytes/(1024*1024),
+				"backoff", allocBackoff)
+			return
+		}
+
+		alloc := mc.alloc
+		if alloc == nil {
+			alloc = allocHeapBlock
+		}
+		block, err := alloc(blockSize)
+		if err != nil {
+			mc.backoffTill = clock.Now().Add(allocBackoff)
+			logger.Warn("内存分配失败，暂停增加内存", "error", err, "backoff", allocBackoff)
+			return
+		}
+		mc.buffer = append(mc.buffer, block)
+		mc.heldBytes += blockSize
+		allocated += blockSize
+	}
+}
+
+// releaseMemory 释放内存（从末尾按块释放，直到释放量不小于 bytes）
+// 碎片化模式下随机选择释放的块，使堆中留下大小不一的空洞
+func (mc *MemoryController) releaseMemory(bytes uint64) {
+	for released := uint64(0); released < bytes && len(mc.buffer) > 0; {
+		last := len(mc.buffer) - 1
+		if mc.fragment {
+			i := rand.Intn(len(mc.buffer))
+			mc.buffer[i], mc.buffer[last] = mc.buffer[last], mc.buffer[i]
+		}
+		size := mc.buffer[last].size()
+		mc.buffer[last].free()
+		mc.buffer[last] = nil
+		mc.buffer = mc.buffer[:last]
+		mc.heldBytes -= size
+		released += size
+	}
+}
+
+// Release 释放全部内存缓冲区
+func (mc *MemoryController) Release() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	for _, block := range mc.buffer {
+		block.free()
+	}
+	mc.buffer = nil
+	mc.heldBytes = 0
+}
+
+// ShrinkTo 释放内存缓冲区直到不超过 limitBytes，返回释放的字节数
+func (mc *MemoryController) ShrinkTo(limitBytes uint64) uint64 {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.heldBytes <= limitBytes {
+		return 0
+	}
+	before := mc.heldBytes
+	mc.releaseMemory(before - limitBytes)
+	return before - mc.heldBytes
+}
+
+// getCurrentProgramMemory 获取当前程序占用的内存（字节）
+// rss 模式下为 VmRSS + VmSwap：包含 Go 运行时的开销、已释放但尚未归还给系统的内存和被换出的内存；
+// 读取失败时退回缓冲区长度
+func (mc *MemoryController) getCurrentProgramMemory() uint64 {
+	if mc.accounting == "buffer" {
+		return mc.heldBytes
+	}
+	rss, swap, err := readSelfMemory()
+	if err != nil {
+		return mc.heldBytes
+	}
+	return rss + swap
+}
+
+// SetAccounting 设置本程序内存占用的统计方式（rss 或 buffer）
+func (mc *MemoryController) SetAccounting(mode string) error {
+	if mode != "rss" && mode != "buffer" {
+		return fmt.Errorf("未知的内存统计方式: %s（可选 rss、buffer）", mode)
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.accounting = mode
+	return nil
+}
+
+// SetTotalMemory 设置整机总内存
+func (mc *MemoryController) SetTotalMemory(totalMemory uint64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.totalMemory = totalMemory
+}
+
+// SetBlockSize 设置每次分配的块大小（字节）和随机浮动比例（0-1）
+func (mc *MemoryController) SetBlockSize(size uint64, jitter float64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.blockSize = size
+	mc.blockJitter = min(max(jitter, 0), 1)
+}
+
+// SetFragment 设置是否启用碎片化模式
+func (mc *MemoryController) SetFragment(enabled bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.fragment = enabled
+}
+
+// SetAllocator 设置内存分配方式（需在分配之前调用）
+func (mc *MemoryController) SetAllocator(alloc memoryAllocator) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.alloc = alloc
+}
+
+// SetLimit 设置内存缓冲区允许占用的上限（字节）
+func (mc *MemoryController) SetLimit(limitBytes uint64) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	mc.limitBytes = limitBytes
+}
+
+// GetCurrentMemory 获取当前程序占用的内存（字节）
+func (mc *MemoryController) GetCurrentMemory() uint64 {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.getCurrentProgramMemory()
+}
+
+// GetOffHeapMemory 不在 Go 堆中的内存缓冲区字节数（MEMORY_BACKEND 不是 heap 时为整个缓冲区）
+func (mc *MemoryController) GetOffHeapMemory() uint64 {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	if mc.alloc == nil {
+		return 0
+	}
+	return mc.heldBytes
+}
+
+// BlockStats 返回内存缓冲区的块数、最小和最大的块大小，以及缓冲区的上限（0 表示不限制）
+func (mc *MemoryController) BlockStats() (blocks int, minSize, maxSize, limit uint64) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	for i, block := range mc.buffer {
+		size := block.size()
+		if i == 0 || size < minSize {
+			minSize = size
+		}
+		maxSize = max(maxSize, size)
+	}
+	return len(mc.buffer), minSize, maxSize, mc.limitBytes
+}
+
+// GetBufferMemory 获取内存缓冲区的总字节数
+func (mc *MemoryController) GetBufferMemory() uint64 {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.heldBytes
+}
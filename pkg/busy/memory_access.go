@@ -0,0 +1,119 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	memoryAccessTick = 100 * time.Millisecond // 访问协程的周期
+	cacheLineSize    = 64                     // 每次访问一个缓存行
+)
+
+// memoryAccess 对已占用内存的访问方式
+type memoryAccess struct {
+	pattern    string  // sequential（顺序扫描）或 random（随机访问）
+	bytesPerS  float64 // 每秒访问的字节数
+	writeRatio float64 // 写访问的比例（0-1）
+
+	block, offset int // 顺序扫描的当前位置
+	sink          byte
+}
+
+// StartAccess 启动访问协程：按 pattern 以 mbps（MB/s）的速率读写已占用的内存，与占用大小无关
+// writeRatio 为写访问的比例；tmpfs 后端的内存没有映射到本进程，不参与访问
+func (mc *MemoryController) StartAccess(pattern string, mbps, writeRatio float64) error {
+	if pattern != "sequential" && pattern != "random" {
+		return fmt.Errorf("未知的内存访问方式: %s", pattern)
+	}
+	if mbps <= 0 {
+		return fmt.Errorf("内存访问速率必须大于 0")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	mc.mu.Lock()
+	mc.stopAccess = func() {
+		cancel()
+		<-done
+	}
+	mc.mu.Unlock()
+
+	access := &memoryAccess{
+		pattern:    pattern,
+		bytesPerS:  mbps * 1024 * 1024,
+		writeRatio: min(max(writeRatio, 0), 1),
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(memoryAccessTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				mc.mu.RLock()
+				access.run(mc.buffer, int(access.bytesPerS*memoryAccessTick.Seconds()))
+				mc.mu.RUnlock()
+			}
+		}
+	}()
+	return nil
+}
+
+// StopAccess 停止访问协程
+func (mc *MemoryController) StopAccess() {
+	mc.mu.Lock()
+	stop := mc.stopAccess
+	mc.stopAccess = nil
+	mc.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// run 访问 budget 字节（按缓存行计）
+func (ma *memoryAccess) run(blocks []memoryBlock, budget int) {
+	if len(blocks) == 0 {
+		return
+	}
+	for touched, misses := 0, 0; touched < budget && misses < len(blocks); {
+		var data []byte
+		var i int
+		if ma.pattern == "random" {
+			data = blocks[rand.Intn(len(blocks))].data()
+			if len(data) > 0 {
+				i = rand.Intn(len(data))
+			}
+		} else {
+			if ma.block >= len(blocks) {
+				ma.block, ma.offset = 0, 0
+			}
+			data = blocks[ma.block].data()
+			if ma.offset >= len(data) {
+				ma.block, ma.offset = ma.block+1, 0
+				if len(data) == 0 {
+					misses++
+				}
+				continue
+			}
+			i = ma.offset
+			ma.offset += cacheLineSize
+		}
+		if len(data) == 0 {
+			misses++
+			continue
+		}
+		misses = 0
+
+		if rand.Float64() < ma.writeRatio {
+			data[i]++
+		} else {
+			ma.sink += data[i]
+		}
+		touched += cacheLineSize
+	}
+}
@@ -0,0 +1,157 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const (
+	memFilePrefix = "cpumembusy-mem-" // tmpfs 模式下内存填充文件名前缀
+	shmPrefix     = "cpumembusy-shm-" // shm 模式下共享内存对象名前缀
+	shmDir        = "/dev/shm"        // POSIX 共享内存对象所在目录（与 shm_open 一致）
+)
+
+// memoryBlock 内存缓冲区中的一块
+type memoryBlock interface {
+	size() uint64
+	data() []byte // 映射到本进程的内容（nil 表示不可直接访问）
+	free()
+}
+
+// memoryAllocator 按大小分配一块内存
+type memoryAllocator func(size uint64) (memoryBlock, error)
+
+// heapBlock 匿名堆内存（默认，体现为 AnonPages）
+type heapBlock []byte
+
+func (b heapBlock) size() uint64 { return uint64(len(b)) }
+
+func (b heapBlock) data() []byte { return b }
+
+func (b heapBlock) free() {}
+
+// allocHeapBlock 分配堆内存并写入数据，确保内存真正被分配
+func allocHeapBlock(size uint64) (memoryBlock, error) {
+	buf := make([]byte, size)
+	for j := range buf {
+		buf[j] = byte(j % 256)
+	}
+	return heapBlock(buf), nil
+}
+
+// fileBlock tmpfs 中的填充文件（体现为 Shmem / Cached，不计入进程 RSS）
+type fileBlock struct {
+	path  string
+	bytes uint64
+}
+
+func (b *fileBlock) size() uint64 { return b.bytes }
+
+func (b *fileBlock) data() []byte { return nil }
+
+func (b *fileBlock) free() {
+	if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+		logger.Warn("删除内存填充文件失败", "path", b.path, "error", err)
+	}
+}
+
+// newTmpfsAllocator 创建 tmpfs 分配器：每块内存是 dir 下的一个文件，启动时清理上次遗留的文件
+func newTmpfsAllocator(dir string) (memoryAllocator, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, memFilePrefix+"*"))
+	for _, path := range leftovers {
+		os.Remove(path)
+	}
+
+	seq := 0
+	return func(size uint64) (memoryBlock, error) {
+		seq++
+		path := filepath.Join(dir, fmt.Sprintf("%s%d-%06d", memFilePrefix, os.Getpid(), seq))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if err := writeFiller(context.Background(), file, size); err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		return &fileBlock{path: path, bytes: size}, nil
+	}, nil
+}
+
+// shmBlock POSIX 共享内存对象（shm_open + mmap），体现为 Shmem 和进程的共享内存映射
+type shmBlock struct {
+	path  string
+	mem   []byte
+	bytes uint64
+}
+
+func (b *shmBlock) size() uint64 { return b.bytes }
+
+func (b *shmBlock) data() []byte { return b.mem }
+
+func (b *shmBlock) free() {
+	if err := syscall.Munmap(b.mem); err != nil {
+		logger.Warn("解除共享内存映射失败", "path", b.path, "error", err)
+	}
+	if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+		logger.Warn("删除共享内存对象失败", "path", b.path, "error", err)
+	}
+}
+
+// newShmAllocator 创建共享内存分配器：每块内存是一个共享内存对象，映射到本进程并写入数据，启动时清理上次遗留的对象
+func newShmAllocator() (memoryAllocator, error) {
+	if _, err := os.Stat(shmDir); err != nil {
+		return nil, err
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(shmDir, shmPrefix+"*"))
+	for _, path := range leftovers {
+		os.Remove(path)
+	}
+
+	seq := 0
+	return func(size uint64) (memoryBlock, error) {
+		seq++
+		path := filepath.Join(shmDir, fmt.Sprintf("%s%d-%06d", shmPrefix, os.Getpid(), seq))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		// 用 fallocate 预先分配 tmpfs 页：空间不足时在这里返回错误，而不是在写入映射时收到 SIGBUS
+		if err := syscall.Fallocate(int(file.Fd()), 0, 0, int64(size)); err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		data, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		// 写入数据，使共享内存页映射到本进程
+		for j := range data {
+			data[j] = byte(j % 256)
+		}
+		return &shmBlock{path: path, mem: data, bytes: size}, nil
+	}, nil
+}
+
+// newMemoryAllocator 按 MEMORY_BACKEND 创建分配器
+func newMemoryAllocator(backend, dir string) (memoryAllocator, error) {
+	switch backend {
+	case "heap":
+		return allocHeapBlock, nil
+	case "tmpfs":
+		return newTmpfsAllocator(dir)
+	case "shm":
+		return newShmAllocator()
+	default:
+		return nil, fmt.Errorf("未知的内存后端: %s", backend)
+	}
+}
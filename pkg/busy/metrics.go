@@ -0,0 +1,136 @@
+package busy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// handleMetrics 以 Prometheus 文本格式输出最近一次监控周期的指标
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := getAgentStatus()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writeGauge(w, "cpumembusy_peak_usage", "当前浮动的峰值使用率", float64(status.PeakUsage))
+	writeGauge(w, "cpumembusy_expected_usage", "期望占用值", status.ExpectedUsage)
+	writeGauge(w, "cpumembusy_cpu_percent", "整机 CPU 使用率", status.CPUPercent)
+	writeGauge(w, "cpumembusy_cpu_steal_percent", "CPU steal 时间百分比", status.StealPercent)
+	writeGauge(w, "cpumembusy_reclaim_scan_pages_per_second", "内核回收内存时每秒扫描的页数（kswapd + 同步回收）", status.ReclaimScanRate)
+	writeGauge(w, "cpumembusy_direct_reclaim_pages_per_second", "分配内存时同步回收每秒扫描的页数", status.DirectReclaimRate)
+	writeGauge(w, "cpumembusy_self_cpu_percent", "本进程贡献的 CPU 使用率", status.SelfCPUPercent)
+	writeGauge(w, "cpumembusy_other_cpu_percent", "其他进程贡献的 CPU 使用率", status.OtherCPUPercent)
+	writeGauge(w, "cpumembusy_memory_percent", "整机内存使用率", status.MemoryPercent)
+	writeGauge(w, "cpumembusy_self_memory_percent", "本进程贡献的内存使用率", status.SelfMemoryPercent)
+	writeGauge(w, "cpumembusy_other_memory_percent", "其他进程贡献的内存使用率", status.OtherMemoryPercent)
+	writeGauge(w, "cpumembusy_self_rss_bytes", "本进程的常驻内存", float64(status.SelfMemoryBytes))
+	writeGauge(w, "cpumembusy_disk_percent", "磁盘使用率", status.DiskPercent)
+	writeGauge(w, "cpumembusy_buffer_bytes", "程序占用的内存缓冲区大小", float64(status.BufferMemoryBytes))
+	writeGauge(w, "cpumembusy_load_cpu_percent", "本进程中有意产生的 CPU 负载", status.LoadCPUPercent)
+	writeGauge(w, "cpumembusy_overhead_cpu_percent", "本进程中控制本身的 CPU 开销（含 GC）", status.OverheadCPUPercent)
+	writeGauge(w, "cpumembusy_gc_cpu_percent", "本进程 GC 消耗的 CPU", status.GCCPUPercent)
+	writeGauge(w, "cpumembusy_overhead_memory_bytes", "本进程中内存缓冲区以外的内存", float64(status.OverheadMemory))
+	writeGauge(w, "cpumembusy_cpu_count", "CPU 工作协程每次 sleep 前的计算次数", float64(status.CPUCount))
+	writeGauge(w, "cpumembusy_cpu_temperature_celsius", "CPU 温度", status.CPUTemp)
+	writeGauge(w, "cpumembusy_cpu_frequency_mhz", "CPU 平均当前频率", status.CPUFreqMHz)
+	writeTracking(w)
+	writeStatsBackends(w)
+	writeSupervisor(w)
+	if status.Budget != nil {
+		writeBudget(w, status.Budget)
+	}
+	if status.GridSignal != nil {
+		writeGauge(w, "cpumembusy_grid_signal", "最近一次读取的电网碳强度或电价", status.GridSignal.Value)
+		writeGauge(w, "cpumembusy_grid_factor", "按电网信号降低期望值的系数（1 表示不降低）", status.GridSignal.Factor)
+	}
+	if status.OOM != nil {
+		fmt.Fprintf(w, "# HELP cpumembusy_oom_kills_total 启动以来检测到的被 OOM killer 杀死的进程数\n# TYPE cpumembusy_oom_kills_total counter\ncpumembusy_oom_kills_total%s %d\n",
+			withMetricLabels(""), status.OOM.Total)
+	}
+	if status.CPUCredits != nil {
+		writeGauge(w, "cpumembusy_cpu_credit_balance", "CPU 积分余额（本地估算或外部接口校正后的值）", status.CPUCredits.Balance)
+		writeGauge(w, "cpumembusy_cpu_credit_allowed_percent", "按 CPU 积分当前允许的整机 CPU 占用", status.CPUCredits.AllowedPercent)
+	}
+}
+
+// writeBudget 输出每日资源预算的消耗和剩余（按资源加标签，只输出设置了预算的资源）
+func writeBudget(w io.Writer, budget *BudgetStatus) {
+	type row struct {
+		resource                  string
+		spent, remaining, allowed float64
+	}
+	var rows []row
+	if budget.CPUCoreHours > 0 {
+		rows = append(rows, row{"cpu", budget.CPUSpentCoreHours, budget.CPURemainingCoreHours, budget.CPUAllowedPercent})
+	}
+	if budget.MemoryGiBHours > 0 {
+		rows = append(rows, row{"memory", budget.MemorySpentGiBHours, budget.MemoryRemainingGiBHours, budget.MemoryAllowedPercent})
+	}
+	fmt.Fprintf(w, "# HELP cpumembusy_budget_spent 当天已消耗的预算（CPU 为核心·小时，内存为 GiB·小时）\n# TYPE cpumembusy_budget_spent gauge\n")
+	for _, r := range rows {
+		fmt.Fprintf(w, "cpumembusy_budget_spent%s %g\n", withMetricLabels(fmt.Sprintf("resource=%q", r.resource)), r.spent)
+	}
+	fmt.Fprintf(w, "# HELP cpumembusy_budget_remaining 当天剩余的预算（CPU 为核心·小时，内存为 GiB·小时）\n# TYPE cpumembusy_budget_remaining gauge\n")
+	for _, r := range rows {
+		fmt.Fprintf(w, "cpumembusy_budget_remaining%s %g\n", withMetricLabels(fmt.Sprintf("resource=%q", r.resource)), r.remaining)
+	}
+	fmt.Fprintf(w, "# HELP cpumembusy_budget_allowed_percent 按预算计划本进程当前允许的占用（占整机的百分比）\n# TYPE cpumembusy_budget_allowed_percent gauge\n")
+	for _, r := range rows {
+		fmt.Fprintf(w, "cpumembusy_budget_allowed_percent%s %g\n", withMetricLabels(fmt.Sprintf("resource=%q", r.resource)), r.allowed)
+	}
+}
+
+// writeStatsBackends 输出各资源信息来源是否正在使用和累计失败次数
+func writeStatsBackends(w io.Writer) {
+	health := statsChain.Health()
+	fmt.Fprintf(w, "# HELP cpumembusy_stats_backend_active 当前使用的资源信息来源（1 表示正在使用，全部为 0 时调整已冻结）\n# TYPE cpumembusy_stats_backend_active gauge\n")
+	for _, h := range health {
+		active := 0
+		if h.Active {
+			active = 1
+		}
+		fmt.Fprintf(w, "cpumembusy_stats_backend_active%s %d\n", withMetricLabels(fmt.Sprintf("backend=%q", h.Name)), active)
+	}
+	fmt.Fprintf(w, "# HELP cpumembusy_stats_backend_failures_total 资源信息来源读取失败的次数\n# TYPE cpumembusy_stats_backend_failures_total counter\n")
+	for _, h := range health {
+		fmt.Fprintf(w, "cpumembusy_stats_backend_failures_total%s %d\n", withMetricLabels(fmt.Sprintf("backend=%q", h.Name)), h.Failures)
+	}
+}
+
+// writeSupervisor 输出已恢复的 panic 次数和重启的工作协程数
+func writeSupervisor(w io.Writer) {
+	fmt.Fprintf(w, "# HELP cpumembusy_panics_total 启动以来恢复的 panic 次数（工作协程和主循环）\n# TYPE cpumembusy_panics_total counter\ncpumembusy_panics_total%s %d\n",
+		withMetricLabels(""), panicsTotal.Load())
+	fmt.Fprintf(w, "# HELP cpumembusy_worker_restarts_total 启动以来重新启动的 CPU 工作协程数\n# TYPE cpumembusy_worker_restarts_total counter\ncpumembusy_worker_restarts_total%s %d\n",
+		withMetricLabels(""), workerRestartsTotal.Load())
+}
+
+// writeTracking 输出各滑动窗口的跟踪误差（按资源和窗口加标签）
+func writeTracking(w io.Writer) {
+	now := clock.Now()
+	fmt.Fprintf(w, "# HELP cpumembusy_tracking_mae_percent 占用值与期望值的平均绝对误差（百分点）\n# TYPE cpumembusy_tracking_mae_percent gauge\n")
+	var inBand []string
+	for _, window := range trackingWindows {
+		cpu, memory := trackingStats.Window(now, window.d)
+		cpuLabels := withMetricLabels(fmt.Sprintf("resource=\"cpu\",window=%q", window.name))
+		memoryLabels := withMetricLabels(fmt.Sprintf("resource=\"memory\",window=%q", window.name))
+		fmt.Fprintf(w, "cpumembusy_tracking_mae_percent%s %g\n", cpuLabels, cpu.MAE)
+		fmt.Fprintf(w, "cpumembusy_tracking_mae_percent%s %g\n", memoryLabels, memory.MAE)
+		inBand = append(inBand,
+			fmt.Sprintf("cpumembusy_tracking_in_band_ratio%s %g\n", cpuLabels, cpu.InBand),
+			fmt.Sprintf("cpumembusy_tracking_in_band_ratio%s %g\n", memoryLabels, memory.InBand))
+	}
+	fmt.Fprintf(w, "# HELP cpumembusy_tracking_in_band_ratio 误差在 ±TRACK_BAND 以内的时间占比\n# TYPE cpumembusy_tracking_in_band_ratio gauge\n")
+	for _, line := range inBand {
+		io.WriteString(w, line)
+	}
+}
+
+// writeGauge 输出一个 gauge 类型的指标（带主机标签）
+func writeGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s%s %g\n", name, help, name, name, withMetricLabels(""), value)
+}
@@ -0,0 +1,416 @@
+package busy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMQTTTelemetryInterval = 30 * time.Second
+	mqttKeepAlive                = 60 * time.Second // CONNECT 中声明的保活时间，每隔一半时间发送 PINGREQ
+	mqttDialTimeout              = 10 * time.Second
+	mqttMaxBackoff               = time.Minute // 断线重连的最大间隔
+)
+
+// MQTT 3.1.1 控制报文类型（固定报头的高 4 位）
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttSubscribe  = 8
+	mqttSuback     = 9
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+// MQTTConfig MQTT 连接配置
+type MQTTConfig struct {
+	Broker            string // tcp://host:port 或 tls://host:port（省略协议时为 tcp）
+	ClientID          string
+	Username          string
+	Password          string
+	CommandTopic      string // 订阅的命令主题
+	TelemetryTopic    string // 发布遥测的主题
+	TelemetryInterval time.Duration
+}
+
+// MQTTCommand 命令主题的消息格式
+//
+//	{"command": "target", "peak_usage": 45, "peak_usage_origin": 60}  与 POST /peak 相同（peak_usage_origin 省略时等于 peak_usage）
+//	{"command": "pause"}   与停止文件相同：释放全部负载并保持空闲
+//	{"command": "resume"}  解除暂停
+type MQTTCommand struct {
+	Command         string `json:"command"`
+	PeakUsage       int    `json:"peak_usage"`
+	PeakUsageOrigin int    `json:"peak_usage_origin"`
+}
+
+// MQTTTelemetry 遥测主题的消息格式：/status 的全部字段加上主机名和停止状态
+type MQTTTelemetry struct {
+	AgentStatus
+	Hostname   string `json:"hostname"`
+	KillSwitch bool   `json:"kill_switch"`
+}
+
+// MQTTClient 通过 MQTT 接收命令并定期发布遥测，适用于没有入站 HTTP 访问的边缘设备
+// 只实现需要的 MQTT 3.1.1 子集：订阅 QoS 1，发布 QoS 0，断线后按指数退避重连
+type MQTTClient struct {
+	cfg    MQTTConfig
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// loadMQTTConfig 从环境变量读取 MQTT 配置（未设置 MQTT_BROKER 时返回 false）
+func loadMQTTConfig() (MQTTConfig, bool) {
+	broker := lookupEnv("MQTT_BROKER")
+	if broker == "" {
+		return MQTTConfig{}, false
+	}
+	host := hostname()
+	return MQTTConfig{
+		Broker:            broker,
+		ClientID:          getEnvString("MQTT_CLIENT_ID", "cpumembusy-"+host),
+		Username:          lookupEnv("MQTT_USERNAME"),
+		Password:          lookupEnv("MQTT_PASSWORD"),
+		CommandTopic:      getEnvString("MQTT_COMMAND_TOPIC", "cpumembusy/"+host+"/command"),
+		TelemetryTopic:    getEnvString("MQTT_TELEMETRY_TOPIC", "cpumembusy/"+host+"/telemetry"),
+		TelemetryInterval: max(getEnvDuration("MQTT_TELEMETRY_INTERVAL", defaultMQTTTelemetryInterval), time.Second),
+	}, true
+}
+
+// startMQTTClient 启动 MQTT 客户端（后台连接，失败时自动重连）
+func startMQTTClient(cfg MQTTConfig) *MQTTClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	mc := &MQTTClient{cfg: cfg, cancel: cancel}
+	mc.wg.Add(1)
+	go mc.loop(ctx)
+	return mc
+}
+
+// Stop 断开连接并停止重连
+func (mc *MQTTClient) Stop() {
+	mc.cancel()
+	mc.wg.Wait()
+}
+
+// loop 保持连接：断开后按指数退避重连
+func (mc *MQTTClient) loop(ctx context.Context) {
+	defer mc.wg.Done()
+	backoff := time.Second
+	for {
+		connected, err := mc.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = time.Second
+		}
+		logger.Warn("MQTT 连接断开，稍后重连", "broker", mc.cfg.Broker, "error", err, "retry_in", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, mqttMaxBackoff)
+	}
+}
+
+// session 建立一次连接并处理到断开为止，connected 表示是否完成了 CONNECT 握手
+func (mc *MQTTClient) session(ctx context.Context) (connected bool, err error) {
+	conn, err := mc.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// ctx 取消时发送 DISCONNECT 并关闭连接，使读取立即返回
+	var writeMu sync.Mutex
+	write := func(packet []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(mqttDialTimeout))
+		_, err := conn.Write(packet)
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			write(mqttPacket(mqttDisconnect<<4, nil))
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	if err := write(mqttConnectPacket(mc.cfg)); err != nil {
+		return false, err
+	}
+	conn.SetReadDeadline(time.Now().Add(mqttDialTimeout))
+	header, body, err := readMQTTPacket(r)
+	if err != nil {
+		return false, err
+	}
+	if header>>4 != mqttConnack || len(body) < 2 {
+		return false, fmt.Errorf("期望 CONNACK，收到报文类型 %d", header>>4)
+	}
+	if body[1] != 0 {
+		return false, fmt.Errorf("broker 拒绝连接，返回码 %d", body[1])
+	}
+
+	if err := write(mqttSubscribePacket(1, mc.cfg.CommandTopic, 1)); err != nil {
+		return true, err
+	}
+	logger.Info("MQTT 已连接", "broker", mc.cfg.Broker, "client_id", mc.cfg.ClientID,
+		"command_topic", mc.cfg.CommandTopic, "telemetry_topic", mc.cfg.TelemetryTopic)
+
+	// 定期发布遥测和保活
+	go func() {
+		telemetry := time.NewTicker(mc.cfg.TelemetryInterval)
+		defer telemetry.Stop()
+		ping := time.NewTicker(mqttKeepAlive / 2)
+		defer ping.Stop()
+		for {
+			var err error
+			select {
+			case <-done:
+				return
+			case <-telemetry.C:
+				err = mc.publishTelemetry(write)
+			case <-ping.C:
+				err = write(mqttPacket(mqttPingreq<<4, nil))
+			}
+			if err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		// 超过 1.5 倍保活时间没有收到任何报文（包括 PINGRESP）视为连接已断开
+		conn.SetReadDeadline(time.Now().Add(mqttKeepAlive * 3 / 2))
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			return true, err
+		}
+		switch header >> 4 {
+		case mqttPublish:
+			topic, payload, packetID, err := parseMQTTPublish(header, body)
+			if err != nil {
+				return true, err
+			}
+			if packetID != 0 {
+				ack := binary.BigEndian.AppendUint16(nil, packetID)
+				if err := write(mqttPacket(mqttPuback<<4, ack)); err != nil {
+					return true, err
+				}
+			}
+			handleMQTTCommand(topic, payload)
+		case mqttSuback:
+			if len(body) >= 3 && body[2] == 0x80 {
+				return true, fmt.Errorf("broker 拒绝订阅 %s", mc.cfg.CommandTopic)
+			}
+		case mqttPingresp:
+		}
+	}
+}
+
+// parseMQTTBroker 解析 MQTT_BROKER，返回是否使用 TLS 和 host:port
+func parseMQTTBroker(broker string) (useTLS bool, addr string, err error) {
+	scheme, addr, found := strings.Cut(broker, "://")
+	if !found {
+		scheme, addr = "tcp", broker
+	}
+	switch scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS = true
+	default:
+		return false, "", fmt.Errorf("不支持的协议: %s（可选: tcp、tls）", scheme)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return false, "", err
+	}
+	return useTLS, addr, nil
+}
+
+// dial 连接 broker
+func (mc *MQTTClient) dial(ctx context.Context) (net.Conn, error) {
+	useTLS, addr, err := parseMQTTBroker(mc.cfg.Broker)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: mqttDialTimeout}
+	if !useTLS {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+	return tlsDialer.DialContext(ctx, "tcp", addr)
+}
+
+// publishTelemetry 发布一次遥测（QoS 0）
+func (mc *MQTTClient) publishTelemetry(write func([]byte) error) error {
+	data, err := json.Marshal(MQTTTelemetry{
+		AgentStatus: getAgentStatus(),
+		Hostname:    hostname(),
+		KillSwitch:  killSwitch.Active(),
+	})
+	if err != nil {
+		return err
+	}
+	return write(mqttPublishPacket(mc.cfg.TelemetryTopic, data))
+}
+
+// handleMQTTCommand 执行命令主题收到的命令，无效的命令只记录日志
+func handleMQTTCommand(topic string, payload []byte) {
+	var cmd MQTTCommand
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		logger.Warn("MQTT 命令无效", "topic", topic, "error", err)
+		return
+	}
+	switch cmd.Command {
+	case "target":
+		if cmd.PeakUsageOrigin == 0 {
+			cmd.PeakUsageOrigin = cmd.PeakUsage
+		}
+		if err := applyPeakUpdate(PeakUpdate{PeakUsageOrigin: cmd.PeakUsageOrigin, PeakUsage: cmd.PeakUsage}, "mqtt "+topic); err != nil {
+			var limited *PeakRateLimitError
+			if !errors.As(err, &limited) {
+				logger.Warn("MQTT 命令无效", "topic", topic, "command", cmd.Command, "error", err)
+			}
+		}
+	case "pause":
+		logger.Info("收到 MQTT 暂停命令", "topic", topic)
+		killSwitch.SetRemote(true)
+	case "resume":
+		logger.Info("收到 MQTT 恢复命令", "topic", topic)
+		killSwitch.SetRemote(false)
+	default:
+		logger.Warn("MQTT 命令无效", "topic", topic, "command", cmd.Command, "error", "未知的命令（可选: target、pause、resume）")
+	}
+}
+
+// mqttPacket 组装报文：固定报头 + 剩余长度 + body
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// appendMQTTString 追加 2 字节长度前缀的字符串
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// mqttConnectPacket CONNECT 报文（clean session；MQTT 3.1.1 不允许只有密码没有用户名，此时忽略密码）
+func mqttConnectPacket(cfg MQTTConfig) []byte {
+	if cfg.Username == "" {
+		cfg.Password = ""
+	}
+	flags := byte(0x02)
+	if cfg.Username != "" {
+		flags |= 0x80
+	}
+	if cfg.Password != "" {
+		flags |= 0x40
+	}
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags) // 协议级别 4 = MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(mqttKeepAlive/time.Second))
+	body = appendMQTTString(body, cfg.ClientID)
+	if cfg.Username != "" {
+		body = appendMQTTString(body, cfg.Username)
+	}
+	if cfg.Password != "" {
+		body = appendMQTTString(body, cfg.Password)
+	}
+	return mqttPacket(mqttConnect<<4, body)
+}
+
+// mqttSubscribePacket SUBSCRIBE 报文（单个主题）
+func mqttSubscribePacket(packetID uint16, topic string, qos byte) []byte {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	body = appendMQTTString(body, topic)
+	body = append(body, qos)
+	return mqttPacket(mqttSubscribe<<4|0x02, body)
+}
+
+// mqttPublishPacket QoS 0 的 PUBLISH 报文
+func mqttPublishPacket(topic string, payload []byte) []byte {
+	body := appendMQTTString(nil, topic)
+	return mqttPacket(mqttPublish<<4, append(body, payload...))
+}
+
+// readMQTTPacket 读取一个报文，返回固定报头的第一个字节和剩余部分
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("剩余长度无效")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// parseMQTTPublish 解析 PUBLISH 报文，QoS 0 时 packetID 为 0
+func parseMQTTPublish(header byte, body []byte) (topic string, payload []byte, packetID uint16, err error) {
+	if len(body) < 2 {
+		return "", nil, 0, errors.New("PUBLISH 报文过短")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return "", nil, 0, errors.New("PUBLISH 报文过短")
+	}
+	topic, rest := string(body[2:2+n]), body[2+n:]
+	if qos := header >> 1 & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, 0, errors.New("PUBLISH 报文过短")
+		}
+		packetID, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	return topic, rest, packetID, nil
+}
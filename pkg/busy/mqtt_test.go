@@ -0,0 +1,185 @@
+package busy
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMQTTRemainingLength(t *testing.T) {
+	// MQTT 3.1.1 第 2.2.3 节中的边界值
+	tests := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{2097151, []byte{0xff, 0xff, 0x7f}},
+		{2097152, []byte{0x80, 0x80, 0x80, 0x01}},
+	}
+	for _, tt := range tests {
+		body := bytes.Repeat([]byte{0xab}, tt.length)
+		packet := mqttPacket(mqttPublish<<4, body)
+		if got := packet[1 : 1+len(tt.want)]; packet[0] != 0x30 || !bytes.Equal(got, tt.want) {
+			t.Errorf("length %d: header % x, want 30 % x", tt.length, packet[:1+len(tt.want)], tt.want)
+			continue
+		}
+
+		header, got, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(packet)))
+		if err != nil {
+			t.Errorf("length %d: readMQTTPacket() error: %v", tt.length, err)
+			continue
+		}
+		if header != 0x30 || !bytes.Equal(got, body) {
+			t.Errorf("length %d: readMQTTPacket() = %#x, %d bytes", tt.length, header, len(got))
+		}
+	}
+}
+
+func TestReadMQTTPacketInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"length-too-long", []byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"length-truncated", []byte{0x30, 0x80}},
+		{"body-truncated", []byte{0x30, 0x05, 0x00, 0x01, 't'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(tt.data))); err == nil {
+				t.Fatalf("readMQTTPacket(% x) = nil error, want error", tt.data)
+			}
+		})
+	}
+}
+
+func TestMQTTEncodePackets(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   []byte
+	}{
+		{
+			name:   "connect",
+			packet: mqttConnectPacket(MQTTConfig{ClientID: "c1"}),
+			want:   []byte{0x10, 0x0e, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x02, 0x00, 0x3c, 0x00, 0x02, 'c', '1'},
+		},
+		{
+			name:   "connect-auth",
+			packet: mqttConnectPacket(MQTTConfig{ClientID: "c1", Username: "u", Password: "p"}),
+			want: []byte{0x10, 0x14, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0xc2, 0x00, 0x3c, 0x00, 0x02, 'c', '1',
+				0x00, 0x01, 'u', 0x00, 0x01, 'p'},
+		},
+		{
+			// 只有密码没有用户名时忽略密码
+			name:   "connect-password-only",
+			packet: mqttConnectPacket(MQTTConfig{ClientID: "c1", Password: "p"}),
+			want:   []byte{0x10, 0x0e, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x02, 0x00, 0x3c, 0x00, 0x02, 'c', '1'},
+		},
+		{
+			name:   "subscribe",
+			packet: mqttSubscribePacket(1, "a/b", 1),
+			want:   []byte{0x82, 0x08, 0x00, 0x01, 0x00, 0x03, 'a', '/', 'b', 0x01},
+		},
+		{
+			name:   "publish",
+			packet: mqttPublishPacket("t", []byte("hi")),
+			want:   []byte{0x30, 0x05, 0x00, 0x01, 't', 'h', 'i'},
+		},
+		{
+			name:   "disconnect",
+			packet: mqttPacket(mqttDisconnect<<4, nil),
+			want:   []byte{0xe0, 0x00},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Equal(tt.packet, tt.want) {
+				t.Errorf("got  % x\nwant % x", tt.packet, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMQTTPublish(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   []byte
+		topic    string
+		payload  string
+		packetID uint16
+		wantErr  bool
+	}{
+		{name: "qos0", packet: []byte{0x30, 0x05, 0x00, 0x01, 't', 'h', 'i'}, topic: "t", payload: "hi"},
+		{name: "qos1", packet: []byte{0x32, 0x07, 0x00, 0x01, 't', 0x00, 0x0a, 'h', 'i'}, topic: "t", payload: "hi", packetID: 10},
+		{name: "qos1-empty-payload", packet: []byte{0x32, 0x05, 0x00, 0x01, 't', 0x01, 0x00}, topic: "t", packetID: 256},
+		{name: "no-topic-length", packet: []byte{0x30, 0x01, 0x00}, wantErr: true},
+		{name: "topic-truncated", packet: []byte{0x30, 0x03, 0x00, 0x05, 't'}, wantErr: true},
+		{name: "qos1-no-packet-id", packet: []byte{0x32, 0x04, 0x00, 0x01, 't', 0x00}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, body, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(tt.packet)))
+			if err != nil {
+				t.Fatalf("readMQTTPacket() error: %v", err)
+			}
+			topic, payload, packetID, err := parseMQTTPublish(header, body)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseMQTTPublish() = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMQTTPublish() error: %v", err)
+			}
+			if topic != tt.topic || string(payload) != tt.payload || packetID != tt.packetID {
+				t.Errorf("parseMQTTPublish() = %q, %q, %d, want %q, %q, %d", topic, payload, packetID, tt.topic, tt.payload, tt.packetID)
+			}
+		})
+	}
+
+	// 编码后再解码得到相同的主题和内容
+	payload := strings.Repeat("x", 300)
+	header, body, err := readMQTTPacket(bufio.NewReader(bytes.NewReader(mqttPublishPacket("cpumembusy/host/telemetry", []byte(payload)))))
+	if err != nil {
+		t.Fatalf("readMQTTPacket() error: %v", err)
+	}
+	if topic, got, _, err := parseMQTTPublish(header, body); err != nil || topic != "cpumembusy/host/telemetry" || string(got) != payload {
+		t.Errorf("round trip = %q, %d bytes, %v", topic, len(got), err)
+	}
+}
+
+func TestParseMQTTBroker(t *testing.T) {
+	tests := []struct {
+		broker  string
+		useTLS  bool
+		addr    string
+		wantErr bool
+	}{
+		{broker: "broker:1883", addr: "broker:1883"},
+		{broker: "tcp://broker:1883", addr: "broker:1883"},
+		{broker: "mqtts://broker:8883", useTLS: true, addr: "broker:8883"},
+		{broker: "tls://[::1]:8883", useTLS: true, addr: "[::1]:8883"},
+		{broker: "ws://broker:80", wantErr: true},
+		{broker: "tcp://broker", wantErr: true},
+	}
+	for _, tt := range tests {
+		useTLS, addr, err := parseMQTTBroker(tt.broker)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMQTTBroker(%q) = nil error, want error", tt.broker)
+			}
+			continue
+		}
+		if err != nil || useTLS != tt.useTLS || addr != tt.addr {
+			t.Errorf("parseMQTTBroker(%q) = %v, %q, %v, want %v, %q", tt.broker, useTLS, addr, err, tt.useTLS, tt.addr)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	netTickInterval = 10 * time.Millisecond // 发送节拍：每 10ms 发送一批
+	netPacketSize   = 1400                  // UDP 包大小，避免 IP 分片
+)
+
+// NetController 网络控制器：以可配置的带宽发送 UDP/TCP 流量，让网卡计数器也有合理的波动
+type NetController struct {
+	mu     sync.Mutex
+	proto  string // udp 或 tcp
+	peer   string // 发送目标地址
+	rate   uint64 // 当前发送速率（字节/秒，使用 atomic 保护）
+	sent   uint64 // 累计发送字节数（使用 atomic 保护）
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	sink   io.Closer // 本地接收端
+}
+
+var netController = &NetController{}
+
+// Start 启动网络流量发送
+// peer 为空时在回环地址上启动本地接收端并发送给自己；listen 不为空时额外启动接收端，供其他实例发送
+func (nc *NetController) Start(proto, peer, listen string) error {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if nc.ctx != nil {
+		// 已经启动
+		return nil
+	}
+	if proto != "udp" && proto != "tcp" {
+		return fmt.Errorf("未知的网络协议: %s", proto)
+	}
+
+	if peer == "" && listen == "" {
+		listen = "127.0.0.1:0"
+	}
+	if listen != "" {
+		addr, sink, err := startNetSink(proto, listen)
+		if err != nil {
+			return fmt.Errorf("启动接收端失败: %w", err)
+		}
+		nc.sink = sink
+		if peer == "" {
+			peer = addr
+		}
+	}
+
+	nc.proto = proto
+	nc.peer = peer
+	nc.ctx, nc.cancel = context.WithCancel(context.Background())
+
+	nc.wg.Add(1)
+	go nc.sender()
+	return nil
+}
+
+// Stop 停止网络流量发送
+func (nc *NetController) Stop() {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if nc.cancel != nil {
+		nc.cancel()
+		nc.wg.Wait()
+		nc.ctx = nil
+		nc.cancel = nil
+	}
+	if nc.sink != nil {
+		nc.sink.Close()
+		nc.sink = nil
+	}
+}
+
+// Enabled 网络控制器是否已启动
+func (nc *NetController) Enabled() bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.ctx != nil
+}
+
+// SetRate 设置发送速率（字节/秒）
+func (nc *NetController) SetRate(bytesPerSec uint64) {
+	atomic.StoreUint64(&nc.rate, bytesPerSec)
+}
+
+// GetRate 获取当前发送速率（字节/秒）
+func (nc *NetController) GetRate() uint64 {
+	return atomic.LoadUint64(&nc.rate)
+}
+
+// GetSent 获取累计发送字节数
+func (nc *NetController) GetSent() uint64 {
+	return atomic.LoadUint64(&nc.sent)
+}
+
+// sender 发送协程：按节拍发送数据，连接断开后自动重连
+func (nc *NetController) sender() {
+	defer nc.wg.Done()
+
+	buf := make([]byte, netPacketSize)
+	for i := range buf {
+		buf[i] = byte(i % 256)
+	}
+
+	ticker := time.NewTicker(netTickInterval)
+	defer ticker.Stop()
+
+	var (
+		conn   net.Conn
+		budget uint64 // 当前可发送的字节数
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-nc.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rate := atomic.LoadUint64(&nc.rate)
+		if rate == 0 {
+			budget = 0
+			continue
+		}
+		// 每个节拍累加额度，最多累积 1 秒，避免断线恢复后突发
+		budget = min(budget+rate*uint64(netTickInterval)/uint64(time.Second), rate)
+
+		if conn == nil {
+			var err error
+			conn, err = net.DialTimeout(nc.proto, nc.peer, time.Second)
+			if err != nil {
+				logger.Warn("网络连接失败，稍后重试", "peer", nc.peer, "error", err)
+				conn = nil
+				select {
+				case <-nc.ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+		}
+
+		for budget >= netPacketSize {
+			conn.SetWriteDeadline(time.Now().Add(time.Second))
+			n, err := conn.Write(buf)
+			if err != nil {
+				// UDP 对端不可达时也会返回错误，重建连接即可
+				conn.Close()
+				conn = nil
+				break
+			}
+			budget -= uint64(n)
+			atomic.AddUint64(&nc.sent, uint64(n))
+		}
+	}
+}
+
+// startNetSink 启动接收端，丢弃收到的所有数据，返回实际监听地址
+func startNetSink(proto, listen string) (string, io.Closer, error) {
+	if proto == "udp" {
+		conn, err := net.ListenPacket("udp", listen)
+		if err != nil {
+			return "", nil, err
+		}
+		go func() {
+			buf := make([]byte, 64*1024)
+			for {
+				if _, _, err := conn.ReadFrom(buf); err != nil {
+					return
+				}
+			}
+		}()
+		return conn.LocalAddr().String(), conn, nil
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return "", nil, err
+	}
+	sink := &tcpSink{ln: ln, conns: make(map[net.Conn]struct{})}
+	go sink.serve()
+	return ln.Addr().String(), sink, nil
+}
+
+// tcpSink TCP 接收端：记录已接受的连接，关闭时一起关闭（其他实例的连接不会在 Stop 之后继续占用）
+type tcpSink struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns map[net.Conn]struct{} // nil 表示已关闭
+}
+
+// serve 接受连接并丢弃收到的数据，监听关闭后返回
+func (s *tcpSink) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.conns == nil {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go func() {
+			io.Copy(io.Discard, conn)
+			conn.Close()
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close 关闭监听和所有已接受的连接
+func (s *tcpSink) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.mu.Unlock()
+	return err
+}
@@ -0,0 +1,193 @@
+package busy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nodeProfilePollInterval 检查 ConfigMap 文件是否变化的间隔（kubelet 同步挂载的 ConfigMap 通常需要数十秒）
+const nodeProfilePollInterval = 5 * time.Second
+
+// nodeProfileRule 按节点标签选择配置
+type nodeProfileRule struct {
+	Selector map[string]string `json:"selector"` // 所有标签都相等时匹配
+	Profile  ConfigDocument    `json:"profile"`
+}
+
+// nodeProfiles NODE_PROFILES_FILE 的格式：按节点名、节点标签、默认值的顺序选择第一个匹配的配置
+type nodeProfiles struct {
+	Nodes   map[string]ConfigDocument `json:"nodes"`
+	Labels  []nodeProfileRule         `json:"labels"`
+	Default *ConfigDocument           `json:"default"`
+}
+
+// nodeProfileStore 从挂载的 ConfigMap 中为本节点选择配置文档（实现 configStore，由 ConfigWatcher 应用）
+// 以 DaemonSet 运行时一份清单即可为不同节点设置不同的目标
+type nodeProfileStore struct {
+	path       string
+	node       string // 节点名（NODE_NAME，通常通过 downward API 的 spec.nodeName 注入）
+	labelsPath string // 节点标签文件（NODE_LABELS_FILE，每行 key="value"）
+	lastMatch  string
+}
+
+// newNodeProfileStore 按 NODE_PROFILES_FILE 创建（未设置时返回 nil）
+func newNodeProfileStore() (*nodeProfileStore, string) {
+	path := lookupEnv("NODE_PROFILES_FILE")
+	if path == "" {
+		return nil, ""
+	}
+	node := getEnvString("NODE_NAME", hostname())
+	store := &nodeProfileStore{path: path, node: node, labelsPath: lookupEnv("NODE_LABELS_FILE")}
+	return store, "configmap " + path + " node=" + node
+}
+
+func (ns *nodeProfileStore) Watch(ctx context.Context, lastIndex uint64) ([]byte, bool, uint64, error) {
+	deadline := time.Now().Add(configStoreWait)
+	for {
+		value, found, index, err := ns.read()
+		if err != nil || index != lastIndex || !time.Now().Before(deadline) {
+			return value, found, index, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, 0, ctx.Err()
+		case <-time.After(nodeProfilePollInterval):
+		}
+	}
+}
+
+// read 读取文件并选择本节点的配置，index 为所选配置的哈希（没有匹配的配置时 found 为 false）
+func (ns *nodeProfileStore) read() ([]byte, bool, uint64, error) {
+	profiles, err := loadNodeProfiles(ns.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, 0, nil
+	}
+	if err != nil {
+		return nil, false, 0, err
+	}
+	labels, err := readNodeLabels(ns.labelsPath)
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	profile, match, ok := profiles.selectFor(ns.node, labels)
+	if match != ns.lastMatch {
+		logger.Info("节点配置匹配结果变化", "node", ns.node, "match", match, "previous", ns.lastMatch)
+		ns.lastMatch = match
+	}
+	if !ok {
+		return nil, false, 0, nil
+	}
+	value, err := json.Marshal(profile)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	h := fnv.New64a()
+	h.Write(value)
+	return value, true, h.Sum64() | 1, nil // 最低位置 1，避免与 "没有读取过" 的 0 相同
+}
+
+// loadNodeProfiles 读取并解析 NODE_PROFILES_FILE
+func loadNodeProfiles(path string) (*nodeProfiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profiles nodeProfiles
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("解析节点配置失败: %w", err)
+	}
+	for i, rule := range profiles.Labels {
+		if len(rule.Selector) == 0 {
+			return nil, fmt.Errorf("labels[%d] 的 selector 为空", i)
+		}
+	}
+	return &profiles, nil
+}
+
+// selectFor 按节点名、标签规则（按顺序）、默认值选择配置，match 描述匹配的来源（用于日志）
+func (p *nodeProfiles) selectFor(node string, labels map[string]string) (ConfigDocument, string, bool) {
+	if profile, ok := p.Nodes[node]; ok {
+		return profile, "nodes[" + node + "]", true
+	}
+	for i, rule := range p.Labels {
+		matched := true
+		for key, value := range rule.Selector {
+			if actual, ok := labels[key]; !ok || actual != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule.Profile, "labels[" + strconv.Itoa(i) + "]", true
+		}
+	}
+	if p.Default != nil {
+		return *p.Default, "default", true
+	}
+	return ConfigDocument{}, "none", false
+}
+
+// readNodeLabels 读取 downward API 格式的标签文件（每行 key="value"），path 为空时返回空映射
+func readNodeLabels(path string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if path == "" {
+		return labels, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("标签格式错误: %q（应为 key=\"value\"）", line)
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		labels[strings.TrimSpace(key)] = value
+	}
+	return labels, scanner.Err()
+}
+
+// checkNodeProfiles 校验 NODE_PROFILES_FILE 中的所有配置（check 子命令使用）
+func checkNodeProfiles(path string) error {
+	profiles, err := loadNodeProfiles(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(profiles.Nodes)) {
+		if err := validateConfigDocument(profiles.Nodes[name]); err != nil {
+			return fmt.Errorf("nodes[%s]: %w", name, err)
+		}
+	}
+	for i, rule := range profiles.Labels {
+		if err := validateConfigDocument(rule.Profile); err != nil {
+			return fmt.Errorf("labels[%d]: %w", i, err)
+		}
+	}
+	if profiles.Default != nil {
+		if err := validateConfigDocument(*profiles.Default); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+	return nil
+}
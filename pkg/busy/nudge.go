@@ -0,0 +1,67 @@
+package busy
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	defaultNudgeStep = 5 // 每个信号调整峰值的百分点
+
+	// sigRTMin glibc 的 SIGRTMIN（内核的 32、33 被 glibc 保留），与 shell 中 kill -RTMIN+1 的编号一致
+	sigRTMin = syscall.Signal(34)
+)
+
+var (
+	sigNudgeUp   = sigRTMin + 1 // 峰值增加一步
+	sigNudgeDown = sigRTMin + 2 // 峰值减少一步
+)
+
+// startSignalNudge 监听 SIGRTMIN+1 / SIGRTMIN+2，收到后把峰值增加或减少 step 个百分点
+// 没有 HTTP 接口的主机上，运维可以在 shell 中用 kill -RTMIN+1 <pid> 微调运行中的进程，返回停止监听的函数
+func startSignalNudge(step int) func() {
+	ch := make(chan os.Signal, 4)
+	signal.Notify(ch, sigNudgeUp, sigNudgeDown)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				if sig == sigNudgeUp {
+					nudgePeak(step, "signal SIGRTMIN+1")
+				} else {
+					nudgePeak(-step, "signal SIGRTMIN+2")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// nudgePeak 把原始峰值和当前峰值同时调整 delta 个百分点（限制在 [minPeakUsage, 100]）
+// 本地信号由运维在主机上发送，不受远程修改的频率限制，也不进入托管状态：之后的本地更新围绕新的原始峰值进行
+func nudgePeak(delta int, caller string) {
+	peakUsageMu.Lock()
+	defer peakUsageMu.Unlock()
+
+	oldPeakUsageOrigin, oldPeakUsage := peakUsageOrigin, peakUsage
+	peakUsageOrigin = min(max(peakUsageOrigin+delta, minPeakUsage), 100)
+	peakUsage = min(max(peakUsage+delta, minPeakUsage), peakUsageOrigin)
+	if peakUsageOrigin == oldPeakUsageOrigin && peakUsage == oldPeakUsage {
+		logger.Info("peakUsage 已达到上下限，忽略信号", "caller", caller, "peak_usage_origin", peakUsageOrigin, "peak_usage", peakUsage)
+		return
+	}
+	logger.Info("信号调整 peakUsage",
+		"caller", caller,
+		"peak_usage_origin_old", oldPeakUsageOrigin,
+		"peak_usage_origin", peakUsageOrigin,
+		"peak_usage_old", oldPeakUsage,
+		"peak_usage_new", peakUsage)
+	emitEvent("peak_change", "caller", caller, "peak_usage_origin", peakUsageOrigin, "peak_usage_old", oldPeakUsage, "peak_usage_new", peakUsage)
+}
@@ -0,0 +1,110 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	numaNodeRoot = "/sys/devices/system/node" // NUMA 节点信息
+	mpolBind     = 2                          // MPOL_BIND：只从指定节点分配
+	maxNUMANodes = 64                         // 支持的最大节点编号 + 1（节点掩码为一个 uint64）
+)
+
+// numaBlock 绑定到指定 NUMA 节点的匿名内存（mmap + mbind）
+type numaBlock struct {
+	mem   []byte
+	node  int
+	alloc *numaAllocator
+}
+
+func (b *numaBlock) size() uint64 { return uint64(len(b.mem)) }
+
+func (b *numaBlock) data() []byte { return b.mem }
+
+func (b *numaBlock) free() {
+	b.alloc.nodeBytes[b.node] -= uint64(len(b.mem))
+	if err := syscall.Munmap(b.mem); err != nil {
+		logger.Warn("释放 NUMA 内存失败", "node", b.node, "error", err)
+	}
+}
+
+// numaAllocator 按比例把内存分配到各个 NUMA 节点（调用方持有 MemoryController 的锁）
+type numaAllocator struct {
+	nodes     []int           // 参与分配的节点（升序）
+	weights   map[int]float64 // 各节点的比例（已归一化）
+	nodeBytes map[int]uint64  // 各节点已分配的字节数
+}
+
+// newNUMAAllocator 解析 MEMORY_NUMA_SPLIT（如 "0:70,1:30"），校验节点存在
+func newNUMAAllocator(split string) (*numaAllocator, error) {
+	weights, err := parseIntFloatMap(split)
+	if err != nil {
+		return nil, err
+	}
+	var total float64
+	na := &numaAllocator{weights: make(map[int]float64), nodeBytes: make(map[int]uint64)}
+	for node, weight := range weights {
+		if node < 0 || node >= maxNUMANodes {
+			return nil, fmt.Errorf("节点编号 %d 超出范围 [0, %d)", node, maxNUMANodes)
+		}
+		if weight <= 0 {
+			return nil, fmt.Errorf("节点 %d 的比例必须大于 0", node)
+		}
+		if _, err := os.Stat(fmt.Sprintf("%s/node%d", numaNodeRoot, node)); err != nil {
+			return nil, fmt.Errorf("NUMA 节点 %d 不存在", node)
+		}
+		na.nodes = append(na.nodes, node)
+		total += weight
+	}
+	if len(na.nodes) == 0 {
+		return nil, fmt.Errorf("没有指定节点")
+	}
+	slices.Sort(na.nodes)
+	for node, weight := range weights {
+		na.weights[node] = weight / total
+	}
+	return na, nil
+}
+
+// pickNode 选择已分配量占比最低于目标比例的节点
+func (na *numaAllocator) pickNode(size uint64) int {
+	var total uint64
+	for _, bytes := range na.nodeBytes {
+		total += bytes
+	}
+	total += size
+
+	best, bestDeficit := na.nodes[0], -1.0
+	for _, node := range na.nodes {
+		deficit := na.weights[node] - float64(na.nodeBytes[node])/float64(total)
+		if deficit > bestDeficit {
+			best, bestDeficit = node, deficit
+		}
+	}
+	return best
+}
+
+// allocate 分配一块绑定到节点的匿名内存，并写入数据使物理页真正分配
+func (na *numaAllocator) allocate(size uint64) (memoryBlock, error) {
+	node := na.pickNode(size)
+	mem, err := syscall.Mmap(-1, 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return nil, err
+	}
+	mask := uint64(1) << node
+	_, _, errno := syscall.Syscall6(syscall.SYS_MBIND, uintptr(unsafe.Pointer(&mem[0])), uintptr(size),
+		mpolBind, uintptr(unsafe.Pointer(&mask)), maxNUMANodes+1, 0)
+	if errno != 0 {
+		syscall.Munmap(mem)
+		return nil, fmt.Errorf("mbind 节点 %d 失败: %w", node, errno)
+	}
+	for j := range mem {
+		mem[j] = byte(j % 256)
+	}
+	na.nodeBytes[node] += size
+	return &numaBlock{mem: mem, node: node, alloc: na}, nil
+}
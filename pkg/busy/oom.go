@@ -0,0 +1,290 @@
+package busy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultOOMReleasePercent = 10               // 默认发生 OOM 时释放多少内存缓冲区（占总内存的百分比）
+	defaultOOMHold           = 10 * time.Minute // 默认发生 OOM 后多长时间内不增加内存占用
+	oomPollInterval          = time.Second      // 读取 OOM 计数的间隔（无法读取内核日志时）
+)
+
+// oomKilledPattern 内核日志中 OOM killer 杀死进程的记录，如
+// "Out of memory: Killed process 1234 (java) total-vm:..."、"Memory cgroup out of memory: Killed process 1234 (java) ..."
+var oomKilledPattern = regexp.MustCompile(`Killed process (\d+) \(([^)]*)\)`)
+
+// OOMKill 一次 OOM 事件
+type OOMKill struct {
+	Time              time.Time `json:"time"`
+	Source            string    `json:"source"`              // 检测来源：kmsg、vmstat 或 cgroup
+	Count             uint64    `json:"count"`               // 本次检测到的被杀死的进程数
+	PID               int       `json:"pid,omitempty"`       // 被杀死的进程（只有 kmsg 来源有）
+	Process           string    `json:"process,omitempty"`   // 被杀死的进程名
+	SelfMemoryBytes   uint64    `json:"self_memory_bytes"`   // 发生时本进程的常驻内存
+	BufferMemoryBytes uint64    `json:"buffer_memory_bytes"` // 发生时本进程的内存缓冲区
+	ReleasedBytes     uint64    `json:"released_bytes"`      // 释放的内存缓冲区
+	MemoryPercent     float64   `json:"memory_percent"`      // 上个周期的整机内存使用率
+	SelfMemoryPercent float64   `json:"self_memory_percent"` // 上个周期本进程的内存使用率
+	HoldUntil         time.Time `json:"hold_until,omitzero"` // 在此之前不增加内存占用
+	Message           string    `json:"message,omitempty"`   // 内核日志的原文
+}
+
+// OOMStatus OOM 事件的统计（GET /status 的 oom 字段）
+type OOMStatus struct {
+	Source string   `json:"source"`         // 检测来源
+	Total  uint64   `json:"total"`          // 启动以来检测到的被杀死的进程数
+	Last   *OOMKill `json:"last,omitempty"` // 最近一次 OOM 事件
+}
+
+// OOMWatcher OOM killer 检测：同机有进程被 OOM killer 杀死时，立即释放一部分内存缓冲区、在一段时间内不再增加内存占用，
+// 记录事件并调用 webhook，用于判断合成负载是否导致了真实服务被杀死
+// 优先读取内核日志（/dev/kmsg，可以得到被杀死的进程），没有权限时读取 /proc/vmstat 的 oom_kill 计数（4.13 及以上），
+// 都不可用时读取本进程所在 cgroup（v2）的 memory.events
+type OOMWatcher struct {
+	mu             sync.Mutex
+	releasePercent float64
+	hold           time.Duration
+	webhook        string
+	totalMemory    uint64
+	source         string
+	total          uint64
+	last           *OOMKill
+	holdUntil      time.Time
+	stats          SystemStats // 上个周期的资源信息（事件中记录发生时的内存使用率）
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var oomWatcher = &OOMWatcher{}
+
+// Start 开始检测 OOM 事件
+func (ow *OOMWatcher) Start(releasePercent float64, hold time.Duration, webhook string, totalMemory uint64) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	if ow.cancel != nil {
+		return
+	}
+	ow.releasePercent = min(max(releasePercent, 0), 100)
+	ow.hold = max(hold, 0)
+	ow.webhook = webhook
+	ow.totalMemory = totalMemory
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if kmsg, err := openKmsg(); err == nil {
+		ow.source = "kmsg"
+		ow.wg.Add(1)
+		go ow.tailKmsg(ctx, kmsg)
+	} else if count, source, err := readOOMCount(); err == nil {
+		ow.source = source
+		ow.wg.Add(1)
+		go ow.pollCount(ctx, source, count)
+	} else {
+		cancel()
+		logger.Warn("无法读取内核日志和 OOM 计数，不检测 OOM", "error", err)
+		return
+	}
+	ow.cancel = cancel
+	logger.Info("已启用 OOM 检测", "source", ow.source, "release_percent", ow.releasePercent, "hold", ow.hold)
+}
+
+// Stop 停止检测
+func (ow *OOMWatcher) Stop() {
+	ow.mu.Lock()
+	cancel := ow.cancel
+	ow.cancel = nil
+	ow.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		ow.wg.Wait()
+	}
+}
+
+// Observe 记录上个周期的资源信息，发生 OOM 时写入事件
+func (ow *OOMWatcher) Observe(stats *SystemStats) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	ow.stats = *stats
+	if stats.TotalMemory > 0 {
+		ow.totalMemory = stats.TotalMemory
+	}
+}
+
+// openKmsg 打开内核日志并跳到末尾（只处理启动之后的记录）
+// 以非阻塞方式打开，使 Close 能够结束正在等待的 Read
+func openKmsg() (*os.File, error) {
+	file, err := os.OpenFile("/dev/kmsg", os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// tailKmsg 逐条读取内核日志（每次 Read 返回一条记录），遇到 OOM killer 的记录时处理
+func (ow *OOMWatcher) tailKmsg(ctx context.Context, file *os.File) {
+	defer ow.wg.Done()
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+
+	buf := make([]byte, 8192)
+	for {
+		n, err := file.Read(buf)
+		switch {
+		case errors.Is(err, syscall.EPIPE):
+			// 读取期间有记录被覆盖，从下一条继续
+			continue
+		case err != nil:
+			if ctx.Err() == nil {
+				logger.Warn("读取内核日志失败，停止检测 OOM", "error", err)
+			}
+			return
+		}
+		// 记录格式为 "优先级,序号,时间戳,标志;消息"
+		_, message, _ := strings.Cut(strings.TrimSpace(string(buf[:n])), ";")
+		if match := oomKilledPattern.FindStringSubmatch(message); match != nil {
+			pid, _ := strconv.Atoi(match[1])
+			ow.handle(&OOMKill{Source: "kmsg", Count: 1, PID: pid, Process: match[2], Message: message})
+		}
+	}
+}
+
+// pollCount 按间隔读取 OOM 计数，增加时处理
+func (ow *OOMWatcher) pollCount(ctx context.Context, source string, last uint64) {
+	defer ow.wg.Done()
+	ticker := time.NewTicker(oomPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		count, _, err := readOOMCount()
+		if err != nil {
+			continue
+		}
+		if count > last {
+			ow.handle(&OOMKill{Source: source, Count: count - last})
+		}
+		last = count
+	}
+}
+
+// readOOMCount 读取累计的 OOM 次数：/proc/vmstat 的 oom_kill，没有时读取本进程所在 cgroup 的 memory.events
+func readOOMCount() (uint64, string, error) {
+	if count, err := readCounter(procPath("vmstat"), "oom_kill"); err == nil {
+		return count, "vmstat", nil
+	}
+	dir, err := selfCgroupDir()
+	if err != nil {
+		return 0, "", err
+	}
+	count, err := readCounter(filepath.Join(dir, "memory.events"), "oom_kill")
+	return count, "cgroup", err
+}
+
+// readCounter 读取 "名称 数值" 格式文件中的一项
+func readCounter(path, name string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), name+" "); ok {
+			return strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New(path + " 中没有 " + name)
+}
+
+// handle 处理一次 OOM 事件：释放一部分内存缓冲区，记录事件并调用 webhook
+func (ow *OOMWatcher) handle(kill *OOMKill) {
+	ow.mu.Lock()
+	kill.Time = clock.Now()
+	kill.SelfMemoryBytes = ow.stats.SelfMemory
+	kill.BufferMemoryBytes = memoryController.GetBufferMemory()
+	kill.MemoryPercent = ow.stats.MemoryPercent
+	kill.SelfMemoryPercent = ow.stats.SelfMemoryPercent
+	if chunk := uint64(float64(ow.totalMemory) * ow.releasePercent / 100); chunk > 0 {
+		kill.ReleasedBytes = memoryController.ShrinkTo(kill.BufferMemoryBytes - min(chunk, kill.BufferMemoryBytes))
+	}
+	if ow.hold > 0 {
+		ow.holdUntil = kill.Time.Add(ow.hold)
+		kill.HoldUntil = ow.holdUntil
+	}
+	ow.total += kill.Count
+	ow.last = kill
+	webhook := ow.webhook
+	ow.mu.Unlock()
+
+	logger.Warn("检测到 OOM killer 杀死进程，释放部分内存并暂停增加内存占用",
+		"source", kill.Source,
+		"count", kill.Count,
+		"pid", kill.PID,
+		"process", kill.Process,
+		"self_memory_mb", kill.SelfMemoryBytes/(1024*1024),
+		"released_mb", kill.ReleasedBytes/(1024*1024),
+		"hold_until", kill.HoldUntil)
+	emitEvent("oom_kill", "resource", "内存", "source", kill.Source, "count", kill.Count, "pid", kill.PID, "process", kill.Process,
+		"self_memory_bytes", kill.SelfMemoryBytes, "buffer_memory_bytes", kill.BufferMemoryBytes, "released_bytes", kill.ReleasedBytes,
+		"memory_percent", kill.MemoryPercent, "self_memory_percent", kill.SelfMemoryPercent)
+
+	if webhook != "" {
+		go postStallWebhook(webhook, map[string]any{
+			"event":               "oom_kill",
+			"source":              kill.Source,
+			"count":               kill.Count,
+			"pid":                 kill.PID,
+			"process":             kill.Process,
+			"message":             kill.Message,
+			"self_memory_bytes":   kill.SelfMemoryBytes,
+			"buffer_memory_bytes": kill.BufferMemoryBytes,
+			"released_bytes":      kill.ReleasedBytes,
+			"memory_percent":      kill.MemoryPercent,
+			"self_memory_percent": kill.SelfMemoryPercent,
+			"hostname":            hostname(),
+			"time":                kill.Time.UTC(),
+		})
+	}
+}
+
+// Holding 是否处于发生 OOM 后不增加内存占用的时段
+func (ow *OOMWatcher) Holding() bool {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	return clock.Now().Before(ow.holdUntil)
+}
+
+// Status OOM 事件的统计，没有启用时返回 nil
+func (ow *OOMWatcher) Status() *OOMStatus {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	if ow.cancel == nil {
+		return nil
+	}
+	return &OOMStatus{Source: ow.source, Total: ow.total, Last: ow.last}
+}
@@ -0,0 +1,39 @@
+package busy
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// loadCPUNs CPU 工作协程和突发协程用于计算的累计时间（纳秒），即有意产生的 CPU 负载
+var loadCPUNs atomic.Int64
+
+// overheadTracker 把本进程的 CPU / 内存拆分为有意产生的负载和控制本身的开销
+// （监控循环、日志、GC、Go 运行时、负载模块等）
+type overheadTracker struct {
+	lastNs   int64
+	lastTime time.Time
+}
+
+var selfOverhead = &overheadTracker{}
+
+// Sample 根据上次调用以来的计算时间填充 stats 中的负载和开销字段
+// 需在 SelfCPUPercent、SelfMemory、SelfSwap 计算之后调用；bufferBytes 为内存缓冲区的大小
+func (ot *overheadTracker) Sample(stats *SystemStats, bufferBytes uint64) {
+	now := clock.Now()
+	ns := loadCPUNs.Load()
+	if !ot.lastTime.IsZero() {
+		elapsed := now.Sub(ot.lastTime).Seconds() * float64(numCPU())
+		if elapsed > 0 {
+			stats.LoadCPUPercent = min(float64(ns-ot.lastNs)/1e9/elapsed*100, stats.SelfCPUPercent)
+		}
+	}
+	ot.lastNs, ot.lastTime = ns, now
+
+	stats.OverheadCPUPercent = max(stats.SelfCPUPercent-stats.LoadCPUPercent, 0)
+	if self := stats.SelfMemory + stats.SelfSwap; self > bufferBytes {
+		stats.OverheadMemory = self - bufferBytes
+	} else {
+		stats.OverheadMemory = 0
+	}
+}
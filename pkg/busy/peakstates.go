@@ -0,0 +1,164 @@
+package busy
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+)
+
+// peakStateSpec 状态文件中的一个状态
+type peakStateSpec struct {
+	Low      float64            `json:"low"`        // 该状态下 peakUsage 的下限系数（× P）
+	High     float64            `json:"high"`       // 该状态下 peakUsage 的上限系数（× P）
+	DwellMin string             `json:"dwell_min"`  // 最短停留时间（如 "30m"）
+	DwellMax string             `json:"dwell_max"`  // 最长停留时间
+	DwellDis string             `json:"dwell_dist"` // 停留时间的分布：uniform（默认）或 exponential
+	Next     map[string]float64 `json:"next"`       // 停留结束后转移到各状态的权重
+}
+
+// peakStatesFile PEAK_STATES_FILE 的格式
+type peakStatesFile struct {
+	Initial string                   `json:"initial"` // 初始状态（不设置时取名称排序后的第一个）
+	States  map[string]peakStateSpec `json:"states"`
+}
+
+// peakState 解析后的状态
+type peakState struct {
+	low, high          float64
+	dwellMin, dwellMax time.Duration
+	exponential        bool
+	next               []string  // 可转移到的状态
+	weights            []float64 // 对应的累积概率
+}
+
+// peakStateMachine 多状态的 peakUsage 模型（马尔可夫链）：在每个状态停留随机的时间，
+// 停留期间 peakUsage 在该状态的范围内随机更新，停留结束后按转移概率切换状态，
+// 能产生 "长时间空闲、偶尔繁忙、短暂峰值" 这类比无记忆随机更真实的长期曲线
+type peakStateMachine struct {
+	states    map[string]*peakState
+	current   string
+	remaining time.Duration // 当前状态剩余的停留时间
+}
+
+// peakStates PEAK_STATES_FILE 加载的状态机（nil 表示使用 PEAK_* 的随机波动）
+var peakStates *peakStateMachine
+
+// loadPeakStates 读取并校验状态文件
+func loadPeakStates(path string) (*peakStateMachine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file peakStatesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	if len(file.States) == 0 {
+		return nil, fmt.Errorf("没有定义状态")
+	}
+
+	sm := &peakStateMachine{states: make(map[string]*peakState)}
+	for name, spec := range file.States {
+		if spec.Low < 0 || spec.High > 1 || spec.Low > spec.High {
+			return nil, fmt.Errorf("状态 %s 的范围无效: [%g, %g]（应满足 0 <= low <= high <= 1）", name, spec.Low, spec.High)
+		}
+		state := &peakState{low: spec.Low, high: spec.High}
+		if state.dwellMin, err = time.ParseDuration(spec.DwellMin); err != nil {
+			return nil, fmt.Errorf("状态 %s 的 dwell_min 无效: %q", name, spec.DwellMin)
+		}
+		if state.dwellMax, err = time.ParseDuration(spec.DwellMax); err != nil {
+			return nil, fmt.Errorf("状态 %s 的 dwell_max 无效: %q", name, spec.DwellMax)
+		}
+		if state.dwellMax < state.dwellMin {
+			return nil, fmt.Errorf("状态 %s 的 dwell_max 小于 dwell_min", name)
+		}
+		switch spec.DwellDis {
+		case "", "uniform":
+		case "exponential":
+			state.exponential = true
+		default:
+			return nil, fmt.Errorf("状态 %s 的 dwell_dist 无效: %q（可选：uniform, exponential）", name, spec.DwellDis)
+		}
+
+		var total float64
+		for next, weight := range spec.Next {
+			if _, ok := file.States[next]; !ok {
+				return nil, fmt.Errorf("状态 %s 转移到未定义的状态 %s", name, next)
+			}
+			if weight < 0 {
+				return nil, fmt.Errorf("状态 %s 到 %s 的权重不能为负数", name, next)
+			}
+			total += weight
+		}
+		if total <= 0 {
+			return nil, fmt.Errorf("状态 %s 没有转移", name)
+		}
+		// 按名称排序，使同一个随机数总是对应同一个转移
+		for _, next := range slices.Sorted(maps.Keys(spec.Next)) {
+			state.next = append(state.next, next)
+			state.weights = append(state.weights, spec.Next[next]/total)
+		}
+		for j := 1; j < len(state.weights); j++ {
+			state.weights[j] += state.weights[j-1]
+		}
+		sm.states[name] = state
+	}
+
+	sm.current = file.Initial
+	if sm.current == "" {
+		sm.current = slices.Sorted(maps.Keys(file.States))[0]
+	}
+	if _, ok := sm.states[sm.current]; !ok {
+		return nil, fmt.Errorf("初始状态 %s 未定义", sm.current)
+	}
+	sm.remaining = sm.states[sm.current].dwell()
+	return sm, nil
+}
+
+// dwell 随机生成停留时间：uniform 在 [min, max] 内均匀分布；
+// exponential 以 (min + max) / 2 为均值的指数分布，截断到 [min, max]
+func (s *peakState) dwell() time.Duration {
+	if s.exponential {
+		mean := float64(s.dwellMin+s.dwellMax) / 2
+		return min(max(time.Duration(rand.ExpFloat64()*mean), s.dwellMin), s.dwellMax)
+	}
+	return s.dwellMin + time.Duration(rand.Int63n(int64(s.dwellMax-s.dwellMin)+1))
+}
+
+// advance 推进 elapsed（每次更新 peakUsage 时为 peakUsageInterval），停留结束时按转移概率切换状态
+func (sm *peakStateMachine) advance(elapsed time.Duration) {
+	sm.remaining -= elapsed
+	for sm.remaining <= 0 {
+		state := sm.states[sm.current]
+		r := rand.Float64()
+		next := state.next[len(state.next)-1]
+		for j, cumulative := range state.weights {
+			if r < cumulative {
+				next = state.next[j]
+				break
+			}
+		}
+		sm.current = next
+		// 停留时间至少一个更新周期，避免 dwell_max 为 0 时死循环
+		sm.remaining += max(sm.states[next].dwell(), peakUsageInterval)
+	}
+}
+
+// bounds 所有状态范围的并集
+func (sm *peakStateMachine) bounds() (float64, float64) {
+	low, high := 1.0, 0.0
+	for _, state := range sm.states {
+		low, high = min(low, state.low), max(high, state.high)
+	}
+	return low, high
+}
+
+// factor 在当前状态的范围内随机生成 peakUsage 系数
+func (sm *peakStateMachine) factor() float64 {
+	state := sm.states[sm.current]
+	return state.low + rand.Float64()*(state.high-state.low)
+}
@@ -0,0 +1,99 @@
+package busy
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultObjectivePercentile = 95               // 分位数模式下默认的分位数
+	defaultPercentileWindow    = 10 * time.Minute // 分位数模式下默认的滑动窗口
+	minPercentileSamples       = 20               // 窗口内至少有多少个记录才修正目标（之前直接按期望值控制）
+)
+
+// percentileSample 一个监控周期的 CPU 占用
+type percentileSample struct {
+	at    time.Time
+	value float64
+}
+
+// PercentileObjective 分位数目标（CPU_OBJECTIVE=percentile）：使滑动窗口内 CPU 占用的分位数（如 10 分钟的 p95）接近期望值，
+// 与 SLO 看板的统计方式一致，而不是让每个周期的瞬时值接近期望值
+// 瞬时控制的目标 = 期望值 − (窗口内的分位数 − 窗口内的平均值)：波动越大（如突发较多），瞬时目标越低，
+// 分位数与平均值之差只取决于波动的形状，目标变化后不需要等待整个窗口重新积累
+type PercentileObjective struct {
+	mu         sync.Mutex
+	percentile float64 // 分位数（0-100）
+	window     time.Duration
+	samples    []percentileSample
+}
+
+var percentileObjective = &PercentileObjective{percentile: defaultObjectivePercentile, window: defaultPercentileWindow}
+
+// Configure 设置分位数和滑动窗口，清空已有的记录
+func (po *PercentileObjective) Configure(percentile float64, window time.Duration) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	po.percentile = min(max(percentile, 1), 100)
+	po.window = max(window, monitorInterval)
+	po.samples = nil
+}
+
+// Observe 记录一个周期的 CPU 占用，丢弃超出窗口的记录
+func (po *PercentileObjective) Observe(now time.Time, value float64) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	po.samples = append(po.samples, percentileSample{at: now, value: value})
+
+	cutoff := now.Add(-po.window)
+	drop := 0
+	for drop < len(po.samples) && po.samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	po.samples = po.samples[drop:]
+}
+
+// Stats 窗口内的分位数（最近秩法）、平均值和记录数
+func (po *PercentileObjective) Stats() (float64, float64, int) {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	n := len(po.samples)
+	if n == 0 {
+		return 0, 0, 0
+	}
+	values := make([]float64, n)
+	var sum float64
+	for i, sample := range po.samples {
+		values[i] = sample.value
+		sum += sample.value
+	}
+	slices.Sort(values)
+	rank := int(math.Ceil(po.percentile / 100 * float64(n)))
+	return values[min(max(rank, 1), n)-1], sum / float64(n), n
+}
+
+// Percentile 设置的分位数
+func (po *PercentileObjective) Percentile() float64 {
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	return po.percentile
+}
+
+// Setpoint 瞬时控制的目标：期望值减去窗口内分位数与平均值之差，限制在 [MIN_USAGE, 硬峰值] 之间
+// 记录不足 minPercentileSamples 个时（刚启动）直接返回期望值
+func (po *PercentileObjective) Setpoint(expectedUsage float64) float64 {
+	value, mean, n := po.Stats()
+	if n < minPercentileSamples {
+		return expectedUsage
+	}
+	setpoint := min(max(expectedUsage-(value-mean), minUsage), hardPeak())
+	cycleLog.Info("CPU 分位数目标",
+		"percentile", po.Percentile(),
+		"window_value", value,
+		"window_mean", mean,
+		"samples", n,
+		"setpoint", setpoint)
+	return setpoint
+}
@@ -0,0 +1,216 @@
+package busy
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	starmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// policyMaxExecutionSteps 策略脚本每个周期最多执行的 Starlark 指令数（防止死循环阻塞主循环）
+const policyMaxExecutionSteps = 1_000_000
+
+// policyResources 策略脚本可以覆盖概率的资源（脚本输出的键名前缀 → 调整日志中的资源名称）
+var policyResources = map[string]string{
+	"cpu":    "CPU",
+	"memory": "内存",
+}
+
+// policyProb 策略脚本给出的概率和步数（NaN 表示使用内置算法）
+type policyProb struct {
+	adjust   float64 // 执行调整的概率
+	increase float64 // 增加占用的概率
+	steps    float64 // 本周期调整的步数（CONTROL_STRATEGY=script 时使用）
+}
+
+// policyRuntime 加载后的策略脚本：脚本定义的 policy 函数和跨周期保留的状态
+type policyRuntime struct {
+	fn      *starlark.Function // policy(obs, state)
+	state   *starlark.Dict     // 每次调用传入同一个字典，脚本可以在其中保存历史状态
+	lastErr string             // 上一次执行失败的原因（相同的错误只记录一次日志）
+}
+
+var (
+	policyScript *policyRuntime        // POLICY_SCRIPT 加载后的脚本（nil 表示不使用）
+	policyProbs  map[string]policyProb // 本周期策略脚本给出的概率（按资源名称，只在主循环中读写）
+)
+
+// loadConfiguredPolicy 按 POLICY_SCRIPT 加载策略脚本（未设置或无效时不使用）
+func loadConfiguredPolicy() {
+	policyScript = nil
+	policyProbs = nil
+	path := lookupEnv("POLICY_SCRIPT")
+	if path == "" {
+		return
+	}
+	script, err := loadPolicyScript(path)
+	if err != nil {
+		logger.Warn("加载策略脚本失败，使用内置算法", "path", path, "error", err)
+		return
+	}
+	policyScript = script
+	logger.Info("已加载策略脚本", "path", path)
+}
+
+// loadPolicyScript 读取并加载 Starlark 策略脚本
+func loadPolicyScript(path string) (*policyRuntime, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return compilePolicyScript(path, data)
+}
+
+// compilePolicyScript 执行脚本的顶层语句，取出其中定义的 policy(obs, state) 函数
+// 允许 while 循环和 set；顶层语句只在加载时执行一次，之后全局变量被冻结，需要修改的状态放在 state 中
+func compilePolicyScript(filename string, src []byte) (*policyRuntime, error) {
+	thread := newPolicyThread()
+	opts := &syntax.FileOptions{While: true, Set: true}
+	globals, err := starlark.ExecFileOptions(opts, thread, filename, src, starlark.StringDict{"math": starmath.Module})
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := globals["policy"].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("脚本没有定义 policy(obs, state) 函数")
+	}
+	if fn.NumParams() != 2 || fn.HasVarargs() || fn.HasKwargs() {
+		return nil, fmt.Errorf("policy 函数应当只有两个参数 (obs, state)")
+	}
+	return &policyRuntime{fn: fn, state: starlark.NewDict(0)}, nil
+}
+
+// newPolicyThread 创建执行策略脚本的线程：限制指令数，print 输出写入日志
+func newPolicyThread() *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: "policy",
+		Print: func(_ *starlark.Thread, msg string) {
+			logger.Info("策略脚本输出", "msg", msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(policyMaxExecutionSteps)
+	return thread
+}
+
+// policyObservations 传给 policy 函数的观测值：TARGET_EXPR 的变量、期望值和参与控制的占用值
+func policyObservations(now time.Time, peakUsage int, stats *SystemStats, expectedUsage float64) *starlark.Dict {
+	env := targetExprEnv(now, peakUsage, stats)
+	env["expected"] = expectedUsage
+	env["cpu_current"] = scopedCPUPercent(stats)
+	env["memory_current"] = scopedMemoryPercent(stats)
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	obs := starlark.NewDict(len(env))
+	for _, name := range names {
+		obs.SetKey(starlark.String(name), starlark.Float(env[name]))
+	}
+	return obs
+}
+
+// policyOutputs policy 函数返回的字典中允许的键
+func policyOutputs() map[string]bool {
+	outputs := map[string]bool{"target": true}
+	for prefix := range policyResources {
+		outputs[prefix+"_adjust_prob"] = true
+		outputs[prefix+"_increase_prob"] = true
+		outputs[prefix+"_steps"] = true
+	}
+	return outputs
+}
+
+// callPolicy 调用 policy 函数，返回脚本给出的输出（未给出或为 None 的键不包含在结果中）
+func (pr *policyRuntime) callPolicy(obs *starlark.Dict) (map[string]float64, error) {
+	result, err := starlark.Call(newPolicyThread(), pr.fn, starlark.Tuple{obs, pr.state}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if result == starlark.None {
+		return nil, nil
+	}
+	dict, ok := result.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("policy 应当返回字典或 None，实际返回 %s", result.Type())
+	}
+
+	allowed := policyOutputs()
+	outputs := make(map[string]float64, dict.Len())
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok || !allowed[key] {
+			return nil, fmt.Errorf("policy 返回了未知的键 %s", item[0])
+		}
+		if item[1] == starlark.None {
+			continue
+		}
+		value, ok := starlark.AsFloat(item[1])
+		if !ok {
+			return nil, fmt.Errorf("policy 返回的 %s 应当是数字，实际为 %s", key, item[1].Type())
+		}
+		outputs[key] = value
+	}
+	return outputs, nil
+}
+
+// runPolicyScript 执行策略脚本，返回期望占用值，并更新本周期各资源的调整概率
+// 脚本执行失败时本周期使用原期望值和内置算法
+func runPolicyScript(now time.Time, peakUsage int, stats *SystemStats, expectedUsage float64) float64 {
+	policyProbs = nil
+	outputs, err := policyScript.callPolicy(policyObservations(now, peakUsage, stats, expectedUsage))
+	if err != nil {
+		if msg := err.Error(); msg != policyScript.lastErr {
+			logger.Warn("策略脚本执行失败，本周期使用内置算法", "error", msg)
+			policyScript.lastErr = msg
+		}
+		return expectedUsage
+	}
+	policyScript.lastErr = ""
+
+	output := func(key string) float64 {
+		if value, ok := outputs[key]; ok {
+			return value
+		}
+		return math.NaN()
+	}
+	policyProbs = make(map[string]policyProb, len(policyResources))
+	for prefix, name := range policyResources {
+		policyProbs[name] = policyProb{
+			adjust:   output(prefix + "_adjust_prob"),
+			increase: output(prefix + "_increase_prob"),
+			steps:    output(prefix + "_steps"),
+		}
+	}
+
+	target, ok := outputs["target"]
+	if !ok {
+		return expectedUsage
+	}
+	if math.IsNaN(target) || math.IsInf(target, 0) {
+		logger.Warn("策略脚本给出的 target 无效，使用原期望值", "target", target)
+		return expectedUsage
+	}
+	return min(max(target, minUsage), hardPeakAt(now))
+}
+
+// policyOverride 用策略脚本给出的概率替换内置算法的概率（未给出或无效时保持不变）
+func policyOverride(name string, adjustProb, increaseProb float64) (float64, float64) {
+	prob, ok := policyProbs[name]
+	if !ok {
+		return adjustProb, increaseProb
+	}
+	if !math.IsNaN(prob.adjust) {
+		adjustProb = min(max(prob.adjust, 0), 1)
+	}
+	if !math.IsNaN(prob.increase) {
+		increaseProb = min(max(prob.increase, 0), 1)
+	}
+	return adjustProb, increaseProb
+}
@@ -0,0 +1,196 @@
+package busy
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestPolicy 编译策略脚本并设为当前使用的脚本，测试结束后恢复
+func useTestPolicy(t *testing.T, src string) {
+	t.Helper()
+	script, err := compilePolicyScript("test.star", []byte(src))
+	if err != nil {
+		t.Fatalf("compilePolicyScript() error: %v", err)
+	}
+	oldScript, oldProbs := policyScript, policyProbs
+	policyScript = script
+	t.Cleanup(func() { policyScript, policyProbs = oldScript, oldProbs })
+}
+
+func TestCompilePolicyScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "valid", src: "def policy(obs, state):\n    return None\n"},
+		{name: "helpers", src: "LIMIT = 60\ndef clamp(x):\n    return min(x, LIMIT)\ndef policy(obs, state):\n    return {\"target\": clamp(obs[\"expected\"])}\n"},
+		{name: "syntax-error", src: "def policy(obs, state)\n    return None\n", wantErr: "got newline"},
+		{name: "missing", src: "x = 1\n", wantErr: "没有定义 policy"},
+		{name: "not-function", src: "policy = 1\n", wantErr: "没有定义 policy"},
+		{name: "arity", src: "def policy(obs):\n    return None\n", wantErr: "两个参数"},
+		{name: "kwargs", src: "def policy(obs, state, **kwargs):\n    return None\n", wantErr: "两个参数"},
+		{name: "top-level-error", src: "x = 1 // 0\ndef policy(obs, state):\n    return None\n", wantErr: "division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compilePolicyScript("test.star", []byte(tt.src))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("compilePolicyScript() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("compilePolicyScript() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunPolicyScript(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	nan := math.NaN()
+	unset := map[string]policyProb{"CPU": {nan, nan, nan}, "内存": {nan, nan, nan}}
+	tests := []struct {
+		name   string
+		src    string
+		target float64
+		probs  map[string]policyProb // nil 表示本周期没有脚本给出的概率
+	}{
+		{
+			name:   "none",
+			src:    "def policy(obs, state):\n    return None\n",
+			target: 40,
+			probs:  unset,
+		},
+		{
+			name:   "outputs",
+			src:    "def policy(obs, state):\n    return {\"target\": obs[\"expected\"] + 5, \"cpu_adjust_prob\": 1, \"cpu_increase_prob\": 0.25, \"memory_steps\": -3, \"cpu_steps\": None}\n",
+			target: 45,
+			probs:  map[string]policyProb{"CPU": {1, 0.25, nan}, "内存": {nan, nan, -3}},
+		},
+		{
+			// 观测值包括按 TARGET_SCOPE 参与控制的占用值
+			name:   "observations",
+			src:    "def policy(obs, state):\n    return {\"target\": obs[\"cpu_current\"] + obs[\"peak\"] / 10}\n",
+			target: 35,
+			probs:  unset,
+		},
+		{name: "clamp-high", src: "def policy(obs, state):\n    return {\"target\": 1000}\n", target: hardPeakAt(now), probs: unset},
+		{name: "clamp-low", src: "def policy(obs, state):\n    return {\"target\": -5}\n", target: minUsage, probs: unset},
+		{name: "nan-target", src: "def policy(obs, state):\n    return {\"target\": float(\"nan\")}\n", target: 40, probs: unset},
+		// 执行失败时本周期使用原期望值，不给出任何概率
+		{name: "runtime-error", src: "def policy(obs, state):\n    return {\"target\": obs[\"missing\"]}\n", target: 40},
+		{name: "wrong-type", src: "def policy(obs, state):\n    return 50\n", target: 40},
+		{name: "unknown-key", src: "def policy(obs, state):\n    return {\"cpu_prob\": 1}\n", target: 40},
+		{name: "not-number", src: "def policy(obs, state):\n    return {\"target\": \"50\"}\n", target: 40},
+		{name: "step-limit", src: "def policy(obs, state):\n    while True:\n        pass\n", target: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestPolicy(t, tt.src)
+			stats := &SystemStats{CPUPercent: 30}
+			if got := runPolicyScript(now, 50, stats, 40); got != tt.target {
+				t.Errorf("runPolicyScript() = %v, want %v", got, tt.target)
+			}
+			if len(policyProbs) != len(tt.probs) {
+				t.Fatalf("policyProbs = %v, want %v", policyProbs, tt.probs)
+			}
+			for name, want := range tt.probs {
+				got := policyProbs[name]
+				if !sameFloat(got.adjust, want.adjust) || !sameFloat(got.increase, want.increase) || !sameFloat(got.steps, want.steps) {
+					t.Errorf("policyProbs[%s] = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// sameFloat 比较浮点数，两个 NaN 视为相等
+func sameFloat(a, b float64) bool {
+	return a == b || math.IsNaN(a) && math.IsNaN(b)
+}
+
+// TestRunPolicyScriptState state 在周期之间保留，可以实现积分等依赖历史的策略
+func TestRunPolicyScriptState(t *testing.T) {
+	useTestPolicy(t, `
+def policy(obs, state):
+    err = obs["expected"] - obs["cpu_current"]
+    state["integral"] = state.get("integral", 0) + err
+    state["cycles"] = state.get("cycles", 0) + 1
+    return {"cpu_steps": state["integral"], "target": state["cycles"]}
+`)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for cycle, want := range []float64{10, 20, 30} {
+		target := runPolicyScript(now, 50, &SystemStats{CPUPercent: 30}, 40)
+		if got := policyProbs["CPU"].steps; got != want {
+			t.Errorf("cycle %d: cpu_steps = %v, want %v", cycle+1, got, want)
+		}
+		if wantTarget := max(float64(cycle+1), minUsage); target != wantTarget {
+			t.Errorf("cycle %d: target = %v, want %v", cycle+1, target, wantTarget)
+		}
+	}
+
+	// 重新加载脚本时状态清空
+	useTestPolicy(t, "def policy(obs, state):\n    return {\"cpu_steps\": len(state)}\n")
+	runPolicyScript(now, 50, &SystemStats{}, 40)
+	if got := policyProbs["CPU"].steps; got != 0 {
+		t.Errorf("cpu_steps after reload = %v, want 0", got)
+	}
+}
+
+func TestPolicyOverride(t *testing.T) {
+	nan := math.NaN()
+	oldProbs := policyProbs
+	t.Cleanup(func() { policyProbs = oldProbs })
+	policyProbs = map[string]policyProb{
+		"CPU": {adjust: 1.5, increase: -0.5, steps: nan},
+		"内存":  {adjust: nan, increase: 0.3, steps: nan},
+	}
+	tests := []struct {
+		name                 string
+		wantAdjust, wantIncr float64
+	}{
+		// 超出 [0, 1] 的概率被限制在范围内
+		{name: "CPU", wantAdjust: 1, wantIncr: 0},
+		// 未给出的概率使用内置算法
+		{name: "内存", wantAdjust: 0.8, wantIncr: 0.3},
+		{name: "磁盘", wantAdjust: 0.8, wantIncr: 0.6},
+	}
+	for _, tt := range tests {
+		adjust, increase := policyOverride(tt.name, 0.8, 0.6)
+		if adjust != tt.wantAdjust || increase != tt.wantIncr {
+			t.Errorf("policyOverride(%s) = (%v, %v), want (%v, %v)", tt.name, adjust, increase, tt.wantAdjust, tt.wantIncr)
+		}
+	}
+}
+
+func TestScriptedStrategy(t *testing.T) {
+	nan := math.NaN()
+	oldProbs := policyProbs
+	t.Cleanup(func() { policyProbs = oldProbs })
+	policyProbs = map[string]policyProb{
+		"CPU": {adjust: nan, increase: nan, steps: 2.6},
+		"内存":  {adjust: nan, increase: nan, steps: -500},
+		"GPU": {adjust: 0, increase: nan, steps: nan},
+	}
+	ss := &scriptedStrategy{}
+	tests := []struct {
+		name string
+		want int
+	}{
+		{name: "CPU", want: 3},
+		// 步数限制在 ±maxScriptSteps 内
+		{name: "内存", want: -maxScriptSteps},
+		// 脚本没有给出步数时使用趋势性概率算法（调整概率为 0 时不调整）
+		{name: "GPU", want: 0},
+	}
+	for _, tt := range tests {
+		if got := ss.Decide(tt.name, 30, 50); got.Steps != tt.want {
+			t.Errorf("Decide(%s).Steps = %d, want %d", tt.name, got.Steps, tt.want)
+		}
+	}
+}
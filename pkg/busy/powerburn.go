@@ -0,0 +1,81 @@
+package busy
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	powerLanes      = 64 // 累加器个数：AVX-512 下为 8 个 zmm 寄存器
+	powerRoundsStep = 16 // 每次迭代执行的 FMA 轮数
+)
+
+// powerBurnFunc 对 acc 执行 n 轮 acc = acc*coef[0] + coef[1]
+type powerBurnFunc func(acc *[powerLanes]float64, coef *[2]float64, n uint64)
+
+// powerBurn 当前 CPU 上使用的实现（首次使用时检测）
+var powerBurn = sync.OnceValues(selectPowerBurn)
+
+// powerBurnISA 返回功耗内核使用的指令集：avx512、avx2 或 generic
+func powerBurnISA() string {
+	isa, _ := powerBurn()
+	return isa
+}
+
+// powerKernel 持续执行宽向量 FMA 指令，单位使用率下的功耗和发热远高于整数内核，用于数据中心供电和散热验证
+// acc = acc*a + b 在 a < 1 时收敛到 b/(1-a)，不会溢出也不会产生非规格化数
+type powerKernel struct {
+	acc  [powerLanes]float64
+	coef [2]float64
+	burn powerBurnFunc
+}
+
+func newPowerKernel() cpuKernel {
+	_, burn := powerBurn()
+	k := &powerKernel{coef: [2]float64{0.999999, 1e-6}, burn: burn}
+	for j := range k.acc {
+		k.acc[j] = float64(j + 1)
+	}
+	return k
+}
+
+// run 每次迭代的计算量相同，整批合并为一次调用
+func (k *powerKernel) run(from, n uint64) {
+	k.burn(&k.acc, &k.coef, powerRoundsStep*n)
+}
+
+// powerBurnGeneric 纯 Go 实现：没有宽向量指令时退化为标量 FMA
+func powerBurnGeneric(acc *[powerLanes]float64, coef *[2]float64, n uint64) {
+	a, b := coef[0], coef[1]
+	for ; n > 0; n-- {
+		for j := range acc {
+			acc[j] = math.FMA(acc[j], a, b)
+		}
+	}
+}
+
+// cpuFlags 读取 /proc/cpuinfo 中第一个处理器的 flags（只包含内核已启用的指令集）
+func cpuFlags() map[string]bool {
+	flags := make(map[string]bool)
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return flags
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		for _, flag := range strings.Fields(value) {
+			flags[flag] = true
+		}
+		break
+	}
+	return flags
+}
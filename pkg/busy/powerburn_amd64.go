@@ -0,0 +1,20 @@
+package busy
+
+// powerBurnAVX2 用 256 位 FMA 指令对 acc 的前 32 个元素执行 n 轮 acc = acc*coef[0] + coef[1]
+func powerBurnAVX2(acc *[powerLanes]float64, coef *[2]float64, n uint64)
+
+// powerBurnAVX512 用 512 位 FMA 指令对 acc 的全部 64 个元素执行 n 轮 acc = acc*coef[0] + coef[1]
+func powerBurnAVX512(acc *[powerLanes]float64, coef *[2]float64, n uint64)
+
+// selectPowerBurn 按 CPU 支持的指令集选择最耗电的实现
+func selectPowerBurn() (string, powerBurnFunc) {
+	flags := cpuFlags()
+	switch {
+	case flags["avx512f"]:
+		return "avx512", powerBurnAVX512
+	case flags["avx2"] && flags["fma"]:
+		return "avx2", powerBurnAVX2
+	default:
+		return "generic", powerBurnGeneric
+	}
+}
@@ -0,0 +1,86 @@
+#include "textflag.h"
+
+// func powerBurnAVX2(acc *[powerLanes]float64, coef *[2]float64, n uint64)
+TEXT ·powerBurnAVX2(SB), NOSPLIT, $0-24
+	MOVQ acc+0(FP), AX
+	MOVQ coef+8(FP), BX
+	MOVQ n+16(FP), CX
+	VMOVUPD 0(AX), Y0
+	VMOVUPD 32(AX), Y1
+	VMOVUPD 64(AX), Y2
+	VMOVUPD 96(AX), Y3
+	VMOVUPD 128(AX), Y4
+	VMOVUPD 160(AX), Y5
+	VMOVUPD 192(AX), Y6
+	VMOVUPD 224(AX), Y7
+	VBROADCASTSD 0(BX), Y8
+	VBROADCASTSD 8(BX), Y9
+
+avx2loop:
+	TESTQ CX, CX
+	JZ    avx2done
+	// 8 条互不依赖的 FMA 链，填满两个 FMA 单元的流水线
+	VFMADD213PD Y9, Y8, Y0
+	VFMADD213PD Y9, Y8, Y1
+	VFMADD213PD Y9, Y8, Y2
+	VFMADD213PD Y9, Y8, Y3
+	VFMADD213PD Y9, Y8, Y4
+	VFMADD213PD Y9, Y8, Y5
+	VFMADD213PD Y9, Y8, Y6
+	VFMADD213PD Y9, Y8, Y7
+	DECQ CX
+	JMP  avx2loop
+
+avx2done:
+	VMOVUPD Y0, 0(AX)
+	VMOVUPD Y1, 32(AX)
+	VMOVUPD Y2, 64(AX)
+	VMOVUPD Y3, 96(AX)
+	VMOVUPD Y4, 128(AX)
+	VMOVUPD Y5, 160(AX)
+	VMOVUPD Y6, 192(AX)
+	VMOVUPD Y7, 224(AX)
+	VZEROUPPER
+	RET
+
+// func powerBurnAVX512(acc *[powerLanes]float64, coef *[2]float64, n uint64)
+TEXT ·powerBurnAVX512(SB), NOSPLIT, $0-24
+	MOVQ acc+0(FP), AX
+	MOVQ coef+8(FP), BX
+	MOVQ n+16(FP), CX
+	VMOVUPD 0(AX), Z0
+	VMOVUPD 64(AX), Z1
+	VMOVUPD 128(AX), Z2
+	VMOVUPD 192(AX), Z3
+	VMOVUPD 256(AX), Z4
+	VMOVUPD 320(AX), Z5
+	VMOVUPD 384(AX), Z6
+	VMOVUPD 448(AX), Z7
+	VBROADCASTSD 0(BX), Z8
+	VBROADCASTSD 8(BX), Z9
+
+avx512loop:
+	TESTQ CX, CX
+	JZ    avx512done
+	VFMADD213PD Z9, Z8, Z0
+	VFMADD213PD Z9, Z8, Z1
+	VFMADD213PD Z9, Z8, Z2
+	VFMADD213PD Z9, Z8, Z3
+	VFMADD213PD Z9, Z8, Z4
+	VFMADD213PD Z9, Z8, Z5
+	VFMADD213PD Z9, Z8, Z6
+	VFMADD213PD Z9, Z8, Z7
+	DECQ CX
+	JMP  avx512loop
+
+avx512done:
+	VMOVUPD Z0, 0(AX)
+	VMOVUPD Z1, 64(AX)
+	VMOVUPD Z2, 128(AX)
+	VMOVUPD Z3, 192(AX)
+	VMOVUPD Z4, 256(AX)
+	VMOVUPD Z5, 320(AX)
+	VMOVUPD Z6, 384(AX)
+	VMOVUPD Z7, 448(AX)
+	VZEROUPPER
+	RET
@@ -0,0 +1,8 @@
+//go:build !amd64
+
+package busy
+
+// selectPowerBurn 其他架构上没有向量实现，使用纯 Go 的浮点计算
+func selectPowerBurn() (string, powerBurnFunc) {
+	return "generic", powerBurnGeneric
+}
@@ -0,0 +1,225 @@
+package busy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSpotPollInterval = 5 * time.Second // 默认检查抢占通知的间隔（AWS 提前 2 分钟、GCP 和 Azure 提前约 30 秒通知）
+	spotRequestTimeout      = time.Second     // 单次请求元数据服务的超时
+)
+
+// preemptionSource 一个云厂商的抢占通知接口
+// check 返回是否收到了通知和通知的内容；能正常访问接口（包括 AWS 没有通知时的 404）时 err 为 nil，用于识别所在的云厂商
+type preemptionSource struct {
+	name  string
+	check func(ctx context.Context, client *http.Client) (bool, string, error)
+}
+
+// preemptionSources AWS（spot/instance-action）、GCP（instance/preempted）、Azure（Scheduled Events 的 Preempt）
+var preemptionSources = []preemptionSource{
+	{name: "aws", check: checkAWSPreemption},
+	{name: "gcp", check: checkGCPPreemption},
+	{name: "azure", check: checkAzurePreemption},
+}
+
+// PreemptionWatcher 抢占式 / Spot 实例的抢占通知：定期检查元数据服务，收到通知时立即释放全部负载并保持空闲，
+// 实例回收前最后几分钟的监控数据和真实服务的关闭过程不受本程序影响
+type PreemptionWatcher struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+var preemptionWatcher = &PreemptionWatcher{}
+
+// Start 识别所在的云厂商（provider 为 auto 时同时尝试各厂商）并开始定期检查
+func (pw *PreemptionWatcher) Start(provider string, interval time.Duration) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if pw.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pw.cancel = cancel
+	pw.wg.Add(1)
+	go pw.run(ctx, provider, max(interval, time.Second))
+}
+
+// Stop 停止检查
+func (pw *PreemptionWatcher) Stop() {
+	pw.mu.Lock()
+	cancel := pw.cancel
+	pw.cancel = nil
+	pw.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		pw.wg.Wait()
+	}
+}
+
+// run 识别云厂商后按间隔检查，收到通知后进入停止状态并结束
+func (pw *PreemptionWatcher) run(ctx context.Context, provider string, interval time.Duration) {
+	defer pw.wg.Done()
+	// 元数据服务只能直接访问，不使用 HTTP_PROXY
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+
+	source, ok := detectPreemptionSource(ctx, client, provider)
+	if !ok {
+		if ctx.Err() == nil {
+			logger.Warn("无法访问云厂商的抢占通知接口，不监视抢占", "provider", provider)
+		}
+		return
+	}
+	logger.Info("开始监视抢占通知", "provider", source.name, "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, spotRequestTimeout)
+		preempted, notice, err := source.check(checkCtx, client)
+		cancel()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			// 元数据服务偶尔超时，连续失败时才记录
+			if failures++; failures == 3 {
+				logger.Warn("检查抢占通知连续失败", "provider", source.name, "error", err)
+			}
+		case preempted:
+			logger.Warn("收到抢占通知，释放全部负载并保持空闲", "provider", source.name, "notice", notice)
+			emitEvent("preemption", "provider", source.name, "notice", notice)
+			killSwitch.SetPreempted()
+			return
+		default:
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// detectPreemptionSource 同时尝试各云厂商的接口，返回第一个能正常访问的（provider 不是 auto 时只尝试该厂商）
+func detectPreemptionSource(ctx context.Context, client *http.Client, provider string) (preemptionSource, bool) {
+	ctx, cancel := context.WithTimeout(ctx, cloudMetadataTimeout)
+	defer cancel()
+
+	sources := preemptionSources
+	if provider != "auto" {
+		i := slices.IndexFunc(sources, func(s preemptionSource) bool { return s.name == provider })
+		if i < 0 {
+			return preemptionSource{}, false
+		}
+		sources = sources[i : i+1]
+	}
+	results := make(chan int, len(sources))
+	for i, source := range sources {
+		go func() {
+			if _, _, err := source.check(ctx, client); err != nil {
+				results <- -1
+				return
+			}
+			results <- i
+		}()
+	}
+	for range sources {
+		if i := <-results; i >= 0 {
+			return sources[i], true
+		}
+	}
+	return preemptionSource{}, false
+}
+
+// getMetadata 请求元数据服务，返回状态码和简短的响应
+func getMetadata(ctx context.Context, client *http.Client, url string, headers map[string]string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, strings.TrimSpace(string(data)), err
+}
+
+// checkAWSPreemption 没有通知时 spot/instance-action 返回 404，有通知时返回 {"action": "terminate", "time": "..."}
+func checkAWSPreemption(ctx context.Context, client *http.Client) (bool, string, error) {
+	key, token, err := awsMetadataToken(ctx, client)
+	if err != nil {
+		return false, "", err
+	}
+	status, body, err := getMetadata(ctx, client, "http://169.254.169.254/latest/meta-data/spot/instance-action", map[string]string{key: token})
+	switch {
+	case err != nil:
+		return false, "", err
+	case status == http.StatusNotFound:
+		return false, "", nil
+	case status != http.StatusOK:
+		return false, "", fmt.Errorf("元数据服务返回 %d", status)
+	}
+	var action struct {
+		Action string `json:"action"`
+	}
+	if json.Unmarshal([]byte(body), &action) != nil || action.Action == "" {
+		return false, "", fmt.Errorf("无法解析抢占通知: %q", body)
+	}
+	return action.Action == "terminate" || action.Action == "stop" || action.Action == "hibernate", body, nil
+}
+
+// checkGCPPreemption instance/preempted 在实例被抢占时返回 TRUE
+func checkGCPPreemption(ctx context.Context, client *http.Client) (bool, string, error) {
+	status, body, err := getMetadata(ctx, client, "http://metadata.google.internal/computeMetadata/v1/instance/preempted", map[string]string{"Metadata-Flavor": "Google"})
+	if err != nil {
+		return false, "", err
+	}
+	if status != http.StatusOK || (body != "TRUE" && body != "FALSE") {
+		return false, "", fmt.Errorf("元数据服务返回 %d: %q", status, body)
+	}
+	return body == "TRUE", body, nil
+}
+
+// checkAzurePreemption Scheduled Events 中出现 Preempt（Spot 回收）或 Terminate 事件
+func checkAzurePreemption(ctx context.Context, client *http.Client) (bool, string, error) {
+	status, body, err := getMetadata(ctx, client, "http://169.254.169.254/metadata/scheduledevents?api-version=2020-07-01", map[string]string{"Metadata": "true"})
+	if err != nil {
+		return false, "", err
+	}
+	if status != http.StatusOK {
+		return false, "", fmt.Errorf("元数据服务返回 %d", status)
+	}
+	var events struct {
+		Events []struct {
+			EventType string `json:"EventType"`
+			NotBefore string `json:"NotBefore"`
+		} `json:"Events"`
+	}
+	if err := json.Unmarshal([]byte(body), &events); err != nil {
+		return false, "", fmt.Errorf("无法解析 Scheduled Events: %w", err)
+	}
+	for _, event := range events.Events {
+		if event.EventType == "Preempt" || event.EventType == "Terminate" {
+			return true, event.EventType + " " + event.NotBefore, nil
+		}
+	}
+	return false, "", nil
+}
@@ -0,0 +1,115 @@
+package busy
+
+import (
+	"slices"
+)
+
+// Preset 内置的负载预设：一组常见场景的环境变量取值（目标水平、时段、计算内核和随机扰动）
+// 预设的值只在对应的环境变量未设置时生效，单独设置的环境变量总是优先
+type Preset struct {
+	Description string
+	Env         map[string]string
+}
+
+// presets 内置的预设（PRESET 选择）；时段为 UTC 小时，与凌晨时段（UTC 16:00-20:00）一致
+// 预设不能提高硬峰值（NIGHT_HARD_PEAK / DAY_HARD_PEAK 只能设置为不高于默认值），提高安全上限需要单独设置环境变量
+var presets = map[string]Preset{
+	"web-frontend": {
+		Description: "Web 前端：白天业务高峰、夜间回落，混合计算和自带的 HTTP 请求，带短时突发",
+		Env: map[string]string{
+			"P":                   "60",
+			"PEAK_DISTRIBUTION":   "normal",
+			"PEAK_MAX_STEP":       "10",
+			"MIN_USAGE":           "10",
+			"DAY_FACTOR":          "0.7",
+			"DAY_WINDOWS":         "1-14=0.9,20-1=0.5",
+			"CPU_KERNEL":          "int:40,memcpy:30,hash:30",
+			"WORKLOADS":           "http",
+			"CPU_SLEEP_JITTER":    "0.3",
+			"CPU_WORKER_SPREAD":   "0.3",
+			"CPU_SPIKE_RATE":      "12",
+			"CPU_SPIKE_PERCENT":   "15",
+			"MEMORY_BLOCK_JITTER": "0.5",
+			"MONITOR_JITTER":      "0.2",
+		},
+	},
+	"batch-night": {
+		Description: "夜间批处理：凌晨时段高负载，白天保持低位，顺序读写大块内存",
+		Env: map[string]string{
+			"P":                     "70",
+			"DAY_HARD_PEAK":         "40",
+			"DAY_FACTOR":            "0.3",
+			"PEAK_LOW_FACTOR":       "0.6",
+			"CPU_KERNEL":            "int:50,data:50",
+			"MEMORY_ACCESS_PATTERN": "sequential",
+			"CPU_SLEEP_JITTER":      "0.1",
+			"MONITOR_JITTER":        "0.1",
+		},
+	},
+	"database": {
+		Description: "数据库：内存常驻且稳定，CPU 中等、变化缓慢，随机访问内存",
+		Env: map[string]string{
+			"P":                         "50",
+			"PEAK_DISTRIBUTION":         "normal",
+			"PEAK_STDDEV_FACTOR":        "0.1",
+			"PEAK_MAX_STEP":             "5",
+			"MIN_USAGE":                 "20",
+			"DAY_FACTOR":                "0.9",
+			"CPU_KERNEL":                "memcpy:40,hash:30,syscall:30",
+			"MEMORY_ACCESS_PATTERN":     "random",
+			"MEMORY_ACCESS_WRITE_RATIO": "0.3",
+			"CPU_SLEEP_JITTER":          "0.2",
+			"CPU_SPIKE_RATE":            "2",
+			"CPU_SPIKE_PERCENT":         "10",
+		},
+	},
+	"idle-blend": {
+		Description: "空闲掩护：低位的背景负载，只使用空闲 CPU，带较多随机扰动",
+		Env: map[string]string{
+			"P":                 "15",
+			"MIN_USAGE":         "3",
+			"CPU_KERNEL":        "int",
+			"WORKER_SCHED_IDLE": "true",
+			"CPU_SLEEP_JITTER":  "0.5",
+			"CPU_WORKER_SPREAD": "0.5",
+			"CPU_SPIKE_RATE":    "4",
+			"CPU_SPIKE_PERCENT": "10",
+			"MONITOR_JITTER":    "0.3",
+		},
+	},
+}
+
+// presetNames 返回所有内置预设的名称
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// presetValue 当前预设（PRESET）中 name 的值，没有选择预设、预设不存在或预设不包含该变量时返回空字符串
+func presetValue(name string) string {
+	return presets[envValue("PRESET")].Env[name]
+}
+
+// logPreset 输出选择的预设，名称无效时警告（此时所有变量使用各自的默认值）
+func logPreset() {
+	name := envValue("PRESET")
+	if name == "" {
+		return
+	}
+	if _, ok := presets[name]; !ok {
+		logger.Warn("PRESET 无效，忽略", "preset", name, "options", presetNames())
+		return
+	}
+	var overridden []string
+	for key := range presets[name].Env {
+		if envValue(key) != "" {
+			overridden = append(overridden, key)
+		}
+	}
+	slices.Sort(overridden)
+	logger.Info("使用预设", "preset", name, "overridden", overridden)
+}
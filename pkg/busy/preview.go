@@ -0,0 +1,84 @@
+package busy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const previewBarWidth = 50 // 预览曲线的宽度（字符数，对应 0-100%）
+
+// Preview 按当前配置输出未来 hours 小时的期望占用曲线，不启动任何负载（preview 子命令）
+// 每行包含：模拟的随机波动（每 5 分钟更新一次 peakUsage）下的期望值，以及 peakUsage 取最小值和最大值时的期望值范围
+// 观测值（CPU、内存等）按 0 计算，依赖观测值的 TARGET_EXPR / POLICY_SCRIPT 只能作为参考
+func Preview(w io.Writer, hours int, step time.Duration) {
+	origin := getPeakUsage()
+	peakUsageMu.Lock()
+	peakUsageOrigin = origin
+	peakUsage = origin
+	peakUsageMu.Unlock()
+	loadCurveConfig()
+
+	fn := configuredExpectedUsage()
+	loadConfiguredPolicy()
+	stats := &SystemStats{}
+	expectedAt := func(t time.Time, peak int) float64 {
+		expectedUsage := evalExpectedUsageAt(fn, t, peak, stats)
+		if policyScript != nil {
+			expectedUsage = runPolicyScript(t, peak, stats, expectedUsage)
+		}
+		return expectedUsage
+	}
+
+	lowPeak, highPeak := peakUsageRange(origin)
+	fmt.Fprintf(w, "峰值 P=%d，peakUsage 每 %s 在 [%d, %d] 内随机更新，硬峰值 凌晨 %g%% / 其他时段 %g%%\n",
+		origin, peakUsageInterval, lowPeak, highPeak, nightHardPeak, dayHardPeak)
+	fmt.Fprintf(w, "图例: # 模拟值  - 范围上限  | 硬峰值\n\n")
+	fmt.Fprintf(w, "时间(UTC)    夜间  期望值  范围          曲线（0-100%%）\n")
+
+	start := time.Now().UTC().Truncate(step)
+	end := start.Add(time.Duration(hours) * time.Hour)
+	peak := origin // 与运行时一致：启动时为 P，之后每 peakUsageInterval 随机更新
+	nextUpdate := start.Add(peakUsageInterval)
+	for t := start; !t.After(end); t = t.Add(step) {
+		// 按 peakUsageInterval 推进随机波动，与运行时的更新频率一致
+		for !nextUpdate.After(t) {
+			peak = nextPeakUsage(origin, peak)
+			nextUpdate = nextUpdate.Add(peakUsageInterval)
+		}
+
+		expected := expectedAt(t, peak)
+		low, high := expectedAt(t, lowPeak), expectedAt(t, highPeak)
+		if low > high {
+			low, high = high, low
+		}
+		night := "    "
+		if isNightTimeAt(t) {
+			night = "是  "
+		}
+		fmt.Fprintf(w, "%-11s  %s  %5.1f%%  [%4.1f, %4.1f]  %s\n",
+			t.Format("01-02 15:04"), night, expected, low, high, previewBar(expected, high, hardPeakAt(t)))
+	}
+}
+
+// previewBar 绘制一行预览曲线，limit 为该时刻的硬峰值
+func previewBar(value, high, limit float64) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < previewBarWidth; i++ {
+		percent := (float64(i) + 0.5) * 100 / previewBarWidth
+		switch {
+		case percent <= value:
+			b.WriteByte('#')
+		case percent <= high:
+			b.WriteByte('-')
+		case i == int(limit*previewBarWidth/100):
+			b.WriteByte('|')
+		default:
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package busy
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// probRow 概率表的一行：差值（绝对值）不小于 MinDiff 时使用 Prob
+type probRow struct {
+	MinDiff float64 `json:"min_diff"`
+	Prob    float64 `json:"prob"`
+}
+
+// probTable PROB_TABLE_FILE 的格式，省略的表使用内置的概率
+type probTable struct {
+	Adjust []probRow `json:"adjust"` // 是否执行调整的概率
+	Toward []probRow `json:"toward"` // 朝期望值方向调整的概率（当前 < 期望时为上涨概率，当前 > 期望时为下跌概率）
+}
+
+// decisionTable PROB_TABLE_FILE 加载的概率表（nil 表示使用内置的概率）
+var decisionTable *probTable
+
+// loadProbTable 读取并校验概率表，每个表按 min_diff 从大到小排序
+func loadProbTable(path string) (*probTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var table probTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("解析概率表失败: %w", err)
+	}
+	if len(table.Adjust) == 0 && len(table.Toward) == 0 {
+		return nil, fmt.Errorf("adjust 和 toward 都为空")
+	}
+	for name, rows := range map[string][]probRow{"adjust": table.Adjust, "toward": table.Toward} {
+		for _, row := range rows {
+			if row.MinDiff < 0 || row.Prob < 0 || row.Prob > 1 {
+				return nil, fmt.Errorf("%s 表的行无效: min_diff=%g prob=%g（应满足 min_diff >= 0，0 <= prob <= 1）", name, row.MinDiff, row.Prob)
+			}
+		}
+		if len(rows) > 0 && slices.MinFunc(rows, func(a, b probRow) int { return cmp.Compare(a.MinDiff, b.MinDiff) }).MinDiff != 0 {
+			return nil, fmt.Errorf("%s 表需要一行 min_diff 为 0，覆盖所有差值", name)
+		}
+	}
+	byDiffDesc := func(a, b probRow) int { return cmp.Compare(b.MinDiff, a.MinDiff) }
+	slices.SortFunc(table.Adjust, byDiffDesc)
+	slices.SortFunc(table.Toward, byDiffDesc)
+	return &table, nil
+}
+
+// loadDecisionTable 读取 PROB_TABLE_FILE，无效时使用内置的概率
+func loadDecisionTable() {
+	decisionTable = nil
+	path := lookupEnv("PROB_TABLE_FILE")
+	if path == "" {
+		return
+	}
+	table, err := loadProbTable(path)
+	if err != nil {
+		logger.Warn("PROB_TABLE_FILE 无效，使用内置的概率", "path", path, "error", err)
+		return
+	}
+	decisionTable = table
+	logger.Info("已加载概率表", "path", path, "adjust_rows", len(table.Adjust), "toward_rows", len(table.Toward))
+}
+
+// lookupProb 按差值（绝对值）查表，rows 为空时返回 false
+func lookupProb(rows []probRow, diff float64) (float64, bool) {
+	for _, row := range rows {
+		if diff >= row.MinDiff {
+			return row.Prob, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package busy
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadProbTable(t *testing.T) {
+	tests := []struct {
+		file    string
+		adjust  []probRow // 按 min_diff 从大到小排序后的结果
+		toward  []probRow
+		wantErr bool
+	}{
+		{
+			file:   "valid.json",
+			adjust: []probRow{{10, 0.9}, {3, 0.5}, {0, 0.1}},
+			toward: []probRow{{5, 0.8}, {0, 0.55}},
+		},
+		// 省略的表使用内置的概率
+		{file: "adjust-only.json", adjust: []probRow{{0, 1}}},
+		{file: "empty.json", wantErr: true},
+		{file: "no-zero-row.json", wantErr: true},
+		{file: "prob-above-one.json", wantErr: true},
+		{file: "negative-prob.json", wantErr: true},
+		{file: "negative-diff.json", wantErr: true},
+		{file: "invalid-json.json", wantErr: true},
+		{file: "missing.json", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			table, err := loadProbTable(filepath.Join("testdata", "probtable", tt.file))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("loadProbTable() = %+v, want error", table)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadProbTable() error: %v", err)
+			}
+			if !slices.Equal(table.Adjust, tt.adjust) || !slices.Equal(table.Toward, tt.toward) {
+				t.Errorf("loadProbTable() = adjust %v toward %v, want adjust %v toward %v", table.Adjust, table.Toward, tt.adjust, tt.toward)
+			}
+		})
+	}
+}
+
+func TestLookupProb(t *testing.T) {
+	table, err := loadProbTable(filepath.Join("testdata", "probtable", "valid.json"))
+	if err != nil {
+		t.Fatalf("loadProbTable() error: %v", err)
+	}
+	tests := []struct {
+		diff float64
+		want float64
+	}{
+		{0, 0.1},
+		{2.9, 0.1},
+		{3, 0.5},
+		{9.99, 0.5},
+		{10, 0.9},
+		{100, 0.9},
+	}
+	for _, tt := range tests {
+		if got, ok := lookupProb(table.Adjust, tt.diff); !ok || got != tt.want {
+			t.Errorf("lookupProb(adjust, %v) = %v, %v, want %v, true", tt.diff, got, ok, tt.want)
+		}
+	}
+	if _, ok := lookupProb(nil, 5); ok {
+		t.Errorf("lookupProb(nil, 5) ok = true, want false")
+	}
+}
@@ -0,0 +1,90 @@
+package busy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// commMaxLen 内核 comm 的最大长度（不含结尾的 \0）
+const commMaxLen = 15
+
+// ApplyProcTitle 按 PROC_TITLE / PROC_THREAD_TITLE 伪装进程名和线程名（需在读取命令行参数之前调用）
+func ApplyProcTitle() {
+	setProcTitle(lookupEnv("PROC_TITLE"))
+	setThreadTitle(getEnvString("PROC_THREAD_TITLE", lookupEnv("PROC_TITLE")))
+}
+
+// setProcTitle 设置进程名：覆盖 argv 区域（影响 ps/top 的命令行显示），
+// 并写入 /proc/self/comm（影响 ps -o comm、top 的进程名）
+func setProcTitle(title string) {
+	if title == "" {
+		return
+	}
+
+	overwriteArgv(title)
+
+	if err := os.WriteFile("/proc/self/comm", []byte(truncateComm(title)), 0); err != nil {
+		logger.Warn("设置进程名失败", "title", title, "error", err)
+	}
+}
+
+// setThreadTitle 设置除主线程外所有线程的名称（影响 ps -L、top -H 的显示），
+// 之后新建的线程会继承创建者线程的名称
+func setThreadTitle(title string) {
+	if title == "" {
+		return
+	}
+
+	tasks, err := filepath.Glob("/proc/self/task/*/comm")
+	if err != nil {
+		return
+	}
+	mainTask := filepath.Join("/proc/self/task", strconv.Itoa(os.Getpid()), "comm")
+	for _, task := range tasks {
+		if task == mainTask {
+			continue
+		}
+		if err := os.WriteFile(task, []byte(truncateComm(title)), 0); err != nil {
+			logger.Warn("设置线程名失败", "task", task, "error", err)
+		}
+	}
+}
+
+// overwriteArgv 用 title 覆盖原始 argv 所在的内存区域
+// os.Args 中的字符串直接指向原始 argv 内存，覆盖前先复制一份，避免后续读取到被修改的参数
+func overwriteArgv(title string) {
+	if len(os.Args) == 0 || len(os.Args[0]) == 0 {
+		return
+	}
+
+	// 计算 argv 连续区域的长度（各参数之间以 \0 分隔）
+	start := unsafe.StringData(os.Args[0])
+	size := len(os.Args[0])
+	for _, arg := range os.Args[1:] {
+		if len(arg) == 0 || unsafe.StringData(arg) != (*byte)(unsafe.Add(unsafe.Pointer(start), size+1)) {
+			break
+		}
+		size += 1 + len(arg)
+	}
+
+	for i := range os.Args {
+		os.Args[i] = strings.Clone(os.Args[i])
+	}
+
+	area := unsafe.Slice(start, size)
+	n := copy(area, title)
+	for i := n; i < len(area); i++ {
+		area[i] = 0
+	}
+}
+
+// truncateComm 截断到内核 comm 允许的长度
+func truncateComm(title string) string {
+	if len(title) > commMaxLen {
+		return title[:commMaxLen]
+	}
+	return title
+}
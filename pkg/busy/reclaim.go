@@ -0,0 +1,85 @@
+package busy
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reclaimCounters /proc/vmstat 中内存回收相关的累计值（单位：页）
+type reclaimCounters struct {
+	kswapd uint64 // kswapd 后台回收扫描的页数（pgscan_kswapd，老内核按内存区域分为 pgscan_kswapd_normal 等）
+	direct uint64 // 分配内存时同步回收扫描的页数（pgscan_direct，不含 pgscan_direct_throttle）
+}
+
+// ReclaimMonitor 内存回收速率：内核开始频繁回收页缓存（kswapd 扫描）或在分配内存时同步回收（direct reclaim）时，
+// 说明可用内存已经紧张，通常早于内存使用率达到硬峰值，此时继续增加内存占用会拖慢同机的其他服务
+type ReclaimMonitor struct {
+	mu          sync.Mutex
+	last        reclaimCounters
+	lastAt      time.Time
+	unavailable bool // /proc/vmstat 不可读时只记录一次日志
+}
+
+var reclaimMonitor = &ReclaimMonitor{}
+
+// Sample 读取 /proc/vmstat，计算上个周期每秒扫描的页数，写入 stats（第一次采样和读取失败时为 0）
+func (rm *ReclaimMonitor) Sample(stats *SystemStats) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	counters, err := readReclaimCounters()
+	if err != nil {
+		if !rm.unavailable {
+			rm.unavailable = true
+			logger.Debug("无法读取 /proc/vmstat，不检查内存回收速率", "error", err)
+		}
+		return
+	}
+	now := clock.Now()
+	// 计数器只增不减，变小说明 PROC_ROOT 指向的系统发生了变化（如重启），重新开始
+	if !rm.lastAt.IsZero() && counters.kswapd >= rm.last.kswapd && counters.direct >= rm.last.direct {
+		if seconds := now.Sub(rm.lastAt).Seconds(); seconds > 0 {
+			stats.ReclaimScanRate = float64(counters.kswapd-rm.last.kswapd+counters.direct-rm.last.direct) / seconds
+			stats.DirectReclaimRate = float64(counters.direct-rm.last.direct) / seconds
+		}
+	}
+	rm.last, rm.lastAt = counters, now
+}
+
+// readReclaimCounters 读取 /proc/vmstat 中的 pgscan 计数
+func readReclaimCounters() (reclaimCounters, error) {
+	file, err := os.Open(procPath("vmstat"))
+	if err != nil {
+		return reclaimCounters{}, err
+	}
+	defer file.Close()
+
+	var counters reclaimCounters
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(key, "pgscan_kswapd"):
+			counters.kswapd += n
+		case strings.HasPrefix(key, "pgscan_direct") && key != "pgscan_direct_throttle":
+			counters.direct += n
+		}
+	}
+	return counters, scanner.Err()
+}
+
+// reclaimPressure 内存回收是否已经达到需要回退的程度：扫描速率超过 MEMORY_RECLAIM_BACKOFF，或出现同步回收（分配内存被阻塞）
+func reclaimPressure(stats *SystemStats) bool {
+	return reclaimBackoff > 0 && (stats.ReclaimScanRate > reclaimBackoff || stats.DirectReclaimRate > 0)
+}
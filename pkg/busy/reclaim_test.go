@@ -0,0 +1,68 @@
+package busy
+
+import "testing"
+
+func TestReadReclaimCounters(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    reclaimCounters
+		wantErr bool
+	}{
+		// pgscan_direct_throttle 和 pgscan_khugepaged 不计入
+		{name: "modern", want: reclaimCounters{kswapd: 1000, direct: 200}},
+		// 老内核按内存区域分别计数
+		{name: "old-kernel", want: reclaimCounters{kswapd: 1015, direct: 42}},
+		{name: "broken", want: reclaimCounters{}},
+		{name: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProcRoot(t, tt.name)
+			got, err := readReclaimCounters()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readReclaimCounters() = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readReclaimCounters() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readReclaimCounters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCounterOOMKill(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    uint64
+		wantErr bool
+	}{
+		// 只匹配完整的名称（不匹配 oom_kill_total_bogus）
+		{name: "modern", want: 3},
+		// 4.13 之前的内核没有 oom_kill
+		{name: "old-kernel", wantErr: true},
+		{name: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProcRoot(t, tt.name)
+			got, err := readCounter(procPath("vmstat"), "oom_kill")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readCounter() = %d, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCounter() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readCounter() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package busy
+
+import (
+	"fmt"
+	"runtime/debug"
+	"syscall"
+)
+
+// defaultRlimitHeadroomMB 地址空间限制中为 Go 运行时预留的空间（MB）
+// Go 运行时启动后虚拟地址空间约 1.5GB，其中大部分为未实际使用的预留区域
+const defaultRlimitHeadroomMB = 2048
+
+// applyMemoryRlimits 根据硬峰值设置 RLIMIT_AS / RLIMIT_DATA 的软限制，作为控制循环失效时的最后一道边界
+// 达到 rlimit 时 Go 运行时分配失败会直接崩溃退出（不会逐步回退），正常的回退依靠 debug.SetMemoryLimit 和内存缓冲区的上限；
+// 只修改软限制（rlim_cur），硬限制保持不变，进程之后仍可以调整
+// 返回内存缓冲区允许占用的上限（字节）
+func applyMemoryRlimits(totalMemory uint64) (uint64, error) {
+	if totalMemory == 0 {
+		return 0, fmt.Errorf("无法获取总内存信息")
+	}
+
+	headroomMB := getEnvInt("RLIMIT_HEADROOM_MB", defaultRlimitHeadroomMB)
+	if headroomMB < 0 {
+		headroomMB = defaultRlimitHeadroomMB
+	}
+
+	bufferLimit := uint64(float64(totalMemory) * maxHardPeak() / 100)
+	limit := bufferLimit + uint64(headroomMB)*1024*1024
+
+	for _, resource := range []int{syscall.RLIMIT_AS, syscall.RLIMIT_DATA} {
+		var rlim syscall.Rlimit
+		if err := syscall.Getrlimit(resource, &rlim); err != nil {
+			return 0, fmt.Errorf("读取 rlimit 失败: %w", err)
+		}
+		// 只收紧不放宽
+		if rlim.Cur <= limit {
+			continue
+		}
+		rlim.Cur = limit
+		if err := syscall.Setrlimit(resource, &rlim); err != nil {
+			return 0, fmt.Errorf("设置 rlimit 失败: %w", err)
+		}
+	}
+
+	// 让 GC 在接近上限时更积极地回收，避免触达内核限制
+	debug.SetMemoryLimit(int64(limit))
+
+	logger.Info("已设置内存 rlimit",
+		"limit_mb", limit/(1024*1024),
+		"buffer_limit_mb", bufferLimit/(1024*1024),
+		"headroom_mb", headroomMB)
+	return bufferLimit, nil
+}
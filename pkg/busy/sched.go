@@ -0,0 +1,59 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// schedIdle Linux 调度策略 SCHED_IDLE：只在 CPU 空闲时运行，任何正常任务都会抢占它
+const schedIdle = 5
+
+// setProcessNice 调整整个进程（所有线程）的 nice 值
+// Linux 的 nice 值是线程级别的，需要逐个线程设置，之后新建的线程会继承创建者线程的 nice 值
+func setProcessNice(nice int) error {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+			return fmt.Errorf("设置线程 %d 的 nice 值失败: %w", tid, err)
+		}
+	}
+	return nil
+}
+
+// setThreadSchedIdle 把当前线程设置为 SCHED_IDLE 调度策略
+// 调用方需要先 runtime.LockOSThread()，确保设置作用于固定的线程
+func setThreadSchedIdle() error {
+	var param struct{ priority int32 }
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, schedIdle, uintptr(unsafe.Pointer(&param)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setThreadAffinity 把当前线程绑定到指定的 CPU 核心
+// 调用方需要先 runtime.LockOSThread()，确保设置作用于固定的线程
+func setThreadAffinity(core int) error {
+	var mask [16]uint64 // 最多 1024 个核心
+	if core < 0 || core >= len(mask)*64 {
+		return fmt.Errorf("无效的 CPU 核心编号: %d", core)
+	}
+	mask[core/64] |= 1 << (core % 64)
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package busy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readSelfCPUTicks 从 /proc/self/stat 读取本进程累计的 CPU 时间（utime + stime，单位：jiffies）
+func readSelfCPUTicks() (uint64, error) {
+	data, err := os.ReadFile(procPath("self", "stat"))
+	if err != nil {
+		return 0, err
+	}
+
+	// 进程名可能包含空格和括号，从最后一个 ")" 之后开始解析
+	content := string(data)
+	end := strings.LastIndex(content, ")")
+	if end < 0 {
+		return 0, fmt.Errorf("无效的 /proc/self/stat")
+	}
+	// ")" 之后第一个字段是第 3 列（state），utime/stime 为第 14/15 列
+	fields := strings.Fields(content[end+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("无效的 /proc/self/stat")
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return utime + stime, nil
+}
+
+// readSelfMemory 从 /proc/self/status 读取本进程的常驻内存（VmRSS）和被换出的内存（VmSwap），单位：字节
+// 老内核没有 VmSwap 时 swap 为 0
+func readSelfMemory() (rss, swap uint64, err error) {
+	file, err := os.Open(procPath("self", "status"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	foundRSS := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || (fields[0] != "VmRSS:" && fields[0] != "VmSwap:") {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		// 单位是 KB
+		if fields[0] == "VmRSS:" {
+			rss, foundRSS = value*1024, true
+		} else {
+			swap = value * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if !foundRSS {
+		return 0, 0, fmt.Errorf("/proc/self/status 中没有 VmRSS")
+	}
+	return rss, swap, nil
+}
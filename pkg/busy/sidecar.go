@@ -0,0 +1,150 @@
+package busy
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cgroupRoot cgroup v2 的挂载路径
+const cgroupRoot = "/sys/fs/cgroup"
+
+// SidecarTracker 跟踪目标容器（cgroup v2）的 CPU 和内存占用
+type SidecarTracker struct {
+	mu        sync.Mutex
+	path      string    // 目标 cgroup 的路径
+	lastUsage uint64    // 上次读取的 cpu.stat usage_usec
+	lastTime  time.Time // 上次读取的时间
+}
+
+var sidecarTracker = &SidecarTracker{}
+
+// Init 按 cgroup 路径或容器 ID 定位目标 cgroup
+func (st *SidecarTracker) Init(target string) error {
+	path, err := resolveSidecarCgroup(target)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(path, "cpu.stat")); err != nil {
+		return fmt.Errorf("目标 cgroup 不可用: %w", err)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.path = path
+	return nil
+}
+
+// Enabled 是否已启用 sidecar 跟踪
+func (st *SidecarTracker) Enabled() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.path != ""
+}
+
+// Path 目标 cgroup 的路径
+func (st *SidecarTracker) Path() string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.path
+}
+
+// Stats 读取目标容器的 CPU 使用率（占整机的百分比）和内存占用（字节）
+// 第一次调用只记录基准值，CPU 使用率返回 0
+func (st *SidecarTracker) Stats() (float64, uint64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	usage, err := readCgroupCPUUsage(st.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	memory, err := readCgroupUint(filepath.Join(st.path, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := clock.Now()
+	var cpuPercent float64
+	if !st.lastTime.IsZero() && usage >= st.lastUsage {
+		elapsed := now.Sub(st.lastTime).Microseconds()
+		if elapsed > 0 {
+			cpuPercent = float64(usage-st.lastUsage) / float64(elapsed*int64(numCPU())) * 100
+		}
+	}
+	st.lastUsage = usage
+	st.lastTime = now
+	return min(cpuPercent, 100), memory, nil
+}
+
+// resolveSidecarCgroup 把 cgroup 路径或容器 ID 解析为 cgroup 目录
+// 以 / 开头的值视为 cgroup 路径（不存在时视为省略了 /sys/fs/cgroup 前缀），其他值视为容器 ID（允许是前缀）
+func resolveSidecarCgroup(target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("未设置目标容器")
+	}
+	if strings.HasPrefix(target, "/") {
+		if _, err := os.Stat(target); err == nil {
+			return target, nil
+		}
+		return filepath.Join(cgroupRoot, target), nil
+	}
+
+	// 按容器 ID 查找：docker/containerd/cri-o 的 cgroup 目录名中都包含完整的容器 ID
+	var found []string
+	err := filepath.WalkDir(cgroupRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		name := strings.TrimSuffix(d.Name(), ".scope")
+		if i := strings.LastIndexAny(name, "-:"); i >= 0 {
+			name = name[i+1:]
+		}
+		if strings.HasPrefix(name, target) {
+			found = append(found, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("找不到容器 %s 的 cgroup", target)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("容器 ID %s 匹配到多个 cgroup: %s", target, strings.Join(found, ", "))
+	}
+}
+
+// readCgroupCPUUsage 读取 cpu.stat 中的 usage_usec（微秒）
+func readCgroupCPUUsage(path string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(path, "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("cpu.stat 中没有 usage_usec")
+}
+
+// readCgroupUint 读取只包含一个整数的 cgroup 文件（如 memory.current）
+func readCgroupUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,444 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+)
+
+const (
+	simulateItersPerMs  = 20000    // 未设置 CALIBRATION_FILE / CALIBRATION_DIR 时假设的单个工作协程每毫秒迭代次数
+	simulateMaxFreqMHz  = 3000     // 模拟的 CPU 最大频率（SIMULATE_CPU_FREQ 按该频率的比例给出）
+	simulateTotalMemory = 16 << 30 // 模拟的整机内存（字节）
+)
+
+// simTracker 一类资源的跟踪误差统计
+type simTracker struct {
+	n       int
+	sumAbs  float64
+	sumSq   float64
+	maxAbs  float64
+	inBand2 int // 误差在 ±2% 以内的周期数
+	inBand5 int // 误差在 ±5% 以内的周期数
+}
+
+// add 记录一个周期的占用值和期望值
+func (st *simTracker) add(current, expected float64) {
+	e := math.Abs(current - expected)
+	st.n++
+	st.sumAbs += e
+	st.sumSq += e * e
+	st.maxAbs = max(st.maxAbs, e)
+	if e <= 2 {
+		st.inBand2++
+	}
+	if e <= 5 {
+		st.inBand5++
+	}
+}
+
+// mae 平均绝对误差
+func (st *simTracker) mae() float64 {
+	if st.n == 0 {
+		return 0
+	}
+	return st.sumAbs / float64(st.n)
+}
+
+// rmse 均方根误差
+func (st *simTracker) rmse() float64 {
+	if st.n == 0 {
+		return 0
+	}
+	return math.Sqrt(st.sumSq / float64(st.n))
+}
+
+// simHour 一小时内的统计（用于逐小时输出）
+type simHour struct {
+	start    time.Time
+	expected float64
+	cpu      float64
+	memory   float64
+	cpuErr   simTracker
+	memErr   simTracker
+}
+
+// simResult 一次模拟的统计
+type simResult struct {
+	cpuErr, memErr simTracker
+	hourly         []*simHour
+	hardPeakOver   int     // CPU 超过硬峰值的周期数
+	hardPeakMax    float64 // CPU 超过硬峰值的最大幅度
+	hardPeakHeld   int     // CPU 超过硬峰值但没有降低计算次数的周期数
+}
+
+// add 记录一个周期的采样值
+func (res *simResult) add(entry HistoryEntry) {
+	res.cpuErr.add(entry.CPUPercent, entry.ExpectedUsage)
+	res.memErr.add(entry.MemoryPercent, entry.ExpectedUsage)
+	hour := entry.Time.UTC().Truncate(time.Hour)
+	if len(res.hourly) == 0 || !res.hourly[len(res.hourly)-1].start.Equal(hour) {
+		res.hourly = append(res.hourly, &simHour{start: hour})
+	}
+	h := res.hourly[len(res.hourly)-1]
+	h.expected += entry.ExpectedUsage
+	h.cpu += entry.CPUPercent
+	h.memory += entry.MemoryPercent
+	h.cpuErr.add(entry.CPUPercent, entry.ExpectedUsage)
+	h.memErr.add(entry.MemoryPercent, entry.ExpectedUsage)
+}
+
+// checkHardPeak 检查一个周期的硬峰值：CPU 超过硬峰值时，本周期调整之后的计算次数 next 必须低于调整之前
+func (res *simResult) checkHardPeak(entry HistoryEntry, next uint64) {
+	over := entry.CPUPercent - hardPeakAt(entry.Time)
+	if over <= 0 {
+		return
+	}
+	res.hardPeakOver++
+	res.hardPeakMax = max(res.hardPeakMax, over)
+	if next >= entry.CPUCount {
+		res.hardPeakHeld++
+	}
+}
+
+// Simulate 在模拟时钟下快进 hours 小时的控制决策，输出跟踪误差统计（simulate 子命令）
+func Simulate(w io.Writer, hours int) error {
+	origin := getPeakUsage()
+	started := time.Now()
+	res, err := simulate(hours)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "模拟 %d 小时，共 %d 个周期，耗时 %s（P=%d）\n\n", hours, res.cpuErr.n, time.Since(started).Round(time.Millisecond), origin)
+	fmt.Fprintf(w, "资源  平均绝对误差  均方根误差  最大误差  ±2%% 内  ±5%% 内\n")
+	for _, r := range []struct {
+		name string
+		st   *simTracker
+	}{{"CPU ", &res.cpuErr}, {"内存", &res.memErr}} {
+		fmt.Fprintf(w, "%s  %11.2f%%  %9.2f%%  %7.2f%%  %6.1f%%  %6.1f%%\n", r.name, r.st.mae(), r.st.rmse(), r.st.maxAbs,
+			float64(r.st.inBand2)*100/float64(max(r.st.n, 1)), float64(r.st.inBand5)*100/float64(max(r.st.n, 1)))
+	}
+	fmt.Fprintf(w, "\nCPU 超过硬峰值: %d 个周期，最多超过 %.2f%%，其中 %d 个周期没有降低\n", res.hardPeakOver, res.hardPeakMax, res.hardPeakHeld)
+
+	fmt.Fprintf(w, "\n逐小时（平均值）:\n")
+	fmt.Fprintf(w, "时间(UTC)    期望值     CPU  CPU误差    内存  内存误差\n")
+	for _, h := range res.hourly {
+		n := float64(h.cpuErr.n)
+		fmt.Fprintf(w, "%-11s  %5.1f%%  %5.1f%%  %6.2f%%  %5.1f%%  %7.2f%%\n", h.start.Format("01-02 15:04"),
+			h.expected/n, h.cpu/n, h.cpuErr.mae(), h.memory/n, h.memErr.mae())
+	}
+	return nil
+}
+
+// simulate 运行模拟并返回统计
+// 在模拟时钟下运行与 agent 相同的主循环（Controller.loop）：资源信息来自 simStatsBackend，
+// 背景负载由 SIMULATE_CPU / SIMULATE_MEMORY 表达式给出（变量与 TARGET_EXPR 相同，观测值按 0 计算），
+// 填充负载按当前的计算次数和内存缓冲区计算；工作协程和突发不会启动，内存缓冲区不实际分配
+func simulate(hours int) (*simResult, error) {
+	backgroundCPU, err := compileExpr(getEnvString("SIMULATE_CPU", "5"), targetExprVars)
+	if err != nil {
+		return nil, fmt.Errorf("SIMULATE_CPU 无效: %w", err)
+	}
+	backgroundMemory, err := compileExpr(getEnvString("SIMULATE_MEMORY", "20"), targetExprVars)
+	if err != nil {
+		return nil, fmt.Errorf("SIMULATE_MEMORY 无效: %w", err)
+	}
+	cpuFreq, err := compileExpr(getEnvString("SIMULATE_CPU_FREQ", "1"), targetExprVars)
+	if err != nil {
+		return nil, fmt.Errorf("SIMULATE_CPU_FREQ 无效: %w", err)
+	}
+	gcCPU, err := compileExpr(getEnvString("SIMULATE_GC_CPU", "0"), targetExprVars)
+	if err != nil {
+		return nil, fmt.Errorf("SIMULATE_GC_CPU 无效: %w", err)
+	}
+
+	// 模拟期间替换主循环用到的全局状态，结束后恢复
+	saved := saveSimGlobals()
+	defer saved.restore()
+
+	origin := getPeakUsage()
+	loadCurveConfig()
+	loadConfiguredPolicy()
+
+	// CPU 填充模型：每个核心一个工作协程，每 count 次计算 sleep 一次
+	rate := float64(simulateItersPerMs)
+	count := uint64(initCount)
+	sleep := min(max(getEnvDuration("CPU_SLEEP", sleepTime), minSleepTime), maxSleepTime)
+	cal, _, err := resolveCalibration()
+	if err != nil {
+		return nil, fmt.Errorf("读取校准文件失败: %w", err)
+	}
+	if cal != nil {
+		kernel := getEnvString("CPU_KERNEL", "int")
+		if r, ok := cal.KernelItersPerMs[kernel]; ok && r > 0 {
+			rate = r
+			if c, ok := cal.initialCount(kernel, calculateExpectedUsage(origin), sleep); ok {
+				count = c
+			}
+		}
+	}
+
+	fc := newFakeClock(time.Now().UTC().Truncate(time.Minute))
+	sim := &simStatsBackend{
+		backgroundCPU:    backgroundCPU,
+		backgroundMemory: backgroundMemory,
+		cpuFreq:          cpuFreq,
+		gcCPU:            gcCPU,
+		itersPerMs:       rate,
+	}
+	clock, logger = fc, slog.New(slog.DiscardHandler)
+	resetSimGlobals(sim)
+	cpuController.SetCount(count)
+	cpuController.SetSleep(sleep, false)
+	if getEnvBool("CPU_FREQ_COMPENSATE", false) {
+		cpuRefFreqMHz = simulateMaxFreqMHz
+	}
+	gcCompensation = getEnvBool("CPU_GC_COMPENSATION", false)
+	peakUsageMu.Lock()
+	peakUsageOrigin, peakUsage = origin, origin
+	peakUsageMu.Unlock()
+
+	stats, err := GetSystemStats()
+	if err != nil {
+		return nil, err
+	}
+	gcTracker.Sample()
+
+	// 每次快进一个监控周期，等待主循环发布本周期的记录
+	samples := sampleStream.Subscribe()
+	defer sampleStream.Unsubscribe(samples)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	c := &Controller{expectedUsageFn: configuredExpectedUsage()}
+	go func() {
+		defer close(done)
+		c.loop(ctx, stats)
+	}()
+
+	// 主循环创建监控、跟踪误差和 peakUsage 三个定时器之后才开始快进
+	fc.BlockUntil(3)
+
+	res := &simResult{}
+	var last *HistoryEntry
+	for end := fc.Now().Add(time.Duration(hours) * time.Hour); fc.Now().Before(end); {
+		fc.Advance(monitorInterval)
+		entry := <-samples
+		// 记录中的计算次数是调整之前的值，下一条记录的计算次数就是本周期调整之后的值
+		if last != nil {
+			res.checkHardPeak(*last, entry.CPUCount)
+		}
+		res.add(entry)
+		last = &entry
+	}
+	cancel()
+	<-done
+	if last != nil {
+		res.checkHardPeak(*last, cpuController.GetCount())
+	}
+	return res, nil
+}
+
+// simStatsBackend simulate 使用的资源信息来源：背景负载按表达式计算，填充负载按当前的计算次数和内存缓冲区计算
+type simStatsBackend struct {
+	backgroundCPU    exprFunc // SIMULATE_CPU
+	backgroundMemory exprFunc // SIMULATE_MEMORY
+	cpuFreq          exprFunc // SIMULATE_CPU_FREQ
+	gcCPU            exprFunc // SIMULATE_GC_CPU
+	itersPerMs       float64  // 单个工作协程每毫秒的迭代次数
+	gcSeconds        float64  // GC 累计消耗的 CPU 时间（秒），gcTracker 从这里读取
+	lastTime         time.Time
+}
+
+func (sb *simStatsBackend) Name() string { return "simulate" }
+
+func (sb *simStatsBackend) Read(stats *SystemStats) error {
+	now := clock.Now()
+	peakUsageMu.RLock()
+	currentPeakUsage := peakUsage
+	peakUsageMu.RUnlock()
+	env := targetExprEnv(now.UTC(), currentPeakUsage, &SystemStats{})
+
+	freq := min(max(sb.cpuFreq(env), 0.1), 1)
+	gc := max(sb.gcCPU(env), 0)
+	if !sb.lastTime.IsZero() {
+		sb.gcSeconds += gc / 100 * now.Sub(sb.lastTime).Seconds() * float64(numCPU())
+	}
+	sb.lastTime = now
+
+	// 降频时每次计算耗时变长，相同的计算次数占用更多的 CPU 时间
+	busyMs := float64(cpuController.GetCount()) / (sb.itersPerMs * freq)
+	sleepMs := float64(cpuController.SleepTime()) / float64(time.Millisecond)
+	self := busyMs/(busyMs+sleepMs)*100 + gc
+	stats.CPUPercent = min(max(sb.backgroundCPU(env), 0)+self, 100)
+	stats.SelfCPUPercent = min(self, stats.CPUPercent)
+	stats.CPUFreqMHz = simulateMaxFreqMHz * freq
+
+	buffer := memoryController.GetBufferMemory()
+	background := uint64(min(max(sb.backgroundMemory(env), 0), 100) / 100 * simulateTotalMemory)
+	stats.TotalMemory = simulateTotalMemory
+	stats.UsedMemory = min(background+buffer, simulateTotalMemory)
+	stats.MemoryPercent = float64(stats.UsedMemory) / simulateTotalMemory * 100
+	stats.SelfMemory = buffer
+	return nil
+}
+
+// simBlock 模拟的内存块：只记录大小，不实际分配
+type simBlock uint64
+
+func (b simBlock) size() uint64 { return uint64(b) }
+
+func (b simBlock) data() []byte { return nil }
+
+func (b simBlock) free() {}
+
+func allocSimBlock(size uint64) (memoryBlock, error) {
+	return simBlock(size), nil
+}
+
+// simGlobals simulate 替换或修改的全局状态
+type simGlobals struct {
+	clock            Clock
+	logger           *slog.Logger
+	statsChain       *StatsChain
+	cgroupLimits     *CgroupLimits
+	sidecarTracker   *SidecarTracker
+	cpuTopology      *CPUTopology
+	gcTracker        *gcCPUTracker
+	selfOverhead     *overheadTracker
+	reclaimMonitor   *ReclaimMonitor
+	oomWatcher       *OOMWatcher
+	dailyBudget      *DailyBudget
+	cpuCredits       *CPUCredits
+	gridSignal       *GridSignal
+	killSwitch       *KillSwitch
+	spikeGenerator   *SpikeGenerator
+	cpuController    *CPUController
+	memoryController *MemoryController
+	cpuCountModel    *countModel
+	history          *History
+	historyFile      *HistoryFile
+	sampleStream     *SampleStream
+	trackingStats    *TrackingStats
+	cycleLog         *CycleLogger
+	eventPublisher   *EventPublisher
+
+	peakUsageOrigin  int
+	peakUsage        int
+	peakManagedAt    time.Time
+	nextPeakUpdateAt int64
+	peakWalk         peakWalkConfig
+	peakStates       *peakStateMachine
+	nightHardPeak    float64
+	dayHardPeak      float64
+	dayFactor        float64
+	dayWindows       []dayWindow
+	minUsage         float64
+	decisionTable    *probTable
+	controlStrategy  Strategy
+	policyScript     *policyRuntime
+	policyProbs      map[string]policyProb
+	cpuRefFreqMHz    float64
+	gcCompensation   bool
+	cpuEnabled       bool
+	memoryEnabled    bool
+	agentStatus      AgentStatus
+}
+
+// saveSimGlobals 保存 simulate 会替换或修改的全局状态
+func saveSimGlobals() *simGlobals {
+	g := &simGlobals{
+		clock:            clock,
+		logger:           logger,
+		statsChain:       statsChain,
+		cgroupLimits:     cgroupLimits,
+		sidecarTracker:   sidecarTracker,
+		cpuTopology:      cpuTopology,
+		gcTracker:        gcTracker,
+		selfOverhead:     selfOverhead,
+		reclaimMonitor:   reclaimMonitor,
+		oomWatcher:       oomWatcher,
+		dailyBudget:      dailyBudget,
+		cpuCredits:       cpuCredits,
+		gridSignal:       gridSignal,
+		killSwitch:       killSwitch,
+		spikeGenerator:   spikeGenerator,
+		cpuController:    cpuController,
+		memoryController: memoryController,
+		cpuCountModel:    cpuCountModel,
+		history:          history,
+		historyFile:      historyFile,
+		sampleStream:     sampleStream,
+		trackingStats:    trackingStats,
+		cycleLog:         cycleLog,
+		eventPublisher:   eventPublisher,
+		nextPeakUpdateAt: nextPeakUpdateAt.Load(),
+		peakWalk:         peakWalk,
+		peakStates:       peakStates,
+		nightHardPeak:    nightHardPeak,
+		dayHardPeak:      dayHardPeak,
+		dayFactor:        dayFactor,
+		dayWindows:       dayWindows,
+		minUsage:         minUsage,
+		decisionTable:    decisionTable,
+		controlStrategy:  controlStrategy,
+		policyScript:     policyScript,
+		policyProbs:      policyProbs,
+		cpuRefFreqMHz:    cpuRefFreqMHz,
+		gcCompensation:   gcCompensation,
+		cpuEnabled:       cpuEnabled,
+		memoryEnabled:    memoryEnabled,
+		agentStatus:      getAgentStatus(),
+	}
+	peakUsageMu.RLock()
+	g.peakUsageOrigin, g.peakUsage, g.peakManagedAt = peakUsageOrigin, peakUsage, peakManagedAt
+	peakUsageMu.RUnlock()
+	return g
+}
+
+// restore 恢复模拟之前的全局状态
+func (g *simGlobals) restore() {
+	clock, logger = g.clock, g.logger
+	statsChain, cgroupLimits, sidecarTracker, cpuTopology = g.statsChain, g.cgroupLimits, g.sidecarTracker, g.cpuTopology
+	gcTracker, selfOverhead, reclaimMonitor, oomWatcher = g.gcTracker, g.selfOverhead, g.reclaimMonitor, g.oomWatcher
+	dailyBudget, cpuCredits, gridSignal = g.dailyBudget, g.cpuCredits, g.gridSignal
+	killSwitch, spikeGenerator = g.killSwitch, g.spikeGenerator
+	cpuController, memoryController, cpuCountModel = g.cpuController, g.memoryController, g.cpuCountModel
+	history, historyFile, sampleStream, trackingStats = g.history, g.historyFile, g.sampleStream, g.trackingStats
+	cycleLog, eventPublisher = g.cycleLog, g.eventPublisher
+
+	peakUsageMu.Lock()
+	peakUsageOrigin, peakUsage, peakManagedAt = g.peakUsageOrigin, g.peakUsage, g.peakManagedAt
+	peakUsageMu.Unlock()
+	nextPeakUpdateAt.Store(g.nextPeakUpdateAt)
+	peakWalk, peakStates = g.peakWalk, g.peakStates
+	nightHardPeak, dayHardPeak, dayFactor, dayWindows, minUsage = g.nightHardPeak, g.dayHardPeak, g.dayFactor, g.dayWindows, g.minUsage
+	decisionTable, controlStrategy, policyScript, policyProbs = g.decisionTable, g.controlStrategy, g.policyScript, g.policyProbs
+	cpuRefFreqMHz, gcCompensation, cpuEnabled, memoryEnabled = g.cpuRefFreqMHz, g.gcCompensation, g.cpuEnabled, g.memoryEnabled
+	setAgentStatus(g.agentStatus)
+}
+
+// resetSimGlobals 把主循环用到的控制器和监控换成全新的实例：资源信息只来自 sim，
+// 不读取 cgroup、sidecar、热插拔、内存回收、OOM 和电网信号，不写入历史文件、计算次数模型和事件
+func resetSimGlobals(sim *simStatsBackend) {
+	statsChain = &StatsChain{backends: []*statsBackendState{{backend: sim}}}
+	cgroupLimits, sidecarTracker, cpuTopology = &CgroupLimits{}, &SidecarTracker{}, &CPUTopology{}
+	gcTracker = &gcCPUTracker{read: func() (float64, bool) { return sim.gcSeconds, true }}
+	selfOverhead, reclaimMonitor, oomWatcher = &overheadTracker{}, &ReclaimMonitor{}, &OOMWatcher{}
+	dailyBudget, cpuCredits, gridSignal = &DailyBudget{}, &CPUCredits{}, &GridSignal{factor: 1}
+	killSwitch, spikeGenerator = &KillSwitch{changes: make(chan bool, 1)}, &SpikeGenerator{}
+	cpuController = &CPUController{count: initCount, kernel: "int"}
+	memoryController = &MemoryController{totalMemory: simulateTotalMemory, accounting: "buffer", alloc: allocSimBlock}
+	cpuCountModel = nil
+	history, historyFile = &History{}, &HistoryFile{}
+	sampleStream = &SampleStream{subscribers: make(map[chan HistoryEntry]struct{})}
+	trackingStats, cycleLog, eventPublisher = &TrackingStats{band: 2}, &CycleLogger{}, nil
+	peakUsageMu.Lock()
+	peakManagedAt = time.Time{}
+	peakUsageMu.Unlock()
+	cpuRefFreqMHz, gcCompensation = 0, false
+	cpuEnabled, memoryEnabled = true, true
+}
@@ -0,0 +1,87 @@
+package busy
+
+import "testing"
+
+// TestSimulateHardPeak 在模拟时钟下运行控制循环：CPU 超过硬峰值的每个周期都必须降低计算次数，
+// 且超过的幅度不能超过一次调整的步长（GC 尖峰本身无法提前避免，只要求下一周期降低）
+// GC 补偿和频率补偿只用于跟踪期望值，期望值高于硬峰值能提供的占用时也不能绕过硬峰值
+func TestSimulateHardPeak(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		maxOver   float64
+		reachPeak bool // 期望值需要的实际占用高于硬峰值，应当触发硬峰值检查
+	}{
+		{
+			name:    "default",
+			env:     map[string]string{"P": "68", "SIMULATE_CPU": "8"},
+			maxOver: 0.5,
+		},
+		{
+			// 降频到一半时按参考频率换算的使用率只有实际的一半，期望值 65 对应的实际占用远高于硬峰值
+			name:      "freq-compensation",
+			env:       map[string]string{"P": "65", "CPU_FREQ_COMPENSATE": "1", "SIMULATE_CPU_FREQ": "0.5"},
+			maxOver:   0.5,
+			reachPeak: true,
+		},
+		{
+			// 每隔一分钟出现 15% 的 GC 占用，GC 补偿扣除的波动不能用来绕过硬峰值
+			name:      "gc-compensation",
+			env:       map[string]string{"P": "65", "CPU_GC_COMPENSATION": "1", "SIMULATE_GC_CPU": "minute % 2 < 1 ? 0 : 15"},
+			maxOver:   15,
+			reachPeak: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TARGET_EXPR", "peak_origin")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			res, err := simulate(12)
+			if err != nil {
+				t.Fatalf("simulate() error: %v", err)
+			}
+			if tt.reachPeak && res.hardPeakOver == 0 {
+				t.Fatalf("CPU never reached the hard peak, the hard peak check was not exercised")
+			}
+			if res.hardPeakHeld > 0 {
+				t.Errorf("%d of %d cycles above the hard peak did not decrease the CPU count", res.hardPeakHeld, res.hardPeakOver)
+			}
+			if res.hardPeakMax > tt.maxOver {
+				t.Errorf("CPU exceeded the hard peak by %.2f%%, want at most %.2f%%", res.hardPeakMax, tt.maxOver)
+			}
+		})
+	}
+}
+
+// TestSimulateRestoresGlobals 模拟结束后主循环用到的全局状态恢复为模拟之前的值
+func TestSimulateRestoresGlobals(t *testing.T) {
+	t.Setenv("P", "70")
+	t.Setenv("POLICY_SCRIPT", "")
+	useTestPolicy(t, "def policy(obs, state):\n    return None\n")
+	peakUsageMu.Lock()
+	oldOrigin, oldPeak := peakUsageOrigin, peakUsage
+	peakUsageOrigin, peakUsage = 40, 35
+	peakUsageMu.Unlock()
+	t.Cleanup(func() {
+		peakUsageMu.Lock()
+		peakUsageOrigin, peakUsage = oldOrigin, oldPeak
+		peakUsageMu.Unlock()
+	})
+
+	script, cpu, memory, chain, stream, clk := policyScript, cpuController, memoryController, statsChain, sampleStream, clock
+	count := cpu.GetCount()
+	if _, err := simulate(1); err != nil {
+		t.Fatalf("simulate() error: %v", err)
+	}
+	if policyScript != script || cpuController != cpu || memoryController != memory || statsChain != chain || sampleStream != stream || clock != clk {
+		t.Errorf("simulate() did not restore the replaced globals")
+	}
+	if got := cpuController.GetCount(); got != count {
+		t.Errorf("cpu count = %d, want %d", got, count)
+	}
+	if testPeakUsage() != 35 || peakUsageOrigin != 40 {
+		t.Errorf("peakUsageOrigin/peakUsage = %d/%d, want 40/35", peakUsageOrigin, testPeakUsage())
+	}
+}
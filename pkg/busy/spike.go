@@ -0,0 +1,133 @@
+package busy
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spikeWindow 突发期间工作协程的占空比周期
+const spikeWindow = 10 * time.Millisecond
+
+// SpikeGenerator 短时 CPU 突发：与 3 秒一次的平滑控制无关，按泊松过程随机产生 1-10 秒的满载突发，
+// 模拟真实服务中频繁出现、但会被控制循环平滑掉的短时尖峰
+type SpikeGenerator struct {
+	mu       sync.Mutex
+	rate     float64       // 每小时的平均突发次数
+	minDur   time.Duration // 突发的最短持续时间
+	maxDur   time.Duration // 突发的最长持续时间
+	percent  float64       // 突发时额外增加的整机 CPU 占用（%）
+	headroom atomic.Uint64 // 距离硬峰值的余量（math.Float64bits），突发不会超过硬峰值
+	current  atomic.Uint64 // 当前突发的占用（math.Float64bits，0 表示没有突发）
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var spikeGenerator = &SpikeGenerator{}
+
+// Start 启动突发调度
+func (sg *SpikeGenerator) Start(rate float64, minDur, maxDur time.Duration, percent float64) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if sg.cancel != nil || rate <= 0 || percent <= 0 {
+		return
+	}
+	sg.rate = rate
+	sg.minDur, sg.maxDur = minDur, max(maxDur, minDur)
+	sg.percent = percent
+	sg.headroom.Store(math.Float64bits(hardPeak()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sg.cancel = cancel
+	sg.wg.Add(1)
+	go sg.run(ctx)
+}
+
+// Stop 停止突发调度（进行中的突发立即结束）
+func (sg *SpikeGenerator) Stop() {
+	sg.mu.Lock()
+	cancel := sg.cancel
+	sg.cancel = nil
+	sg.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		sg.wg.Wait()
+	}
+}
+
+// SetHeadroom 更新当前整机 CPU 占用，用于计算突发的上限
+func (sg *SpikeGenerator) SetHeadroom(cpuPercent float64) {
+	sg.headroom.Store(math.Float64bits(max(hardPeak()-cpuPercent, 0)))
+}
+
+// Current 当前突发额外增加的 CPU 占用（%），没有突发时为 0
+func (sg *SpikeGenerator) Current() float64 {
+	return math.Float64frombits(sg.current.Load())
+}
+
+// run 按指数分布的间隔产生突发
+func (sg *SpikeGenerator) run(ctx context.Context) {
+	defer sg.wg.Done()
+	for {
+		wait := time.Duration(rand.ExpFloat64() / sg.rate * float64(time.Hour))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if killSwitch.Active() {
+			continue
+		}
+		percent := min(sg.percent, math.Float64frombits(sg.headroom.Load()))
+		if percent < 1 {
+			logger.Info("CPU 突发跳过：距离硬峰值的余量不足", "headroom", percent)
+			continue
+		}
+		duration := sg.minDur + time.Duration(rand.Int63n(int64(sg.maxDur-sg.minDur)+1))
+		logger.Info("CPU 突发开始", "percent", percent, "duration", duration)
+		sg.current.Store(math.Float64bits(percent))
+		sg.burst(ctx, percent, duration)
+		sg.current.Store(0)
+		logger.Info("CPU 突发结束")
+	}
+}
+
+// burst 启动若干协程，使整机 CPU 占用额外增加 percent，持续 duration
+func (sg *SpikeGenerator) burst(ctx context.Context, percent float64, duration time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	cores := percent / 100 * float64(numCPU())
+	workers := int(math.Ceil(cores))
+	active := time.Duration(cores / float64(workers) * float64(spikeWindow))
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var sum uint64
+			for !killSwitch.Active() {
+				start := time.Now()
+				for time.Since(start) < active {
+					for j := uint64(0); j < 1000; j++ {
+						sum += j
+					}
+				}
+				loadCPUNs.Add(int64(time.Since(start)))
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(spikeWindow - active):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
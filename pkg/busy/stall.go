@@ -0,0 +1,149 @@
+package busy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStallCycles  = 100             // 默认连续多少个周期未收敛时告警（约 5 分钟）
+	defaultStallMinGap  = 2.0             // 默认小于该差值（百分点）视为已达到目标
+	stallMinProgress    = 0.25            // 差值缩小不到起始差值的该比例时视为未收敛
+	stallWebhookTimeout = 5 * time.Second // webhook 请求超时
+)
+
+// stallStreak 一类资源当前朝同一方向调整的连续周期
+type stallStreak struct {
+	direction int     // 1=需要增加，-1=需要减少
+	cycles    int     // 连续周期数
+	startGap  float64 // 本轮开始时的差值（绝对值）
+	alerted   bool    // 本次连续调整是否已经告警
+}
+
+// StallDetector 目标无法达到的检测：某类资源连续 cycles 个周期朝同一方向调整、差值却没有缩小时告警
+// （如其他进程的占用已经超过目标，或调整步长太小），而不是无声地一直调整下去
+type StallDetector struct {
+	mu      sync.Mutex
+	cycles  int     // 告警所需的连续周期数（0 表示关闭）
+	minGap  float64 // 小于该差值视为已达到目标
+	webhook string  // 告警时 POST 的地址（空表示只写日志）
+	streaks map[string]*stallStreak
+}
+
+var stallDetector = &StallDetector{}
+
+// Configure 设置告警参数（cycles 为 0 时关闭检测）
+func (sd *StallDetector) Configure(cycles int, minGap float64, webhook string) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.cycles = max(cycles, 0)
+	sd.minGap = max(minGap, 0)
+	sd.webhook = webhook
+	sd.streaks = make(map[string]*stallStreak)
+}
+
+// Observe 记录一类资源本周期的占用和目标
+func (sd *StallDetector) Observe(name string, currentPercent, expectedUsage float64) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if sd.cycles == 0 {
+		return
+	}
+
+	gap := expectedUsage - currentPercent
+	streak := sd.streaks[name]
+	if abs(gap) < sd.minGap {
+		if streak != nil && streak.alerted {
+			logger.Info(name+"已达到目标", "current_percent", currentPercent, "expected_usage", expectedUsage, "cycles", streak.cycles)
+			emitEvent("target_reached", "resource", name, "current_percent", currentPercent, "expected_usage", expectedUsage, "cycles", streak.cycles)
+		}
+		delete(sd.streaks, name)
+		return
+	}
+
+	direction := 1
+	if gap < 0 {
+		direction = -1
+	}
+	if streak == nil || streak.direction != direction {
+		sd.streaks[name] = &stallStreak{direction: direction, cycles: 1, startGap: abs(gap)}
+		return
+	}
+
+	streak.cycles++
+	if streak.cycles%sd.cycles != 0 {
+		return
+	}
+	if abs(gap) < streak.startGap*(1-stallMinProgress) {
+		// 仍在收敛，从当前差值重新计算
+		streak.startGap = abs(gap)
+		return
+	}
+
+	reason := "调整步长过小或本程序已达到分配上限"
+	if direction < 0 {
+		reason = "其他进程的占用可能已超过目标"
+	}
+	logger.Warn(name+"占用无法达到目标",
+		"event", "target_unreachable",
+		"current_percent", currentPercent,
+		"expected_usage", expectedUsage,
+		"gap", gap,
+		"start_gap", streak.startGap,
+		"cycles", streak.cycles,
+		"reason", reason)
+	streak.alerted = true
+	streak.startGap = abs(gap)
+	emitEvent("target_unreachable", "resource", name, "current_percent", currentPercent, "expected_usage", expectedUsage,
+		"gap", gap, "cycles", streak.cycles, "reason", reason)
+
+	if sd.webhook != "" {
+		go postStallWebhook(sd.webhook, map[string]any{
+			"event":           "target_unreachable",
+			"resource":        name,
+			"current_percent": currentPercent,
+			"expected_usage":  expectedUsage,
+			"gap":             gap,
+			"cycles":          streak.cycles,
+			"reason":          reason,
+			"hostname":        hostname(),
+			"time":            clock.Now().UTC(),
+		})
+	}
+}
+
+// postStallWebhook 以 JSON 格式 POST 告警事件，失败只记录日志
+func postStallWebhook(url string, event map[string]any) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), stallWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		logger.Warn("告警 webhook 请求创建失败", "url", url, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		logger.Warn("告警 webhook 发送失败", "url", url, "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.Warn("告警 webhook 返回错误状态", "url", url, "status", resp.StatusCode)
+	}
+}
+
+// hostname 本机主机名（获取失败时为空）
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
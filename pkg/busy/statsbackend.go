@@ -0,0 +1,335 @@
+package busy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	statsRetryInterval  = 30 * time.Second // 来源失败后多久再重试（恢复后切换回优先级更高的来源）
+	statsBaselineMaxAge = time.Minute      // CPU 基准超过该时长时重新采样（差值跨度过长，结果不代表当前）
+)
+
+// defaultStatsBackends 默认的来源顺序
+var defaultStatsBackends = []string{"procfs", "cgroup", "sysinfo"}
+
+// errStatsWarmingUp 来源刚启用或很久没有使用，本次只记录 CPU 基准（不计为失败）
+var errStatsWarmingUp = errors.New("首次采样，尚无 CPU 基准")
+
+// statsBackend 基础资源信息的来源：填充内存、CPU（含本进程 CPU）、本进程内存和平均负载
+type statsBackend interface {
+	Name() string
+	Read(stats *SystemStats) error
+}
+
+// statsBackendState 单个来源的健康状态
+type statsBackendState struct {
+	backend   statsBackend
+	failures  uint64    // 累计失败次数
+	retryAt   time.Time // 失败后在此之前不再尝试
+	lastError string
+}
+
+// StatsChain 按顺序尝试各来源，失败的来源在 statsRetryInterval 之后重试，恢复后切换回去
+// 所有来源都不可用时 GetSystemStats 返回错误，主循环冻结调整（保持当前负载）
+// 曾在根文件系统为 NFS 的容器中遇到 /proc 读取持续失败，原来会一直使用过时的值继续调整
+type StatsChain struct {
+	mu       sync.Mutex
+	backends []*statsBackendState
+	active   string // 当前使用的来源（空表示全部不可用）
+}
+
+var statsChain = &StatsChain{backends: []*statsBackendState{{backend: procfsStatsBackend{}}}}
+
+// Configure 按名称设置来源顺序（STATS_BACKENDS），无法使用的来源记录日志后跳过
+func (sc *StatsChain) Configure(names []string) error {
+	var backends []*statsBackendState
+	for _, name := range names {
+		var backend statsBackend
+		switch name {
+		case "procfs":
+			backend = procfsStatsBackend{}
+		case "cgroup":
+			cb, err := newCgroupStatsBackend()
+			if err != nil {
+				logger.Info("cgroup 资源信息来源不可用，跳过", "error", err)
+				continue
+			}
+			backend = cb
+		case "sysinfo":
+			backend = &sysinfoStatsBackend{}
+		default:
+			return fmt.Errorf("未知的资源信息来源: %s（可选: %s）", name, strings.Join(defaultStatsBackends, "、"))
+		}
+		backends = append(backends, &statsBackendState{backend: backend})
+	}
+	if len(backends) == 0 {
+		return errors.New("没有可用的资源信息来源")
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.backends = backends
+	return nil
+}
+
+// Read 从第一个可用的来源读取基础资源信息
+func (sc *StatsChain) Read(stats *SystemStats) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	now := clock.Now()
+	var errs []string
+	for _, state := range sc.backends {
+		if now.Before(state.retryAt) {
+			continue
+		}
+		err := state.backend.Read(stats)
+		if err == nil {
+			sc.setActive(state.backend.Name())
+			return nil
+		}
+		*stats = SystemStats{}
+		if errors.Is(err, errStatsWarmingUp) {
+			continue
+		}
+		state.failures++
+		state.retryAt = now.Add(statsRetryInterval)
+		state.lastError = err.Error()
+		logger.Warn("资源信息来源读取失败，尝试下一个来源", "backend", state.backend.Name(), "error", err, "retry_in", statsRetryInterval)
+		errs = append(errs, state.backend.Name()+": "+err.Error())
+	}
+	sc.setActive("")
+	if len(errs) == 0 {
+		return errors.New("所有资源信息来源都在等待重试或采样基准")
+	}
+	return fmt.Errorf("所有资源信息来源都不可用: %s", strings.Join(errs, "; "))
+}
+
+// setActive 记录当前使用的来源，变化时记录日志并发布事件（调用方持有锁）
+func (sc *StatsChain) setActive(name string) {
+	if name == sc.active {
+		return
+	}
+	if name != "" { // 全部不可用时由主循环记录冻结日志
+		logger.Info("资源信息来源切换", "backend", name, "previous", sc.active)
+	}
+	emitEvent("stats_backend", "backend", name, "previous", sc.active)
+	sc.active = name
+}
+
+// Active 当前使用的来源（空表示全部不可用）
+func (sc *StatsChain) Active() string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.active
+}
+
+// statsBackendHealth 单个来源的健康状态（/metrics 使用）
+type statsBackendHealth struct {
+	Name      string
+	Active    bool
+	Failures  uint64
+	LastError string
+}
+
+// Health 各来源的健康状态（按优先级顺序）
+func (sc *StatsChain) Health() []statsBackendHealth {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	health := make([]statsBackendHealth, len(sc.backends))
+	for i, state := range sc.backends {
+		name := state.backend.Name()
+		health[i] = statsBackendHealth{Name: name, Active: name == sc.active, Failures: state.failures, LastError: state.lastError}
+	}
+	return health
+}
+
+// procfsStatsBackend /proc/meminfo、/proc/stat、/proc/self/status 和 /proc/loadavg（整机数据，默认来源）
+type procfsStatsBackend struct{}
+
+func (procfsStatsBackend) Name() string { return "procfs" }
+
+func (procfsStatsBackend) Read(stats *SystemStats) error {
+	// 长时间没有成功读取（如从其他来源切换回来）时重新采样基准，第一次读取时 getCPUStats 自己处理
+	stale := !lastCPUTime.IsZero() && clock.Now().Sub(lastCPUTime) > statsBaselineMaxAge
+	if stale {
+		lastCPUTime = time.Time{}
+	}
+	if err := getMemoryStats(stats); err != nil {
+		return fmt.Errorf("获取内存信息失败: %w", err)
+	}
+	if err := getCPUStats(stats); err != nil {
+		return fmt.Errorf("获取 CPU 信息失败: %w", err)
+	}
+	rss, swap, err := readSelfMemory()
+	if err != nil {
+		return fmt.Errorf("获取本进程内存失败: %w", err)
+	}
+	stats.SelfMemory, stats.SelfSwap = rss, swap
+	if err := getLoadAvg(stats); err != nil {
+		return fmt.Errorf("获取平均负载失败: %w", err)
+	}
+	if stale {
+		return errStatsWarmingUp
+	}
+	return nil
+}
+
+// cpuBaseline 基于累计 CPU 时间的来源使用的基准
+type cpuBaseline struct {
+	usage    time.Duration // 累计 CPU 时间
+	self     time.Duration // 本进程累计 CPU 时间
+	lastTime time.Time
+}
+
+// update 记录新的累计值，返回与基准的差值换算的整机和本进程 CPU 使用率（基准无效时返回 errStatsWarmingUp）
+func (b *cpuBaseline) update(usage, self time.Duration) (float64, float64, error) {
+	now := clock.Now()
+	last := *b
+	b.usage, b.self, b.lastTime = usage, self, now
+	elapsed := now.Sub(last.lastTime)
+	if last.lastTime.IsZero() || elapsed > statsBaselineMaxAge || elapsed <= 0 || usage < last.usage || self < last.self {
+		return 0, 0, errStatsWarmingUp
+	}
+	capacity := float64(elapsed) * float64(numCPU())
+	return min(float64(usage-last.usage)/capacity*100, 100), min(float64(self-last.self)/capacity*100, 100), nil
+}
+
+// cgroupStatsBackend 本进程所在 cgroup（v2）的 memory.current 和 cpu.stat，只包含该 cgroup 内的进程
+// 在容器中约等于容器的占用；使用率仍以整机总内存（sysinfo）和核心数为分母，CGROUP_LIMITS 的换算照常生效
+type cgroupStatsBackend struct {
+	dir      string
+	baseline cpuBaseline
+}
+
+// newCgroupStatsBackend 在启动时定位 cgroup 目录（之后 /proc 不可用时也能继续读取）
+func newCgroupStatsBackend() (*cgroupStatsBackend, error) {
+	dir, err := selfCgroupDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := readCgroupUint(filepath.Join(dir, "memory.current")); err != nil {
+		return nil, fmt.Errorf("cgroup 没有内存统计（根 cgroup 或未启用 memory 控制器）: %w", err)
+	}
+	return &cgroupStatsBackend{dir: dir}, nil
+}
+
+func (cb *cgroupStatsBackend) Name() string { return "cgroup" }
+
+func (cb *cgroupStatsBackend) Read(stats *SystemStats) error {
+	info, err := readSysinfo()
+	if err != nil {
+		return err
+	}
+	current, err := readCgroupUint(filepath.Join(cb.dir, "memory.current"))
+	if err != nil {
+		return err
+	}
+	usage, err := readCgroupCPUUsage(cb.dir)
+	if err != nil {
+		return err
+	}
+	self, err := selfCPUTime()
+	if err != nil {
+		return err
+	}
+	stats.TotalMemory = info.totalMemory
+	stats.UsedMemory = min(current, info.totalMemory)
+	stats.MemoryPercent = float64(stats.UsedMemory) / float64(info.totalMemory) * 100
+	stats.Load1 = info.load1
+	stats.SelfMemory = estimateSelfMemory()
+	cpuPercent, selfPercent, err := cb.baseline.update(time.Duration(usage)*time.Microsecond, self)
+	if err != nil {
+		return err
+	}
+	stats.CPUPercent, stats.SelfCPUPercent = cpuPercent, min(selfPercent, cpuPercent)
+	return nil
+}
+
+// sysinfoStatsBackend sysinfo(2) 系统调用，不读取任何文件，作为冻结之前的最后一个来源
+// 没有 CPU 时间，整机 CPU 使用率按 1 分钟平均负载估算；已用内存包含页缓存（偏高，调整偏保守）
+type sysinfoStatsBackend struct {
+	baseline cpuBaseline
+}
+
+func (sb *sysinfoStatsBackend) Name() string { return "sysinfo" }
+
+func (sb *sysinfoStatsBackend) Read(stats *SystemStats) error {
+	info, err := readSysinfo()
+	if err != nil {
+		return err
+	}
+	self, err := selfCPUTime()
+	if err != nil {
+		return err
+	}
+	stats.TotalMemory = info.totalMemory
+	stats.UsedMemory = info.totalMemory - min(info.freeMemory, info.totalMemory)
+	stats.MemoryPercent = float64(stats.UsedMemory) / float64(info.totalMemory) * 100
+	stats.Load1 = info.load1
+	stats.SelfMemory = estimateSelfMemory()
+	_, selfPercent, err := sb.baseline.update(0, self)
+	if err != nil {
+		return err
+	}
+	stats.CPUPercent = min(max(info.load1/float64(numCPU())*100, selfPercent), 100)
+	stats.SelfCPUPercent = selfPercent
+	return nil
+}
+
+// sysinfoResult sysinfo(2) 中用到的字段
+type sysinfoResult struct {
+	totalMemory uint64
+	freeMemory  uint64 // MemFree + Buffers（不含页缓存）
+	load1       float64
+}
+
+// readSysinfo 调用 sysinfo(2)
+func readSysinfo() (sysinfoResult, error) {
+	var info syscall.Sysinfo_t
+	if err := syscall.Sysinfo(&info); err != nil {
+		return sysinfoResult{}, fmt.Errorf("sysinfo 失败: %w", err)
+	}
+	unit := max(uint64(info.Unit), 1)
+	result := sysinfoResult{
+		totalMemory: uint64(info.Totalram) * unit,
+		freeMemory:  (uint64(info.Freeram) + uint64(info.Bufferram)) * unit,
+		load1:       float64(info.Loads[0]) / (1 << 16), // 定点数，SI_LOAD_SHIFT = 16
+	}
+	if result.totalMemory == 0 {
+		return sysinfoResult{}, errors.New("sysinfo 没有返回总内存")
+	}
+	return result, nil
+}
+
+// selfCPUTime 本进程累计的 CPU 时间（getrusage，不读取 /proc）
+func selfCPUTime() (time.Duration, error) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, err
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), nil
+}
+
+// selfMemorySamples Go 运行时已映射且未归还系统的内存
+var selfMemorySamples = []metrics.Sample{{Name: "/memory/classes/total:bytes"}, {Name: "/memory/classes/heap/released:bytes"}}
+
+// estimateSelfMemory 不读取 /proc 时估算本进程的常驻内存：Go 运行时占用的内存加上堆外的内存缓冲区（MEMORY_BACKEND）
+func estimateSelfMemory() uint64 {
+	metrics.Read(selfMemorySamples)
+	var total, released uint64
+	if selfMemorySamples[0].Value.Kind() == metrics.KindUint64 {
+		total = selfMemorySamples[0].Value.Uint64()
+	}
+	if selfMemorySamples[1].Value.Kind() == metrics.KindUint64 {
+		released = selfMemorySamples[1].Value.Uint64()
+	}
+	return total - min(released, total) + memoryController.GetOffHeapMemory()
+}
@@ -0,0 +1,187 @@
+package busy
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+)
+
+// maxScriptSteps 策略脚本每个周期最多调整的步数
+const maxScriptSteps = 100
+
+// Decision 控制策略在一个周期内对一类资源的决定
+type Decision struct {
+	Steps int    // 调整的步数：正数为增加，负数为减少，0 表示跳过（每步为一次 0.1% 的调整）
+	Label string // 写入调整日志的决策依据（如上涨概率）
+}
+
+// Strategy 控制策略：每个周期根据一类资源的当前占用和期望值决定调整的方向和步数
+// 硬峰值和最低占用的强制调整在策略之外处理，所有策略都受其约束
+type Strategy interface {
+	Decide(name string, currentPercent, expectedUsage float64) Decision
+}
+
+// StrategyFactory 创建控制策略（从环境变量读取参数）
+type StrategyFactory func() Strategy
+
+var (
+	strategyRegistry   = make(map[string]StrategyFactory) // 已注册的控制策略
+	strategyRegistryMu sync.RWMutex                       // 保护 strategyRegistry
+	controlStrategy    Strategy                           // CONTROL_STRATEGY 选择的控制策略
+)
+
+// RegisterStrategy 注册控制策略，注册后可以通过 CONTROL_STRATEGY 环境变量选择（通常在 init 中调用）
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategyRegistryMu.Lock()
+	defer strategyRegistryMu.Unlock()
+	strategyRegistry[name] = factory
+}
+
+// newStrategy 按名称创建控制策略
+func newStrategy(name string) (Strategy, error) {
+	strategyRegistryMu.RLock()
+	defer strategyRegistryMu.RUnlock()
+
+	factory, ok := strategyRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的控制策略: %s（可选: %v）", name, strategyNames())
+	}
+	return factory(), nil
+}
+
+// strategyNames 已注册的控制策略名称（调用方需持有读锁）
+func strategyNames() []string {
+	names := make([]string, 0, len(strategyRegistry))
+	for name := range strategyRegistry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// loadStrategy 按 CONTROL_STRATEGY 创建控制策略（无效时使用 probability）
+func loadStrategy() {
+	name := getEnvString("CONTROL_STRATEGY", "probability")
+	strategy, err := newStrategy(name)
+	if err != nil {
+		logger.Warn("CONTROL_STRATEGY 无效，使用默认值", "error", err, "default", "probability")
+		strategy = &probabilityWalk{}
+	}
+	controlStrategy = strategy
+}
+
+func init() {
+	RegisterStrategy("probability", func() Strategy { return &probabilityWalk{} })
+	RegisterStrategy("pid", newPIDStrategy)
+	RegisterStrategy("script", func() Strategy { return &scriptedStrategy{} })
+	RegisterStrategy("bangbang", func() Strategy {
+		return &bangBangStrategy{
+			band:  max(getEnvFloat("BANGBANG_BAND", 3), 0),
+			steps: max(getEnvInt("BANGBANG_STEPS", 1), 1),
+		}
+	})
+}
+
+// probabilityWalk 趋势性概率算法（默认）：按差值决定是否调整和上涨概率，每个周期随机调整一步
+type probabilityWalk struct{}
+
+func (probabilityWalk) Decide(name string, currentPercent, expectedUsage float64) Decision {
+	diff := currentPercent - expectedUsage // 正数表示当前 > 期望（需要减少），负数表示当前 < 期望（需要增加）
+
+	// 计算调整概率（是否执行调整）和上涨/下跌的概率
+	// 差值越大，概率越极端；差值越小，概率越接近；策略脚本给出概率时以脚本为准
+	adjustProb, increaseProb := policyOverride(name, calculateAdjustProbability(abs(diff)), calculateDirectionProbability(diff, expectedUsage))
+	if !shouldAdjust(adjustProb) {
+		return Decision{}
+	}
+
+	// 随机决定是增加还是减少占用
+	steps := -1
+	if rand.Float64() < increaseProb {
+		steps = 1
+	}
+	return Decision{Steps: steps, Label: formatProbability(increaseProb)}
+}
+
+// pidState 一类资源的 PID 状态
+type pidState struct {
+	integral float64
+	lastErr  float64
+	started  bool
+}
+
+// pidStrategy PID 控制：误差 = 期望 - 当前，输出为本周期调整的步数，没有随机性
+type pidStrategy struct {
+	kp, ki, kd float64
+	maxSteps   int
+	states     map[string]*pidState
+}
+
+func newPIDStrategy() Strategy {
+	return &pidStrategy{
+		kp:       getEnvFloat("PID_KP", 0.5),
+		ki:       getEnvFloat("PID_KI", 0.05),
+		kd:       getEnvFloat("PID_KD", 0),
+		maxSteps: max(getEnvInt("PID_MAX_STEPS", 20), 1),
+		states:   make(map[string]*pidState),
+	}
+}
+
+func (ps *pidStrategy) Decide(name string, currentPercent, expectedUsage float64) Decision {
+	state := ps.states[name]
+	if state == nil {
+		state = &pidState{}
+		ps.states[name] = state
+	}
+
+	err := expectedUsage - currentPercent
+	var derivative float64
+	if state.started {
+		derivative = err - state.lastErr
+	}
+	state.lastErr, state.started = err, true
+
+	// 积分项限制在最大步数以内，避免目标无法达到时积分饱和；
+	// ki 不为正时积分项不参与输出，同样限制（为 0），运行中修改 ki 后不会突然输出累积多时的积分
+	limit := 0.0
+	if ps.ki > 0 {
+		limit = float64(ps.maxSteps) / ps.ki
+	}
+	state.integral = min(max(state.integral+err, -limit), limit)
+
+	output := ps.kp*err + ps.ki*state.integral + ps.kd*derivative
+	steps := int(math.Round(min(max(output, -float64(ps.maxSteps)), float64(ps.maxSteps))))
+	return Decision{Steps: steps, Label: fmt.Sprintf("PID%+d", steps)}
+}
+
+// bangBangStrategy 阈值控制：低于 期望 - band 时增加，高于 期望 + band 时减少，带内不调整，没有随机性
+type bangBangStrategy struct {
+	band  float64 // 允许的偏差（百分点）
+	steps int     // 超出范围时每个周期调整的步数
+}
+
+func (bs *bangBangStrategy) Decide(name string, currentPercent, expectedUsage float64) Decision {
+	switch {
+	case currentPercent < expectedUsage-bs.band:
+		return Decision{Steps: bs.steps, Label: "低于范围"}
+	case currentPercent > expectedUsage+bs.band:
+		return Decision{Steps: -bs.steps, Label: "高于范围"}
+	}
+	return Decision{}
+}
+
+// scriptedStrategy 策略脚本直接给出步数（<资源>_steps），脚本未给出时使用趋势性概率算法
+type scriptedStrategy struct {
+	fallback probabilityWalk
+}
+
+func (ss *scriptedStrategy) Decide(name string, currentPercent, expectedUsage float64) Decision {
+	prob, ok := policyProbs[name]
+	if !ok || math.IsNaN(prob.steps) {
+		return ss.fallback.Decide(name, currentPercent, expectedUsage)
+	}
+	steps := int(math.Round(min(max(prob.steps, -maxScriptSteps), maxScriptSteps)))
+	return Decision{Steps: steps, Label: fmt.Sprintf("脚本%+d", steps)}
+}
@@ -0,0 +1,93 @@
+package busy
+
+import "testing"
+
+func TestBangBangStrategy(t *testing.T) {
+	bs := &bangBangStrategy{band: 3, steps: 2}
+	tests := []struct {
+		name    string
+		current float64
+		want    int
+	}{
+		{name: "below", current: 46.9, want: 2},
+		{name: "in-band-low", current: 47, want: 0},
+		{name: "in-band", current: 50, want: 0},
+		{name: "in-band-high", current: 53, want: 0},
+		{name: "above", current: 53.1, want: -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bs.Decide("CPU", tt.current, 50); got.Steps != tt.want {
+				t.Errorf("Decide(%v, 50).Steps = %d, want %d", tt.current, got.Steps, tt.want)
+			}
+		})
+	}
+}
+
+func TestPIDStrategy(t *testing.T) {
+	type step struct {
+		current float64 // 期望值固定为 50
+		want    int
+	}
+	tests := []struct {
+		name       string
+		kp, ki, kd float64
+		maxSteps   int
+		steps      []step
+	}{
+		{
+			// 四舍五入（远离 0），小于半步时不调整
+			name: "proportional", kp: 0.5, maxSteps: 20,
+			steps: []step{{40, 5}, {47, 2}, {53, -2}, {49.1, 0}},
+		},
+		{
+			name: "output-clamp", kp: 0.5, maxSteps: 20,
+			steps: []step{{0, 20}, {100, -20}},
+		},
+		{
+			// 积分限制在 maxSteps / ki = 8：长时间达不到目标后，误差反向时立即减小输出
+			name: "anti-windup", ki: 0.5, maxSteps: 4,
+			steps: []step{{40, 4}, {40, 4}, {40, 4}, {40, 4}, {40, 4}, {54, 2}, {54, 0}},
+		},
+		{
+			// 第一次采样没有上一次的误差，微分项为 0
+			name: "derivative", kd: 1, maxSteps: 20,
+			steps: []step{{40, 0}, {37, 3}, {37, 0}, {45, -8}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := &pidStrategy{kp: tt.kp, ki: tt.ki, kd: tt.kd, maxSteps: tt.maxSteps, states: make(map[string]*pidState)}
+			for i, s := range tt.steps {
+				if got := ps.Decide("CPU", s.current, 50); got.Steps != s.want {
+					t.Errorf("step %d: Decide(%v, 50).Steps = %d, want %d", i, s.current, got.Steps, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPIDStrategyIntegralBoundedWithoutKI(t *testing.T) {
+	ps := &pidStrategy{kp: 0.5, maxSteps: 20, states: make(map[string]*pidState)}
+	for range 1000 {
+		ps.Decide("CPU", 0, 50)
+	}
+	if integral := ps.states["CPU"].integral; integral != 0 {
+		t.Fatalf("integral = %v with ki = 0, want 0", integral)
+	}
+
+	// 之后启用积分项时从 0 开始累积
+	ps.ki = 0.1
+	if got := ps.Decide("CPU", 50, 50); got.Steps != 0 {
+		t.Errorf("Decide(50, 50).Steps = %d after enabling ki, want 0", got.Steps)
+	}
+}
+
+func TestPIDStrategyPerResource(t *testing.T) {
+	ps := &pidStrategy{kd: 1, maxSteps: 20, states: make(map[string]*pidState)}
+	ps.Decide("CPU", 40, 50)
+	// 内存的第一次采样不使用 CPU 的误差计算微分
+	if got := ps.Decide("内存", 30, 50); got.Steps != 0 {
+		t.Errorf("first 内存 Decide().Steps = %d, want 0", got.Steps)
+	}
+}
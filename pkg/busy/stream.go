@@ -0,0 +1,121 @@
+package busy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	streamBuffer    = 64               // 每个订阅者缓存的记录数，客户端读取过慢时丢弃新的记录
+	streamHeartbeat = 15 * time.Second // 没有新记录时发送注释行的间隔，避免代理断开空闲连接
+)
+
+// SampleStream 把每个监控周期的记录实时推送给 GET /stream 的订阅者（Server-Sent Events）
+type SampleStream struct {
+	mu          sync.Mutex
+	subscribers map[chan HistoryEntry]struct{}
+}
+
+var sampleStream = &SampleStream{subscribers: make(map[chan HistoryEntry]struct{})}
+
+// Subscribe 添加订阅者，返回接收记录的 channel
+func (s *SampleStream) Subscribe() chan HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan HistoryEntry, streamBuffer)
+	s.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe 移除订阅者
+func (s *SampleStream) Unsubscribe(ch chan HistoryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, ch)
+}
+
+// Publish 把记录发送给所有订阅者（不阻塞主循环，缓存已满的订阅者丢弃本条记录）
+func (s *SampleStream) Publish(entry HistoryEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// handleStream 以 Server-Sent Events 实时推送每个监控周期的采样值和决定（事件名 sample，数据与 /history 的元素相同）
+// 参数 since 与 /history 相同：连接后先补发这段时间内的历史记录
+func handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	since, err := parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 先订阅再读取历史，避免两者之间的记录丢失
+	ch := sampleStream.Subscribe()
+	defer sampleStream.Unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var last time.Time
+	if !since.IsZero() {
+		for _, entry := range history.Since(since, 0) {
+			if err := writeSampleEvent(w, entry); err != nil {
+				return
+			}
+			last = entry.Time
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case entry := <-ch:
+			// 补发历史时已经发送过的记录
+			if !entry.Time.After(last) {
+				continue
+			}
+			if err := writeSampleEvent(w, entry); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSampleEvent 输出一条 sample 事件，id 为记录时间的 Unix 毫秒数
+func writeSampleEvent(w http.ResponseWriter, entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: sample\nid: %d\ndata: %s\n\n", entry.Time.UnixMilli(), data)
+	return err
+}
@@ -0,0 +1,111 @@
+package busy
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultWorkerStallTimeout = 30 * time.Second // 默认工作协程多长时间没有进展时视为卡住
+	superviseInterval         = 5 * time.Second  // 检查工作协程的间隔
+	maxLoopRestartDelay       = time.Minute      // 主循环 panic 后重新进入的最长等待
+)
+
+var (
+	panicsTotal         atomic.Uint64 // 启动以来恢复的 panic 次数（工作协程和主循环）
+	workerRestartsTotal atomic.Uint64 // 启动以来重启的 CPU 工作协程数
+)
+
+// reportPanic 记录一次已恢复的 panic（在 defer 中 recover 之后调用），包含调用栈，并发送 panic 事件
+func reportPanic(component string, r any) {
+	panicsTotal.Add(1)
+	logger.Error("panic 已恢复", "component", component, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	emitEvent("panic", "component", component, "panic", fmt.Sprint(r))
+}
+
+// supervise 定期检查工作协程：panic 退出或超过 stallTimeout 没有进展的协程用相同的核心、计算次数和强度重新启动，
+// 避免一个协程出错后负载能力无声地降低，控制循环只能用剩下的协程追赶期望值
+// SCHED_IDLE 的协程在整机繁忙时可能长时间得不到调度，不检查是否卡住
+func (cc *CPUController) supervise(ctx context.Context, stallTimeout time.Duration, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(superviseInterval)
+	defer ticker.Stop()
+
+	type progressMark struct {
+		progress uint64
+		at       time.Time
+	}
+	marks := make(map[*cpuWorkerState]progressMark)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		cc.mu.Lock()
+		seen := make(map[*cpuWorkerState]progressMark, len(cc.workers))
+		for i, w := range cc.workers {
+			reason, stalled := "", time.Duration(0)
+			progress := w.progress.Load()
+			mark, ok := marks[w]
+			switch {
+			case w.exited.Load():
+				reason = "已退出"
+			case !ok || progress != mark.progress:
+				mark = progressMark{progress: progress, at: now}
+			case stallTimeout > 0 && !cc.schedIdle && now.Sub(mark.at) > stallTimeout:
+				reason, stalled = "没有进展", now.Sub(mark.at)
+			}
+			if reason == "" {
+				seen[w] = mark
+				continue
+			}
+			replacement := cc.restartWorker(i)
+			seen[replacement] = progressMark{progress: replacement.progress.Load(), at: now}
+			workerRestartsTotal.Add(1)
+			logger.Warn("CPU 工作协程异常，已重新启动", "reason", reason, "core", w.core, "stalled", stalled.Round(time.Second))
+			emitEvent("worker_restart", "resource", "CPU", "reason", reason, "core", w.core)
+		}
+		marks = seen
+		cc.mu.Unlock()
+	}
+}
+
+// restartWorker 用相同的核心、计算次数和强度启动新的协程替换第 i 个工作协程（调用方需持有锁）
+// 卡住的协程无法强制结束，取消后不再等待它退出（Stop 时也不等待）
+func (cc *CPUController) restartWorker(i int) *cpuWorkerState {
+	old := cc.workers[i]
+	old.cancel()
+	cc.release(old)
+
+	w := &cpuWorkerState{core: old.core}
+	atomic.StoreUint64(&w.count, atomic.LoadUint64(&old.count))
+	w.weight.Store(old.weight.Load())
+	cc.workers[i] = w
+	cc.launch(w)
+	return w
+}
+
+// release 工作协程结束（正常退出、panic 或被放弃）时调用一次 wg.Done
+func (cc *CPUController) release(w *cpuWorkerState) {
+	if w.released.CompareAndSwap(false, true) {
+		cc.wg.Done()
+	}
+}
+
+// runLoop 运行一次主循环，返回是否因为 panic 退出
+func (c *Controller) runLoop(ctx context.Context, stats *SystemStats) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic("controller", r)
+			panicked = true
+		}
+	}()
+	c.loop(ctx, stats)
+	return false
+}
@@ -0,0 +1,376 @@
+package busy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SystemStats 系统资源统计
+type SystemStats struct {
+	CPUPercent        float64   // CPU 使用率百分比
+	MemoryPercent     float64   // 内存使用率百分比
+	TotalMemory       uint64    // 总内存（字节）
+	UsedMemory        uint64    // 已用内存（字节）
+	DiskPercent       float64   // 磁盘使用率百分比（启用磁盘控制器时有效）
+	CPUTemp           float64   // CPU 温度（摄氏度，没有温度传感器时为 0）
+	CPUFreqMHz        float64   // CPU 平均当前频率（MHz，无法获取时为 0）
+	StealPercent      float64   // CPU 被宿主机抢占（steal）的时间百分比
+	IOWaitPercent     float64   // CPU 等待 IO（iowait）的时间百分比
+	ReclaimScanRate   float64   // 内核回收内存时每秒扫描的页数（kswapd + 同步回收）
+	DirectReclaimRate float64   // 分配内存时同步回收每秒扫描的页数（出现时说明分配已经被阻塞）
+	Load1             float64   // 1 分钟平均负载
+	PerCPU            []float64 // 每个核心的 CPU 使用率百分比（下标为核心编号）
+
+	SelfCPUPercent     float64 // 本进程贡献的 CPU 使用率百分比
+	SelfMemory         uint64  // 本进程的常驻内存（VmRSS，字节）
+	SelfSwap           uint64  // 本进程被换出的内存（VmSwap，字节）
+	SelfMemoryPercent  float64 // 本进程贡献的内存使用率百分比
+	OtherCPUPercent    float64 // 其他进程贡献的 CPU 使用率百分比
+	OtherMemoryPercent float64 // 其他进程贡献的内存使用率百分比
+	GCCPUPercent       float64 // 本进程 GC 在上个周期消耗的 CPU 占整机的百分比
+	GCCPUAvgPercent    float64 // GC CPU 占用的平均值
+	LoadCPUPercent     float64 // 本进程中有意产生的 CPU 负载（工作协程和突发的计算时间）
+	OverheadCPUPercent float64 // 本进程中控制本身的 CPU 开销（监控、日志、GC、运行时等）
+	OverheadMemory     uint64  // 本进程中内存缓冲区以外的内存（Go 运行时、已释放但未归还的内存等，字节）
+
+	SidecarCPUPercent    float64 // sidecar 模式下目标容器的 CPU 使用率百分比（占整机）
+	SidecarMemoryPercent float64 // sidecar 模式下目标容器的内存使用率百分比（占整机）
+}
+
+// cpuTimes /proc/stat 中 cpu 行的各项时间（单位：jiffies）
+type cpuTimes struct {
+	user      uint64
+	nice      uint64
+	system    uint64
+	idle      uint64
+	iowait    uint64
+	irq       uint64
+	softirq   uint64
+	steal     uint64
+	guest     uint64 // 已包含在 user 中
+	guestNice uint64 // 已包含在 nice 中
+}
+
+// total 总 CPU 时间（guest 已计入 user/nice，不重复累加）
+func (t cpuTimes) total() uint64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+var (
+	lastCPUTimes    cpuTimes
+	lastPerCPUTimes map[int]cpuTimes
+	lastCPUTime     time.Time
+	lastSelfTicks   uint64
+
+	// cpuExcludeSteal 为 true 时 steal 时间不计入分母：
+	// 超售的虚拟机上 steal 时间本程序无法使用，计入分母会导致永远追不上期望值
+	cpuExcludeSteal bool
+
+	// cpuIOWaitBusy 为 true 时 iowait 计入忙碌时间，否则计入空闲时间
+	cpuIOWaitBusy bool
+
+	// procRoot procfs 的挂载路径（容器中宿主机的 /proc 可能挂载在 /host/proc）
+	procRoot = "/proc"
+)
+
+// procPath 返回 procfs 中文件的路径，如 procPath("stat") 返回 /proc/stat
+func procPath(elem ...string) string {
+	return filepath.Join(append([]string{procRoot}, elem...)...)
+}
+
+// GetSystemStats 获取系统资源使用情况
+func GetSystemStats() (*SystemStats, error) {
+	stats := &SystemStats{}
+
+	// 获取内存、CPU、本进程内存和平均负载（按 STATS_BACKENDS 的顺序选择可用的来源）
+	if err := statsChain.Read(stats); err != nil {
+		return nil, err
+	}
+
+	// 容器有 cgroup 限制时换算为相对限制的使用率（CGROUP_LIMITS）
+	if err := cgroupLimits.Apply(stats); err != nil {
+		return nil, fmt.Errorf("读取 cgroup 限制失败: %w", err)
+	}
+
+	// 区分本进程和其他进程的贡献
+	getSelfStats(stats)
+
+	// 获取目标容器的占用（仅在 sidecar 模式下）
+	if sidecarTracker.Enabled() {
+		cpuPercent, memory, err := sidecarTracker.Stats()
+		if err != nil {
+			return nil, fmt.Errorf("获取目标容器占用失败: %w", err)
+		}
+		stats.SidecarCPUPercent = cpuPercent
+		stats.SidecarMemoryPercent = float64(memory) / float64(stats.TotalMemory) * 100
+	}
+
+	// 获取 CPU 温度（没有温度传感器时忽略）
+	if temp, err := readCPUTemperature(); err == nil {
+		stats.CPUTemp = temp
+	}
+
+	// 获取 CPU 频率（无法获取时忽略；来源已经给出频率时不再读取，如 simulate 的模拟来源）
+	if stats.CPUFreqMHz == 0 {
+		if freq, err := readCPUFrequency(); err == nil {
+			stats.CPUFreqMHz = freq
+		}
+	}
+
+	// 获取磁盘信息（仅在启用磁盘控制器时）
+	if diskController.Enabled() {
+		percent, _, err := getDiskUsage(diskController.Dir())
+		if err != nil {
+			return nil, fmt.Errorf("获取磁盘信息失败: %w", err)
+		}
+		stats.DiskPercent = percent
+	}
+
+	return stats, nil
+}
+
+// memAvailableFallback 没有 MemAvailable 时只记录一次日志
+var memAvailableFallback sync.Once
+
+// getMemoryStats 从 /proc/meminfo 获取内存信息
+// 3.14 之前的内核没有 MemAvailable，此时按 MemFree + Buffers + Cached 估算可用内存（与旧版 free 的 -/+ buffers/cache 一致），
+// 否则已用内存被当作 100%，每个周期都会强制降低
+func getMemoryStats(stats *SystemStats) error {
+	file, err := os.Open(procPath("meminfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var memTotal, memAvailable, memFree, buffers, cached uint64
+	hasAvailable := false
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		key := fields[0]
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		// 转换为字节（meminfo 中的单位是 KB）
+		value *= 1024
+
+		switch key {
+		case "MemTotal:":
+			memTotal = value
+		case "MemAvailable:":
+			memAvailable, hasAvailable = value, true
+		case "MemFree:":
+			memFree = value
+		case "Buffers:":
+			buffers = value
+		case "Cached:":
+			cached = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if memTotal == 0 {
+		return fmt.Errorf("无法获取总内存信息")
+	}
+	if !hasAvailable {
+		memAvailableFallback.Do(func() {
+			logger.Info("内核没有提供 MemAvailable，按 MemFree + Buffers + Cached 估算可用内存")
+		})
+		memAvailable = memFree + buffers + cached
+	}
+	memAvailable = min(memAvailable, memTotal)
+
+	stats.TotalMemory = memTotal
+	stats.UsedMemory = memTotal - memAvailable
+	stats.MemoryPercent = float64(stats.UsedMemory) / float64(memTotal) * 100
+
+	return nil
+}
+
+// getCPUStats 从 /proc/stat 获取整机和每个核心的 CPU 使用率
+func getCPUStats(stats *SystemStats) error {
+	file, err := os.Open(procPath("stat"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return fmt.Errorf("无法读取 /proc/stat")
+	}
+
+	line := scanner.Text()
+	fields := strings.Fields(line)
+	if len(fields) < 8 || fields[0] != "cpu" {
+		return fmt.Errorf("无效的 CPU 统计信息")
+	}
+	times := parseCPUTimes(fields)
+
+	// 解析每个核心的 cpuN 行（紧跟在 cpu 行之后）
+	perCPU := make(map[int]cpuTimes)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || !strings.HasPrefix(fields[0], "cpu") {
+			break
+		}
+		core, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			continue
+		}
+		perCPU[core] = parseCPUTimes(fields)
+	}
+
+	selfTicks, err := readSelfCPUTicks()
+	if err != nil {
+		return err
+	}
+
+	now := clock.Now()
+	if lastCPUTime.IsZero() {
+		// 第一次调用，保存状态
+		lastCPUTimes = times
+		lastPerCPUTimes = perCPU
+		lastCPUTime = now
+		lastSelfTicks = selfTicks
+		stats.CPUPercent = 0
+		return nil
+	}
+
+	// 计算时间差
+	elapsed := now.Sub(lastCPUTime).Seconds()
+	if elapsed <= 0 {
+		stats.CPUPercent = 0
+		return nil
+	}
+
+	stats.CPUPercent, stats.StealPercent, stats.IOWaitPercent = cpuUsage(times, lastCPUTimes)
+
+	// 本进程的 CPU 时间与 /proc/stat 单位相同（jiffies），分母与整机使用率一致
+	totalDelta := times.total() - lastCPUTimes.total()
+	if cpuExcludeSteal {
+		totalDelta -= times.steal - lastCPUTimes.steal
+	}
+	if totalDelta > 0 && selfTicks >= lastSelfTicks {
+		stats.SelfCPUPercent = min(float64(selfTicks-lastSelfTicks)/float64(totalDelta)*100, stats.CPUPercent)
+	}
+
+	// 每个核心的使用率（下标为核心编号，离线的核心为 0）
+	maxCore := -1
+	for core := range perCPU {
+		maxCore = max(maxCore, core)
+	}
+	stats.PerCPU = make([]float64, maxCore+1)
+	for core, t := range perCPU {
+		if last, ok := lastPerCPUTimes[core]; ok {
+			stats.PerCPU[core], _, _ = cpuUsage(t, last)
+		}
+	}
+
+	// 更新状态
+	lastCPUTimes = times
+	lastPerCPUTimes = perCPU
+	lastCPUTime = now
+	lastSelfTicks = selfTicks
+
+	return nil
+}
+
+// getSelfStats 计算本进程和其他进程各自贡献的 CPU / 内存使用率（需在读取基础资源信息之后调用）
+func getSelfStats(stats *SystemStats) {
+	stats.SelfMemoryPercent = min(float64(stats.SelfMemory)/float64(stats.TotalMemory)*100, stats.MemoryPercent)
+	stats.OtherCPUPercent = stats.CPUPercent - stats.SelfCPUPercent
+	stats.OtherMemoryPercent = stats.MemoryPercent - stats.SelfMemoryPercent
+}
+
+// parseCPUTimes 解析 /proc/stat 中的一行 CPU 时间（老内核没有 steal/guest 字段，缺失时为 0）
+func parseCPUTimes(fields []string) cpuTimes {
+	var values [10]uint64
+	for i := 1; i < len(fields) && i <= len(values); i++ {
+		val, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[i-1] = val
+	}
+	return cpuTimes{
+		user:      values[0],
+		nice:      values[1],
+		system:    values[2],
+		idle:      values[3],
+		iowait:    values[4],
+		irq:       values[5],
+		softirq:   values[6],
+		steal:     values[7],
+		guest:     values[8],
+		guestNice: values[9],
+	}
+}
+
+// cpuUsage 根据两次采样计算 CPU 使用率、steal 百分比和 iowait 百分比
+func cpuUsage(times, last cpuTimes) (float64, float64, float64) {
+	// 计算空闲时间（idle，默认加上 iowait）
+	iowaitDelta := times.iowait - last.iowait
+	idleDelta := times.idle - last.idle
+	if !cpuIOWaitBusy {
+		idleDelta += iowaitDelta
+	}
+	stealDelta := times.steal - last.steal
+	totalDelta := times.total() - last.total()
+	if totalDelta == 0 {
+		return 0, 0, 0
+	}
+
+	stealPercent := float64(stealDelta) / float64(totalDelta) * 100
+	iowaitPercent := float64(iowaitDelta) / float64(totalDelta) * 100
+
+	// steal 计入忙碌时间（与 top 一致）；排除 steal 时从分母中去掉
+	busyDelta := totalDelta - idleDelta
+	if cpuExcludeSteal {
+		busyDelta -= stealDelta
+		totalDelta -= stealDelta
+		if totalDelta == 0 {
+			return 0, stealPercent, iowaitPercent
+		}
+	}
+
+	// CPU 使用率 = (总时间 - 空闲时间) / 总时间 * 100
+	return float64(busyDelta) / float64(totalDelta) * 100, stealPercent, iowaitPercent
+}
+
+// getLoadAvg 从 /proc/loadavg 获取 1 分钟平均负载
+func getLoadAvg(stats *SystemStats) error {
+	data, err := os.ReadFile(procPath("loadavg"))
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 1 {
+		return fmt.Errorf("无效的平均负载信息")
+	}
+
+	load1, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return err
+	}
+	stats.Load1 = load1
+	return nil
+}
@@ -0,0 +1,193 @@
+package busy
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useProcRoot 让 procfs 解析函数读取 testdata/proc 下的样例目录，测试结束后恢复
+func useProcRoot(t *testing.T, name string) {
+	t.Helper()
+	old := procRoot
+	procRoot = filepath.Join("testdata", "proc", name)
+	t.Cleanup(func() { procRoot = old })
+}
+
+// approxEqual 比较浮点数（百分比保留到 1e-9）
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestGetMemoryStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   uint64
+		used    uint64
+		percent float64
+		wantErr bool
+	}{
+		{name: "modern", total: 16384000 * 1024, used: 8192000 * 1024, percent: 50},
+		// 没有 MemAvailable：按 MemFree + Buffers + Cached 估算可用内存
+		{name: "old-kernel", total: 4096000 * 1024, used: 1792000 * 1024, percent: 43.75},
+		{name: "broken", wantErr: true},
+		{name: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProcRoot(t, tt.name)
+			stats := &SystemStats{}
+			err := getMemoryStats(stats)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("getMemoryStats() = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getMemoryStats() error: %v", err)
+			}
+			if stats.TotalMemory != tt.total || stats.UsedMemory != tt.used || !approxEqual(stats.MemoryPercent, tt.percent) {
+				t.Errorf("got total=%d used=%d percent=%v, want total=%d used=%d percent=%v",
+					stats.TotalMemory, stats.UsedMemory, stats.MemoryPercent, tt.total, tt.used, tt.percent)
+			}
+		})
+	}
+}
+
+func TestGetCPUStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		excludeSteal bool
+		iowaitBusy   bool
+		cpu          float64
+		steal        float64
+		iowait       float64
+		self         float64
+		perCPU       []float64
+	}{
+		// 总时间 2000：user 600、system 300、idle 800、iowait 100、softirq 100、steal 100，本进程 300
+		{name: "modern", cpu: 55, steal: 5, iowait: 5, self: 15, perCPU: []float64{87.5, 6.25}},
+		{name: "modern", excludeSteal: true, cpu: 1000.0 / 1900 * 100, steal: 5, iowait: 5, self: 300.0 / 1900 * 100, perCPU: []float64{1000.0 / 1150 * 100, 0}},
+		{name: "modern", iowaitBusy: true, cpu: 60, steal: 5, iowait: 5, self: 15, perCPU: []float64{1100.0 / 1200 * 100, 12.5}},
+		// 老内核没有 steal / guest 字段：总时间 1000，idle 500、iowait 100，本进程 100
+		{name: "old-kernel", cpu: 40, iowait: 10, self: 10, perCPU: []float64{40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldExclude, oldIOWait := cpuExcludeSteal, cpuIOWaitBusy
+			cpuExcludeSteal, cpuIOWaitBusy = tt.excludeSteal, tt.iowaitBusy
+			lastCPUTime = time.Time{}
+			t.Cleanup(func() {
+				cpuExcludeSteal, cpuIOWaitBusy = oldExclude, oldIOWait
+				lastCPUTime = time.Time{}
+			})
+
+			// 第一次采样只记录基准
+			useProcRoot(t, tt.name)
+			stats := &SystemStats{}
+			if err := getCPUStats(stats); err != nil {
+				t.Fatalf("getCPUStats() error: %v", err)
+			}
+			if stats.CPUPercent != 0 {
+				t.Fatalf("first sample CPUPercent = %v, want 0", stats.CPUPercent)
+			}
+
+			useProcRoot(t, tt.name+"-next")
+			stats = &SystemStats{}
+			if err := getCPUStats(stats); err != nil {
+				t.Fatalf("getCPUStats() error: %v", err)
+			}
+			if !approxEqual(stats.CPUPercent, tt.cpu) || !approxEqual(stats.StealPercent, tt.steal) ||
+				!approxEqual(stats.IOWaitPercent, tt.iowait) || !approxEqual(stats.SelfCPUPercent, tt.self) {
+				t.Errorf("got cpu=%v steal=%v iowait=%v self=%v, want cpu=%v steal=%v iowait=%v self=%v",
+					stats.CPUPercent, stats.StealPercent, stats.IOWaitPercent, stats.SelfCPUPercent,
+					tt.cpu, tt.steal, tt.iowait, tt.self)
+			}
+			if len(stats.PerCPU) != len(tt.perCPU) {
+				t.Fatalf("PerCPU = %v, want %v", stats.PerCPU, tt.perCPU)
+			}
+			for i := range tt.perCPU {
+				if !approxEqual(stats.PerCPU[i], tt.perCPU[i]) {
+					t.Errorf("PerCPU = %v, want %v", stats.PerCPU, tt.perCPU)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGetCPUStatsInvalid(t *testing.T) {
+	for _, name := range []string{"broken", "missing"} {
+		t.Run(name, func(t *testing.T) {
+			useProcRoot(t, name)
+			if err := getCPUStats(&SystemStats{}); err == nil {
+				t.Fatalf("getCPUStats() = nil, want error")
+			}
+		})
+	}
+}
+
+func TestReadSelfMemory(t *testing.T) {
+	tests := []struct {
+		name    string
+		rss     uint64
+		swap    uint64
+		wantErr bool
+	}{
+		{name: "modern", rss: 20480 * 1024, swap: 1024 * 1024},
+		// 老内核没有 VmSwap
+		{name: "old-kernel", rss: 4096 * 1024},
+		{name: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProcRoot(t, tt.name)
+			rss, swap, err := readSelfMemory()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readSelfMemory() = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readSelfMemory() error: %v", err)
+			}
+			if rss != tt.rss || swap != tt.swap {
+				t.Errorf("got rss=%d swap=%d, want rss=%d swap=%d", rss, swap, tt.rss, tt.swap)
+			}
+		})
+	}
+}
+
+func TestGetLoadAvg(t *testing.T) {
+	tests := []struct {
+		name    string
+		load1   float64
+		wantErr bool
+	}{
+		{name: "modern", load1: 1.25},
+		{name: "old-kernel", load1: 0.05},
+		{name: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useProcRoot(t, tt.name)
+			stats := &SystemStats{}
+			err := getLoadAvg(stats)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("getLoadAvg() = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getLoadAvg() error: %v", err)
+			}
+			if stats.Load1 != tt.load1 {
+				t.Errorf("Load1 = %v, want %v", stats.Load1, tt.load1)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package busy
+
+import (
+	"math"
+	"time"
+)
+
+// ExpectedUsageFunc 计算期望占用值的函数
+// now: 当前时间（UTC）；peakUsage: 当前浮动的峰值；stats: 本周期的系统资源信息
+// 返回值会被限制在 [MIN_USAGE, 当前时段的硬峰值] 范围内，NaN 或 Inf 时改用内置算法
+type ExpectedUsageFunc func(now time.Time, peakUsage int, stats *SystemStats) float64
+
+// defaultExpectedUsage 内置的期望占用值计算：凌晨时段使用峰值，其他时段为峰值 × 0.8
+func defaultExpectedUsage(now time.Time, peakUsage int, stats *SystemStats) float64 {
+	return calculateExpectedUsageAt(now, peakUsage)
+}
+
+// configuredExpectedUsage 按 TARGET_EXPR 返回期望占用值计算函数（未设置或无效时使用内置算法）
+func configuredExpectedUsage() ExpectedUsageFunc {
+	src := lookupEnv("TARGET_EXPR")
+	if src == "" {
+		return defaultExpectedUsage
+	}
+	fn, err := newExprExpectedUsage(src)
+	if err != nil {
+		logger.Warn("TARGET_EXPR 无效，使用内置算法", "expr", src, "error", err)
+		return defaultExpectedUsage
+	}
+	logger.Info("使用 TARGET_EXPR 计算期望占用值", "expr", src)
+	return fn
+}
+
+// targetExprVars TARGET_EXPR 中可用的变量
+var targetExprVars = []string{
+	"peak", "peak_origin", "builtin",
+	"hour", "minute", "weekday", "night",
+	"cpu", "memory", "load1", "cpu_cores", "steal", "iowait", "temp",
+	"self_cpu", "other_cpu", "self_memory", "other_memory",
+}
+
+// newExprExpectedUsage 编译 TARGET_EXPR 表达式，返回对应的期望占用值计算函数
+func newExprExpectedUsage(src string) (ExpectedUsageFunc, error) {
+	expr, err := compileExpr(src, targetExprVars)
+	if err != nil {
+		return nil, err
+	}
+	return func(now time.Time, peakUsage int, stats *SystemStats) float64 {
+		return expr(targetExprEnv(now, peakUsage, stats))
+	}, nil
+}
+
+// targetExprEnv 构造表达式求值时的变量
+func targetExprEnv(now time.Time, peakUsage int, stats *SystemStats) map[string]float64 {
+	peakUsageMu.RLock()
+	origin := peakUsageOrigin
+	peakUsageMu.RUnlock()
+
+	now = now.UTC()
+	night := 0.0
+	if isNightTimeAt(now) {
+		night = 1
+	}
+	return map[string]float64{
+		"peak":         float64(peakUsage),
+		"peak_origin":  float64(origin),
+		"builtin":      calculateExpectedUsageAt(now, peakUsage),
+		"hour":         float64(now.Hour()) + float64(now.Minute())/60,
+		"minute":       float64(now.Minute()),
+		"weekday":      float64(now.Weekday()),
+		"night":        night,
+		"cpu":          stats.CPUPercent,
+		"memory":       stats.MemoryPercent,
+		"load1":        stats.Load1,
+		"cpu_cores":    float64(numCPU()),
+		"steal":        stats.StealPercent,
+		"iowait":       stats.IOWaitPercent,
+		"temp":         stats.CPUTemp,
+		"self_cpu":     stats.SelfCPUPercent,
+		"other_cpu":    stats.OtherCPUPercent,
+		"self_memory":  stats.SelfMemoryPercent,
+		"other_memory": stats.OtherMemoryPercent,
+	}
+}
+
+// evalExpectedUsage 调用期望占用值计算函数，结果无效时回退到内置算法，并限制在硬峰值以内
+func evalExpectedUsage(fn ExpectedUsageFunc, peakUsage int, stats *SystemStats) float64 {
+	return evalExpectedUsageAt(fn, clock.Now().UTC(), peakUsage, stats)
+}
+
+// evalExpectedUsageAt 计算指定时间的期望占用值
+func evalExpectedUsageAt(fn ExpectedUsageFunc, now time.Time, peakUsage int, stats *SystemStats) float64 {
+	expectedUsage := fn(now, peakUsage, stats)
+	if math.IsNaN(expectedUsage) || math.IsInf(expectedUsage, 0) {
+		logger.Warn("期望占用值无效，使用内置算法", "value", expectedUsage)
+		expectedUsage = calculateExpectedUsageAt(now, peakUsage)
+	}
+	return min(max(expectedUsage, minUsage), hardPeakAt(now))
+}
@@ -0,0 +1,80 @@
+package busy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cpuThermalTypes 代表 CPU 温度的 thermal zone 类型（按优先级排列）
+var cpuThermalTypes = []string{"x86_pkg_temp", "cpu", "soc", "coretemp"}
+
+// cpuHwmonNames 代表 CPU 温度的 hwmon 驱动名称
+var cpuHwmonNames = []string{"coretemp", "k10temp", "zenpower", "cpu_thermal"}
+
+// readCPUTemperature 读取 CPU 温度（摄氏度）
+// 优先读取 CPU 相关的 thermal zone，其次是 hwmon，都找不到时取所有 thermal zone 的最大值
+func readCPUTemperature() (float64, error) {
+	zones, _ := filepath.Glob("/sys/class/thermal/thermal_zone*")
+
+	// CPU 相关的 thermal zone
+	for _, want := range cpuThermalTypes {
+		for _, zone := range zones {
+			zoneType, err := os.ReadFile(filepath.Join(zone, "type"))
+			if err != nil || !strings.Contains(strings.ToLower(string(zoneType)), want) {
+				continue
+			}
+			if temp, err := readMilliCelsius(filepath.Join(zone, "temp")); err == nil {
+				return temp, nil
+			}
+		}
+	}
+
+	// CPU 相关的 hwmon
+	hwmons, _ := filepath.Glob("/sys/class/hwmon/hwmon*")
+	for _, hwmon := range hwmons {
+		name, err := os.ReadFile(filepath.Join(hwmon, "name"))
+		if err != nil {
+			continue
+		}
+		for _, want := range cpuHwmonNames {
+			if strings.TrimSpace(string(name)) != want {
+				continue
+			}
+			if temp, err := readMilliCelsius(filepath.Join(hwmon, "temp1_input")); err == nil {
+				return temp, nil
+			}
+		}
+	}
+
+	// 所有 thermal zone 的最大值
+	var (
+		maxTemp float64
+		found   bool
+	)
+	for _, zone := range zones {
+		if temp, err := readMilliCelsius(filepath.Join(zone, "temp")); err == nil && (!found || temp > maxTemp) {
+			maxTemp = temp
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("未找到可用的温度传感器")
+	}
+	return maxTemp, nil
+}
+
+// readMilliCelsius 读取以毫摄氏度为单位的温度文件
+func readMilliCelsius(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return value / 1000, nil
+}
@@ -0,0 +1,86 @@
+package busy
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	idleWakeMin = 1 * time.Second // 空闲协程最短唤醒间隔
+	idleWakeMax = 5 * time.Second // 空闲协程最长唤醒间隔
+)
+
+// ThreadController 线程控制器：维持一定数量的（大部分时间在睡眠的）OS 线程和协程
+type ThreadController struct {
+	mu         sync.Mutex
+	threads    []chan struct{} // 每个独占线程的停止信号
+	goroutines []chan struct{} // 每个普通协程的停止信号
+}
+
+var threadController = &ThreadController{}
+
+// SetTargets 设置目标线程数和协程数，并立即调整到目标值
+func (tc *ThreadController) SetTargets(threadTarget, goroutineTarget int) error {
+	if threadTarget < 0 || goroutineTarget < 0 {
+		return fmt.Errorf("目标值不能为负数")
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.threads = resizeIdleWorkers(tc.threads, threadTarget, true)
+	tc.goroutines = resizeIdleWorkers(tc.goroutines, goroutineTarget, false)
+	return nil
+}
+
+// GetCounts 获取当前维持的线程数和协程数
+func (tc *ThreadController) GetCounts() (int, int) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return len(tc.threads), len(tc.goroutines)
+}
+
+// Stop 停止所有线程和协程
+func (tc *ThreadController) Stop() {
+	tc.SetTargets(0, 0)
+}
+
+// resizeIdleWorkers 启动或停止空闲协程，使数量达到目标值
+func resizeIdleWorkers(workers []chan struct{}, target int, lockThread bool) []chan struct{} {
+	for len(workers) < target {
+		stop := make(chan struct{})
+		go idleWorker(stop, lockThread)
+		workers = append(workers, stop)
+	}
+	for len(workers) > target {
+		last := len(workers) - 1
+		close(workers[last])
+		workers = workers[:last]
+	}
+	return workers
+}
+
+// idleWorker 空闲协程：大部分时间在睡眠，每隔几秒醒来做一点点工作
+// lockThread 为 true 时独占一个 OS 线程，退出时不解锁，线程随之销毁
+func idleWorker(stop chan struct{}, lockThread bool) {
+	if lockThread {
+		runtime.LockOSThread()
+	}
+
+	var counter uint64
+	for {
+		wake := idleWakeMin + time.Duration(rand.Int63n(int64(idleWakeMax-idleWakeMin)))
+		select {
+		case <-stop:
+			return
+		case <-time.After(wake):
+			// 少量计算，让线程看上去在处理请求
+			for i := 0; i < 1000; i++ {
+				counter += uint64(i)
+			}
+		}
+	}
+}
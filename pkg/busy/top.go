@@ -0,0 +1,164 @@
+package busy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	topTimeout  = 5 * time.Second // 单次读取内部状态的超时
+	topBarWidth = 20              // 工作协程强度条的宽度
+)
+
+// RunTop 连接运行中 agent 的控制 socket，每隔 interval 刷新显示内部状态（类似 docker stats），直到 ctx 结束
+func RunTop(ctx context.Context, w io.Writer, socket string, interval time.Duration) error {
+	if socket == "" {
+		return errors.New("没有指定控制 socket：设置 CONTROL_SOCKET 或 --socket")
+	}
+	client := &http.Client{
+		Timeout: topTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		internals, err := fetchInternals(ctx, client)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("读取内部状态失败: %w", err)
+		}
+		io.WriteString(w, renderTop(internals, socket))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchInternals 通过控制 socket 读取 GET /internals
+func fetchInternals(ctx context.Context, client *http.Client) (Internals, error) {
+	var internals Internals
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://cpumembusy/internals", nil)
+	if err != nil {
+		return internals, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return internals, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return internals, fmt.Errorf("agent 返回 %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&internals)
+	return internals, err
+}
+
+// renderTop 绘制一帧画面：状态概览、计划、内存缓冲区、各工作协程的强度
+func renderTop(in Internals, socket string) string {
+	var b strings.Builder
+	now := time.Now()
+	st := in.Status
+	b.WriteString("\x1b[H\x1b[2J")
+
+	state := "运行中"
+	if in.Paused {
+		state = "已暂停"
+	}
+	if st.Managed {
+		state += "（controller 托管）"
+	}
+	period := "白天"
+	if st.IsNightTime {
+		period = "凌晨时段"
+	}
+	fmt.Fprintf(&b, "cpumembusy top  %s  %s  %s\n\n", socket, now.Format("15:04:05"), state)
+	fmt.Fprintf(&b, "峰值 %d%%（原始 %d%%）  期望 %s  %s\n", st.PeakUsage, st.PeakUsageOrigin, formatPercent(st.ExpectedUsage), period)
+	fmt.Fprintf(&b, "CPU   整机 %-7s 本进程 %-7s 其他进程 %s\n", formatPercent(st.CPUPercent), formatPercent(st.SelfCPUPercent), formatPercent(st.OtherCPUPercent))
+	fmt.Fprintf(&b, "内存  整机 %-7s 本进程 %-7s 其他进程 %s\n\n", formatPercent(st.MemoryPercent), formatPercent(st.SelfMemoryPercent), formatPercent(st.OtherMemoryPercent))
+
+	if budget := st.Budget; budget != nil {
+		b.WriteString("每日预算（UTC " + budget.Day + "）：\n")
+		if budget.CPUCoreHours > 0 {
+			fmt.Fprintf(&b, "  CPU   已用 %.2f / %.2f 核心·小时  当前允许 %s\n", budget.CPUSpentCoreHours, budget.CPUCoreHours, formatPercent(budget.CPUAllowedPercent))
+		}
+		if budget.MemoryGiBHours > 0 {
+			fmt.Fprintf(&b, "  内存  已用 %.2f / %.2f GiB·小时  当前允许 %s\n", budget.MemorySpentGiBHours, budget.MemoryGiBHours, formatPercent(budget.MemoryAllowedPercent))
+		}
+		b.WriteString("\n")
+	}
+
+	if credits := st.CPUCredits; credits != nil {
+		fmt.Fprintf(&b, "CPU 积分  余额 %.1f（下限 %.1f）  基准 %s  当前允许 %s\n\n", credits.Balance, credits.Floor, formatPercent(credits.BaselinePercent), formatPercent(credits.AllowedPercent))
+	}
+
+	b.WriteString("计划：\n")
+	next := "下次 peakUsage 更新 " + formatTopTime(in.NextPeakUpdate, now)
+	if st.Managed {
+		next += "（托管中，跳过）"
+	}
+	fmt.Fprintf(&b, "  %s\n", next)
+	if in.NextTransition.IsZero() {
+		b.WriteString("  24 小时内期望值不随时段变化\n\n")
+	} else {
+		fmt.Fprintf(&b, "  下次时段切换 %s，期望值 %s → %s\n\n", formatTopTime(in.NextTransition, now), formatPercent(st.ExpectedUsage), formatPercent(in.NextExpectedUsage))
+	}
+
+	limit := "不限制"
+	if in.BufferLimitBytes > 0 {
+		limit = formatMB(in.BufferLimitBytes)
+	}
+	fmt.Fprintf(&b, "内存缓冲区：%d 块，共 %s", in.BufferBlocks, formatMB(in.BufferBytes))
+	if in.BufferBlocks > 0 {
+		fmt.Fprintf(&b, "（块大小 %s - %s）", formatMB(in.BlockMinBytes), formatMB(in.BlockMaxBytes))
+	}
+	fmt.Fprintf(&b, "，上限 %s\n\n", limit)
+
+	fmt.Fprintf(&b, "CPU 工作协程：%d 个，每轮 sleep %s\n", len(in.Workers), in.CPUSleep)
+	b.WriteString("  编号  核心  计算次数    倍数   强度\n")
+	var maxCount uint64
+	for _, worker := range in.Workers {
+		maxCount = max(maxCount, worker.Count)
+	}
+	for i, worker := range in.Workers {
+		core := "-"
+		if worker.Core >= 0 {
+			core = fmt.Sprint(worker.Core)
+		}
+		bar := 0
+		if maxCount > 0 {
+			bar = int(float64(worker.Count) / float64(maxCount) * topBarWidth)
+		}
+		fmt.Fprintf(&b, "  %-4d  %-4s  %-10d  %.2fx  %s\n", i, core, worker.Count, worker.Weight, strings.Repeat("█", bar))
+	}
+	return b.String()
+}
+
+// formatTopTime 输出时间和距离现在的时长，如 "19:10:00（4m12s 后）"
+func formatTopTime(t, now time.Time) string {
+	if t.Unix() <= 0 {
+		return "未知"
+	}
+	return fmt.Sprintf("%s（%s 后）", t.Local().Format("15:04:05"), max(t.Sub(now), 0).Round(time.Second))
+}
+
+// formatMB 以 MB 为单位输出字节数
+func formatMB(bytes uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+}
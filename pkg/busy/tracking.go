@@ -0,0 +1,131 @@
+package busy
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	trackingMaxWindow = 24 * time.Hour      // 跟踪误差保留的最长时间窗口
+	trackingMaxGap    = 4 * monitorInterval // 两次记录之间按时间加权的上限
+)
+
+// trackingWindows 统计跟踪误差的滑动窗口（用于日志和 /metrics）
+var trackingWindows = []struct {
+	name string
+	d    time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"24h", trackingMaxWindow},
+}
+
+// trackSample 一个监控周期的跟踪误差
+type trackSample struct {
+	at     time.Time
+	dt     time.Duration // 距上一个周期的时间（用于按时间加权）
+	cpuErr float64       // |CPU 占用 - 期望值|
+	memErr float64       // |内存占用 - 期望值|
+}
+
+// trackWindow 一个窗口内一类资源的跟踪误差
+type trackWindow struct {
+	MAE    float64 // 平均绝对误差（百分点，按时间加权）
+	InBand float64 // 误差在 ±band 以内的时间占比（0-1）
+}
+
+// TrackingStats 跟踪误差统计：记录每个周期 "参与控制的占用值" 与期望值的差，
+// 按滑动窗口计算平均绝对误差和在范围内的时间占比，用于客观比较不同的控制参数
+type TrackingStats struct {
+	mu      sync.Mutex
+	band    float64 // 视为跟踪上的误差范围（百分点）
+	samples []trackSample
+}
+
+var trackingStats = &TrackingStats{band: 2}
+
+// SetBand 设置视为跟踪上的误差范围（百分点）
+func (ts *TrackingStats) SetBand(band float64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.band = max(band, 0)
+}
+
+// Band 视为跟踪上的误差范围（百分点）
+func (ts *TrackingStats) Band() float64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.band
+}
+
+// Add 记录一个周期的占用值和期望值，丢弃超出最长窗口的记录
+func (ts *TrackingStats) Add(now time.Time, cpuPercent, memoryPercent, expectedUsage float64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	// 长时间没有记录（如停止文件存在期间）时最多按 4 个周期计算
+	dt := monitorInterval
+	if n := len(ts.samples); n > 0 {
+		dt = min(now.Sub(ts.samples[n-1].at), trackingMaxGap)
+	}
+	ts.samples = append(ts.samples, trackSample{
+		at:     now,
+		dt:     dt,
+		cpuErr: math.Abs(cpuPercent - expectedUsage),
+		memErr: math.Abs(memoryPercent - expectedUsage),
+	})
+
+	cutoff := now.Add(-trackingMaxWindow)
+	drop := 0
+	for drop < len(ts.samples) && ts.samples[drop].at.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		ts.samples = append(ts.samples[:0], ts.samples[drop:]...)
+	}
+}
+
+// Window 最近 d 内 CPU 和内存的跟踪误差（没有记录时为零值）
+func (ts *TrackingStats) Window(now time.Time, d time.Duration) (cpu, memory trackWindow) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	cutoff := now.Add(-d)
+	var total, cpuIn, memIn float64
+	for i := len(ts.samples) - 1; i >= 0 && !ts.samples[i].at.Before(cutoff); i-- {
+		s := ts.samples[i]
+		w := s.dt.Seconds()
+		total += w
+		cpu.MAE += s.cpuErr * w
+		memory.MAE += s.memErr * w
+		if s.cpuErr <= ts.band {
+			cpuIn += w
+		}
+		if s.memErr <= ts.band {
+			memIn += w
+		}
+	}
+	if total == 0 {
+		return trackWindow{}, trackWindow{}
+	}
+	cpu.MAE /= total
+	memory.MAE /= total
+	cpu.InBand = cpuIn / total
+	memory.InBand = memIn / total
+	return cpu, memory
+}
+
+// logTracking 输出各窗口的跟踪误差（每小时一次）
+func logTracking(now time.Time) {
+	args := []any{"band", trackingStats.Band()}
+	for _, window := range trackingWindows {
+		cpu, memory := trackingStats.Window(now, window.d)
+		args = append(args,
+			"cpu_mae_"+window.name, cpu.MAE,
+			"cpu_in_band_"+window.name, cpu.InBand,
+			"memory_mae_"+window.name, memory.MAE,
+			"memory_in_band_"+window.name, memory.InBand)
+	}
+	logger.Info("跟踪误差统计", args...)
+}
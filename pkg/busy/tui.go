@@ -0,0 +1,230 @@
+package busy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	tuiRefresh    = time.Second // 界面刷新间隔
+	tuiGaugeWidth = 50          // 仪表的宽度（字符）
+	tuiDecisions  = 6           // 显示最近几个周期的决定
+	tuiLogLines   = 6           // 显示最近几行日志
+	tuiLogWidth   = 150         // 每行日志最多显示的字符数（监控日志很长，超出的部分截断）
+)
+
+// tuiLog 保存最近的日志行（终端界面运行期间日志不直接输出，避免打乱画面）
+type tuiLog struct {
+	mu    sync.Mutex
+	lines []string
+	buf   []byte // 未以换行结束的部分
+}
+
+func (l *tuiLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.lines = append(l.lines, string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+	}
+	if len(l.lines) > tuiLogLines {
+		l.lines = slices.Clone(l.lines[len(l.lines)-tuiLogLines:])
+	}
+	return len(p), nil
+}
+
+// Lines 最近的日志行
+func (l *tuiLog) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.lines)
+}
+
+// RunTUI 启动 controller 并在终端中显示实时状态，直到 ctx 结束或按下 q
+// 仪表显示整机 / 本进程的 CPU 和内存占用与期望值，下方是最近几个周期的调整决定和日志；
+// 按键：p 暂停 / 恢复（与 MQTT 的 pause 命令相同），+ / ↑ 和 - / ↓ 把峰值增加或减少 1 个百分点，q 退出
+func RunTUI(ctx context.Context, c *Controller, in *os.File, out io.Writer) error {
+	restore, err := makeRaw(in)
+	if err != nil {
+		return fmt.Errorf("标准输入不是终端: %w", err)
+	}
+	defer restore()
+
+	logs := &tuiLog{}
+	savedLogger := logger
+	SetLogger(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	if err := c.Start(); err != nil {
+		SetLogger(savedLogger)
+		return err
+	}
+
+	// 备用屏幕，隐藏光标；退出时恢复原来的终端内容，停止过程的日志正常输出
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+		SetLogger(savedLogger)
+		c.Stop()
+	}()
+
+	keys := make(chan byte, 16)
+	go readKeys(in, keys)
+
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+	for {
+		renderTUI(out, logs.Lines())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case key, ok := <-keys:
+			if !ok || key == 'q' || key == 'Q' {
+				return nil
+			}
+			handleTUIKey(key)
+		}
+	}
+}
+
+// handleTUIKey 处理一个按键（方向键已由 readKeys 转换为 + / -）
+func handleTUIKey(key byte) {
+	switch key {
+	case 'p', 'P', ' ':
+		paused := !killSwitch.remote.Load()
+		killSwitch.SetRemote(paused)
+		logger.Info("终端界面暂停 / 恢复", "paused", paused)
+	case '+', '=':
+		nudgePeak(1, "tui")
+	case '-', '_':
+		nudgePeak(-1, "tui")
+	}
+}
+
+// readKeys 逐字节读取按键，把方向键 ↑ / ↓（ESC [ A / ESC [ B）转换为 + / -，读取失败时关闭 keys
+func readKeys(in io.Reader, keys chan<- byte) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		seq := buf[:n]
+		for len(seq) > 0 {
+			if len(seq) >= 3 && seq[0] == 0x1b && seq[1] == '[' {
+				switch seq[2] {
+				case 'A':
+					keys <- '+'
+				case 'B':
+					keys <- '-'
+				}
+				seq = seq[3:]
+				continue
+			}
+			keys <- seq[0]
+			seq = seq[1:]
+		}
+	}
+}
+
+// renderTUI 绘制一帧画面
+func renderTUI(out io.Writer, logs []string) {
+	status := getAgentStatus()
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	state := "运行中"
+	if killSwitch.Active() {
+		state = "已暂停"
+	}
+	if status.Managed {
+		state += "（controller 托管）"
+	}
+	fmt.Fprintf(&b, "cpumembusy  %s  %s  %s\n\n", hostname(), clock.Now().Format("15:04:05"), state)
+	fmt.Fprintf(&b, "峰值 %d%%（原始 %d%%）  期望 %s\n\n", status.PeakUsage, status.PeakUsageOrigin, formatPercent(status.ExpectedUsage))
+	fmt.Fprintf(&b, "CPU   %s  整机 %s  本进程 %s\n", tuiGauge(status.CPUPercent, status.SelfCPUPercent, status.ExpectedUsage), formatPercent(status.CPUPercent), formatPercent(status.SelfCPUPercent))
+	fmt.Fprintf(&b, "内存  %s  整机 %s  本进程 %s\n", tuiGauge(status.MemoryPercent, status.SelfMemoryPercent, status.ExpectedUsage), formatPercent(status.MemoryPercent), formatPercent(status.SelfMemoryPercent))
+	b.WriteString("      █ 本进程  ▒ 其他进程  │ 期望值\n\n")
+
+	b.WriteString("最近的调整决定：\n")
+	for _, entry := range slices.Backward(history.Since(time.Time{}, tuiDecisions)) {
+		fmt.Fprintf(&b, "  %s  %s\n", entry.Time.Format("15:04:05"), formatDecisions(entry))
+	}
+	b.WriteString("\n最近的日志：\n")
+	for _, line := range logs {
+		if runes := []rune(line); len(runes) > tuiLogWidth {
+			line = string(runes[:tuiLogWidth-1]) + "…"
+		}
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+	b.WriteString("\n按键：p 暂停 / 恢复  + / ↑ 峰值 +1%  - / ↓ 峰值 -1%  q 退出\n")
+	io.WriteString(out, b.String())
+}
+
+// tuiGauge 绘制占用仪表：本进程的部分用 █，其他进程用 ▒，期望值的位置用 │ 标出
+func tuiGauge(total, self, expected float64) string {
+	cell := func(p float64) int {
+		return min(max(int(p/100*tuiGaugeWidth+0.5), 0), tuiGaugeWidth)
+	}
+	selfCells, totalCells, mark := cell(self), cell(total), min(cell(expected), tuiGaugeWidth-1)
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := range tuiGaugeWidth {
+		switch {
+		case i == mark:
+			b.WriteString("│")
+		case i < selfCells:
+			b.WriteString("█")
+		case i < totalCells:
+			b.WriteString("▒")
+		default:
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// formatDecisions 按资源名称排序输出一个周期的决定
+func formatDecisions(entry HistoryEntry) string {
+	if entry.KillSwitch {
+		return "已暂停"
+	}
+	if len(entry.Decisions) == 0 {
+		return "无调整"
+	}
+	return joinDecisions(entry.Decisions)
+}
+
+// makeRaw 关闭终端的行缓冲和回显（保留 Ctrl-C 产生 SIGINT 和输出的换行转换），返回恢复原设置的函数
+func makeRaw(f *os.File) (func(), error) {
+	fd := f.Fd()
+	var saved syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCGETS, uintptr(unsafe.Pointer(&saved))); errno != 0 {
+		return nil, errno
+	}
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, fmt.Errorf("设置终端模式失败: %w", errno)
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TCSETS, uintptr(unsafe.Pointer(&saved)))
+	}, nil
+}
@@ -0,0 +1,66 @@
+package busy
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// 版本信息，由 build.sh 通过 -ldflags "-X" 注入
+var (
+	Version = "dev" // 版本号（git describe）
+	Commit  = ""    // 提交哈希（未注入时从构建信息中读取）
+)
+
+// statsBackends 支持的系统资源信息来源
+var statsBackends = []string{"procfs", "cgroup-v2", "sysinfo", "sysfs-thermal", "sysfs-cpufreq", "nvidia-smi"}
+
+// BuildInfo 版本和构建信息
+type BuildInfo struct {
+	Version       string   `json:"version"`
+	Commit        string   `json:"commit"`
+	GoVersion     string   `json:"go_version"`
+	Platform      string   `json:"platform"`
+	StatsBackends []string `json:"stats_backends"`
+}
+
+// GetBuildInfo 获取版本和构建信息
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:       Version,
+		Commit:        Commit,
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOOS + "/" + runtime.GOARCH,
+		StatsBackends: statsBackends,
+	}
+
+	// 未通过 ldflags 注入时，使用 go build 记录的 VCS 信息
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		if info.Commit == "" {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	return info
+}
+
+// PrintVersion 输出版本信息（version 子命令、-version / --version）
+func PrintVersion(w io.Writer) {
+	info := GetBuildInfo()
+	fmt.Fprintf(w, "cpumembusy %s\n", info.Version)
+	fmt.Fprintf(w, "  commit:         %s\n", info.Commit)
+	fmt.Fprintf(w, "  go:             %s\n", info.GoVersion)
+	fmt.Fprintf(w, "  platform:       %s\n", info.Platform)
+	fmt.Fprintf(w, "  stats backends: %s\n", strings.Join(info.StatsBackends, ", "))
+}
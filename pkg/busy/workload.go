@@ -0,0 +1,112 @@
+package busy
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 每个监控周期会以期望占用值（0-100）调用一次 SetIntensity，CPU 超过硬峰值的周期以 0 调用
+// 每个监控周期会以期望占用值（0-100）调用一次 SetIntensity
+type Workload interface {
+	Start() error
+	Stop()
+	SetIntensity(percent float64)
+}
+
+// WorkloadFactory 创建负载模块
+type WorkloadFactory func() Workload
+
+var (
+	workloadRegistry   = make(map[string]WorkloadFactory) // 已注册的负载模块
+	workloadRegistryMu sync.RWMutex                       // 保护 workloadRegistry
+)
+
+// RegisterWorkload 注册负载模块，注册后可以通过 WORKLOADS 环境变量启用（通常在 init 中调用）
+func RegisterWorkload(name string, factory WorkloadFactory) {
+	workloadRegistryMu.Lock()
+	defer workloadRegistryMu.Unlock()
+	workloadRegistry[name] = factory
+}
+
+// newWorkload 按名称创建负载模块
+func newWorkload(name string) (Workload, error) {
+	workloadRegistryMu.RLock()
+	defer workloadRegistryMu.RUnlock()
+
+	factory, ok := workloadRegistry[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的负载模块: %s（可选: %v）", name, workloadNames())
+	}
+	return factory(), nil
+}
+
+// workloadNames 已注册的负载模块名称（调用方需持有读锁）
+func workloadNames() []string {
+	names := make([]string, 0, len(workloadRegistry))
+	for name := range workloadRegistry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	RegisterWorkload("hash", func() Workload { return &hashWorkload{} })
+}
+
+// hashWorkloadWindow hash 负载的占空比周期
+const hashWorkloadWindow = 100 * time.Millisecond
+
+// hashWorkload 示例负载：单个协程循环计算 SHA-256，每个周期内计算的时间占比等于期望占用值
+type hashWorkload struct {
+	intensity atomic.Uint64 // 期望占用值（math.Float64bits）
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// Start 启动计算协程
+func (hw *hashWorkload) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	hw.cancel = cancel
+	hw.done = make(chan struct{})
+	go hw.run(ctx)
+	return nil
+}
+
+// Stop 停止计算协程
+func (hw *hashWorkload) Stop() {
+	hw.cancel()
+	<-hw.done
+}
+
+// SetIntensity 设置期望占用值
+func (hw *hashWorkload) SetIntensity(percent float64) {
+	hw.intensity.Store(math.Float64bits(min(max(percent, 0), 100)))
+}
+
+// run 按占空比计算 SHA-256
+func (hw *hashWorkload) run(ctx context.Context) {
+	defer close(hw.done)
+
+	var block [4096]byte
+	for {
+		active := time.Duration(math.Float64frombits(hw.intensity.Load()) / 100 * float64(hashWorkloadWindow))
+		start := time.Now()
+		for time.Since(start) < active {
+			sum := sha256.Sum256(block[:])
+			copy(block[:], sum[:])
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(hashWorkloadWindow - active):
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package busy
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	httpSelfTick        = 10 * time.Millisecond // 客户端发起请求的调度周期
+	httpSelfStatsPeriod = time.Minute           // 请求统计的输出周期
+)
+
+func init() {
+	RegisterWorkload("http", func() Workload { return newHTTPWorkload() })
+}
+
+// httpWorkload 内置 HTTP 服务端 + 自身客户端：按期望占用值比例产生请求，
+// 处理函数执行合成计算，使连接数、socket 状态和请求形态的 CPU 突发与后台负载同时出现
+type httpWorkload struct {
+	listen    string        // 服务端监听地址
+	maxRPS    float64       // 期望占用值为 100 时的每秒请求数
+	work      time.Duration // 每个请求在处理函数中的计算时间
+	respBytes int           // 响应体大小
+	conns     int           // 最大并发请求数（也是空闲连接池大小）
+	keepAlive bool          // 是否复用连接；关闭时每个请求新建连接，产生大量 TIME_WAIT
+
+	intensity atomic.Uint64 // 期望占用值（math.Float64bits）
+	sent      atomic.Uint64 // 统计周期内完成的请求数
+	failed    atomic.Uint64 // 统计周期内失败的请求数
+	dropped   atomic.Uint64 // 统计周期内因并发已满而丢弃的请求数
+
+	server *http.Server
+	client *http.Client
+	url    string
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newHTTPWorkload() *httpWorkload {
+	return &httpWorkload{
+		listen:    getEnvString("HTTP_SELF_LISTEN", "127.0.0.1:0"),
+		maxRPS:    getEnvFloat("HTTP_SELF_RPS", 100),
+		work:      getEnvDuration("HTTP_SELF_WORK", 2*time.Millisecond),
+		respBytes: getEnvInt("HTTP_SELF_RESPONSE_KB", 4) * 1024,
+		conns:     max(getEnvInt("HTTP_SELF_CONNS", 16), 1),
+		keepAlive: getEnvBool("HTTP_SELF_KEEPALIVE", true),
+	}
+}
+
+// Start 启动服务端和客户端
+func (hw *httpWorkload) Start() error {
+	ln, err := net.Listen("tcp", hw.listen)
+	if err != nil {
+		return err
+	}
+	hw.server = &http.Server{Handler: http.HandlerFunc(hw.handle), ReadHeaderTimeout: 5 * time.Second}
+	hw.url = "http://" + ln.Addr().String() + "/"
+	hw.client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        hw.conns,
+			MaxIdleConnsPerHost: hw.conns,
+			DisableKeepAlives:   !hw.keepAlive,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hw.cancel = cancel
+	hw.wg.Add(2)
+	go func() {
+		defer hw.wg.Done()
+		if err := hw.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn("自身 HTTP 服务端异常退出", "error", err)
+		}
+	}()
+	go hw.run(ctx)
+	logger.Info("自身 HTTP 流量已启动", "url", hw.url, "max_rps", hw.maxRPS, "work", hw.work, "conns", hw.conns, "keepalive", hw.keepAlive)
+	return nil
+}
+
+// Stop 停止客户端并关闭服务端
+func (hw *httpWorkload) Stop() {
+	hw.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hw.server.Shutdown(ctx)
+	hw.wg.Wait()
+	hw.client.CloseIdleConnections()
+}
+
+// SetIntensity 设置期望占用值，请求速率 = HTTP_SELF_RPS × 期望占用值 / 100
+func (hw *httpWorkload) SetIntensity(percent float64) {
+	hw.intensity.Store(math.Float64bits(min(max(percent, 0), 100)))
+}
+
+// handle 处理函数：计算 SHA-256 直到达到设定的计算时间，再返回固定大小的响应
+func (hw *httpWorkload) handle(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	var block [sha256.Size]byte
+	for start := time.Now(); time.Since(start) < hw.work; {
+		block = sha256.Sum256(block[:])
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	body := make([]byte, hw.respBytes)
+	copy(body, block[:])
+	w.Write(body)
+}
+
+// run 每个调度周期按速率发起请求（小数部分累积到下个周期），并发已满时丢弃
+func (hw *httpWorkload) run(ctx context.Context) {
+	defer hw.wg.Done()
+
+	slots := make(chan struct{}, hw.conns)
+	ticker := time.NewTicker(httpSelfTick)
+	defer ticker.Stop()
+	stats := time.NewTicker(httpSelfStatsPeriod)
+	defer stats.Stop()
+
+	var due float64
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stats.C:
+			logger.Info("自身 HTTP 流量统计", "requests", hw.sent.Swap(0), "failed", hw.failed.Swap(0), "dropped", hw.dropped.Swap(0))
+		case <-ticker.C:
+			rps := hw.maxRPS * math.Float64frombits(hw.intensity.Load()) / 100
+			due += rps * httpSelfTick.Seconds()
+			for ; due >= 1; due-- {
+				select {
+				case slots <- struct{}{}:
+					inflight.Add(1)
+					go func() {
+						defer inflight.Done()
+						defer func() { <-slots }()
+						hw.request(ctx)
+					}()
+				default:
+					hw.dropped.Add(1)
+				}
+			}
+		}
+	}
+}
+
+// request 发起一个请求并读完响应体（读完才能复用连接）
+func (hw *httpWorkload) request(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hw.url, nil)
+	if err != nil {
+		hw.failed.Add(1)
+		return
+	}
+	resp, err := hw.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			hw.failed.Add(1)
+		}
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	hw.sent.Add(1)
+}